@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// maxDatacenterID is the highest datacenter ID of the plugin's layout
+const maxDatacenterID = int64(1)<<eonId.DefaultDatacenterBits - 1
+
+func (c *cli) datacenters(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(c.stderr, "Usage: eonid datacenters list|assign")
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		return c.datacentersList(args[1:])
+	case "assign":
+		return c.datacentersAssign(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "eonid: unknown datacenters command %q\n", args[0])
+	return errUsage
+}
+
+// datacenterView is one datacenter name mapping
+type datacenterView struct {
+	Name         string `json:"name"`
+	DatacenterID int64  `json:"datacenter_id"`
+}
+
+func (c *cli) datacentersList(args []string) error {
+	if _, err := c.subcommand("datacenters list", "", args, nil); err != nil {
+		return err
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	datacenters, err := allocator.Datacenters(ctx)
+	if err != nil {
+		return err
+	}
+	views := make([]datacenterView, 0, len(datacenters))
+	for name, datacenterID := range datacenters {
+		views = append(views, datacenterView{Name: name, DatacenterID: datacenterID})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].DatacenterID < views[j].DatacenterID })
+	if c.json {
+		return c.printJSON(views)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DC\tNAME")
+	for _, v := range views {
+		fmt.Fprintf(w, "%d\t%s\n", v.DatacenterID, v.Name)
+	}
+	return w.Flush()
+}
+
+// datacentersAssign maps a new name to a chosen datacenter ID, e.g. the datacenter_id a deployment sets by hand,
+// so that names resolved later do not take it
+func (c *cli) datacentersAssign(args []string) error {
+	args, err := c.subcommand("datacenters assign", "<name> <datacenter-id>", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		fmt.Fprintln(c.stderr, "eonid: expected <name> <datacenter-id>")
+		return errUsage
+	}
+	name := args[0]
+	if err := eonId.ValidateDatacenterName(name); err != nil {
+		return err
+	}
+	datacenterID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid datacenter ID %q", args[1])
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if err := allocator.AssignDatacenter(ctx, name, datacenterID, maxDatacenterID); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "datacenter %q is datacenter ID %d\n", name, datacenterID)
+	return nil
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// futureTolerance is how far past the local clock a timestamp may be before the ID counts as a layout
+// violation; an ID decoded with the wrong layout usually lands decades ahead
+const futureTolerance = time.Minute
+
+// decodeID decodes one textual ID; violation describes why the value is not an ID of the layout
+func decodeID(generator *eonId.Generator, value string, now time.Time) (sid *eonId.SID, violation string) {
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return nil, "not a 64-bit integer"
+	}
+	sid, err = generator.ParseID(id)
+	if err != nil {
+		return nil, err.Error()
+	}
+	sid.Timestamp = sid.Timestamp.UTC()
+	if sid.Timestamp.After(now.Add(futureTolerance)) {
+		return sid, fmt.Sprintf("timestamp %s is in the future", sid.Timestamp.Format(timeFormat))
+	}
+	return sid, ""
+}
+
+// decodedID is one decoded input value; the SID fields are left out when the value is not an ID
+type decodedID struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Value string `json:"value"`
+	*eonId.SID
+	Violation string `json:"violation,omitempty"`
+}
+
+// idReader yields the ID values of one input with their line numbers; io.EOF ends the input
+type idReader func() (value string, line int, err error)
+
+func (c *cli) decode(args []string) error {
+	var layout, format, column string
+	var header, violationsOnly bool
+	args, err := c.subcommand("decode", "[-layout spec] [-format lines|csv|jsonl] [-column col] [-json] [file ...]", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&layout, "layout", defaultLayout, "bit layout: epoch=<Unix ms|date|RFC 3339>,dc=<bits>,worker=<bits>,seq=<bits>")
+		fs.StringVar(&format, "format", "lines", "input format: lines (one ID per line), csv or jsonl")
+		fs.StringVar(&column, "column", "", "column holding the ID: a CSV header name or 1-based index, or a JSONL field (a.b for nested fields)")
+		fs.BoolVar(&header, "header", false, "the first CSV record is a header (implied when -column is a name)")
+		fs.BoolVar(&violationsOnly, "violations", false, "print only the values that violate the layout")
+	})
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "lines":
+	case "csv":
+		if column == "" {
+			column = "1"
+		}
+	case "jsonl":
+		if column == "" {
+			fmt.Fprintln(c.stderr, "eonid: -format jsonl needs -column")
+			return errUsage
+		}
+	default:
+		fmt.Fprintf(c.stderr, "eonid: unknown format %q (want lines, csv or jsonl)\n", format)
+		return errUsage
+	}
+	generator, err := newLayoutGenerator(layout)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	out := newDecodeWriter(c.stdout, c.json)
+	now := time.Now()
+	total, violations := 0, 0
+	for _, name := range args {
+		err := c.withInput(name, func(in io.Reader) error {
+			next, err := newIDReader(in, format, column, header)
+			if err != nil {
+				return err
+			}
+			for {
+				value, line, err := next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				d := decodedID{File: name, Line: line, Value: value}
+				if value == "" {
+					d.Violation = "no ID in column " + column
+				} else {
+					d.SID, d.Violation = decodeID(generator, value, now)
+				}
+				total++
+				if d.Violation != "" {
+					violations++
+				} else if violationsOnly {
+					continue
+				}
+				if err := out.write(d); err != nil {
+					return err
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+	if violations > 0 {
+		return fmt.Errorf("%d of %d value(s) violate the layout %q", violations, total, layout)
+	}
+	return nil
+}
+
+// withInput calls fn with the named file, or stdin for "-"
+func (c *cli) withInput(name string, fn func(io.Reader) error) error {
+	if name == "-" {
+		return fn(c.stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fn(f)
+}
+
+func newIDReader(in io.Reader, format, column string, header bool) (idReader, error) {
+	switch format {
+	case "csv":
+		return newCSVIDReader(in, column, header)
+	case "jsonl":
+		return newJSONLIDReader(in, column), nil
+	}
+	scanner := bufio.NewScanner(in)
+	line := 0
+	return func() (string, int, error) {
+		for scanner.Scan() {
+			line++
+			if value := strings.TrimSpace(scanner.Text()); value != "" {
+				return value, line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", 0, err
+		}
+		return "", 0, io.EOF
+	}, nil
+}
+
+// newCSVIDReader reads the ID column of a CSV input; a column name is looked up in the header record
+func newCSVIDReader(in io.Reader, column string, header bool) (idReader, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	index, err := strconv.Atoi(column)
+	if err != nil {
+		record, err := r.Read()
+		if err == io.EOF {
+			return func() (string, int, error) { return "", 0, io.EOF }, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		index = -1
+		for i, name := range record {
+			if strings.TrimSpace(name) == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("no column %q in the header", column)
+		}
+	} else {
+		if index < 1 {
+			return nil, fmt.Errorf("column index must be 1 or more, got %d", index)
+		}
+		index--
+		if header {
+			if _, err := r.Read(); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+	}
+	return func() (string, int, error) {
+		record, err := r.Read()
+		if err != nil {
+			return "", 0, err
+		}
+		line, _ := r.FieldPos(0)
+		if index >= len(record) {
+			return "", line, nil
+		}
+		return strings.TrimSpace(record[index]), line, nil
+	}, nil
+}
+
+// newJSONLIDReader reads a field of each JSON object; the field may be a number or a string
+func newJSONLIDReader(in io.Reader, field string) idReader {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	path := strings.Split(field, ".")
+	line := 0
+	return func() (string, int, error) {
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			var object map[string]any
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := dec.Decode(&object); err != nil {
+				return "", 0, fmt.Errorf("line %d: %w", line, err)
+			}
+			var value any = object
+			for _, key := range path {
+				m, ok := value.(map[string]any)
+				if !ok {
+					value = nil
+					break
+				}
+				value = m[key]
+			}
+			switch v := value.(type) {
+			case json.Number:
+				return v.String(), line, nil
+			case string:
+				return strings.TrimSpace(v), line, nil
+			case nil:
+				return "", line, nil
+			default:
+				return fmt.Sprint(v), line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", 0, err
+		}
+		return "", 0, io.EOF
+	}
+}
+
+// decodeWriter prints decoded IDs as CSV, or as JSON lines with -json
+type decodeWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newDecodeWriter(w io.Writer, asJSON bool) *decodeWriter {
+	if asJSON {
+		return &decodeWriter{json: json.NewEncoder(w)}
+	}
+	out := &decodeWriter{csv: csv.NewWriter(w)}
+	_ = out.csv.Write([]string{"file", "line", "value", "id", "timestamp", "datacenter_id", "worker_id", "sequence", "violation"})
+	return out
+}
+
+func (w *decodeWriter) write(d decodedID) error {
+	if w.json != nil {
+		return w.json.Encode(d)
+	}
+	record := []string{d.File, strconv.Itoa(d.Line), d.Value, "", "", "", "", "", d.Violation}
+	if d.SID != nil {
+		record[3] = strconv.FormatInt(d.ID, 10)
+		record[4] = d.Timestamp.Format(timeFormat)
+		record[5] = strconv.FormatInt(d.DatacenterID, 10)
+		record[6] = strconv.FormatInt(d.WorkerID, 10)
+		record[7] = strconv.FormatInt(d.Sequence, 10)
+	}
+	return w.csv.Write(record)
+}
+
+func (w *decodeWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
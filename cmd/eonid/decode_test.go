@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// generateIDs runs eonid generate and returns the IDs it printed
+func generateIDs(t *testing.T, args ...string) []int64 {
+	code, out, stderr := runCLI(t, "", append([]string{"generate"}, args...)...)
+	require.Equal(t, 0, code, stderr)
+	var ids []int64
+	for _, line := range strings.Fields(out) {
+		id, err := strconv.ParseInt(line, 10, 64)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestGenerate(t *testing.T) {
+	ids := generateIDs(t, "-layout", "worker=8,seq=9", "-dc", "3", "-worker", "200", "-n", "1000")
+	require.Len(t, ids, 1000)
+	generator, err := newLayoutGenerator("worker=8,seq=9")
+	require.NoError(t, err)
+	for i, id := range ids {
+		if i > 0 {
+			require.Greater(t, id, ids[i-1], "IDs increase")
+		}
+		sid, err := generator.ParseID(id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), sid.DatacenterID)
+		assert.Equal(t, int64(200), sid.WorkerID)
+		assert.WithinDuration(t, time.Now(), sid.Timestamp, time.Minute)
+	}
+
+	code, out, stderr := runCLI(t, "", "generate", "-json", "-worker", "7")
+	require.Equal(t, 0, code, stderr)
+	var sid eonId.SID
+	require.NoError(t, json.Unmarshal([]byte(out), &sid))
+	assert.Equal(t, int64(7), sid.WorkerID)
+	assert.Equal(t, time.UTC, sid.Timestamp.Location())
+
+	code, _, stderr = runCLI(t, "", "generate", "-worker", "32")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "worker ID must be between 0 and 31")
+	code, _, _ = runCLI(t, "", "generate", "-n", "0")
+	assert.Equal(t, 2, code)
+}
+
+func TestDecode(t *testing.T) {
+	ids := generateIDs(t, "-dc", "2", "-worker", "5", "-n", "3")
+	id := func(i int) string { return strconv.FormatInt(ids[i], 10) }
+
+	t.Run("lines", func(t *testing.T) {
+		code, out, stderr := runCLI(t, id(0)+"\n\n  "+id(1)+"  \n"+id(2)+"\n", "decode")
+		require.Equal(t, 0, code, stderr)
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, []string{"file", "line", "value", "id", "timestamp", "datacenter_id", "worker_id", "sequence", "violation"}, records[0])
+		assert.Equal(t, []string{"-", "3", id(1)}, records[2][:3], "blank lines count towards line numbers")
+		assert.Equal(t, []string{id(1), "2", "5"}, []string{records[2][3], records[2][5], records[2][6]})
+		assert.Empty(t, records[2][8])
+	})
+
+	t.Run("csv column name", func(t *testing.T) {
+		input := "name,\"order_id\"\nfirst," + id(0) + "\n\"second, quoted\",\"" + id(1) + "\"\n"
+		code, out, stderr := runCLI(t, input, "decode", "-format", "csv", "-column", "order_id", "-json")
+		require.Equal(t, 0, code, stderr)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 2)
+		var d struct {
+			Line         int    `json:"line"`
+			ID           int64  `json:"id"`
+			DatacenterID int64  `json:"datacenter_id"`
+			Violation    string `json:"violation"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &d))
+		assert.Equal(t, 3, d.Line)
+		assert.Equal(t, ids[1], d.ID)
+		assert.Equal(t, int64(2), d.DatacenterID)
+		assert.Empty(t, d.Violation)
+
+		code, _, stderr = runCLI(t, input, "decode", "-format", "csv", "-column", "missing")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, `no column "missing"`)
+	})
+
+	t.Run("csv column index from files", func(t *testing.T) {
+		dir := t.TempDir()
+		first := filepath.Join(dir, "a.csv")
+		second := filepath.Join(dir, "b.csv")
+		require.NoError(t, os.WriteFile(first, []byte("id,n\n"+id(0)+",1\n"), 0o644))
+		require.NoError(t, os.WriteFile(second, []byte("id,n\n"+id(2)+",3\n"), 0o644))
+		code, out, stderr := runCLI(t, "", "decode", "-format", "csv", "-column", "1", "-header", first, second)
+		require.Equal(t, 0, code, stderr)
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{first, "2", id(0)}, records[1][:3])
+		assert.Equal(t, []string{second, "2", id(2)}, records[2][:3])
+	})
+
+	t.Run("jsonl nested field", func(t *testing.T) {
+		input := `{"order":{"id":` + id(0) + `}}` + "\n" + `{"order":{"id":"` + id(1) + `"}}` + "\n" + `{"order":{}}` + "\n"
+		code, out, stderr := runCLI(t, input, "decode", "-format", "jsonl", "-column", "order.id")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "1 of 3 value(s) violate the layout")
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, id(0), records[1][3], "large numbers keep every digit")
+		assert.Equal(t, id(1), records[2][3])
+		assert.Equal(t, "no ID in column order.id", records[3][8])
+
+		code, _, _ = runCLI(t, input, "decode", "-format", "jsonl")
+		assert.Equal(t, 2, code, "jsonl needs -column")
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		input := strings.Join([]string{id(0), "-5", "12ab", id(1)}, "\n")
+		code, out, stderr := runCLI(t, input, "decode", "-violations")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "2 of 4 value(s) violate the layout")
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, "-5", records[1][2])
+		assert.Contains(t, records[1][8], "invalid snowflake ID")
+		assert.Equal(t, "not a 64-bit integer", records[2][8])
+
+		// IDs decoded with a layout with fewer low bits land far in the future
+		code, out, _ = runCLI(t, id(0), "decode", "-layout", "seq=7", "-json")
+		assert.Equal(t, 1, code)
+		var d decodedID
+		require.NoError(t, json.Unmarshal([]byte(out), &d))
+		assert.Contains(t, d.Violation, "is in the future")
+		require.NotNil(t, d.SID)
+	})
+}
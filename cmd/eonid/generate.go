@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// generate issues IDs locally, without claiming the worker ID anywhere. IDs from a worker ID that a running
+// instance also uses may collide with that instance's IDs.
+func (c *cli) generate(args []string) error {
+	var layout string
+	var datacenterID, workerID int64
+	var count int
+	args, err := c.subcommand("generate", "[-layout spec] [-dc id] [-worker id] [-n count] [-json]", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&layout, "layout", defaultLayout, "bit layout: epoch=<Unix ms|date|RFC 3339>,dc=<bits>,worker=<bits>,seq=<bits>")
+		fs.Int64Var(&datacenterID, "dc", 0, "datacenter ID")
+		fs.Int64Var(&workerID, "worker", 0, "worker ID")
+		fs.IntVar(&count, "n", 1, "number of IDs")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || count < 1 {
+		fmt.Fprintln(c.stderr, "Usage: eonid generate [-layout spec] [-dc id] [-worker id] [-n count] [-json]")
+		return errUsage
+	}
+	config, err := parseLayout(layout)
+	if err != nil {
+		return err
+	}
+	generator, err := eonId.NewSnowflakeGeneratorCore(datacenterID, workerID, config)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(c.stdout)
+	enc := json.NewEncoder(out)
+	for i := 0; i < count; i++ {
+		id, sid, err := generator.GenerateIDWithMetadata()
+		if err != nil {
+			return err
+		}
+		if c.json {
+			sid.Timestamp = sid.Timestamp.UTC()
+			err = enc.Encode(sid)
+		} else {
+			_, err = out.WriteString(strconv.FormatInt(id, 10) + "\n")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
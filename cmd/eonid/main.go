@@ -0,0 +1,183 @@
+// Command eonid inspects and manages the eon-id worker registry in Redis and decodes eon-id IDs.
+//
+// Usage:
+//
+//	eonid [connection flags] workers list [-json]
+//	eonid [connection flags] workers inspect <datacenter-id> <worker-id>
+//	eonid [connection flags] workers evict [-yes] <datacenter-id> <worker-id>
+//	eonid [connection flags] workers revoke [-yes] [-reason text] <datacenter-id> <worker-id>
+//	eonid [connection flags] counter reset [-yes]
+//	eonid [connection flags] datacenters list [-json]
+//	eonid [connection flags] datacenters assign <name> <datacenter-id>
+//	eonid parse [-layout spec] [-json] <id>
+//	eonid decode [-layout spec] [-format lines|csv|jsonl] [-column col] [-violations] [-json] [file ...]
+//	eonid generate [-layout spec] [-dc id] [-worker id] [-n count] [-json]
+//
+// Keys are named as by the plugin: -prefix is normalized like redis_key_prefix, and -key-layout must match
+// redis_key_layout. parse, decode and generate work offline; -layout describes the bit layout of the IDs.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// timeFormat prints times in UTC with milliseconds, the resolution of IDs
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// errUsage is returned for invalid arguments; the usage has already been printed
+var errUsage = errors.New("invalid usage")
+
+// cli holds the global flags and the streams of one invocation
+type cli struct {
+	addr      string
+	username  string
+	password  string
+	db        int
+	cluster   bool
+	prefix    string
+	keyLayout string
+	timeout   time.Duration
+	json      bool
+
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("eonid", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.addr, "addr", "localhost:6379", "Redis address; several comma-separated addresses connect to a cluster")
+	fs.StringVar(&c.username, "username", "", "Redis username")
+	fs.StringVar(&c.password, "password", os.Getenv("EONID_REDIS_PASSWORD"), "Redis password (default $EONID_REDIS_PASSWORD)")
+	fs.IntVar(&c.db, "db", 0, "Redis database")
+	fs.BoolVar(&c.cluster, "cluster", false, "connect to a Redis Cluster through a single address")
+	fs.StringVar(&c.prefix, "prefix", eonId.DefaultRedisKeyPrefix, "redis_key_prefix of the plugin")
+	fs.StringVar(&c.keyLayout, "key-layout", eonId.RedisKeyLayoutLegacy, "redis_key_layout of the plugin: legacy or cluster")
+	fs.DurationVar(&c.timeout, "timeout", 5*time.Second, "timeout of each command")
+	fs.BoolVar(&c.json, "json", false, "print JSON instead of a table")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: eonid [flags] <command>
+
+Commands:
+  workers list                       list registered workers with their remaining TTL
+  workers inspect <dc> <worker-id>   show everything stored about one worker ID
+  workers evict <dc> <worker-id>     delete the claim on a worker ID (asks for confirmation)
+  workers revoke <dc> <worker-id>    make the owner of a worker ID stop issuing IDs and release it
+  counter reset                      restart probing registration at worker ID 0
+  datacenters list                   list datacenter names with their datacenter IDs
+  datacenters assign <name> <dc>     map a new datacenter name to a chosen datacenter ID
+  parse <id>                         decode an ID (-layout describes the bit layout)
+  decode [file ...]                  decode IDs from lines, CSV or JSONL and report layout violations
+  generate                           issue IDs locally for a datacenter and worker ID
+
+Flags:`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := eonId.ValidateRedisKeyLayout(c.keyLayout); err != nil {
+		fmt.Fprintln(stderr, "eonid:", err)
+		return 2
+	}
+
+	var err error
+	switch command := fs.Arg(0); command {
+	case "workers":
+		err = c.workers(fs.Args()[1:])
+	case "counter":
+		err = c.counter(fs.Args()[1:])
+	case "datacenters":
+		err = c.datacenters(fs.Args()[1:])
+	case "parse":
+		err = c.parse(fs.Args()[1:])
+	case "decode":
+		err = c.decode(fs.Args()[1:])
+	case "generate":
+		err = c.generate(fs.Args()[1:])
+	case "":
+		fs.Usage()
+		return 2
+	default:
+		fmt.Fprintf(stderr, "eonid: unknown command %q\n", command)
+		fs.Usage()
+		return 2
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "eonid:", err)
+		return 1
+	}
+	return 0
+}
+
+// subcommand parses the flags of a subcommand; -json may be given before or after the subcommand
+func (c *cli) subcommand(name, usage string, args []string, setup func(fs *flag.FlagSet)) ([]string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", c.json, "print JSON instead of a table")
+	if setup != nil {
+		setup(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: eonid %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+// allocator connects to Redis; the caller closes the returned client
+func (c *cli) allocator() (*eonId.RedisWorkerIDAllocator, redis.UniversalClient) {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:         strings.Split(c.addr, ","),
+		Username:      c.username,
+		Password:      c.password,
+		DB:            c.db,
+		IsClusterMode: c.cluster,
+	})
+	return eonId.NewRedisWorkerIDAllocator(client, &eonId.RedisWorkerIDAllocatorConfig{
+		KeyPrefix: c.prefix,
+		KeyLayout: c.keyLayout,
+	}), client
+}
+
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// confirm asks a yes/no question on stdin; anything but "y" or "yes" is no
+func (c *cli) confirm(question string) bool {
+	fmt.Fprintf(c.stderr, "%s [y/N] ", question)
+	var answer string
+	_, _ = fmt.Fscanln(c.stdin, &answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// newRegistry starts a Redis with two claims in datacenter 1 and returns the eonid flags to reach it
+func newRegistry(t *testing.T) (*eonId.RedisWorkerIDAllocator, []string) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	allocator := eonId.NewRedisWorkerIDAllocator(client, &eonId.RedisWorkerIDAllocatorConfig{KeyPrefix: "test"})
+	ctx := context.Background()
+	for _, instance := range []string{"inst-a", "inst-b"} {
+		info, err := allocator.Acquire(ctx, eonId.WorkerInfo{
+			WorkerID: -1, DatacenterID: 1, InstanceID: instance, ServiceName: "orders", ServiceVersion: "v2",
+			RegisterTime: time.Now().UnixMilli(),
+		}, 31, time.Minute)
+		require.NoError(t, err)
+		info.LastTimestamp = 1_700_000_000_000
+		require.NoError(t, allocator.Renew(ctx, *info, time.Minute))
+	}
+	return allocator, []string{"-addr", mr.Addr(), "-prefix", "test"}
+}
+
+func runCLI(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestWorkersList(t *testing.T) {
+	_, flags := newRegistry(t)
+
+	code, out, stderr := runCLI(t, "", append(flags, "workers", "list")...)
+	require.Equal(t, 0, code, stderr)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "INSTANCE")
+	assert.Contains(t, lines[1], "inst-a")
+	assert.Contains(t, lines[1], "orders@v2")
+	assert.Contains(t, lines[2], "inst-b")
+
+	code, out, stderr = runCLI(t, "", append(flags, "workers", "list", "-json")...)
+	require.Equal(t, 0, code, stderr)
+	var views []workerView
+	require.NoError(t, json.Unmarshal([]byte(out), &views))
+	require.Len(t, views, 2)
+	assert.Equal(t, "inst-a", views[0].Claim.InstanceID)
+	assert.Greater(t, views[0].TTLMillis, int64(50_000))
+	assert.True(t, views[0].Registered)
+	assert.Equal(t, "2023-11-14T22:13:20.000Z", views[0].LastIssued)
+}
+
+func TestWorkersInspect(t *testing.T) {
+	_, flags := newRegistry(t)
+
+	code, out, stderr := runCLI(t, "", append(flags, "workers", "inspect", "1", "0")...)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "inst-a")
+	assert.Contains(t, out, "last issued     2023-11-14T22:13:20.000Z")
+
+	code, out, stderr = runCLI(t, "", append(flags, "-json", "workers", "inspect", "1", "9")...)
+	require.Equal(t, 0, code, stderr)
+	var view workerView
+	require.NoError(t, json.Unmarshal([]byte(out), &view))
+	assert.Nil(t, view.Claim)
+	assert.False(t, view.Registered)
+
+	code, _, _ = runCLI(t, "", append(flags, "workers", "inspect", "1")...)
+	assert.Equal(t, 2, code)
+	code, _, stderr = runCLI(t, "", append(flags, "workers", "inspect", "x", "1")...)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid datacenter ID")
+}
+
+func TestWorkersEvict(t *testing.T) {
+	allocator, flags := newRegistry(t)
+	ctx := context.Background()
+
+	code, out, _ := runCLI(t, "n\n", append(flags, "workers", "evict", "1", "0")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "aborted")
+	record, err := allocator.InspectWorker(ctx, 1, 0)
+	require.NoError(t, err)
+	require.NotNil(t, record.Claim)
+
+	code, out, stderr := runCLI(t, "y\n", append(flags, "workers", "evict", "1", "0")...)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "held by inst-a")
+	assert.Contains(t, out, "evicted worker ID 0 in datacenter 1 from inst-a")
+	record, err = allocator.InspectWorker(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Nil(t, record.Claim)
+	assert.False(t, record.Registered)
+	assert.Equal(t, int64(1_700_000_000_000), record.LastTimestamp, "the mark survives eviction")
+
+	code, out, _ = runCLI(t, "", append(flags, "workers", "evict", "-yes", "1", "0")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "not claimed")
+}
+
+func TestWorkersRevoke(t *testing.T) {
+	allocator, flags := newRegistry(t)
+	ctx := context.Background()
+
+	code, out, stderr := runCLI(t, "", append(flags, "workers", "revoke", "-yes", "-reason", "wedged", "-by", "ops", "1", "0")...)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "revoked worker ID 0 in datacenter 1 from inst-a")
+	record, err := allocator.InspectWorker(ctx, 1, 0)
+	require.NoError(t, err)
+	require.NotNil(t, record.Claim)
+	require.NotNil(t, record.Claim.Revocation)
+	assert.Equal(t, "ops", record.Claim.Revocation.By)
+	assert.Equal(t, "wedged", record.Claim.Revocation.Reason)
+
+	code, out, _ = runCLI(t, "", append(flags, "workers", "inspect", "1", "0")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "revoke reason   wedged")
+	code, out, _ = runCLI(t, "", append(flags, "workers", "list")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "inst-a (revoked)")
+	assert.NotContains(t, out, "inst-b (revoked)")
+
+	code, out, _ = runCLI(t, "", append(flags, "workers", "revoke", "-yes", "1", "0")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "already revoked by ops")
+	code, out, _ = runCLI(t, "", append(flags, "workers", "revoke", "-yes", "1", "9")...)
+	require.Equal(t, 0, code)
+	assert.Contains(t, out, "not claimed")
+}
+
+func TestDatacenters(t *testing.T) {
+	allocator, flags := newRegistry(t)
+	ctx := context.Background()
+
+	code, out, stderr := runCLI(t, "", append(flags, "datacenters", "assign", "legacy-dc", "0")...)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, `datacenter "legacy-dc" is datacenter ID 0`)
+	datacenterID, err := allocator.ResolveDatacenter(ctx, "us-east-1", 31)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), datacenterID, "names resolved later skip assigned IDs")
+
+	code, out, stderr = runCLI(t, "", append(flags, "datacenters", "list")...)
+	require.Equal(t, 0, code, stderr)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"0", "legacy-dc"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"1", "us-east-1"}, strings.Fields(lines[2]))
+
+	code, _, stderr = runCLI(t, "", append(flags, "datacenters", "assign", "eu-west-1", "1")...)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "already mapped to another name")
+	code, _, stderr = runCLI(t, "", append(flags, "datacenters", "assign", "us-east-1", "4")...)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "already mapped to datacenter ID 1")
+	code, _, _ = runCLI(t, "", append(flags, "datacenters", "assign", "us-east-1", "1")...)
+	assert.Equal(t, 0, code, "assigning a mapping again is not an error")
+	code, _, _ = runCLI(t, "", append(flags, "datacenters", "assign", "ap-south-1", "32")...)
+	assert.Equal(t, 1, code)
+}
+
+func TestCounterReset(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set("test:dc:1:counter", "7"))
+	require.NoError(t, mr.Set("test:dc:12:counter", "3"))
+	require.NoError(t, mr.Set("test:dc:1:worker:3", "{}"))
+	flags := []string{"-addr", mr.Addr(), "-prefix", "test"}
+
+	code, out, stderr := runCLI(t, "", append(flags, "counter", "reset", "-yes")...)
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "reset 2 counter(s)\n", out)
+	assert.False(t, mr.Exists("test:dc:1:counter"))
+	assert.False(t, mr.Exists("test:dc:12:counter"))
+	assert.True(t, mr.Exists("test:dc:1:worker:3"))
+}
+
+func TestParse(t *testing.T) {
+	generator, err := newLayoutGenerator("worker=8,seq=9")
+	require.NoError(t, err)
+	id, err := eonId.NewSnowflakeGeneratorCore(3, 200, mustLayout(t, "worker=8,seq=9"))
+	require.NoError(t, err)
+	value, err := id.GenerateID()
+	require.NoError(t, err)
+	want, err := generator.ParseID(value)
+	require.NoError(t, err)
+
+	code, _, _ := runCLI(t, "", "parse")
+	assert.Equal(t, 2, code)
+	code, _, stderr := runCLI(t, "", "parse", "abc")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid ID")
+
+	code, out, stderr := runCLI(t, "", "parse", "-layout", "worker=8,seq=9", "-json", jsonNumber(value))
+	require.Equal(t, 0, code, stderr)
+	var sid eonId.SID
+	require.NoError(t, json.Unmarshal([]byte(out), &sid))
+	assert.Equal(t, int64(3), sid.DatacenterID)
+	assert.Equal(t, int64(200), sid.WorkerID)
+	assert.Equal(t, want.Sequence, sid.Sequence)
+
+	code, out, stderr = runCLI(t, "", "parse", jsonNumber(value))
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, out, "datacenter")
+
+	code, _, stderr = runCLI(t, "", "parse", "-layout", "worker=20,seq=20", "1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid layout")
+}
+
+func TestParseLayout(t *testing.T) {
+	config, err := parseLayout(defaultLayout)
+	require.NoError(t, err)
+	assert.Equal(t, int64(eonId.DefaultEpoch), config.CustomEpoch)
+	assert.Equal(t, 5, config.DatacenterIDBits)
+
+	config, err = parseLayout("epoch=2024-01-01T00:00:00Z, worker=10 ,seq=7")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(), config.CustomEpoch)
+	assert.Equal(t, 10, config.WorkerIDBits)
+	assert.Equal(t, 7, config.SequenceBits)
+
+	for _, spec := range []string{"worker", "bits=3", "seq=x", "epoch=yesterday"} {
+		_, err := parseLayout(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func mustLayout(t *testing.T, spec string) *eonId.GeneratorConfig {
+	config, err := parseLayout(spec)
+	require.NoError(t, err)
+	return config
+}
+
+func jsonNumber(v int64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+// defaultLayout is the plugin's default bit layout
+const defaultLayout = "epoch=2021-01-01,dc=5,worker=5,seq=12"
+
+// parseLayout reads a bit layout "epoch=<Unix ms|date|RFC 3339>,dc=<bits>,worker=<bits>,seq=<bits>" into a
+// generator configuration; keys that are left out keep their default. epoch, worker and seq match custom_epoch,
+// worker_id_bits and sequence_bits of the plugin configuration; the plugin always uses 5 datacenter bits.
+func parseLayout(spec string) (*eonId.GeneratorConfig, error) {
+	config := eonId.DefaultGeneratorConfig()
+	config.EnableMetrics = false
+	config.EnableClockDriftProtection = false
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid layout %q: expected key=value, got %q", spec, part)
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "epoch":
+			config.CustomEpoch, err = parseEpoch(strings.TrimSpace(value))
+		case "dc":
+			config.DatacenterIDBits, err = strconv.Atoi(strings.TrimSpace(value))
+		case "worker":
+			config.WorkerIDBits, err = strconv.Atoi(strings.TrimSpace(value))
+		case "seq":
+			config.SequenceBits, err = strconv.Atoi(strings.TrimSpace(value))
+		default:
+			return nil, fmt.Errorf("invalid layout %q: unknown key %q (want epoch, dc, worker or seq)", spec, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid layout %q: %s: %w", spec, key, err)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", spec, err)
+	}
+	return config, nil
+}
+
+// parseEpoch reads an epoch as Unix milliseconds, a date (2006-01-02, UTC) or an RFC 3339 time
+func parseEpoch(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.UnixMilli(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, fmt.Errorf("expected Unix ms, a date or an RFC 3339 time, got %q", value)
+	}
+	return t.UnixMilli(), nil
+}
+
+// newLayoutGenerator returns a generator for decoding IDs of a layout; it never issues IDs
+func newLayoutGenerator(spec string) (*eonId.Generator, error) {
+	config, err := parseLayout(spec)
+	if err != nil {
+		return nil, err
+	}
+	return eonId.NewSnowflakeGeneratorCore(0, 0, config)
+}
+
+func (c *cli) parse(args []string) error {
+	var layout string
+	args, err := c.subcommand("parse", "[-layout spec] [-json] <id>", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&layout, "layout", defaultLayout, "bit layout: epoch=<Unix ms|date|RFC 3339>,dc=<bits>,worker=<bits>,seq=<bits>")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(c.stderr, "eonid: expected one ID")
+		return errUsage
+	}
+	generator, err := newLayoutGenerator(layout)
+	if err != nil {
+		return err
+	}
+	sid, violation := decodeID(generator, args[0], time.Now())
+	if sid == nil {
+		return fmt.Errorf("invalid ID %q: %s", args[0], violation)
+	}
+	if violation != "" {
+		fmt.Fprintf(c.stderr, "eonid: warning: %s; check the layout\n", violation)
+	}
+	if c.json {
+		return c.printJSON(sid)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "id\t%d\n", sid.ID)
+	fmt.Fprintf(w, "timestamp\t%s\n", sid.Timestamp.Format(timeFormat))
+	fmt.Fprintf(w, "datacenter\t%d\n", sid.DatacenterID)
+	fmt.Fprintf(w, "worker\t%d\n", sid.WorkerID)
+	fmt.Fprintf(w, "sequence\t%d\n", sid.Sequence)
+	return w.Flush()
+}
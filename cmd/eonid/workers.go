@@ -0,0 +1,354 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	eonId "github.com/go-lynx/lynx-eon-id"
+)
+
+func (c *cli) workers(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(c.stderr, "Usage: eonid workers list|inspect|evict|revoke")
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		return c.workersList(args[1:])
+	case "inspect":
+		return c.workersInspect(args[1:])
+	case "evict":
+		return c.workersEvict(args[1:])
+	case "revoke":
+		return c.workersRevoke(args[1:])
+	}
+	fmt.Fprintf(c.stderr, "eonid: unknown workers command %q\n", args[0])
+	return errUsage
+}
+
+func (c *cli) workersList(args []string) error {
+	if _, err := c.subcommand("workers list", "", args, nil); err != nil {
+		return err
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	records, err := allocator.Workers(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].DatacenterID != records[j].DatacenterID {
+			return records[i].DatacenterID < records[j].DatacenterID
+		}
+		return records[i].WorkerID < records[j].WorkerID
+	})
+	if c.json {
+		views := make([]workerView, 0, len(records))
+		for _, r := range records {
+			views = append(views, newWorkerView(r))
+		}
+		return c.printJSON(views)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DC\tWORKER\tINSTANCE\tSERVICE\tIP\tTTL\tLAST HEARTBEAT\tUPTIME")
+	now := time.Now()
+	for _, r := range records {
+		if r.Claim == nil {
+			continue // expired between List and the record read
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", r.DatacenterID, r.WorkerID, instanceLabel(r.Claim),
+			serviceLabel(r.Claim), r.Claim.IP, r.TTL.Round(time.Second), ago(now, r.LastHeartbeat),
+			ago(now, r.Claim.RegisterTime))
+	}
+	return w.Flush()
+}
+
+func (c *cli) workersInspect(args []string) error {
+	args, err := c.subcommand("workers inspect", "<datacenter-id> <worker-id>", args, nil)
+	if err != nil {
+		return err
+	}
+	datacenterID, workerID, err := c.workerArgs(args)
+	if err != nil {
+		return err
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	record, err := allocator.InspectWorker(ctx, datacenterID, workerID)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(newWorkerView(*record))
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	now := time.Now()
+	if record.Claim != nil && record.Claim.DatacenterName != "" {
+		fmt.Fprintf(w, "datacenter\t%d (%s)\n", record.DatacenterID, record.Claim.DatacenterName)
+	} else {
+		fmt.Fprintf(w, "datacenter\t%d\n", record.DatacenterID)
+	}
+	fmt.Fprintf(w, "worker\t%d\n", record.WorkerID)
+	if record.Claim == nil {
+		fmt.Fprintf(w, "claim\tnone (free)\n")
+	} else {
+		fmt.Fprintf(w, "instance\t%s\n", record.Claim.InstanceID)
+		fmt.Fprintf(w, "service\t%s\n", serviceLabel(record.Claim))
+		fmt.Fprintf(w, "ip\t%s\n", record.Claim.IP)
+		fmt.Fprintf(w, "registered at\t%s\n", formatUnixMilli(record.Claim.RegisterTime))
+		fmt.Fprintf(w, "ttl\t%s\n", record.TTL.Round(time.Millisecond))
+		if r := record.Claim.Revocation; r != nil {
+			fmt.Fprintf(w, "revoked\t%s by %s\n", formatUnixMilli(r.At), r.By)
+			if r.Reason != "" {
+				fmt.Fprintf(w, "revoke reason\t%s\n", r.Reason)
+			}
+		}
+	}
+	if record.Registered {
+		fmt.Fprintf(w, "last heartbeat\t%s (%s ago)\n", formatUnixMilli(record.LastHeartbeat), ago(now, record.LastHeartbeat))
+	} else {
+		fmt.Fprintf(w, "registry\tnot listed\n")
+	}
+	if record.LastTimestamp > 0 {
+		fmt.Fprintf(w, "last issued\t%s\n", formatUnixMilli(record.LastTimestamp))
+	} else {
+		fmt.Fprintf(w, "last issued\tnever recorded\n")
+	}
+	return w.Flush()
+}
+
+func (c *cli) workersEvict(args []string) error {
+	var yes bool
+	args, err := c.subcommand("workers evict", "[-yes] <datacenter-id> <worker-id>", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&yes, "yes", false, "do not ask for confirmation")
+	})
+	if err != nil {
+		return err
+	}
+	datacenterID, workerID, err := c.workerArgs(args)
+	if err != nil {
+		return err
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	record, err := allocator.InspectWorker(ctx, datacenterID, workerID)
+	if err != nil {
+		return err
+	}
+	if record.Claim == nil {
+		fmt.Fprintf(c.stdout, "worker ID %d in datacenter %d is not claimed\n", workerID, datacenterID)
+		return nil
+	}
+	if !yes {
+		question := fmt.Sprintf("Evict worker ID %d in datacenter %d held by %s (last heartbeat %s ago)? "+
+			"A running instance will register again.",
+			workerID, datacenterID, record.Claim.InstanceID, ago(time.Now(), record.LastHeartbeat))
+		if !c.confirm(question) {
+			fmt.Fprintln(c.stdout, "aborted")
+			return nil
+		}
+	}
+
+	// The confirmation may have taken longer than the timeout
+	ctx, cancel = c.context()
+	defer cancel()
+	evicted, err := allocator.EvictWorker(ctx, datacenterID, workerID)
+	if err != nil {
+		return err
+	}
+	if evicted == nil {
+		fmt.Fprintf(c.stdout, "worker ID %d in datacenter %d is not claimed\n", workerID, datacenterID)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "evicted worker ID %d in datacenter %d from %s\n", workerID, datacenterID, evicted.InstanceID)
+	return nil
+}
+
+func (c *cli) workersRevoke(args []string) error {
+	var yes bool
+	var reason, by string
+	args, err := c.subcommand("workers revoke", "[-yes] [-reason text] [-by name] <datacenter-id> <worker-id>", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&yes, "yes", false, "do not ask for confirmation")
+		fs.StringVar(&reason, "reason", "", "why the worker ID is revoked, recorded with the revocation")
+		fs.StringVar(&by, "by", defaultRevoker(), "who revokes the worker ID, recorded with the revocation")
+	})
+	if err != nil {
+		return err
+	}
+	datacenterID, workerID, err := c.workerArgs(args)
+	if err != nil {
+		return err
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	record, err := allocator.InspectWorker(ctx, datacenterID, workerID)
+	if err != nil {
+		return err
+	}
+	if record.Claim == nil {
+		fmt.Fprintf(c.stdout, "worker ID %d in datacenter %d is not claimed\n", workerID, datacenterID)
+		return nil
+	}
+	if r := record.Claim.Revocation; r != nil {
+		fmt.Fprintf(c.stdout, "worker ID %d in datacenter %d was already revoked by %s at %s\n", workerID, datacenterID,
+			r.By, formatUnixMilli(r.At))
+		return nil
+	}
+	if !yes {
+		question := fmt.Sprintf("Revoke worker ID %d in datacenter %d held by %s (last heartbeat %s ago)? "+
+			"The instance stops issuing IDs at its next heartbeat.",
+			workerID, datacenterID, record.Claim.InstanceID, ago(time.Now(), record.LastHeartbeat))
+		if !c.confirm(question) {
+			fmt.Fprintln(c.stdout, "aborted")
+			return nil
+		}
+	}
+
+	ctx, cancel = c.context()
+	defer cancel()
+	revoked, err := allocator.RevokeWorker(ctx, datacenterID, workerID, eonId.WorkerRevocation{By: by, Reason: reason})
+	if err != nil {
+		return err
+	}
+	if revoked == nil {
+		fmt.Fprintf(c.stdout, "worker ID %d in datacenter %d is not claimed\n", workerID, datacenterID)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "revoked worker ID %d in datacenter %d from %s; it is released at the next heartbeat or "+
+		"when the claim expires\n", workerID, datacenterID, revoked.InstanceID)
+	return nil
+}
+
+// defaultRevoker names the operator in revocations: eonid, the OS user and the host
+func defaultRevoker() string {
+	by := "eonid"
+	if u, err := user.Current(); err == nil {
+		by += " " + u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		by += "@" + host
+	}
+	return by
+}
+
+func (c *cli) counter(args []string) error {
+	if len(args) == 0 || args[0] != "reset" {
+		fmt.Fprintln(c.stderr, "Usage: eonid counter reset [-yes]")
+		return errUsage
+	}
+	var yes bool
+	if _, err := c.subcommand("counter reset", "[-yes]", args[1:], func(fs *flag.FlagSet) {
+		fs.BoolVar(&yes, "yes", false, "do not ask for confirmation")
+	}); err != nil {
+		return err
+	}
+	if !yes && !c.confirm("Reset the registration counter of every datacenter?") {
+		fmt.Fprintln(c.stdout, "aborted")
+		return nil
+	}
+	allocator, client := c.allocator()
+	defer client.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	deleted, err := allocator.ResetCounters(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "reset %d counter(s)\n", deleted)
+	return nil
+}
+
+// workerArgs parses "<datacenter-id> <worker-id>"
+func (c *cli) workerArgs(args []string) (datacenterID, workerID int64, err error) {
+	if len(args) != 2 {
+		fmt.Fprintln(c.stderr, "eonid: expected <datacenter-id> <worker-id>")
+		return 0, 0, errUsage
+	}
+	if datacenterID, err = strconv.ParseInt(args[0], 10, 64); err != nil || datacenterID < 0 {
+		return 0, 0, fmt.Errorf("invalid datacenter ID %q", args[0])
+	}
+	if workerID, err = strconv.ParseInt(args[1], 10, 64); err != nil || workerID < 0 {
+		return 0, 0, fmt.Errorf("invalid worker ID %q", args[1])
+	}
+	return datacenterID, workerID, nil
+}
+
+// workerView is the JSON form of a worker record, with readable times
+type workerView struct {
+	DatacenterID  int64             `json:"datacenter_id"`
+	WorkerID      int64             `json:"worker_id"`
+	Claim         *eonId.WorkerInfo `json:"claim"`
+	TTL           string            `json:"ttl,omitempty"`
+	TTLMillis     int64             `json:"ttl_ms"`
+	Registered    bool              `json:"registered"`
+	LastHeartbeat string            `json:"last_heartbeat,omitempty"`
+	LastIssued    string            `json:"last_issued,omitempty"`
+}
+
+func newWorkerView(r eonId.RedisWorkerRecord) workerView {
+	v := workerView{
+		DatacenterID: r.DatacenterID,
+		WorkerID:     r.WorkerID,
+		Claim:        r.Claim,
+		TTLMillis:    r.TTL.Milliseconds(),
+		Registered:   r.Registered,
+	}
+	if r.Claim != nil {
+		v.TTL = r.TTL.Round(time.Millisecond).String()
+	}
+	if r.Registered {
+		v.LastHeartbeat = formatUnixMilli(r.LastHeartbeat)
+	}
+	if r.LastTimestamp > 0 {
+		v.LastIssued = formatUnixMilli(r.LastTimestamp)
+	}
+	return v
+}
+
+// instanceLabel is the instance ID, marked when the claim has been revoked
+func instanceLabel(info *eonId.WorkerInfo) string {
+	if info.Revocation != nil {
+		return info.InstanceID + " (revoked)"
+	}
+	return info.InstanceID
+}
+
+func serviceLabel(info *eonId.WorkerInfo) string {
+	if info.ServiceVersion == "" {
+		return info.ServiceName
+	}
+	return info.ServiceName + "@" + info.ServiceVersion
+}
+
+func formatUnixMilli(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format(timeFormat)
+}
+
+// ago formats the time since a Unix ms timestamp; "-" when there is none
+func ago(now time.Time, ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return now.Sub(time.UnixMilli(ms)).Round(time.Second).String()
+}
@@ -32,16 +32,45 @@ type EonId struct {
 	// —— Basic Configuration ——
 	// Data center ID (0-31)
 	DatacenterId int32 `protobuf:"varint,1,opt,name=datacenter_id,json=datacenterId,proto3" json:"datacenter_id,omitempty"`
+	// Region or zone name resolved to a datacenter ID at startup through the worker ID allocator's registry; the
+	// first instance with a new name allocates the lowest free ID. Replaces datacenter_id.
+	DatacenterName string `protobuf:"bytes,46,opt,name=datacenter_name,json=datacenterName,proto3" json:"datacenter_name,omitempty"`
+	// Environment variable holding the datacenter name; when it is set and not empty it wins over datacenter_name
+	DatacenterNameEnv string `protobuf:"bytes,47,opt,name=datacenter_name_env,json=datacenterNameEnv,proto3" json:"datacenter_name_env,omitempty"`
 	// Worker ID (0-1023), if not set, will auto-register via Redis
 	WorkerId int32 `protobuf:"varint,2,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
 	// Enable auto worker ID registration via Redis
 	AutoRegisterWorkerId bool `protobuf:"varint,3,opt,name=auto_register_worker_id,json=autoRegisterWorkerId,proto3" json:"auto_register_worker_id,omitempty"`
 	// Redis key prefix for worker ID registration
 	RedisKeyPrefix string `protobuf:"bytes,4,opt,name=redis_key_prefix,json=redisKeyPrefix,proto3" json:"redis_key_prefix,omitempty"`
+	// Redis key layout: "legacy" (default) or "cluster", which hash-tags the keys of each datacenter into one
+	// Redis Cluster slot so claim, registry and release run as single atomic scripts
+	RedisKeyLayout string `protobuf:"bytes,42,opt,name=redis_key_layout,json=redisKeyLayout,proto3" json:"redis_key_layout,omitempty"`
+	// Copy legacy layout claims and last-timestamp marks into the cluster layout at startup
+	RedisMigrateKeyLayout bool `protobuf:"varint,43,opt,name=redis_migrate_key_layout,json=redisMigrateKeyLayout,proto3" json:"redis_migrate_key_layout,omitempty"`
+	// How often the worker registry is trimmed of expired claims (default: 1m; negative disables)
+	RegistryJanitorInterval *durationpb.Duration `protobuf:"bytes,44,opt,name=registry_janitor_interval,json=registryJanitorInterval,proto3" json:"registry_janitor_interval,omitempty"`
 	// Worker ID registration TTL (default: 30s)
 	WorkerIdTtl *durationpb.Duration `protobuf:"bytes,5,opt,name=worker_id_ttl,json=workerIdTtl,proto3" json:"worker_id_ttl,omitempty"`
 	// Worker ID heartbeat interval (default: 10s)
 	HeartbeatInterval *durationpb.Duration `protobuf:"bytes,6,opt,name=heartbeat_interval,json=heartbeatInterval,proto3" json:"heartbeat_interval,omitempty"`
+	// Backend that stores worker ID claims when auto_register_worker_id is enabled:
+	// "redis" (default), "etcd", "zookeeper", "kubernetes-lease", "statefulset", "sql", "file" or "memory" (single process only)
+	WorkerIdAllocator string `protobuf:"bytes,22,opt,name=worker_id_allocator,json=workerIdAllocator,proto3" json:"worker_id_allocator,omitempty"`
+	// Added to the last timestamp the previous owner of a reused worker ID issued; no ID is issued before the
+	// clock passes it (default: 1s)
+	WorkerReuseSafetyMargin *durationpb.Duration `protobuf:"bytes,38,opt,name=worker_reuse_safety_margin,json=workerReuseSafetyMargin,proto3" json:"worker_reuse_safety_margin,omitempty"`
+	// Subtracted from worker_id_ttl for the local lease deadline; IDs are refused once no renewal has succeeded
+	// within worker_id_ttl minus this margin, capped at a third of the TTL (default: 2s)
+	WorkerLeaseSafetyMargin *durationpb.Duration `protobuf:"bytes,39,opt,name=worker_lease_safety_margin,json=workerLeaseSafetyMargin,proto3" json:"worker_lease_safety_margin,omitempty"`
+	// When the worker ID lease is lost, a new worker ID is registered with up to this many attempts
+	// (default: 10; negative disables recovery)
+	WorkerRecoveryMaxAttempts int32 `protobuf:"varint,40,opt,name=worker_recovery_max_attempts,json=workerRecoveryMaxAttempts,proto3" json:"worker_recovery_max_attempts,omitempty"`
+	// Wait before the second recovery attempt, doubled after every failure up to 30s (default: 1s)
+	WorkerRecoveryBackoff *durationpb.Duration `protobuf:"bytes,41,opt,name=worker_recovery_backoff,json=workerRecoveryBackoff,proto3" json:"worker_recovery_backoff,omitempty"`
+	// What to do after an operator has revoked the worker ID: "reregister" registers a new worker ID like after a
+	// lost lease, "fence" refuses IDs until restart (default: reregister)
+	WorkerRevokeAction string `protobuf:"bytes,45,opt,name=worker_revoke_action,json=workerRevokeAction,proto3" json:"worker_revoke_action,omitempty"`
 	// —— Clock Drift Protection ——
 	// Enable clock drift protection
 	EnableClockDriftProtection bool `protobuf:"varint,7,opt,name=enable_clock_drift_protection,json=enableClockDriftProtection,proto3" json:"enable_clock_drift_protection,omitempty"`
@@ -58,6 +87,48 @@ type EonId struct {
 	SequenceCacheSize int32 `protobuf:"varint,12,opt,name=sequence_cache_size,json=sequenceCacheSize,proto3" json:"sequence_cache_size,omitempty"`
 	// Enable metrics collection
 	EnableMetrics bool `protobuf:"varint,13,opt,name=enable_metrics,json=enableMetrics,proto3" json:"enable_metrics,omitempty"`
+	// What to do when the sequence is exhausted within one millisecond:
+	// "spin" (busy-wait), "sleep" (sleep until the next tick, default), "error" (fail fast with SequenceOverflowError)
+	SequenceOverflowStrategy string `protobuf:"bytes,20,opt,name=sequence_overflow_strategy,json=sequenceOverflowStrategy,proto3" json:"sequence_overflow_strategy,omitempty"`
+	// Starting sequence of each millisecond: "zero" (default), "random" or "round_robin".
+	// Non-zero starts spread low-traffic IDs evenly across id % N shards.
+	SequenceStartMode string `protobuf:"bytes,21,opt,name=sequence_start_mode,json=sequenceStartMode,proto3" json:"sequence_start_mode,omitempty"`
+	// —— etcd Integration (worker_id_allocator: "etcd") ——
+	// Name of the shared resource that provides the *clientv3.Client (default: "etcd")
+	EtcdPluginName string `protobuf:"bytes,23,opt,name=etcd_plugin_name,json=etcdPluginName,proto3" json:"etcd_plugin_name,omitempty"`
+	// Key prefix for worker ID claims (default: "/lynx/eon-id/")
+	EtcdKeyPrefix string `protobuf:"bytes,24,opt,name=etcd_key_prefix,json=etcdKeyPrefix,proto3" json:"etcd_key_prefix,omitempty"`
+	// —— ZooKeeper Integration (worker_id_allocator: "zookeeper") ——
+	// Name of the shared resource that provides the *zk.Conn (default: "zookeeper")
+	ZookeeperPluginName string `protobuf:"bytes,25,opt,name=zookeeper_plugin_name,json=zookeeperPluginName,proto3" json:"zookeeper_plugin_name,omitempty"`
+	// Root znode for worker ID claims (default: "/lynx/eon-id")
+	ZookeeperKeyPrefix string `protobuf:"bytes,26,opt,name=zookeeper_key_prefix,json=zookeeperKeyPrefix,proto3" json:"zookeeper_key_prefix,omitempty"`
+	// —— Kubernetes Integration (worker_id_allocator: "kubernetes-lease" or "statefulset") ——
+	// Name of the shared resource that provides the kubernetes.Interface (default: "kubernetes")
+	KubernetesPluginName string `protobuf:"bytes,27,opt,name=kubernetes_plugin_name,json=kubernetesPluginName,proto3" json:"kubernetes_plugin_name,omitempty"`
+	// Namespace of the worker ID Leases (default: POD_NAMESPACE, then the service account namespace)
+	KubernetesNamespace string `protobuf:"bytes,28,opt,name=kubernetes_namespace,json=kubernetesNamespace,proto3" json:"kubernetes_namespace,omitempty"`
+	// Prefix of the worker ID Lease names, a lowercase DNS label (default: "eon-id")
+	KubernetesLeasePrefix string `protobuf:"bytes,29,opt,name=kubernetes_lease_prefix,json=kubernetesLeasePrefix,proto3" json:"kubernetes_lease_prefix,omitempty"`
+	// Environment variable holding the pod name or pod index for "statefulset" (default: "POD_NAME",
+	// falling back to the hostname)
+	KubernetesOrdinalEnv string `protobuf:"bytes,30,opt,name=kubernetes_ordinal_env,json=kubernetesOrdinalEnv,proto3" json:"kubernetes_ordinal_env,omitempty"`
+	// —— SQL Integration (worker_id_allocator: "sql") ——
+	// Name of the shared resource that provides the *sql.DB (default: "sql")
+	SqlPluginName string `protobuf:"bytes,31,opt,name=sql_plugin_name,json=sqlPluginName,proto3" json:"sql_plugin_name,omitempty"`
+	// "mysql" (default), "postgres" or "sqlite"
+	SqlDialect string `protobuf:"bytes,32,opt,name=sql_dialect,json=sqlDialect,proto3" json:"sql_dialect,omitempty"`
+	// Worker node table, see schema/worker_node_<dialect>.sql (default: "WORKER_NODE")
+	SqlTableName string `protobuf:"bytes,33,opt,name=sql_table_name,json=sqlTableName,proto3" json:"sql_table_name,omitempty"`
+	// How long an expired row keeps its worker ID before it can be reused (default: 1m)
+	SqlSafetyDelay *durationpb.Duration `protobuf:"bytes,34,opt,name=sql_safety_delay,json=sqlSafetyDelay,proto3" json:"sql_safety_delay,omitempty"`
+	// —— File Lock Integration (worker_id_allocator: "file") ——
+	// Directory of the flock slot files, one per worker ID (default: "/var/run/eon-id")
+	FileLockDir string `protobuf:"bytes,35,opt,name=file_lock_dir,json=fileLockDir,proto3" json:"file_lock_dir,omitempty"`
+	// First worker ID of this host; give each host a range that does not overlap with the others
+	FileLockBaseOffset int64 `protobuf:"varint,36,opt,name=file_lock_base_offset,json=fileLockBaseOffset,proto3" json:"file_lock_base_offset,omitempty"`
+	// Number of worker IDs from the base offset this host may claim (default: up to the max worker ID)
+	FileLockSlots int32 `protobuf:"varint,37,opt,name=file_lock_slots,json=fileLockSlots,proto3" json:"file_lock_slots,omitempty"`
 	// —— Redis Integration ——
 	// Redis plugin name to use for worker ID registration
 	RedisPluginName string `protobuf:"bytes,14,opt,name=redis_plugin_name,json=redisPluginName,proto3" json:"redis_plugin_name,omitempty"`
@@ -69,9 +140,12 @@ type EonId struct {
 	// Worker ID bits (default: 10, range: 1-20)
 	WorkerIdBits int32 `protobuf:"varint,17,opt,name=worker_id_bits,json=workerIdBits,proto3" json:"worker_id_bits,omitempty"`
 	// Sequence bits (default: 12, range: 1-20)
-	SequenceBits  int32 `protobuf:"varint,18,opt,name=sequence_bits,json=sequenceBits,proto3" json:"sequence_bits,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	SequenceBits int32 `protobuf:"varint,18,opt,name=sequence_bits,json=sequenceBits,proto3" json:"sequence_bits,omitempty"`
+	// —— ULID ——
+	// Entropy source for ULID generation: "crypto" (default) or "fast"
+	UlidEntropySource string `protobuf:"bytes,19,opt,name=ulid_entropy_source,json=ulidEntropySource,proto3" json:"ulid_entropy_source,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *EonId) Reset() {
@@ -111,6 +185,20 @@ func (x *EonId) GetDatacenterId() int32 {
 	return 0
 }
 
+func (x *EonId) GetDatacenterName() string {
+	if x != nil {
+		return x.DatacenterName
+	}
+	return ""
+}
+
+func (x *EonId) GetDatacenterNameEnv() string {
+	if x != nil {
+		return x.DatacenterNameEnv
+	}
+	return ""
+}
+
 func (x *EonId) GetWorkerId() int32 {
 	if x != nil {
 		return x.WorkerId
@@ -132,6 +220,27 @@ func (x *EonId) GetRedisKeyPrefix() string {
 	return ""
 }
 
+func (x *EonId) GetRedisKeyLayout() string {
+	if x != nil {
+		return x.RedisKeyLayout
+	}
+	return ""
+}
+
+func (x *EonId) GetRedisMigrateKeyLayout() bool {
+	if x != nil {
+		return x.RedisMigrateKeyLayout
+	}
+	return false
+}
+
+func (x *EonId) GetRegistryJanitorInterval() *durationpb.Duration {
+	if x != nil {
+		return x.RegistryJanitorInterval
+	}
+	return nil
+}
+
 func (x *EonId) GetWorkerIdTtl() *durationpb.Duration {
 	if x != nil {
 		return x.WorkerIdTtl
@@ -146,6 +255,48 @@ func (x *EonId) GetHeartbeatInterval() *durationpb.Duration {
 	return nil
 }
 
+func (x *EonId) GetWorkerIdAllocator() string {
+	if x != nil {
+		return x.WorkerIdAllocator
+	}
+	return ""
+}
+
+func (x *EonId) GetWorkerReuseSafetyMargin() *durationpb.Duration {
+	if x != nil {
+		return x.WorkerReuseSafetyMargin
+	}
+	return nil
+}
+
+func (x *EonId) GetWorkerLeaseSafetyMargin() *durationpb.Duration {
+	if x != nil {
+		return x.WorkerLeaseSafetyMargin
+	}
+	return nil
+}
+
+func (x *EonId) GetWorkerRecoveryMaxAttempts() int32 {
+	if x != nil {
+		return x.WorkerRecoveryMaxAttempts
+	}
+	return 0
+}
+
+func (x *EonId) GetWorkerRecoveryBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.WorkerRecoveryBackoff
+	}
+	return nil
+}
+
+func (x *EonId) GetWorkerRevokeAction() string {
+	if x != nil {
+		return x.WorkerRevokeAction
+	}
+	return ""
+}
+
 func (x *EonId) GetEnableClockDriftProtection() bool {
 	if x != nil {
 		return x.EnableClockDriftProtection
@@ -195,6 +346,125 @@ func (x *EonId) GetEnableMetrics() bool {
 	return false
 }
 
+func (x *EonId) GetSequenceOverflowStrategy() string {
+	if x != nil {
+		return x.SequenceOverflowStrategy
+	}
+	return ""
+}
+
+func (x *EonId) GetSequenceStartMode() string {
+	if x != nil {
+		return x.SequenceStartMode
+	}
+	return ""
+}
+
+func (x *EonId) GetEtcdPluginName() string {
+	if x != nil {
+		return x.EtcdPluginName
+	}
+	return ""
+}
+
+func (x *EonId) GetEtcdKeyPrefix() string {
+	if x != nil {
+		return x.EtcdKeyPrefix
+	}
+	return ""
+}
+
+func (x *EonId) GetZookeeperPluginName() string {
+	if x != nil {
+		return x.ZookeeperPluginName
+	}
+	return ""
+}
+
+func (x *EonId) GetZookeeperKeyPrefix() string {
+	if x != nil {
+		return x.ZookeeperKeyPrefix
+	}
+	return ""
+}
+
+func (x *EonId) GetKubernetesPluginName() string {
+	if x != nil {
+		return x.KubernetesPluginName
+	}
+	return ""
+}
+
+func (x *EonId) GetKubernetesNamespace() string {
+	if x != nil {
+		return x.KubernetesNamespace
+	}
+	return ""
+}
+
+func (x *EonId) GetKubernetesLeasePrefix() string {
+	if x != nil {
+		return x.KubernetesLeasePrefix
+	}
+	return ""
+}
+
+func (x *EonId) GetKubernetesOrdinalEnv() string {
+	if x != nil {
+		return x.KubernetesOrdinalEnv
+	}
+	return ""
+}
+
+func (x *EonId) GetSqlPluginName() string {
+	if x != nil {
+		return x.SqlPluginName
+	}
+	return ""
+}
+
+func (x *EonId) GetSqlDialect() string {
+	if x != nil {
+		return x.SqlDialect
+	}
+	return ""
+}
+
+func (x *EonId) GetSqlTableName() string {
+	if x != nil {
+		return x.SqlTableName
+	}
+	return ""
+}
+
+func (x *EonId) GetSqlSafetyDelay() *durationpb.Duration {
+	if x != nil {
+		return x.SqlSafetyDelay
+	}
+	return nil
+}
+
+func (x *EonId) GetFileLockDir() string {
+	if x != nil {
+		return x.FileLockDir
+	}
+	return ""
+}
+
+func (x *EonId) GetFileLockBaseOffset() int64 {
+	if x != nil {
+		return x.FileLockBaseOffset
+	}
+	return 0
+}
+
+func (x *EonId) GetFileLockSlots() int32 {
+	if x != nil {
+		return x.FileLockSlots
+	}
+	return 0
+}
+
 func (x *EonId) GetRedisPluginName() string {
 	if x != nil {
 		return x.RedisPluginName
@@ -230,18 +500,36 @@ func (x *EonId) GetSequenceBits() int32 {
 	return 0
 }
 
+func (x *EonId) GetUlidEntropySource() string {
+	if x != nil {
+		return x.UlidEntropySource
+	}
+	return ""
+}
+
 var File_eon_id_proto protoreflect.FileDescriptor
 
 const file_eon_id_proto_rawDesc = "" +
 	"\n" +
-	"\feon-id.proto\x12\x1alynx.protobuf.plugin.eonId\x1a\x1egoogle/protobuf/duration.proto\"\xf5\x06\n" +
+	"\feon-id.proto\x12\x1alynx.protobuf.plugin.eonId\x1a\x1egoogle/protobuf/duration.proto\"\x8e\x13\n" +
 	"\x06eon_id\x12#\n" +
-	"\rdatacenter_id\x18\x01 \x01(\x05R\fdatacenterId\x12\x1b\n" +
+	"\rdatacenter_id\x18\x01 \x01(\x05R\fdatacenterId\x12'\n" +
+	"\x0fdatacenter_name\x18. \x01(\tR\x0edatacenterName\x12.\n" +
+	"\x13datacenter_name_env\x18/ \x01(\tR\x11datacenterNameEnv\x12\x1b\n" +
 	"\tworker_id\x18\x02 \x01(\x05R\bworkerId\x125\n" +
 	"\x17auto_register_worker_id\x18\x03 \x01(\bR\x14autoRegisterWorkerId\x12(\n" +
-	"\x10redis_key_prefix\x18\x04 \x01(\tR\x0eredisKeyPrefix\x12=\n" +
+	"\x10redis_key_prefix\x18\x04 \x01(\tR\x0eredisKeyPrefix\x12(\n" +
+	"\x10redis_key_layout\x18* \x01(\tR\x0eredisKeyLayout\x127\n" +
+	"\x18redis_migrate_key_layout\x18+ \x01(\bR\x15redisMigrateKeyLayout\x12U\n" +
+	"\x19registry_janitor_interval\x18, \x01(\v2\x19.google.protobuf.DurationR\x17registryJanitorInterval\x12=\n" +
 	"\rworker_id_ttl\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\vworkerIdTtl\x12H\n" +
-	"\x12heartbeat_interval\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x11heartbeatInterval\x12A\n" +
+	"\x12heartbeat_interval\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x11heartbeatInterval\x12.\n" +
+	"\x13worker_id_allocator\x18\x16 \x01(\tR\x11workerIdAllocator\x12V\n" +
+	"\x1aworker_reuse_safety_margin\x18& \x01(\v2\x19.google.protobuf.DurationR\x17workerReuseSafetyMargin\x12V\n" +
+	"\x1aworker_lease_safety_margin\x18' \x01(\v2\x19.google.protobuf.DurationR\x17workerLeaseSafetyMargin\x12?\n" +
+	"\x1cworker_recovery_max_attempts\x18( \x01(\x05R\x19workerRecoveryMaxAttempts\x12Q\n" +
+	"\x17worker_recovery_backoff\x18) \x01(\v2\x19.google.protobuf.DurationR\x15workerRecoveryBackoff\x120\n" +
+	"\x14worker_revoke_action\x18- \x01(\tR\x12workerRevokeAction\x12A\n" +
 	"\x1denable_clock_drift_protection\x18\a \x01(\bR\x1aenableClockDriftProtection\x12A\n" +
 	"\x0fmax_clock_drift\x18\b \x01(\v2\x19.google.protobuf.DurationR\rmaxClockDrift\x12K\n" +
 	"\x14clock_check_interval\x18\t \x01(\v2\x19.google.protobuf.DurationR\x12clockCheckInterval\x12,\n" +
@@ -249,12 +537,31 @@ const file_eon_id_proto_rawDesc = "" +
 	" \x01(\tR\x10clockDriftAction\x122\n" +
 	"\x15enable_sequence_cache\x18\v \x01(\bR\x13enableSequenceCache\x12.\n" +
 	"\x13sequence_cache_size\x18\f \x01(\x05R\x11sequenceCacheSize\x12%\n" +
-	"\x0eenable_metrics\x18\r \x01(\bR\renableMetrics\x12*\n" +
+	"\x0eenable_metrics\x18\r \x01(\bR\renableMetrics\x12<\n" +
+	"\x1asequence_overflow_strategy\x18\x14 \x01(\tR\x18sequenceOverflowStrategy\x12.\n" +
+	"\x13sequence_start_mode\x18\x15 \x01(\tR\x11sequenceStartMode\x12(\n" +
+	"\x10etcd_plugin_name\x18\x17 \x01(\tR\x0eetcdPluginName\x12&\n" +
+	"\x0fetcd_key_prefix\x18\x18 \x01(\tR\retcdKeyPrefix\x122\n" +
+	"\x15zookeeper_plugin_name\x18\x19 \x01(\tR\x13zookeeperPluginName\x120\n" +
+	"\x14zookeeper_key_prefix\x18\x1a \x01(\tR\x12zookeeperKeyPrefix\x124\n" +
+	"\x16kubernetes_plugin_name\x18\x1b \x01(\tR\x14kubernetesPluginName\x121\n" +
+	"\x14kubernetes_namespace\x18\x1c \x01(\tR\x13kubernetesNamespace\x126\n" +
+	"\x17kubernetes_lease_prefix\x18\x1d \x01(\tR\x15kubernetesLeasePrefix\x124\n" +
+	"\x16kubernetes_ordinal_env\x18\x1e \x01(\tR\x14kubernetesOrdinalEnv\x12&\n" +
+	"\x0fsql_plugin_name\x18\x1f \x01(\tR\rsqlPluginName\x12\x1f\n" +
+	"\vsql_dialect\x18  \x01(\tR\n" +
+	"sqlDialect\x12$\n" +
+	"\x0esql_table_name\x18! \x01(\tR\fsqlTableName\x12C\n" +
+	"\x10sql_safety_delay\x18\" \x01(\v2\x19.google.protobuf.DurationR\x0esqlSafetyDelay\x12\"\n" +
+	"\rfile_lock_dir\x18# \x01(\tR\vfileLockDir\x121\n" +
+	"\x15file_lock_base_offset\x18$ \x01(\x03R\x12fileLockBaseOffset\x12&\n" +
+	"\x0ffile_lock_slots\x18% \x01(\x05R\rfileLockSlots\x12*\n" +
 	"\x11redis_plugin_name\x18\x0e \x01(\tR\x0fredisPluginName\x12\x19\n" +
 	"\bredis_db\x18\x0f \x01(\x05R\aredisDb\x12!\n" +
 	"\fcustom_epoch\x18\x10 \x01(\x03R\vcustomEpoch\x12$\n" +
 	"\x0eworker_id_bits\x18\x11 \x01(\x05R\fworkerIdBits\x12#\n" +
-	"\rsequence_bits\x18\x12 \x01(\x05R\fsequenceBitsB*Z(github.com/go-lynx/lynx-eon-id/conf;confb\x06proto3"
+	"\rsequence_bits\x18\x12 \x01(\x05R\fsequenceBits\x12.\n" +
+	"\x13ulid_entropy_source\x18\x13 \x01(\tR\x11ulidEntropySourceB*Z(github.com/go-lynx/lynx-eon-id/conf;confb\x06proto3"
 
 var (
 	file_eon_id_proto_rawDescOnce sync.Once
@@ -274,15 +581,20 @@ var file_eon_id_proto_goTypes = []any{
 	(*durationpb.Duration)(nil), // 1: google.protobuf.Duration
 }
 var file_eon_id_proto_depIdxs = []int32{
-	1, // 0: lynx.protobuf.plugin.eonId.eon_id.worker_id_ttl:type_name -> google.protobuf.Duration
-	1, // 1: lynx.protobuf.plugin.eonId.eon_id.heartbeat_interval:type_name -> google.protobuf.Duration
-	1, // 2: lynx.protobuf.plugin.eonId.eon_id.max_clock_drift:type_name -> google.protobuf.Duration
-	1, // 3: lynx.protobuf.plugin.eonId.eon_id.clock_check_interval:type_name -> google.protobuf.Duration
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	1, // 0: lynx.protobuf.plugin.eonId.eon_id.registry_janitor_interval:type_name -> google.protobuf.Duration
+	1, // 1: lynx.protobuf.plugin.eonId.eon_id.worker_id_ttl:type_name -> google.protobuf.Duration
+	1, // 2: lynx.protobuf.plugin.eonId.eon_id.heartbeat_interval:type_name -> google.protobuf.Duration
+	1, // 3: lynx.protobuf.plugin.eonId.eon_id.worker_reuse_safety_margin:type_name -> google.protobuf.Duration
+	1, // 4: lynx.protobuf.plugin.eonId.eon_id.worker_lease_safety_margin:type_name -> google.protobuf.Duration
+	1, // 5: lynx.protobuf.plugin.eonId.eon_id.worker_recovery_backoff:type_name -> google.protobuf.Duration
+	1, // 6: lynx.protobuf.plugin.eonId.eon_id.max_clock_drift:type_name -> google.protobuf.Duration
+	1, // 7: lynx.protobuf.plugin.eonId.eon_id.clock_check_interval:type_name -> google.protobuf.Duration
+	1, // 8: lynx.protobuf.plugin.eonId.eon_id.sql_safety_delay:type_name -> google.protobuf.Duration
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_eon_id_proto_init() }
@@ -2,6 +2,7 @@ package eonId
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	pb "github.com/go-lynx/lynx-eon-id/conf"
@@ -53,6 +54,27 @@ func validateBasicConfig(config *pb.EonId) error {
 		return fmt.Errorf("datacenter ID must be between 0 and 31, got %d", config.DatacenterId)
 	}
 
+	// Validate datacenter name resolution; the name is mapped through the worker ID allocator
+	if config.DatacenterName != "" || config.DatacenterNameEnv != "" {
+		if config.DatacenterId != 0 {
+			return fmt.Errorf("datacenter_id cannot be combined with datacenter_name or datacenter_name_env")
+		}
+		if !config.AutoRegisterWorkerId {
+			return fmt.Errorf("datacenter names are resolved by the worker ID allocator and need auto_register_worker_id")
+		}
+		switch config.WorkerIdAllocator {
+		case "", WorkerIDAllocatorRedis, WorkerIDAllocatorMemory:
+		default:
+			return fmt.Errorf("worker ID allocator %s cannot resolve datacenter names (supported: %s, %s)",
+				config.WorkerIdAllocator, WorkerIDAllocatorRedis, WorkerIDAllocatorMemory)
+		}
+	}
+	if config.DatacenterName != "" {
+		if err := ValidateDatacenterName(config.DatacenterName); err != nil {
+			return err
+		}
+	}
+
 	// Validate worker ID if not using auto-registration
 	if !config.AutoRegisterWorkerId {
 		maxWorkerID := int32((1 << 10) - 1) // Default 10 bits
@@ -68,59 +90,150 @@ func validateBasicConfig(config *pb.EonId) error {
 	return nil
 }
 
-// validateRedisIntegrationConfig validates Redis integration configuration
+// validateRedisIntegrationConfig validates the worker ID allocator and Redis integration configuration
 func validateRedisIntegrationConfig(config *pb.EonId) error {
-	// If auto-registration is enabled, Redis configuration is required
-	if config.AutoRegisterWorkerId {
-		if config.RedisPluginName == "" {
-			return fmt.Errorf("redis plugin name is required when auto worker ID registration is enabled")
+	if !config.AutoRegisterWorkerId {
+		return nil
+	}
+
+	switch config.WorkerIdAllocator {
+	case "", WorkerIDAllocatorRedis:
+		if err := ValidateRedisKeyLayout(config.RedisKeyLayout); err != nil {
+			return err
+		}
+		if config.RedisMigrateKeyLayout && config.RedisKeyLayout != RedisKeyLayoutCluster {
+			return fmt.Errorf("redis key layout migration needs redis_key_layout %q", RedisKeyLayoutCluster)
+		}
+	case WorkerIDAllocatorMemory:
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorEtcd:
+		if strings.ContainsAny(config.EtcdKeyPrefix, " \t\n\r") {
+			return fmt.Errorf("etcd key prefix cannot contain whitespace characters: %s", config.EtcdKeyPrefix)
+		}
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorZooKeeper:
+		if strings.ContainsAny(config.ZookeeperKeyPrefix, " \t\n\r") {
+			return fmt.Errorf("zookeeper key prefix cannot contain whitespace characters: %s", config.ZookeeperKeyPrefix)
+		}
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorKubernetesLease:
+		if err := ValidateKubernetesLeasePrefix(config.KubernetesLeasePrefix); err != nil {
+			return err
+		}
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorStatefulSet:
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorSQL:
+		if err := ValidateSQLDialect(config.SqlDialect); err != nil {
+			return err
 		}
+		if err := ValidateSQLTableName(config.SqlTableName); err != nil {
+			return err
+		}
+		if config.SqlSafetyDelay != nil && config.SqlSafetyDelay.AsDuration() < 0 {
+			return fmt.Errorf("sql safety delay cannot be negative, got %v", config.SqlSafetyDelay.AsDuration())
+		}
+		return validateWorkerIDTiming(config)
+	case WorkerIDAllocatorFile:
+		if !flockSupported {
+			return fmt.Errorf("file worker ID allocator is not supported on this platform")
+		}
+		if err := validateFileLockSlots(config); err != nil {
+			return err
+		}
+		return validateWorkerIDTiming(config)
+	default:
+		return fmt.Errorf("invalid worker ID allocator: %s", config.WorkerIdAllocator)
+	}
+
+	// If auto-registration via Redis is enabled, Redis configuration is required
+	if config.RedisPluginName == "" {
+		return fmt.Errorf("redis plugin name is required when auto worker ID registration is enabled")
+	}
+
+	if config.RedisKeyPrefix == "" {
+		return fmt.Errorf("redis key prefix is required when auto worker ID registration is enabled")
+	}
+
+	// Validate Redis database number
+	if config.RedisDb < 0 || config.RedisDb > 15 {
+		return fmt.Errorf("redis database number must be between 0 and 15, got %d", config.RedisDb)
+	}
+
+	return validateWorkerIDTiming(config)
+}
+
+// validateFileLockSlots checks that the file lock slot range lies within the worker ID space
+func validateFileLockSlots(config *pb.EonId) error {
+	maxWorkerID := int64((1 << 10) - 1) // Default 10 bits
+	if config.WorkerIdBits > 0 {
+		maxWorkerID = int64((1 << config.WorkerIdBits) - 1)
+	}
+	if config.FileLockBaseOffset < 0 || config.FileLockBaseOffset > maxWorkerID {
+		return fmt.Errorf("file lock base offset must be between 0 and %d, got %d", maxWorkerID, config.FileLockBaseOffset)
+	}
+	if config.FileLockSlots < 0 {
+		return fmt.Errorf("file lock slots cannot be negative, got %d", config.FileLockSlots)
+	}
+	if last := config.FileLockBaseOffset + int64(config.FileLockSlots) - 1; config.FileLockSlots > 0 && last > maxWorkerID {
+		return fmt.Errorf("file lock slots %d..%d exceed max worker ID %d", config.FileLockBaseOffset, last, maxWorkerID)
+	}
+	return nil
+}
 
-		if config.RedisKeyPrefix == "" {
-			return fmt.Errorf("redis key prefix is required when auto worker ID registration is enabled")
+// validateWorkerIDTiming validates the worker ID TTL, heartbeat interval, reuse and lease safety margins, recovery backoff
+// and the action after a revocation
+func validateWorkerIDTiming(config *pb.EonId) error {
+	if config.WorkerReuseSafetyMargin != nil && config.WorkerReuseSafetyMargin.AsDuration() < 0 {
+		return fmt.Errorf("worker reuse safety margin cannot be negative, got %v", config.WorkerReuseSafetyMargin.AsDuration())
+	}
+	if config.WorkerLeaseSafetyMargin != nil && config.WorkerLeaseSafetyMargin.AsDuration() < 0 {
+		return fmt.Errorf("worker lease safety margin cannot be negative, got %v", config.WorkerLeaseSafetyMargin.AsDuration())
+	}
+	if config.WorkerRecoveryBackoff != nil && config.WorkerRecoveryBackoff.AsDuration() < 0 {
+		return fmt.Errorf("worker recovery backoff cannot be negative, got %v", config.WorkerRecoveryBackoff.AsDuration())
+	}
+	switch config.WorkerRevokeAction {
+	case "", WorkerRevokeActionReregister, WorkerRevokeActionFence:
+	default:
+		return fmt.Errorf("invalid worker revoke action: %s (valid: reregister, fence)", config.WorkerRevokeAction)
+	}
+
+	// Validate TTL settings
+	if config.WorkerIdTtl != nil {
+		ttl := config.WorkerIdTtl.AsDuration()
+		if ttl <= 0 {
+			return fmt.Errorf("worker ID TTL must be positive")
+		}
+		if ttl < 10*time.Second {
+			return fmt.Errorf("worker ID TTL is too small (<10 seconds): %v", ttl)
+		}
+		if ttl > 24*time.Hour {
+			return fmt.Errorf("worker ID TTL is too large (>24 hours): %v", ttl)
 		}
+	}
 
-		// Validate Redis database number
-		if config.RedisDb < 0 || config.RedisDb > 15 {
-			return fmt.Errorf("redis database number must be between 0 and 15, got %d", config.RedisDb)
+	// Validate heartbeat interval
+	if config.HeartbeatInterval != nil {
+		interval := config.HeartbeatInterval.AsDuration()
+		if interval <= 0 {
+			return fmt.Errorf("heartbeat interval must be positive")
+		}
+		if interval < 1*time.Second {
+			return fmt.Errorf("heartbeat interval is too small (<1 second): %v", interval)
+		}
+		if interval > 1*time.Hour {
+			return fmt.Errorf("heartbeat interval is too large (>1 hour): %v", interval)
 		}
 
-		// Validate TTL settings
+		// Validate relationship between TTL and heartbeat
 		if config.WorkerIdTtl != nil {
 			ttl := config.WorkerIdTtl.AsDuration()
-			if ttl <= 0 {
-				return fmt.Errorf("worker ID TTL must be positive")
+			if interval >= ttl {
+				return fmt.Errorf("heartbeat interval (%v) must be less than worker ID TTL (%v)", interval, ttl)
 			}
-			if ttl < 10*time.Second {
-				return fmt.Errorf("worker ID TTL is too small (<10 seconds): %v", ttl)
-			}
-			if ttl > 24*time.Hour {
-				return fmt.Errorf("worker ID TTL is too large (>24 hours): %v", ttl)
-			}
-		}
-
-		// Validate heartbeat interval
-		if config.HeartbeatInterval != nil {
-			interval := config.HeartbeatInterval.AsDuration()
-			if interval <= 0 {
-				return fmt.Errorf("heartbeat interval must be positive")
-			}
-			if interval < 1*time.Second {
-				return fmt.Errorf("heartbeat interval is too small (<1 second): %v", interval)
-			}
-			if interval > 1*time.Hour {
-				return fmt.Errorf("heartbeat interval is too large (>1 hour): %v", interval)
-			}
-
-			// Validate relationship between TTL and heartbeat
-			if config.WorkerIdTtl != nil {
-				ttl := config.WorkerIdTtl.AsDuration()
-				if interval >= ttl {
-					return fmt.Errorf("heartbeat interval (%v) must be less than worker ID TTL (%v)", interval, ttl)
-				}
-				if ttl < interval*3 {
-					return fmt.Errorf("worker ID TTL (%v) should be at least 3x heartbeat interval (%v) for reliability", ttl, interval)
-				}
+			if ttl < interval*3 {
+				return fmt.Errorf("worker ID TTL (%v) should be at least 3x heartbeat interval (%v) for reliability", ttl, interval)
 			}
 		}
 	}
@@ -202,6 +315,22 @@ func validatePerformanceConfig(config *pb.EonId) error {
 		}
 	}
 
+	// Validate sequence overflow strategy
+	switch config.SequenceOverflowStrategy {
+	case "", SequenceOverflowStrategySpin, SequenceOverflowStrategySleep, SequenceOverflowStrategyError:
+		// Valid strategies
+	default:
+		return fmt.Errorf("invalid sequence overflow strategy: %s (valid: spin, sleep, error)", config.SequenceOverflowStrategy)
+	}
+
+	// Validate sequence start mode
+	switch config.SequenceStartMode {
+	case "", SequenceStartZero, SequenceStartRandom, SequenceStartRoundRobin:
+		// Valid modes
+	default:
+		return fmt.Errorf("invalid sequence start mode: %s (valid: zero, random, round_robin)", config.SequenceStartMode)
+	}
+
 	return nil
 }
 
@@ -235,6 +364,14 @@ func validateAdvancedConfig(config *pb.EonId) error {
 		return err
 	}
 
+	// Validate ULID entropy source
+	switch config.UlidEntropySource {
+	case "", ULIDEntropyCrypto, ULIDEntropyFast:
+		// Valid sources
+	default:
+		return fmt.Errorf("invalid ULID entropy source: %s (valid: crypto, fast)", config.UlidEntropySource)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,102 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+const (
+	// maxDatacenterNameLength bounds datacenter names, which are stored with every claim
+	maxDatacenterNameLength = 64
+	// datacenterResolveTimeout bounds the lookup of the datacenter name at startup
+	datacenterResolveTimeout = 10 * time.Second
+)
+
+// configuredDatacenterName returns the datacenter name from the datacenter_name_env variable if it is set,
+// otherwise datacenter_name; "" means the datacenter ID is configured by datacenter_id
+func configuredDatacenterName(conf *pb.EonId) string {
+	if conf.DatacenterNameEnv != "" {
+		if name := strings.TrimSpace(os.Getenv(conf.DatacenterNameEnv)); name != "" {
+			return name
+		}
+	}
+	return strings.TrimSpace(conf.DatacenterName)
+}
+
+// ValidateDatacenterName checks a datacenter name: up to 64 characters without spaces or control characters
+func ValidateDatacenterName(name string) error {
+	if name == "" {
+		return fmt.Errorf("datacenter name is empty")
+	}
+	if len(name) > maxDatacenterNameLength {
+		return fmt.Errorf("datacenter name %q is longer than %d bytes", name, maxDatacenterNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("datacenter name %q contains spaces or control characters", name)
+		}
+	}
+	return nil
+}
+
+// resolveDatacenterID returns the datacenter ID to run with and the name it was resolved from: datacenter_id
+// when no datacenter name is configured, otherwise the ID that allocator maps the name to
+func resolveDatacenterID(ctx context.Context, allocator WorkerIDAllocator, conf *pb.EonId) (int64, string, error) {
+	name := configuredDatacenterName(conf)
+	if name == "" {
+		return int64(conf.DatacenterId), "", nil
+	}
+	if err := ValidateDatacenterName(name); err != nil {
+		return -1, "", err
+	}
+	if conf.DatacenterId != 0 {
+		return -1, "", fmt.Errorf("datacenter name %q and datacenter_id %d are both set", name, conf.DatacenterId)
+	}
+	resolver, ok := allocator.(DatacenterResolver)
+	if !ok {
+		backend := "none"
+		if allocator != nil {
+			backend = allocator.Name()
+		}
+		return -1, "", fmt.Errorf("datacenter name %q needs a worker ID allocator that maps datacenter names, have %s", name, backend)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, datacenterResolveTimeout)
+	defer cancel()
+	datacenterID, err := resolver.ResolveDatacenter(ctx, name, int64(1<<DefaultDatacenterBits)-1)
+	if err != nil {
+		return -1, "", fmt.Errorf("failed to resolve datacenter %q: %w", name, err)
+	}
+	lynxlog.Infof("datacenter %q resolved to datacenter ID %d (allocator: %s)", name, datacenterID, allocator.Name())
+	return datacenterID, name, nil
+}
+
+// registryDatacenters reads the datacenter name to ID mapping of the registry once name has been resolved to
+// datacenterID. The mapping only grows, so a copy read at startup stays correct for the names it holds; when it
+// cannot be read, the resolved name alone is returned.
+func registryDatacenters(ctx context.Context, allocator WorkerIDAllocator, name string, datacenterID int64) map[string]int64 {
+	mapping := map[string]int64{name: datacenterID}
+	resolver, ok := allocator.(DatacenterResolver)
+	if !ok {
+		return mapping
+	}
+	ctx, cancel := context.WithTimeout(ctx, datacenterResolveTimeout)
+	defer cancel()
+	datacenters, err := resolver.Datacenters(ctx)
+	if err != nil {
+		lynxlog.Warnf("failed to read the datacenter names of the registry: %v", err)
+		return mapping
+	}
+	for n, id := range datacenters {
+		mapping[n] = id
+	}
+	return mapping
+}
@@ -0,0 +1,86 @@
+package eonId
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+func TestResolveDatacenterID(t *testing.T) {
+	ctx := context.Background()
+	allocator := NewMemoryWorkerIDAllocator()
+
+	datacenterID, name, err := resolveDatacenterID(ctx, allocator, &pb.EonId{DatacenterId: 7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), datacenterID)
+	assert.Empty(t, name, "without a name datacenter_id is used as is")
+
+	datacenterID, name, err = resolveDatacenterID(ctx, allocator, &pb.EonId{DatacenterName: "us-east-1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), datacenterID)
+	assert.Equal(t, "us-east-1", name)
+
+	// The environment variable wins over the configured name; an empty variable falls back to it
+	conf := &pb.EonId{DatacenterName: "us-east-1", DatacenterNameEnv: "EON_ID_TEST_REGION"}
+	t.Setenv("EON_ID_TEST_REGION", "eu-west-1")
+	datacenterID, name, err = resolveDatacenterID(ctx, allocator, conf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), datacenterID)
+	assert.Equal(t, "eu-west-1", name)
+	t.Setenv("EON_ID_TEST_REGION", "")
+	datacenterID, name, err = resolveDatacenterID(ctx, allocator, conf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), datacenterID)
+	assert.Equal(t, "us-east-1", name)
+
+	_, _, err = resolveDatacenterID(ctx, allocator, &pb.EonId{DatacenterName: "us-east-1", DatacenterId: 2})
+	assert.Error(t, err)
+	_, _, err = resolveDatacenterID(ctx, nil, &pb.EonId{DatacenterName: "us-east-1"})
+	assert.Error(t, err)
+	_, _, err = resolveDatacenterID(ctx, NewStatefulSetWorkerIDAllocator(0), &pb.EonId{DatacenterName: "us-east-1"})
+	assert.Error(t, err)
+}
+
+func TestWorkerIDManager_DatacenterName(t *testing.T) {
+	ctx := context.Background()
+	allocator := NewMemoryWorkerIDAllocator()
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 4, &WorkerManagerConfig{DatacenterName: "us-west-2"})
+	_, err := mgr.RegisterWorkerID(ctx, 31)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mgr.UnregisterWorkerID(ctx) })
+	assert.Equal(t, "us-west-2", mgr.DatacenterName())
+
+	workers, err := allocator.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.Equal(t, int64(4), workers[0].DatacenterID)
+	assert.Equal(t, "us-west-2", workers[0].DatacenterName)
+	require.NoError(t, mgr.sendHeartbeat())
+	workers, err = allocator.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "us-west-2", workers[0].DatacenterName, "heartbeats keep the name")
+}
+
+func TestRegistryDatacenters(t *testing.T) {
+	ctx := context.Background()
+	allocator := NewMemoryWorkerIDAllocator()
+	_, err := allocator.ResolveDatacenter(ctx, "eu-west-1", 31)
+	require.NoError(t, err)
+	datacenterID, name, err := resolveDatacenterID(ctx, allocator, &pb.EonId{DatacenterName: "us-east-1"})
+	require.NoError(t, err)
+
+	// The whole registry mapping, not only this instance's name
+	mapping := registryDatacenters(ctx, allocator, name, datacenterID)
+	assert.Equal(t, map[string]int64{"eu-west-1": 0, "us-east-1": 1}, mapping)
+	assert.Equal(t, map[string]int64{"us-east-1": 1},
+		registryDatacenters(ctx, NewStatefulSetWorkerIDAllocator(0), "us-east-1", 1))
+
+	plugin := NewSnowflakePlugin()
+	plugin.datacenterNames = mapping
+	assert.Equal(t, mapping, plugin.GetHealth().Details["datacenter_names"])
+	assert.NotContains(t, NewSnowflakePlugin().GetHealth().Details, "datacenter_names")
+}
@@ -0,0 +1,110 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+)
+
+// DefaultDrainTimeout bounds each shutdown step when the stop context has no earlier deadline
+const DefaultDrainTimeout = 5 * time.Second
+
+// drainReport describes one drain-before-release shutdown
+type drainReport struct {
+	WorkerID       int64
+	FinalTimestamp int64         // last issued timestamp in Unix ms, recorded with the release; -1 if none
+	InFlightWait   time.Duration // waiting for generation calls in progress
+	InFlightLeft   int64         // calls still in progress when that wait ended
+	ClockWait      time.Duration // waiting for the clock to pass FinalTimestamp
+	Release        time.Duration // releasing the worker ID claim
+	Total          time.Duration
+	Err            error // the first step that failed; later steps still run
+}
+
+// metadata is the plugin event metadata of the report
+func (r drainReport) metadata() map[string]any {
+	metadata := map[string]any{
+		"worker_id":        r.WorkerID,
+		"final_timestamp":  r.FinalTimestamp,
+		"in_flight_wait":   r.InFlightWait.String(),
+		"in_flight_left":   r.InFlightLeft,
+		"clock_wait":       r.ClockWait.String(),
+		"release_duration": r.Release.String(),
+		"total_duration":   r.Total.String(),
+	}
+	if r.Err != nil {
+		metadata["error"] = r.Err.Error()
+	}
+	return metadata
+}
+
+// drainAndRelease shuts the generator down in order: it stops accepting calls, waits for the calls in progress,
+// waits until the clock has passed the last issued timestamp and only then releases the worker ID claim, which
+// records that final timestamp. The waits are bounded by ctx, or DefaultDrainTimeout each; a wait that runs out
+// is reported and the claim is released anyway, since no new ID can be issued once the generator is shut down.
+func drainAndRelease(ctx context.Context, generator *Generator, workerManager *WorkerIDManager) drainReport {
+	start := time.Now()
+	report := drainReport{WorkerID: -1, FinalTimestamp: -1}
+	fail := func(err error) {
+		if report.Err == nil {
+			report.Err = err
+		}
+	}
+
+	if generator != nil {
+		waitCtx, cancel := createTimeoutContext(ctx, DefaultDrainTimeout)
+		step := time.Now()
+		if err := generator.Shutdown(waitCtx); err != nil {
+			fail(fmt.Errorf("draining generation calls: %w", err))
+		}
+		report.InFlightWait = time.Since(step)
+		report.InFlightLeft = generator.InFlight()
+
+		step = time.Now()
+		last, err := generator.WaitPastLastTimestamp(waitCtx)
+		if err != nil {
+			fail(fmt.Errorf("waiting for the clock: %w", err))
+		}
+		report.FinalTimestamp = last
+		report.ClockWait = time.Since(step)
+		cancel()
+	}
+
+	if workerManager != nil {
+		report.WorkerID = workerManager.GetWorkerID()
+		releaseCtx, cancel := createTimeoutContext(ctx, DefaultDrainTimeout)
+		step := time.Now()
+		if err := workerManager.UnregisterWorkerID(releaseCtx); err != nil {
+			fail(fmt.Errorf("releasing the worker ID: %w", err))
+		}
+		report.Release = time.Since(step)
+		cancel()
+	}
+	report.Total = time.Since(start)
+	return report
+}
+
+// reportDrain logs a drain and emits it as a plugin event: health.ok when every step finished in time,
+// health.warning otherwise
+func (p *PlugSnowflake) reportDrain(report drainReport) {
+	eventType := plugins.EventHealthStatusOK
+	if report.Err != nil {
+		eventType = plugins.EventHealthStatusWarning
+		lynxlog.Warnf("eon-id shutdown drain of worker ID %d incomplete after %v: %v", report.WorkerID,
+			report.Total.Round(time.Millisecond), report.Err)
+	}
+	lynxlog.Infof("eon-id drained worker ID %d in %v (in-flight calls %v, clock %v, release %v), final timestamp %d",
+		report.WorkerID, report.Total.Round(time.Microsecond), report.InFlightWait.Round(time.Microsecond),
+		report.ClockWait.Round(time.Microsecond), report.Release.Round(time.Microsecond), report.FinalTimestamp)
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     eventType,
+		Priority: plugins.PriorityNormal,
+		Source:   "ShutdownDrain",
+		Category: "lifecycle",
+		Metadata: report.metadata(),
+		Error:    report.Err,
+	})
+}
@@ -0,0 +1,131 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// releaseRecorder records the wall clock at each release
+type releaseRecorder struct {
+	*MemoryWorkerIDAllocator
+	releasedAt []int64
+}
+
+func (r *releaseRecorder) Release(ctx context.Context, info WorkerInfo) error {
+	r.releasedAt = append(r.releasedAt, time.Now().UnixMilli())
+	return r.MemoryWorkerIDAllocator.Release(ctx, info)
+}
+
+// newDrainFixture returns a generator whose worker ID is registered with a manager that records its timestamps
+func newDrainFixture(t *testing.T) (*Generator, *WorkerIDManager, *releaseRecorder) {
+	allocator := &releaseRecorder{MemoryWorkerIDAllocator: NewMemoryWorkerIDAllocator()}
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{TTL: time.Hour, HeartbeatInterval: time.Hour})
+	workerID, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	generator, err := NewSnowflakeGeneratorCore(1, workerID, nil)
+	require.NoError(t, err)
+	mgr.SetLastTimestampSource(func() int64 { return generator.GetStats().LastGeneratedTime })
+	t.Cleanup(func() { _ = mgr.UnregisterWorkerID(context.Background()) })
+	return generator, mgr, allocator
+}
+
+func TestDrainAndRelease_WaitsForClock(t *testing.T) {
+	generator, mgr, allocator := newDrainFixture(t)
+	workerID := mgr.GetWorkerID()
+	_, err := generator.GenerateID()
+	require.NoError(t, err)
+
+	// As after ignore mode kept issuing through a clock step back: the last timestamp is ahead of the clock
+	generator.mu.Lock()
+	generator.lastTimestamp = time.Now().UnixMilli() + 40
+	final := generator.lastTimestamp
+	generator.mu.Unlock()
+
+	report := drainAndRelease(context.Background(), generator, mgr)
+	require.NoError(t, report.Err)
+	assert.Equal(t, workerID, report.WorkerID)
+	assert.Equal(t, final, report.FinalTimestamp)
+	assert.GreaterOrEqual(t, report.ClockWait, 30*time.Millisecond)
+	assert.Zero(t, report.InFlightLeft)
+
+	require.Len(t, allocator.releasedAt, 1)
+	assert.Greater(t, allocator.releasedAt[0], final, "released only after the clock passed the final timestamp")
+	assert.Equal(t, final, allocator.lastTimestamp[workerClaimKey{1, workerID}], "the release records the final timestamp")
+	assert.False(t, mgr.IsRegistered())
+	_, err = generator.GenerateID()
+	assert.ErrorContains(t, err, "shutting down")
+}
+
+func TestDrainAndRelease_WaitsForInFlightCalls(t *testing.T) {
+	generator, mgr, allocator := newDrainFixture(t)
+
+	// A reused worker ID makes the call sleep outside the lock until notBefore
+	generator.mu.Lock()
+	generator.notBefore = time.Now().UnixMilli() + 60
+	generator.mu.Unlock()
+	result := make(chan error, 1)
+	go func() {
+		_, err := generator.GenerateID()
+		result <- err
+	}()
+	require.Eventually(t, func() bool { return generator.InFlight() == 1 }, time.Second, time.Millisecond)
+
+	report := drainAndRelease(context.Background(), generator, mgr)
+	require.NoError(t, report.Err)
+	select {
+	case err := <-result:
+		assert.ErrorContains(t, err, "shutting down", "a call in progress claims no slot after shutdown")
+	default:
+		t.Fatal("the drain returned before the call in progress")
+	}
+	assert.Zero(t, report.InFlightLeft)
+	assert.Greater(t, report.InFlightWait, time.Duration(0))
+	assert.Equal(t, int64(-1), report.FinalTimestamp)
+	assert.Len(t, allocator.releasedAt, 1)
+}
+
+func TestDrainAndRelease_Deadline(t *testing.T) {
+	generator, mgr, allocator := newDrainFixture(t)
+	generator.mu.Lock()
+	generator.notBefore = time.Now().UnixMilli() + 500
+	generator.mu.Unlock()
+	go func() { _, _ = generator.GenerateID() }()
+	require.Eventually(t, func() bool { return generator.InFlight() == 1 }, time.Second, time.Millisecond)
+
+	// The stop deadline cuts the wait short, and the claim is released anyway
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report := drainAndRelease(ctx, generator, mgr)
+	require.Error(t, report.Err)
+	assert.True(t, errors.Is(report.Err, context.DeadlineExceeded), "got %v", report.Err)
+	assert.Equal(t, int64(1), report.InFlightLeft)
+	assert.Equal(t, int64(1), report.metadata()["in_flight_left"])
+	assert.Len(t, allocator.releasedAt, 1)
+	assert.False(t, mgr.IsRegistered())
+}
+
+func TestPlugin_StopDrainsBeforeRelease(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{WorkerIdBits: 5})
+	generator := plugin.generator
+	mgr.SetLastTimestampSource(func() int64 { return generator.GetStats().LastGeneratedTime })
+	workerID := mgr.GetWorkerID()
+	_, err := plugin.GenerateID()
+	require.NoError(t, err)
+	last := generator.GetStats().LastGeneratedTime
+
+	require.NoError(t, plugin.cleanupTasksContext(context.Background()))
+	_, err = plugin.GenerateID()
+	assert.Error(t, err)
+	workers, err := allocator.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, workers)
+	assert.Equal(t, last, allocator.lastTimestamp[workerClaimKey{1, workerID}])
+}
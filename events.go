@@ -0,0 +1,253 @@
+package eonId
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+)
+
+// EventType identifies a worker ID or clock lifecycle event, see PlugSnowflake.OnEvent
+type EventType string
+
+const (
+	// EventWorkerRegistered: a worker ID was claimed (WorkerRegisteredPayload)
+	EventWorkerRegistered EventType = "worker.registered"
+	// EventHeartbeatDegraded: a heartbeat failed and ID issuance stops until one succeeds (HeartbeatDegradedPayload)
+	EventHeartbeatDegraded EventType = "worker.heartbeat_degraded"
+	// EventLeaseLost: the worker ID may belong to another instance now (LeaseLostPayload)
+	EventLeaseLost EventType = "worker.lease_lost"
+	// EventWorkerReregistered: a worker ID was claimed again after failed heartbeats or a lost lease, the same
+	// one or a new one (WorkerReregisteredPayload)
+	EventWorkerReregistered EventType = "worker.reregistered"
+	// EventClockBackward: the clock went behind the last issued timestamp (ClockBackwardPayload)
+	EventClockBackward EventType = "clock.backward"
+	// EventDriftWaitStarted: generation started waiting for the clock (DriftWaitPayload)
+	EventDriftWaitStarted EventType = "clock.drift_wait_started"
+	// EventDriftWaitEnded: the clock caught up and generation resumed (DriftWaitPayload)
+	EventDriftWaitEnded EventType = "clock.drift_wait_ended"
+	// EventSequenceOverflowStorm: the sequence ran out unusually often (SequenceOverflowStormPayload)
+	EventSequenceOverflowStorm EventType = "sequence.overflow_storm"
+)
+
+// Lease loss reasons of LeaseLostPayload
+const (
+	LeaseLostExpired = "expired" // the claim expired before it was renewed
+	LeaseLostTaken   = "taken"   // another instance holds the worker ID
+	LeaseLostWatch   = "watch"   // the allocator reported the claim lost, e.g. an etcd lease that ended
+	LeaseLostRevoked = "revoked" // an operator revoked the claim, see WorkerRevoker
+)
+
+// Drift wait reasons of DriftWaitPayload
+const (
+	DriftWaitClockBackward = "clock_backward" // the clock is behind the last issued timestamp
+	DriftWaitWorkerReuse   = "worker_reuse"   // a reused worker ID waits out its previous owner
+)
+
+// Event is a worker ID or clock lifecycle change. Payload holds the *Payload struct named by the Type's doc.
+type Event struct {
+	Type         EventType `json:"type"`
+	Time         time.Time `json:"time"`
+	DatacenterID int64     `json:"datacenter_id"`
+	WorkerID     int64     `json:"worker_id"`
+	Payload      any       `json:"payload,omitempty"`
+}
+
+// WorkerRegisteredPayload describes a new claim
+type WorkerRegisteredPayload struct {
+	InstanceID     string `json:"instance_id"`
+	DatacenterName string `json:"datacenter_name,omitempty"`
+	Allocator      string `json:"allocator"`
+	// PreviousTimestamp is the last timestamp issued under the worker ID by an earlier owner (Unix ms, 0 if none)
+	PreviousTimestamp int64 `json:"previous_timestamp,omitempty"`
+}
+
+// HeartbeatDegradedPayload describes a failed heartbeat
+type HeartbeatDegradedPayload struct {
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	ReregisterAfter     int    `json:"reregister_after"` // failures before the manager re-registers
+	Error               string `json:"error"`
+}
+
+// LeaseLostPayload describes a lost claim
+type LeaseLostPayload struct {
+	Reason string `json:"reason"` // LeaseLostExpired, LeaseLostTaken, LeaseLostWatch or LeaseLostRevoked
+	Error  string `json:"error,omitempty"`
+}
+
+// WorkerReregisteredPayload describes a claim made again
+type WorkerReregisteredPayload struct {
+	PreviousWorkerID int64 `json:"previous_worker_id"`
+	SameWorkerID     bool  `json:"same_worker_id"`
+}
+
+// ClockBackwardPayload describes a clock that went behind the last issued timestamp
+type ClockBackwardPayload struct {
+	LastTimestamp    int64         `json:"last_timestamp"`    // Unix ms
+	CurrentTimestamp int64         `json:"current_timestamp"` // Unix ms
+	Drift            time.Duration `json:"drift"`
+	Action           string        `json:"action"` // clock_drift_action
+}
+
+// DriftWaitPayload describes a wait for the clock; Wait is the first wait planned when it starts and the whole
+// wait when it ends
+type DriftWaitPayload struct {
+	Reason string        `json:"reason"` // DriftWaitClockBackward or DriftWaitWorkerReuse
+	Wait   time.Duration `json:"wait"`
+}
+
+// SequenceOverflowStormPayload describes a window with at least SequenceOverflowStormThreshold overflows
+type SequenceOverflowStormPayload struct {
+	Overflows int64         `json:"overflows"`
+	Window    time.Duration `json:"window"`
+	Strategy  string        `json:"strategy"` // sequence_overflow_strategy
+}
+
+const (
+	// SequenceOverflowStormThreshold is how many sequence overflows within SequenceOverflowStormWindow make a storm
+	SequenceOverflowStormThreshold = 100
+	// SequenceOverflowStormWindow is the window sequence overflows are counted in; one storm event per window
+	SequenceOverflowStormWindow = time.Second
+	// eventQueueSize is how many events wait for delivery before new ones are dropped
+	eventQueueSize = 1024
+)
+
+// eventBus delivers events to OnEvent handlers on one goroutine, in order. Publishing never blocks, so events can
+// be published under locks and on the ID generation path; when handlers fall behind, new events are dropped and
+// counted.
+type eventBus struct {
+	mu       sync.RWMutex
+	handlers []eventHandler // in subscription order
+	nextID   uint64
+	queue    chan Event
+	start    sync.Once
+	done     chan struct{}
+	closed   sync.Once
+	dropped  int64 // atomic
+}
+
+type eventHandler struct {
+	id uint64
+	fn func(Event)
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		queue: make(chan Event, eventQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// subscribe adds a handler and returns a function that removes it
+func (b *eventBus) subscribe(handler func(Event)) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers = append(b.handlers, eventHandler{id: id, fn: handler})
+	b.mu.Unlock()
+	b.start.Do(func() { go b.deliver() })
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			for i, h := range b.handlers {
+				if h.id == id {
+					// Copy so that a dispatch iterating the old slice is not affected
+					b.handlers = append(b.handlers[:i:i], b.handlers[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// publish queues an event for the handlers; without handlers it does nothing
+func (b *eventBus) publish(event Event) {
+	b.mu.RLock()
+	subscribed := len(b.handlers) > 0
+	b.mu.RUnlock()
+	if !subscribed {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+	select {
+	case b.queue <- event:
+	default:
+		atomic.AddInt64(&b.dropped, 1)
+	}
+}
+
+// Dropped returns how many events were dropped because the handlers fell behind
+func (b *eventBus) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// close stops accepting events; the ones already queued are still delivered
+func (b *eventBus) close() {
+	b.closed.Do(func() { close(b.done) })
+}
+
+func (b *eventBus) deliver() {
+	for {
+		select {
+		case event := <-b.queue:
+			b.dispatch(event)
+		case <-b.done:
+			for {
+				select {
+				case event := <-b.queue:
+					b.dispatch(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatch calls every handler subscribed when the event is delivered
+func (b *eventBus) dispatch(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		callEventHandler(handler.fn, event)
+	}
+}
+
+// callEventHandler keeps a panicking handler from stopping delivery
+func callEventHandler(handler func(Event), event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			lynxlog.Errorf("eon-id event handler panicked on %s: %v", event.Type, r)
+		}
+	}()
+	handler(event)
+}
+
+// OnEvent subscribes handler to worker ID and clock lifecycle events and returns a function that unsubscribes
+// it. Handlers run one at a time on a delivery goroutine, in the order events happened; a slow handler delays
+// the others and, once eventQueueSize events are pending, makes new events get dropped (see the events_dropped
+// health detail). A typical use is taking the node out of rotation on EventLeaseLost:
+//
+//	plugin.OnEvent(func(e eonId.Event) {
+//		if e.Type == eonId.EventLeaseLost {
+//			lb.Drain()
+//		}
+//	})
+func (p *PlugSnowflake) OnEvent(handler func(Event)) (unsubscribe func()) {
+	if handler == nil {
+		return func() {}
+	}
+	return p.events.subscribe(handler)
+}
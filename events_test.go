@@ -0,0 +1,303 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// eventRecorder collects events from a handler that may run on another goroutine
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) ofType(eventType EventType) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matching []Event
+	for _, event := range r.events {
+		if event.Type == eventType {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]EventType, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestEventBus_DeliversInOrderAndUnsubscribes(t *testing.T) {
+	bus := newEventBus()
+	defer bus.close()
+	bus.publish(Event{Type: EventClockBackward}) // no handler yet: dropped silently, not counted
+
+	var first, second eventRecorder
+	unsubscribe := bus.subscribe(first.record)
+	bus.subscribe(func(Event) { panic("broken handler") })
+	bus.subscribe(second.record)
+
+	bus.publish(Event{Type: EventWorkerRegistered})
+	bus.publish(Event{Type: EventLeaseLost})
+	require.Eventually(t, func() bool { return len(second.types()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []EventType{EventWorkerRegistered, EventLeaseLost}, first.types())
+	assert.False(t, second.ofType(EventLeaseLost)[0].Time.IsZero())
+
+	unsubscribe()
+	unsubscribe()
+	bus.publish(Event{Type: EventWorkerReregistered})
+	require.Eventually(t, func() bool { return len(second.types()) == 3 }, time.Second, time.Millisecond)
+	assert.Len(t, first.types(), 2)
+	assert.Zero(t, bus.Dropped())
+}
+
+func TestEventBus_DropsWhenHandlersFallBehind(t *testing.T) {
+	bus := newEventBus()
+	release := make(chan struct{})
+	var recorder eventRecorder
+	bus.subscribe(func(event Event) {
+		<-release
+		recorder.record(event)
+	})
+
+	// One event is held by the blocked handler, eventQueueSize wait in the queue, the rest are dropped
+	for i := 0; i < eventQueueSize+10; i++ {
+		bus.publish(Event{Type: EventHeartbeatDegraded})
+	}
+	assert.GreaterOrEqual(t, bus.Dropped(), int64(9))
+
+	// Closing stops new events but delivers the queued ones
+	bus.close()
+	bus.publish(Event{Type: EventLeaseLost})
+	close(release)
+	require.Eventually(t, func() bool {
+		return int64(len(recorder.types()))+bus.Dropped() == eventQueueSize+10
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, recorder.ofType(EventLeaseLost))
+}
+
+func TestEvents_LeaseLostAndReregisteredWithNewWorkerID(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:          5,
+		WorkerRecoveryBackoff: durationpb.New(time.Millisecond),
+	})
+	var recorder eventRecorder
+	plugin.OnEvent(recorder.record)
+
+	lostWorkerID := stealWorkerID(t, allocator, mgr)
+	require.Eventually(t, func() bool { return len(recorder.ofType(EventWorkerReregistered)) == 1 },
+		2*time.Second, 5*time.Millisecond)
+	newWorkerID := mgr.GetWorkerID()
+
+	assert.Equal(t, []EventType{EventLeaseLost, EventWorkerRegistered, EventWorkerReregistered}, recorder.types())
+	lost := recorder.ofType(EventLeaseLost)[0]
+	assert.Equal(t, lostWorkerID, lost.WorkerID)
+	assert.Equal(t, int64(1), lost.DatacenterID)
+	assert.Equal(t, LeaseLostTaken, lost.Payload.(LeaseLostPayload).Reason)
+
+	registered := recorder.ofType(EventWorkerRegistered)[0]
+	assert.Equal(t, newWorkerID, registered.WorkerID)
+	assert.Equal(t, "memory", registered.Payload.(WorkerRegisteredPayload).Allocator)
+
+	reregistered := recorder.ofType(EventWorkerReregistered)[0]
+	assert.Equal(t, newWorkerID, reregistered.WorkerID)
+	assert.Equal(t, WorkerReregisteredPayload{PreviousWorkerID: lostWorkerID}, reregistered.Payload)
+}
+
+func TestEvents_RevokedLeaseLost(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:       5,
+		WorkerRevokeAction: WorkerRevokeActionFence,
+	})
+	var recorder eventRecorder
+	plugin.OnEvent(recorder.record)
+	workerID := mgr.GetWorkerID()
+
+	_, err := plugin.RevokeWorkerID(context.Background(), workerID, "wedged")
+	require.NoError(t, err)
+	require.Error(t, mgr.sendHeartbeat())
+	require.Eventually(t, func() bool { return len(recorder.ofType(EventLeaseLost)) == 1 },
+		time.Second, time.Millisecond)
+	lost := recorder.ofType(EventLeaseLost)[0]
+	assert.Equal(t, workerID, lost.WorkerID)
+	assert.Equal(t, LeaseLostRevoked, lost.Payload.(LeaseLostPayload).Reason)
+}
+
+// failingRenewAllocator fails every renewal, like a registry that cannot be reached
+type failingRenewAllocator struct {
+	WorkerIDAllocator
+}
+
+func (a failingRenewAllocator) Renew(context.Context, WorkerInfo, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestEvents_HeartbeatDegradedAndReregisteredWithSameWorkerID(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{TTL: time.Hour, HeartbeatInterval: time.Hour})
+	var recorder eventRecorder
+	mgr.SetEventHandler(recorder.record)
+	workerID, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	defer func() { _ = mgr.UnregisterWorkerID(context.Background()) }()
+	require.Len(t, recorder.ofType(EventWorkerRegistered), 1)
+
+	// A renewal that succeeds after failed heartbeats keeps the worker ID
+	require.NoError(t, mgr.tryReRegister(context.Background()))
+	reregistered := recorder.ofType(EventWorkerReregistered)
+	require.Len(t, reregistered, 1)
+	assert.Equal(t, workerID, reregistered[0].WorkerID)
+	assert.Equal(t, WorkerReregisteredPayload{PreviousWorkerID: workerID, SameWorkerID: true}, reregistered[0].Payload)
+
+	// Every failed heartbeat is reported with the failure count
+	failing := NewWorkerIDManagerWithAllocator(failingRenewAllocator{allocator}, 1, &WorkerManagerConfig{TTL: time.Hour, HeartbeatInterval: time.Hour})
+	failing.SetEventHandler(recorder.record)
+	_, err = failing.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	defer func() { _ = failing.UnregisterWorkerID(context.Background()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go failing.heartbeatLoop(ctx, time.Millisecond)
+	require.Eventually(t, func() bool { return len(recorder.ofType(EventHeartbeatDegraded)) >= 2 },
+		time.Second, time.Millisecond)
+	cancel()
+	degraded := recorder.ofType(EventHeartbeatDegraded)
+	assert.Equal(t, failing.GetWorkerID(), degraded[0].WorkerID)
+	assert.Equal(t, HeartbeatDegradedPayload{ConsecutiveFailures: 1, ReregisterAfter: 3, Error: "connection refused"},
+		degraded[0].Payload)
+	assert.Equal(t, 2, degraded[1].Payload.(HeartbeatDegradedPayload).ConsecutiveFailures)
+}
+
+func TestEvents_ClockBackwardDriftWait(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.ClockDriftAction = ClockDriftActionWait
+	generator, err := NewSnowflakeGeneratorCore(1, 2, cfg)
+	require.NoError(t, err)
+	var recorder eventRecorder
+	generator.SetEventHandler(recorder.record)
+
+	last := time.Now().UnixMilli() + 30
+	generator.mu.Lock()
+	generator.lastTimestamp = last
+	generator.mu.Unlock()
+	_, err = generator.GenerateID()
+	require.NoError(t, err)
+	_, err = generator.GenerateID()
+	require.NoError(t, err)
+
+	// One episode: reported once however many retries it took
+	assert.Equal(t, []EventType{EventClockBackward, EventDriftWaitStarted, EventDriftWaitEnded}, recorder.types())
+	backward := recorder.ofType(EventClockBackward)[0]
+	assert.Equal(t, int64(2), backward.WorkerID)
+	payload := backward.Payload.(ClockBackwardPayload)
+	assert.Equal(t, last, payload.LastTimestamp)
+	assert.Positive(t, payload.Drift)
+	assert.Equal(t, ClockDriftActionWait, payload.Action)
+	assert.Equal(t, DriftWaitClockBackward, recorder.ofType(EventDriftWaitStarted)[0].Payload.(DriftWaitPayload).Reason)
+	ended := recorder.ofType(EventDriftWaitEnded)[0].Payload.(DriftWaitPayload)
+	assert.Equal(t, DriftWaitClockBackward, ended.Reason)
+	assert.GreaterOrEqual(t, ended.Wait, 20*time.Millisecond)
+}
+
+func TestEvents_WorkerReuseDriftWait(t *testing.T) {
+	generator, err := NewSnowflakeGeneratorCore(1, 2, nil)
+	require.NoError(t, err)
+	var recorder eventRecorder
+	generator.SetEventHandler(recorder.record)
+	generator.SetNotBefore(time.Now().UnixMilli() + 10)
+
+	_, err = generator.GenerateID()
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{EventDriftWaitStarted, EventDriftWaitEnded}, recorder.types())
+	assert.Equal(t, DriftWaitWorkerReuse, recorder.ofType(EventDriftWaitEnded)[0].Payload.(DriftWaitPayload).Reason)
+}
+
+func TestEvents_SequenceOverflowStorm(t *testing.T) {
+	generator := newOverflowTestGenerator(t, SequenceOverflowStrategyError)
+	var recorder eventRecorder
+	generator.SetEventHandler(recorder.record)
+
+	// Exhausted milliseconds fail fast with the error strategy, so the threshold is crossed within the window
+	var overflows int
+	for overflows < 2*SequenceOverflowStormThreshold {
+		var overflowErr *SequenceOverflowError
+		if _, err := generator.GenerateID(); errors.As(err, &overflowErr) {
+			overflows++
+		} else {
+			require.NoError(t, err)
+		}
+	}
+	storms := recorder.ofType(EventSequenceOverflowStorm)
+	require.NotEmpty(t, storms)
+	payload := storms[0].Payload.(SequenceOverflowStormPayload)
+	assert.Equal(t, int64(SequenceOverflowStormThreshold), payload.Overflows)
+	assert.LessOrEqual(t, payload.Window, SequenceOverflowStormWindow)
+	assert.Equal(t, SequenceOverflowStrategyError, payload.Strategy)
+	assert.LessOrEqual(t, len(storms), 2, "one storm event per window")
+}
+
+func TestPlugin_OnEventHealthDetail(t *testing.T) {
+	plugin := NewSnowflakePlugin()
+	unsubscribe := plugin.OnEvent(nil)
+	unsubscribe()
+	assert.Equal(t, int64(0), plugin.GetHealth().Details["events_dropped"])
+}
+
+func TestEvents_NoHeartbeatDegradedAfterLeaseLost(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:              5,
+		WorkerRecoveryMaxAttempts: -1, // recovery disabled: the worker ID stays lost
+	})
+	var recorder eventRecorder
+	plugin.OnEvent(recorder.record)
+	require.NoError(t, mgr.UpdateTiming(time.Second, 5*time.Millisecond))
+
+	// Another instance takes the worker ID; the heartbeat loop finds out by itself
+	ctx := context.Background()
+	workers, err := allocator.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	require.NoError(t, allocator.Release(ctx, workers[0]))
+	workers[0].InstanceID = "other"
+	_, err = allocator.Acquire(ctx, workers[0], workers[0].WorkerID, time.Hour)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(recorder.ofType(EventLeaseLost)) == 1 },
+		2*time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond) // twenty heartbeat intervals
+
+	types := recorder.types()
+	for i, eventType := range types {
+		if eventType == EventLeaseLost {
+			assert.NotContains(t, types[i+1:], EventHeartbeatDegraded, "events: %v", types)
+		}
+	}
+	mgr.mu.RLock()
+	running := mgr.heartbeatRunning
+	mgr.mu.RUnlock()
+	assert.False(t, running, "the heartbeat stops with the lost lease")
+}
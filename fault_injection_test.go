@@ -630,3 +630,186 @@ func TestErrorRecovery(t *testing.T) {
 	// Should have reasonable success rate even with 10% failure injection
 	assert.Greater(t, successRate, 80.0, "Success rate should be greater than 80%")
 }
+
+// stallingAllocator wraps the memory allocator; while stalled, Renew blocks until resumed or cancelled
+type stallingAllocator struct {
+	*MemoryWorkerIDAllocator
+	mu      sync.Mutex
+	stalled chan struct{}
+}
+
+func (a *stallingAllocator) stall() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stalled = make(chan struct{})
+}
+
+func (a *stallingAllocator) resume() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stalled != nil {
+		close(a.stalled)
+		a.stalled = nil
+	}
+}
+
+func (a *stallingAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	a.mu.Lock()
+	stalled := a.stalled
+	a.mu.Unlock()
+	if stalled != nil {
+		select {
+		case <-stalled:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return a.MemoryWorkerIDAllocator.Renew(ctx, info, ttl)
+}
+
+// newFrozenMemoryAllocator returns a memory allocator whose claims never expire, so that only the local
+// lease deadline decides whether IDs are issued
+func newFrozenMemoryAllocator() *MemoryWorkerIDAllocator {
+	allocator := NewMemoryWorkerIDAllocator()
+	frozen := time.Now()
+	allocator.now = func() time.Time { return frozen }
+	return allocator
+}
+
+// newLeaseFencedPlugin returns a plugin whose worker ID is held through allocator with a short TTL:
+// the lease deadline lies 500ms after the last successful renewal
+func newLeaseFencedPlugin(t *testing.T, allocator WorkerIDAllocator) (*PlugSnowflake, *WorkerIDManager) {
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{
+		TTL:               600 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+		LeaseSafetyMargin: 100 * time.Millisecond,
+	})
+	workerID, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mgr.UnregisterWorkerID(context.Background()) })
+
+	generator, err := NewSnowflakeGeneratorCore(1, workerID, nil)
+	require.NoError(t, err)
+	generator.SetLeaseCheck(mgr.LeaseExpired)
+	plugin := NewSnowflakePlugin()
+	plugin.generator = generator
+	plugin.workerManager = mgr
+	return plugin, mgr
+}
+
+// TestLeaseDeadline_StalledHeartbeat hangs every renewal: the heartbeat loop never sees a failure, yet ID
+// issuance must stop once the lease deadline passes and resume after the next successful renewal
+func TestLeaseDeadline_StalledHeartbeat(t *testing.T) {
+	allocator := &stallingAllocator{MemoryWorkerIDAllocator: newFrozenMemoryAllocator()}
+	plugin, mgr := newLeaseFencedPlugin(t, allocator)
+
+	_, err := plugin.GenerateID()
+	require.NoError(t, err)
+	remaining, ok := mgr.LeaseRemaining()
+	require.True(t, ok)
+	assert.LessOrEqual(t, remaining, 500*time.Millisecond)
+
+	allocator.stall()
+	var expired *WorkerLeaseExpiredError
+	require.Eventually(t, func() bool {
+		_, err := plugin.GenerateID()
+		return errors.As(err, &expired)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, mgr.IsHealthy(), "a hanging renewal has not reported a failure")
+	assert.Equal(t, mgr.GetWorkerID(), expired.WorkerID)
+	_, _, err = plugin.GenerateIDWithMetadata()
+	assert.True(t, errors.As(err, &expired))
+	_, err = plugin.GenerateUUIDv7()
+	assert.True(t, errors.As(err, &expired))
+
+	readiness := plugin.Readiness()
+	assert.False(t, readiness.OK)
+	require.Len(t, readiness.Reasons, 1)
+	assert.Contains(t, readiness.Reasons[0], "lease deadline passed")
+
+	allocator.resume()
+	require.Eventually(t, func() bool {
+		_, err := plugin.GenerateID()
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.NoError(t, mgr.CheckLease())
+}
+
+// TestLeaseDeadline_StarvedHeartbeat stops the heartbeat loop without a failure, as a goroutine starved by
+// GC pauses or CPU throttling would; the lease deadline alone must fence ID issuance
+func TestLeaseDeadline_StarvedHeartbeat(t *testing.T) {
+	plugin, mgr := newLeaseFencedPlugin(t, newFrozenMemoryAllocator())
+
+	mgr.mu.Lock()
+	mgr.heartbeatCancel()
+	mgr.mu.Unlock()
+
+	_, err := plugin.GenerateID()
+	require.NoError(t, err)
+	time.Sleep(600 * time.Millisecond)
+
+	var expired *WorkerLeaseExpiredError
+	_, err = plugin.GenerateID()
+	require.True(t, errors.As(err, &expired), "got %v", err)
+	assert.Greater(t, expired.ExpiredFor, time.Duration(0))
+	assert.True(t, mgr.IsHealthy())
+
+	// A late renewal that still finds the claim lifts the fence
+	require.NoError(t, mgr.sendHeartbeat())
+	_, err = plugin.GenerateID()
+	assert.NoError(t, err)
+}
+
+// TestLeaseDeadline_ClockWaitCrossesDeadline starts generation with the lease still valid and makes it wait for a
+// clock that stepped back past the lease deadline; the ID must not be issued once the wait ends
+func TestLeaseDeadline_ClockWaitCrossesDeadline(t *testing.T) {
+	plugin, mgr := newLeaseFencedPlugin(t, newFrozenMemoryAllocator())
+	mgr.mu.Lock()
+	mgr.heartbeatCancel()
+	mgr.mu.Unlock()
+	remaining, ok := mgr.LeaseRemaining()
+	require.True(t, ok)
+
+	generator := plugin.generator
+	last := time.Now().Add(remaining + 300*time.Millisecond).UnixMilli()
+	generator.mu.Lock()
+	generator.lastTimestamp = last
+	generator.mu.Unlock()
+	require.NoError(t, mgr.CheckLease(), "the lease holds when generation starts")
+
+	var expired *WorkerLeaseExpiredError
+	_, err := plugin.GenerateID()
+	require.True(t, errors.As(err, &expired), "got %v", err)
+	assert.Equal(t, mgr.GetWorkerID(), expired.WorkerID)
+	assert.Equal(t, last, generator.GetStats().LastGeneratedTime, "no ID was issued after the wait")
+}
+
+// TestLeaseDeadline_SlowRenewalCountsFromStart checks that a renewal extends the lease from when it was sent,
+// not from when it returned, and that releasing the worker ID clears the deadline
+func TestLeaseDeadline_SlowRenewalCountsFromStart(t *testing.T) {
+	allocator := &stallingAllocator{MemoryWorkerIDAllocator: NewMemoryWorkerIDAllocator()}
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{
+		TTL:               time.Minute,
+		HeartbeatInterval: time.Hour,
+		LeaseSafetyMargin: 30 * time.Second, // capped at TTL/3
+	})
+	_, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+
+	allocator.stall()
+	done := make(chan error, 1)
+	go func() { done <- mgr.sendHeartbeat() }()
+	time.Sleep(200 * time.Millisecond)
+	allocator.resume()
+	require.NoError(t, <-done)
+
+	remaining, ok := mgr.LeaseRemaining()
+	require.True(t, ok)
+	assert.Less(t, remaining, 40*time.Second-150*time.Millisecond)
+	assert.Greater(t, remaining, 39*time.Second)
+
+	require.NoError(t, mgr.UnregisterWorkerID(context.Background()))
+	_, ok = mgr.LeaseRemaining()
+	assert.False(t, ok)
+	assert.NoError(t, mgr.CheckLease())
+}
@@ -2,7 +2,10 @@ package eonId
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"runtime"
 	"sync/atomic"
 	"time"
 
@@ -19,6 +22,10 @@ func NewSnowflakeGeneratorWithConfig(config *pb.EonId) (*Generator, error) {
 	if config.MaxClockDrift != nil {
 		maxClockDrift = config.MaxClockDrift.AsDuration()
 	}
+	clockCheckInterval := DefaultClockCheckInterval
+	if config.ClockCheckInterval != nil {
+		clockCheckInterval = config.ClockCheckInterval.AsDuration()
+	}
 	clockDriftAction := config.ClockDriftAction
 	if clockDriftAction == "" {
 		clockDriftAction = ClockDriftActionWait
@@ -31,10 +38,13 @@ func NewSnowflakeGeneratorWithConfig(config *pb.EonId) (*Generator, error) {
 		SequenceBits:               int(config.SequenceBits),
 		EnableClockDriftProtection: config.EnableClockDriftProtection,
 		MaxClockDrift:              maxClockDrift,
+		ClockCheckInterval:         clockCheckInterval,
 		ClockDriftAction:           clockDriftAction,
 		EnableSequenceCache:        config.EnableSequenceCache,
 		SequenceCacheSize:          int(config.SequenceCacheSize),
 		EnableMetrics:              config.EnableMetrics,
+		SequenceOverflowStrategy:   config.SequenceOverflowStrategy,
+		SequenceStartMode:          config.SequenceStartMode,
 	}
 
 	return NewSnowflakeGeneratorCore(int64(config.DatacenterId), int64(config.WorkerId), internalConfig)
@@ -96,9 +106,15 @@ func NewSnowflakeGeneratorCore(datacenterID, workerID int64, config *GeneratorCo
 		maxIgnoreBackwardDriftMs:   3600000, // 1 hour: reject Ignore if drift exceeds this
 		enableClockDriftProtection: config.EnableClockDriftProtection,
 		maxClockDrift:              config.MaxClockDrift,
+		clockCheckInterval:         clockCheckIntervalOr(config.ClockCheckInterval),
 		clockDriftAction:           config.ClockDriftAction,
 		enableSequenceCache:        config.EnableSequenceCache,
 		cacheSize:                  config.SequenceCacheSize,
+		sequenceOverflowStrategy:   config.SequenceOverflowStrategy,
+		sequenceStartMode:          config.SequenceStartMode,
+	}
+	if generator.sequenceOverflowStrategy == "" {
+		generator.sequenceOverflowStrategy = SequenceOverflowStrategySleep
 	}
 
 	// Initialize sequence cache if enabled
@@ -110,6 +126,8 @@ func NewSnowflakeGeneratorCore(datacenterID, workerID int64, config *GeneratorCo
 	// Initialize metrics only when enabled
 	if config.EnableMetrics {
 		generator.metrics = NewSnowflakeMetrics()
+	} else {
+		generator.metricsDisabled = 1
 	}
 
 	return generator, nil
@@ -118,66 +136,114 @@ func NewSnowflakeGeneratorCore(datacenterID, workerID int64, config *GeneratorCo
 // GenerateID generates a new snowflake ID
 // This method is optimized to minimize lock holding time - no sleep while holding lock
 func (g *Generator) GenerateID() (int64, error) {
+	slot, err := g.nextSlot()
+	if err != nil {
+		return 0, err
+	}
+	return g.composeID(slot), nil
+}
+
+// idSlot is a (timestamp, sequence) pair claimed under g.mu together with the node IDs in effect at that moment.
+// Every ID format produced by the generator is derived from exactly one slot, so formats share uniqueness guarantees.
+type idSlot struct {
+	timestamp    int64 // Unix milliseconds
+	sequence     int64
+	datacenterID int64
+	workerID     int64
+}
+
+// composeID packs a slot into the 64-bit snowflake layout
+func (g *Generator) composeID(slot idSlot) int64 {
+	return ((slot.timestamp - g.customEpoch) << g.timestampShift) |
+		(slot.datacenterID << g.datacenterShift) |
+		(slot.workerID << g.workerShift) |
+		slot.sequence
+}
+
+// nextSlot claims the next free slot, waiting outside the lock on clock backward or sequence overflow
+func (g *Generator) nextSlot() (idSlot, error) {
+	// Counted before the shutdown check: Shutdown sets the flag before it reads the count, so a call it does
+	// not wait for sees the flag
+	atomic.AddInt64(&g.inFlight, 1)
+	defer atomic.AddInt64(&g.inFlight, -1)
 	startTime := time.Now()
 	maxRetries := 10
 
 	for retry := 0; retry < maxRetries; retry++ {
 		// Check shutdown before each attempt to exit quickly
 		if atomic.LoadInt32(&g.isShuttingDownAtomic) != 0 {
-			if g.metrics != nil {
-				g.metrics.RecordError("generation")
+			if m := g.activeMetrics(); m != nil {
+				m.RecordError("generation")
 			}
-			return 0, fmt.Errorf("generator is shutting down")
+			return idSlot{}, fmt.Errorf("generator is shutting down")
 		}
 
-		id, needWait, waitDuration, cacheHit, err := g.tryGenerateID()
+		slot, needWait, waitDuration, cacheHit, err := g.tryNextSlot()
 		if err != nil {
-			return 0, err
+			var overflowErr *SequenceOverflowError
+			if !errors.As(err, &overflowErr) || g.sequenceOverflowStrategy == SequenceOverflowStrategyError {
+				return idSlot{}, err
+			}
+			// Sequence exhausted - wait for the next tick OUTSIDE the lock
+			if atomic.LoadInt32(&g.isShuttingDownAtomic) != 0 {
+				if m := g.activeMetrics(); m != nil {
+					m.RecordError("generation")
+				}
+				return idSlot{}, fmt.Errorf("generator is shutting down")
+			}
+			g.waitForNextTick(overflowErr.Timestamp.UnixMilli())
+			continue
 		}
 
 		if !needWait {
 			// Success - record metrics and return
 			latency := time.Since(startTime)
-			if g.metrics != nil {
-				g.metrics.RecordIDGeneration(latency, cacheHit)
+			if m := g.activeMetrics(); m != nil {
+				m.RecordIDGeneration(latency, cacheHit)
 			}
-			return id, nil
+			return slot, nil
 		}
 
 		// Need to wait - do it OUTSIDE the lock
 		// Check shutdown before sleeping to avoid unnecessary delay
 		if atomic.LoadInt32(&g.isShuttingDownAtomic) != 0 {
-			if g.metrics != nil {
-				g.metrics.RecordError("generation")
+			if m := g.activeMetrics(); m != nil {
+				m.RecordError("generation")
 			}
-			return 0, fmt.Errorf("generator is shutting down")
-		}
-		if waitDuration > 0 {
-			time.Sleep(waitDuration)
-		} else {
-			// Minimal wait for sequence overflow
-			time.Sleep(100 * time.Microsecond)
+			return idSlot{}, fmt.Errorf("generator is shutting down")
 		}
+		time.Sleep(waitDuration)
 	}
 
-	if g.metrics != nil {
-		g.metrics.RecordError("generation")
+	if m := g.activeMetrics(); m != nil {
+		m.RecordError("generation")
 	}
-	return 0, fmt.Errorf("failed to generate ID after %d retries", maxRetries)
+	return idSlot{}, fmt.Errorf("failed to generate ID after %d retries", maxRetries)
 }
 
-// tryGenerateID attempts to generate an ID, returns (id, needWait, waitDuration, cacheHit, error)
+// tryNextSlot attempts to claim a slot, returns (slot, needWait, waitDuration, cacheHit, error)
 // If needWait is true, caller should wait for waitDuration and retry
-func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
+func (g *Generator) tryNextSlot() (idSlot, bool, time.Duration, bool, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	// Check if generator is shutting down
 	if g.isShuttingDown {
-		if g.metrics != nil {
-			g.metrics.RecordError("generation")
+		if m := g.activeMetrics(); m != nil {
+			m.RecordError("generation")
+		}
+		return idSlot{}, false, 0, false, fmt.Errorf("generator is shutting down")
+	}
+
+	// The worker ID lease was lost; the worker ID may already belong to another instance
+	if g.fenced {
+		return idSlot{}, false, 0, false, &WorkerIDFencedError{WorkerID: g.workerID}
+	}
+	// Checked here rather than only before generation: a clock or overflow wait can outlast the lease
+	if g.leaseExpired != nil {
+		if expiredFor, expired := g.leaseExpired(); expired {
+			return idSlot{}, false, 0, false, &WorkerLeaseExpiredError{WorkerID: g.workerID, ExpiredFor: expiredFor}
 		}
-		return 0, false, 0, false, fmt.Errorf("generator is shutting down")
 	}
 
 	timestamp := g.getCurrentTimestamp()
@@ -185,20 +251,44 @@ func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
 	// Check for clock drift (no sleep in this check)
 	if g.enableClockDriftProtection {
 		if err := g.checkClockDriftNoSleep(timestamp); err != nil {
-			return 0, false, 0, false, err
+			return idSlot{}, false, 0, false, err
+		}
+	}
+
+	// A reused worker ID must not issue at timestamps its previous owner may have used
+	if timestamp <= g.notBefore {
+		wait := time.Duration(g.notBefore-timestamp+1) * time.Millisecond
+		if g.clockDriftAction == ClockDriftActionWait && wait <= MaxClockBackwardWait {
+			g.driftWaitLocked(DriftWaitWorkerReuse, wait)
+			return idSlot{}, true, wait, false, nil
+		}
+		return idSlot{}, false, 0, false, &WorkerIDReuseError{
+			WorkerID:  g.workerID,
+			NotBefore: time.UnixMilli(g.notBefore),
+			Wait:      wait,
 		}
 	}
 
 	// Handle clock going backwards - return wait duration instead of sleeping
-	if timestamp < g.lastTimestamp {
+	backward := timestamp < g.lastTimestamp
+	if backward {
 		driftMs := g.lastTimestamp - timestamp
 		drift := time.Duration(driftMs) * time.Millisecond
 
 		atomic.AddInt64(&g.clockBackwardCount, 1)
+		if !g.clockBackward {
+			g.clockBackward = true
+			g.emitLocked(EventClockBackward, ClockBackwardPayload{
+				LastTimestamp:    g.lastTimestamp,
+				CurrentTimestamp: timestamp,
+				Drift:            drift,
+				Action:           g.clockDriftAction,
+			})
+		}
 
 		switch g.clockDriftAction {
 		case ClockDriftActionError:
-			return 0, false, 0, false, &ClockDriftError{
+			return idSlot{}, false, 0, false, &ClockDriftError{
 				CurrentTime:   time.Unix(timestamp/1000, (timestamp%1000)*1000000),
 				LastTimestamp: time.Unix(g.lastTimestamp/1000, (g.lastTimestamp%1000)*1000000),
 				Drift:         drift,
@@ -206,7 +296,7 @@ func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
 		case ClockDriftActionWait:
 			// For large backward drift (>MaxClockBackwardWait), return error instead of futile retries
 			if drift > MaxClockBackwardWait {
-				return 0, false, 0, false, &ClockDriftError{
+				return idSlot{}, false, 0, false, &ClockDriftError{
 					CurrentTime:   time.Unix(timestamp/1000, (timestamp%1000)*1000000),
 					LastTimestamp: time.Unix(g.lastTimestamp/1000, (g.lastTimestamp%1000)*1000000),
 					Drift:         drift,
@@ -217,12 +307,13 @@ func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
 			if waitTime > MaxClockBackwardWait {
 				waitTime = MaxClockBackwardWait
 			}
-			return 0, true, waitTime, false, nil
+			g.driftWaitLocked(DriftWaitClockBackward, waitTime)
+			return idSlot{}, true, waitTime, false, nil
 		case ClockDriftActionIgnore:
 			// Reject if lastTimestamp has drifted too far from real time (timestamp overflow risk)
 			artificialDriftMs := g.lastTimestamp - timestamp
 			if artificialDriftMs > g.maxIgnoreBackwardDriftMs {
-				return 0, false, 0, false, &ClockDriftError{
+				return idSlot{}, false, 0, false, &ClockDriftError{
 					CurrentTime:   time.Unix(timestamp/1000, (timestamp%1000)*1000000),
 					LastTimestamp: time.Unix(g.lastTimestamp/1000, (g.lastTimestamp%1000)*1000000),
 					Drift:         drift,
@@ -232,7 +323,7 @@ func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
 			timestamp = g.lastTimestamp + 1
 			g.sequence = 0
 		default:
-			return 0, false, 0, false, fmt.Errorf("unknown clock drift action: %s", g.clockDriftAction)
+			return idSlot{}, false, 0, false, fmt.Errorf("unknown clock drift action: %s", g.clockDriftAction)
 		}
 	}
 
@@ -243,45 +334,157 @@ func (g *Generator) tryGenerateID() (int64, bool, time.Duration, bool, error) {
 			// Use cached sequence if available and valid
 			cachedSeq := g.sequenceCache[g.cacheIndex]
 			g.cacheIndex++
-			if cachedSeq > 0 && cachedSeq <= g.maxSequence {
+			if cachedSeq >= 0 && cachedSeq <= g.maxSequence {
 				g.sequence = cachedSeq
+				g.sequenceCount++
 				cacheHit = true
-			} else {
-				// Invalid cached sequence, fall back to normal increment
-				g.sequence = (g.sequence + 1) & g.maxSequence
-				if g.sequence == 0 {
-					// Sequence overflow - return signal to wait
-					return 0, true, 0, false, nil
-				}
-			}
-		} else {
-			// Cache exhausted or disabled, use normal increment
-			g.sequence = (g.sequence + 1) & g.maxSequence
-			if g.sequence == 0 {
-				// Sequence overflow - return signal to wait outside lock
-				return 0, true, 0, false, nil
+			} else if !g.incrementSequence() {
+				// Invalid cached sequence and no normal increment left - caller applies overflow strategy
+				return idSlot{}, false, 0, false, g.sequenceOverflow(timestamp)
 			}
+		} else if !g.incrementSequence() {
+			// Cache exhausted or disabled and sequence used up - caller applies overflow strategy outside lock
+			return idSlot{}, false, 0, false, g.sequenceOverflow(timestamp)
 		}
 	} else {
-		// New millisecond, reset sequence and refill cache if enabled
-		g.sequence = 0
+		// New millisecond, pick the starting sequence and refill cache if enabled
+		g.sequence = g.nextSequenceStart()
+		g.sequenceCount = 1
 		if g.enableSequenceCache {
 			g.refillSequenceCache()
 		}
 	}
 
 	g.lastTimestamp = timestamp
+	if !backward {
+		g.clockBackward = false
+	}
+	if g.driftWaitReason != "" {
+		g.emitLocked(EventDriftWaitEnded, DriftWaitPayload{Reason: g.driftWaitReason, Wait: time.Since(g.driftWaitStart)})
+		g.driftWaitReason = ""
+	}
 
-	// Generate the ID
-	id := ((timestamp - g.customEpoch) << g.timestampShift) |
-		(g.datacenterID << g.datacenterShift) |
-		(g.workerID << g.workerShift) |
-		g.sequence
+	slot := idSlot{
+		timestamp:    timestamp,
+		sequence:     g.sequence,
+		datacenterID: g.datacenterID,
+		workerID:     g.workerID,
+	}
 
 	// Update statistics using atomic operation
 	atomic.AddInt64(&g.generatedCount, 1)
 
-	return id, false, 0, cacheHit, nil
+	return slot, false, 0, cacheHit, nil
+}
+
+// incrementSequence advances the sequence within the current millisecond, wrapping past maxSequence
+// back to 0 when the millisecond started at a non-zero offset. Exhaustion is detected by count, not value:
+// once maxSequence+1 values were issued it returns false and leaves the sequence untouched,
+// so a retry in the same millisecond cannot reissue a value.
+func (g *Generator) incrementSequence() bool {
+	if g.sequenceCount > g.maxSequence {
+		return false
+	}
+	g.sequence = (g.sequence + 1) & g.maxSequence
+	g.sequenceCount++
+	return true
+}
+
+// nextSequenceStart returns the first sequence value of a new millisecond. Caller must hold g.mu.
+// A non-zero start spreads low-traffic IDs across id % N shards instead of piling them onto sequence 0.
+func (g *Generator) nextSequenceStart() int64 {
+	switch g.sequenceStartMode {
+	case SequenceStartRandom:
+		return rand.Int64N(g.maxSequence + 1)
+	case SequenceStartRoundRobin:
+		g.sequenceRotation = (g.sequenceRotation + 1) & g.maxSequence
+		return g.sequenceRotation
+	default:
+		return 0
+	}
+}
+
+// sequenceOverflow records an overflow event and builds the error returned to nextSlot. Caller must hold g.mu.
+func (g *Generator) sequenceOverflow(timestamp int64) error {
+	if m := g.activeMetrics(); m != nil {
+		m.RecordSequenceOverflow()
+	}
+	now := time.Now()
+	if now.Sub(g.overflowWindow) >= SequenceOverflowStormWindow {
+		g.overflowWindow = now
+		g.overflowsInWindow = 0
+		g.overflowStormSent = false
+	}
+	g.overflowsInWindow++
+	if g.overflowsInWindow >= SequenceOverflowStormThreshold && !g.overflowStormSent {
+		g.overflowStormSent = true
+		g.emitLocked(EventSequenceOverflowStorm, SequenceOverflowStormPayload{
+			Overflows: g.overflowsInWindow,
+			Window:    now.Sub(g.overflowWindow),
+			Strategy:  g.sequenceOverflowStrategy,
+		})
+	}
+	return &SequenceOverflowError{
+		Timestamp:   time.UnixMilli(timestamp),
+		MaxSequence: g.maxSequence,
+	}
+}
+
+// driftWaitLocked starts a drift episode unless one is open; the episode ends when an ID is issued again.
+// Caller must hold g.mu.
+func (g *Generator) driftWaitLocked(reason string, wait time.Duration) {
+	if g.driftWaitReason != "" {
+		return
+	}
+	g.driftWaitReason = reason
+	g.driftWaitStart = time.Now()
+	g.emitLocked(EventDriftWaitStarted, DriftWaitPayload{Reason: reason, Wait: wait})
+}
+
+// SetEventHandler sets the function that receives clock lifecycle events: clock backward, drift wait started
+// and ended, and sequence overflow storms. It is called on the generation path with the generator's lock held
+// and must not block or call back into the generator.
+func (g *Generator) SetEventHandler(handler func(Event)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onEvent = handler
+}
+
+// emitLocked sends an event to the event handler. Caller must hold g.mu.
+func (g *Generator) emitLocked(eventType EventType, payload any) {
+	if g.onEvent == nil {
+		return
+	}
+	g.onEvent(Event{
+		Type:         eventType,
+		Time:         time.Now(),
+		DatacenterID: g.datacenterID,
+		WorkerID:     g.workerID,
+		Payload:      payload,
+	})
+}
+
+// waitForNextTick blocks until the clock has passed lastMs using the configured overflow strategy and records the wait.
+func (g *Generator) waitForNextTick(lastMs int64) {
+	// lastMs ahead of the wall clock means the clock stepped back; the clock drift action handles that on retry
+	if lastMs > g.getCurrentTimestamp() {
+		return
+	}
+
+	start := time.Now()
+	switch g.sequenceOverflowStrategy {
+	case SequenceOverflowStrategySpin:
+		for g.getCurrentTimestamp() <= lastMs {
+			runtime.Gosched()
+		}
+	default:
+		if wait := time.Until(time.UnixMilli(lastMs + 1)); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	if m := g.activeMetrics(); m != nil {
+		m.RecordSequenceOverflowWait(time.Since(start))
+	}
 }
 
 // checkClockDriftNoSleep checks for clock drift without sleeping
@@ -291,7 +494,7 @@ func (g *Generator) checkClockDriftNoSleep(currentTimestamp int64) error {
 	}
 
 	now := time.Now()
-	if now.Sub(g.lastClockCheck) < time.Second {
+	if now.Sub(g.lastClockCheck) < g.clockCheckInterval {
 		return nil // Skip check if checked recently
 	}
 	g.lastClockCheck = now
@@ -344,46 +547,171 @@ func (g *Generator) GetStats() *GeneratorStats {
 
 // GetMetrics returns detailed metrics about the generator
 func (g *Generator) GetMetrics() *Metrics {
-	if g.metrics == nil {
+	m := g.activeMetrics()
+	if m == nil {
 		return nil
 	}
-	return g.metrics.GetSnapshot()
+	return m.GetSnapshot()
+}
+
+// IsAlive reports whether the generator has not been shut down
+func (g *Generator) IsAlive() bool {
+	return atomic.LoadInt32(&g.isShuttingDownAtomic) == 0
 }
 
-// IsHealthy returns whether the generator is healthy
+// IsHealthy returns whether the generator can issue IDs right now; see NotReadyReasons
 func (g *Generator) IsHealthy() bool {
+	return len(g.NotReadyReasons()) == 0
+}
+
+// NotReadyReasons lists why the generator cannot safely issue IDs right now, empty when ready.
+// The generator is not ready while shutting down, while the clock is behind the last issued timestamp
+// (generation waits or fails until it catches up), while a reused worker ID waits out its previous owner and when the epoch is about to run out of timestamp bits.
+func (g *Generator) NotReadyReasons() []string {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// Check if generator is shutting down
+	var reasons []string
 	if g.isShuttingDown {
-		return false
+		reasons = append(reasons, "generator is shutting down")
 	}
 
-	// Check for recent clock drift issues
-	if g.enableClockDriftProtection {
-		now := time.Now()
-		if now.Sub(g.lastClockCheck) < time.Minute {
-			// If we've checked recently and no errors, we're healthy
-			return true
-		}
+	now := g.getCurrentTimestamp()
+	// In ignore mode IDs keep being issued from lastTimestamp, so a lagging clock does not block generation
+	if g.clockDriftAction != ClockDriftActionIgnore && now < g.lastTimestamp {
+		reasons = append(reasons, fmt.Sprintf("clock is %v behind the last issued timestamp",
+			time.Duration(g.lastTimestamp-now)*time.Millisecond))
 	}
 
-	return true
+	if g.fenced {
+		reasons = append(reasons, fmt.Sprintf("worker ID %d lease lost, waiting for a new worker ID", g.workerID))
+	}
+
+	if now <= g.notBefore {
+		reasons = append(reasons, fmt.Sprintf("reused worker ID %d may issue IDs in %v",
+			g.workerID, time.Duration(g.notBefore-now+1)*time.Millisecond))
+	}
+
+	if remaining, ok := g.epochRemainingLocked(now); ok && remaining < EpochExhaustionMargin {
+		reasons = append(reasons, fmt.Sprintf("epoch exhausted in %v", remaining.Truncate(time.Second)))
+	}
+	return reasons
 }
 
-// Shutdown gracefully shuts down the generator
-func (g *Generator) Shutdown(ctx context.Context) error {
+// SetNotBefore makes the generator issue IDs only at timestamps after notBefore (Unix ms). It is set after
+// a worker ID is registered, from the previous owner's last timestamp plus the reuse safety margin; 0 clears it.
+// Until the clock passes notBefore, generation waits if the clock drift action is "wait" and the wait is at
+// most MaxClockBackwardWait, and fails with *WorkerIDReuseError otherwise.
+func (g *Generator) SetNotBefore(notBefore int64) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	g.notBefore = notBefore
+}
 
-	if g.isShuttingDown {
-		return nil // Already shutting down
+// Fence stops ID issuance under the current worker ID: once Fence returns, every generation attempt fails with
+// *WorkerIDFencedError until a new worker ID is applied. It is called when the worker ID lease is lost.
+func (g *Generator) Fence() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fenced = true
+}
+
+// SetLeaseCheck sets the function that reports a passed worker ID lease deadline, see
+// WorkerIDManager.LeaseExpired. It is called under the generator's lock whenever a slot is claimed, so no ID is
+// issued after the deadline even when generation waited for the clock past it; it must not block.
+func (g *Generator) SetLeaseCheck(leaseExpired func() (expiredFor time.Duration, expired bool)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.leaseExpired = leaseExpired
+}
+
+// IsFenced reports whether ID issuance is fenced after a lost worker ID lease
+func (g *Generator) IsFenced() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.fenced
+}
+
+// EpochRemaining returns how long until the timestamp bits overflow; ok is false if the layout is unknown
+func (g *Generator) EpochRemaining() (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.epochRemainingLocked(g.getCurrentTimestamp())
+}
+
+// epochRemainingLocked computes the time left before timestamp overflow. Caller must hold g.mu.
+func (g *Generator) epochRemainingLocked(now int64) (time.Duration, bool) {
+	// timestampBits is zero only for generators not built by NewSnowflakeGeneratorCore
+	if g.timestampBits <= 0 || g.timestampBits >= 63 {
+		return 0, false
+	}
+	remainingMs := g.customEpoch + ((int64(1) << g.timestampBits) - 1) - now
+	if remainingMs > int64(time.Duration(1<<63-1)/time.Millisecond) {
+		return time.Duration(1<<63 - 1), true
+	}
+	return time.Duration(remainingMs) * time.Millisecond, true
+}
+
+// Shutdown stops the generator from issuing IDs, then waits until the generation calls in progress have returned
+// or ctx is done. Calls made after Shutdown fail with "generator is shutting down".
+func (g *Generator) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.isShuttingDown {
+		g.isShuttingDown = true
+		atomic.StoreInt32(&g.isShuttingDownAtomic, 1)
 	}
+	g.mu.Unlock()
 
-	g.isShuttingDown = true
-	atomic.StoreInt32(&g.isShuttingDownAtomic, 1)
-	return nil
+	// No slot can be claimed any more; wait for the calls that are still retrying or sleeping to return
+	return waitUntil(ctx, func() bool { return atomic.LoadInt64(&g.inFlight) == 0 },
+		func() error {
+			return fmt.Errorf("%d generation call(s) still in progress", atomic.LoadInt64(&g.inFlight))
+		})
+}
+
+// InFlight returns the number of generation calls in progress
+func (g *Generator) InFlight() int64 {
+	return atomic.LoadInt64(&g.inFlight)
+}
+
+// WaitPastLastTimestamp waits until the clock has passed the last issued timestamp, or ctx is done, and returns
+// that timestamp (Unix ms, -1 if no ID was issued). After Shutdown the timestamp is final, so a claim released
+// once this returns nil records the last timestamp the worker ID was used at, and that timestamp is in the past.
+func (g *Generator) WaitPastLastTimestamp(ctx context.Context) (int64, error) {
+	g.mu.Lock()
+	last := g.lastTimestamp
+	g.mu.Unlock()
+	err := waitUntil(ctx, func() bool { return g.getCurrentTimestamp() > last },
+		func() error {
+			return fmt.Errorf("clock is still %v behind the last issued timestamp",
+				time.Duration(last-g.getCurrentTimestamp()+1)*time.Millisecond)
+		})
+	return last, err
+}
+
+// drainPollInterval is how often Shutdown and WaitPastLastTimestamp check their condition
+const drainPollInterval = time.Millisecond
+
+// waitUntil polls done until it holds or ctx is done; pending describes what is left when ctx ends the wait
+func waitUntil(ctx context.Context, done func() bool, pending func() error) error {
+	if done() {
+		return nil
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if done() {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", ctx.Err(), pending())
+		case <-ticker.C:
+			if done() {
+				return nil
+			}
+		}
+	}
 }
 
 // ParseID parses a snowflake ID and returns its components; validates timestamp is within [epoch, epoch+timestampBits].
@@ -426,8 +754,8 @@ func (g *Generator) refillSequenceCache() {
 	}
 
 	// Calculate how many valid sequences we can cache
-	// Start from sequence 1 (since 0 is already used)
-	maxValidCount := int(g.maxSequence) // Maximum sequences per millisecond
+	// The current sequence is already used by this millisecond's first ID
+	maxValidCount := int(g.maxSequence) // Maximum remaining sequences per millisecond
 
 	// Limit cache size to available sequence space
 	cacheSize := len(g.sequenceCache)
@@ -435,32 +763,21 @@ func (g *Generator) refillSequenceCache() {
 		cacheSize = maxValidCount
 	}
 
-	// Fill cache with sequential numbers starting from 1
-	// Note: sequence is already 0 at this point (new millisecond)
-	// Limit to actual available sequences
-	actualCacheSize := cacheSize
-	if actualCacheSize > maxValidCount {
-		actualCacheSize = maxValidCount
+	// Fill cache with the sequences following the starting one, wrapping within maxSequence
+	for i := 0; i < cacheSize; i++ {
+		g.sequenceCache[i] = (g.sequence + int64(i) + 1) & g.maxSequence
 	}
-
-	for i := 0; i < actualCacheSize; i++ {
-		seq := int64(i + 1) // Start from 1, not 0
-		if seq > g.maxSequence {
-			// Should not happen if cacheSize is validated, but check anyway
-			break
-		}
-		g.sequenceCache[i] = seq
+	// Mark entries beyond the available sequence space invalid so they fall back to incrementSequence
+	for i := cacheSize; i < len(g.sequenceCache); i++ {
+		g.sequenceCache[i] = -1
 	}
 
-	// Note: We keep the original slice size, but only use actualCacheSize entries
-	// The cacheIndex check in GenerateID ensures we don't access beyond actualCacheSize
-
 	// Reset cache index
 	g.cacheIndex = 0
 
 	// Record cache refill metrics
-	if g.metrics != nil {
-		g.metrics.RecordCacheRefill()
+	if m := g.activeMetrics(); m != nil {
+		m.RecordCacheRefill()
 	}
 }
 
@@ -472,10 +789,13 @@ type GeneratorConfig struct {
 	SequenceBits               int
 	EnableClockDriftProtection bool
 	MaxClockDrift              time.Duration
+	ClockCheckInterval         time.Duration // how often forward drift is checked; DefaultClockCheckInterval when zero
 	ClockDriftAction           string
 	EnableSequenceCache        bool
 	SequenceCacheSize          int
-	EnableMetrics              bool // when false, no metrics are created to reduce overhead
+	EnableMetrics              bool   // when false, no metrics are created to reduce overhead
+	SequenceOverflowStrategy   string // "spin", "sleep" (default when empty) or "error"
+	SequenceStartMode          string // "zero" (default when empty), "random" or "round_robin"
 }
 
 // DefaultGeneratorConfig returns default generator configuration
@@ -487,10 +807,13 @@ func DefaultGeneratorConfig() *GeneratorConfig {
 		SequenceBits:               12, // 0-4095
 		EnableClockDriftProtection: true,
 		MaxClockDrift:              DefaultMaxClockDrift,
+		ClockCheckInterval:         DefaultClockCheckInterval,
 		ClockDriftAction:           ClockDriftActionWait,
 		EnableSequenceCache:        false,
 		SequenceCacheSize:          DefaultSequenceCacheSize,
 		EnableMetrics:              true,
+		SequenceOverflowStrategy:   SequenceOverflowStrategySleep,
+		SequenceStartMode:          SequenceStartZero,
 	}
 }
 
@@ -519,6 +842,21 @@ func (c *GeneratorConfig) Validate() error {
 		return err
 	}
 
+	// Settings that can also change at runtime
+	if err := c.validateRuntime(); err != nil {
+		return err
+	}
+
+	// Validate bit allocation efficiency
+	if err := c.validateBitAllocationEfficiency(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateRuntime validates the settings a running generator accepts through ApplyRuntimeConfig
+func (c *GeneratorConfig) validateRuntime() error {
 	// Enhanced clock drift validation
 	if err := c.validateClockDrift(); err != nil {
 		return err
@@ -529,9 +867,18 @@ func (c *GeneratorConfig) Validate() error {
 		return err
 	}
 
-	// Validate bit allocation efficiency
-	if err := c.validateBitAllocationEfficiency(); err != nil {
-		return err
+	switch c.SequenceOverflowStrategy {
+	case "", SequenceOverflowStrategySpin, SequenceOverflowStrategySleep, SequenceOverflowStrategyError:
+		// Valid strategies
+	default:
+		return fmt.Errorf("invalid sequence overflow strategy: %s", c.SequenceOverflowStrategy)
+	}
+
+	switch c.SequenceStartMode {
+	case "", SequenceStartZero, SequenceStartRandom, SequenceStartRoundRobin:
+		// Valid modes
+	default:
+		return fmt.Errorf("invalid sequence start mode: %s", c.SequenceStartMode)
 	}
 
 	return nil
@@ -589,9 +936,21 @@ func (c *GeneratorConfig) validateClockDrift() error {
 		}
 	}
 
+	if c.ClockCheckInterval < 0 {
+		return fmt.Errorf("clock check interval cannot be negative: %v", c.ClockCheckInterval)
+	}
+
 	return nil
 }
 
+// clockCheckIntervalOr returns interval, or DefaultClockCheckInterval when it is not set
+func clockCheckIntervalOr(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return DefaultClockCheckInterval
+	}
+	return interval
+}
+
 // validateCache validates sequence cache settings
 func (c *GeneratorConfig) validateCache() error {
 	if c.EnableSequenceCache {
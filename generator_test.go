@@ -1,6 +1,7 @@
 package eonId
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -174,3 +175,209 @@ func TestGenerator_GenerateID_ConcurrentMetricsAndCache(t *testing.T) {
 		t.Fatalf("GenerateID failed: %v", err)
 	}
 }
+
+func newOverflowTestGenerator(t *testing.T, strategy string) *Generator {
+	t.Helper()
+	cfg := DefaultGeneratorConfig()
+	cfg.WorkerIDBits = 5
+	cfg.SequenceBits = 7 // 128 IDs per millisecond, easy to exhaust
+	cfg.SequenceOverflowStrategy = strategy
+	g, err := NewSnowflakeGeneratorCore(1, 1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestSequenceOverflowStrategy_WaitStrategiesStayUnique(t *testing.T) {
+	for _, strategy := range []string{SequenceOverflowStrategySpin, SequenceOverflowStrategySleep} {
+		t.Run(strategy, func(t *testing.T) {
+			g := newOverflowTestGenerator(t, strategy)
+			seen := make(map[int64]struct{}, 5000)
+			for i := 0; i < 5000; i++ {
+				id, err := g.GenerateID()
+				if err != nil {
+					t.Fatalf("GenerateID failed: %v", err)
+				}
+				if _, dup := seen[id]; dup {
+					t.Fatalf("duplicate ID %d after %d IDs", id, i)
+				}
+				seen[id] = struct{}{}
+			}
+			snap := g.GetMetrics()
+			if snap.SequenceOverflows == 0 {
+				t.Fatal("expected sequence overflows to be recorded")
+			}
+			if snap.SequenceOverflowWait <= 0 {
+				t.Error("expected overflow wait time to be recorded")
+			}
+		})
+	}
+}
+
+func TestSequenceOverflowStrategy_ErrorFailsFast(t *testing.T) {
+	g := newOverflowTestGenerator(t, SequenceOverflowStrategyError)
+	var overflowErr *SequenceOverflowError
+	for i := 0; i < 100000 && overflowErr == nil; i++ {
+		if _, err := g.GenerateID(); err != nil && !errors.As(err, &overflowErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if overflowErr == nil {
+		t.Fatal("expected a SequenceOverflowError")
+	}
+	if overflowErr.MaxSequence != 127 {
+		t.Errorf("MaxSequence want 127 got %d", overflowErr.MaxSequence)
+	}
+	if g.GetMetrics().SequenceOverflows == 0 {
+		t.Error("overflow should be recorded in metrics")
+	}
+}
+
+func TestGeneratorConfig_InvalidSequenceOverflowStrategy(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.SequenceOverflowStrategy = "retry"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate should reject unknown sequence overflow strategy")
+	}
+}
+
+// shardCounts generates one ID per millisecond (the low-traffic case) and counts id % shards
+func shardCounts(t *testing.T, g *Generator, samples, shards int) []int {
+	t.Helper()
+	counts := make([]int, shards)
+	for i := 0; i < samples; i++ {
+		id, err := g.GenerateID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[id%int64(shards)]++
+		time.Sleep(time.Millisecond)
+	}
+	return counts
+}
+
+func TestSequenceStartMode_ShardDistribution(t *testing.T) {
+	const samples, shards = 800, 8
+	expected := samples / shards
+
+	newGen := func(mode string) *Generator {
+		cfg := DefaultGeneratorConfig()
+		cfg.EnableMetrics = false
+		cfg.SequenceStartMode = mode
+		g, err := NewSnowflakeGeneratorCore(1, 1, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	// Baseline: every millisecond starts at sequence 0, so all IDs land in one shard
+	zero := shardCounts(t, newGen(SequenceStartZero), samples/4, shards)
+	if zero[0] != samples/4 {
+		t.Fatalf("zero start should put every low-traffic ID in shard 0, got %v", zero)
+	}
+
+	for _, mode := range []string{SequenceStartRandom, SequenceStartRoundRobin} {
+		counts := shardCounts(t, newGen(mode), samples, shards)
+		for shard, n := range counts {
+			// Binomial sd is ~9.4 for 800/8; 60% slack keeps the random case far from flaky
+			if n < expected*4/10 || n > expected*16/10 {
+				t.Errorf("%s: shard %d got %d IDs, want about %d (%v)", mode, shard, n, expected, counts)
+			}
+		}
+	}
+}
+
+func TestSequenceStartMode_OverflowAndCacheStayUnique(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		cfg := DefaultGeneratorConfig()
+		cfg.SequenceBits = 7
+		cfg.SequenceStartMode = SequenceStartRandom
+		cfg.EnableSequenceCache = cache
+		cfg.SequenceCacheSize = 128 // full sequence space; the last entry must be treated as invalid
+		g, err := NewSnowflakeGeneratorCore(1, 1, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[int64]struct{}, 5000)
+		for i := 0; i < 5000; i++ {
+			id, err := g.GenerateID()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, dup := seen[id]; dup {
+				t.Fatalf("cache=%v: duplicate ID %d after %d IDs", cache, id, i)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestGenerator_SetNotBefore(t *testing.T) {
+	g, err := NewSnowflakeGeneratorCore(1, 3, DefaultGeneratorConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait mode: generation waits until the clock passes the previous owner's last timestamp
+	notBefore := time.Now().UnixMilli() + 50
+	g.SetNotBefore(notBefore)
+	if len(g.NotReadyReasons()) == 0 {
+		t.Error("generator should not be ready before notBefore")
+	}
+	_, sid, err := g.GenerateIDWithMetadata()
+	if err != nil {
+		t.Fatalf("GenerateID failed: %v", err)
+	}
+	if sid.Timestamp.UnixMilli() <= notBefore {
+		t.Errorf("ID timestamp %d is not after %d", sid.Timestamp.UnixMilli(), notBefore)
+	}
+
+	// A mark further ahead than MaxClockBackwardWait, or any mark in error mode, is refused
+	g.SetNotBefore(time.Now().Add(MaxClockBackwardWait + time.Second).UnixMilli())
+	var reuseErr *WorkerIDReuseError
+	if _, err := g.GenerateID(); !errors.As(err, &reuseErr) {
+		t.Fatalf("expected WorkerIDReuseError, got %v", err)
+	}
+	if reuseErr.WorkerID != 3 || reuseErr.Wait <= MaxClockBackwardWait {
+		t.Errorf("unexpected error fields: %+v", reuseErr)
+	}
+
+	cfg := DefaultGeneratorConfig()
+	cfg.ClockDriftAction = ClockDriftActionError
+	g, err = NewSnowflakeGeneratorCore(1, 3, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetNotBefore(time.Now().UnixMilli() + 1000)
+	if _, err := g.GenerateID(); !errors.As(err, &reuseErr) {
+		t.Fatalf("expected WorkerIDReuseError, got %v", err)
+	}
+	g.SetNotBefore(0)
+	if _, err := g.GenerateID(); err != nil {
+		t.Fatalf("GenerateID failed after clearing notBefore: %v", err)
+	}
+}
+
+func TestGenerator_Fence(t *testing.T) {
+	g, err := NewSnowflakeGeneratorCore(1, 3, DefaultGeneratorConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.GenerateID(); err != nil {
+		t.Fatalf("GenerateID failed: %v", err)
+	}
+
+	g.Fence()
+	var fenced *WorkerIDFencedError
+	if _, err := g.GenerateID(); !errors.As(err, &fenced) {
+		t.Fatalf("expected WorkerIDFencedError, got %v", err)
+	}
+	if fenced.WorkerID != 3 {
+		t.Errorf("unexpected worker ID in error: %d", fenced.WorkerID)
+	}
+	if len(g.NotReadyReasons()) == 0 {
+		t.Error("fenced generator should not be ready")
+	}
+}
@@ -1,9 +1,18 @@
 package eonId
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/go-kratos/kratos/v2/config/file"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-lynx/lynx/plugins"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -32,12 +41,9 @@ func TestPlugSnowflake_GetHealth(t *testing.T) {
 					clockBackwardCount: 0,
 					isShuttingDown:     false,
 				}
-				generator.metrics = &Metrics{
-					IDsGenerated:       100,
-					GenerationErrors:   0,
-					IDGenerationRate:   1000.0,
-					UptimeDuration:     time.Hour,
-					LastGenerationTime: time.Now(),
+				generator.metrics = NewSnowflakeMetrics()
+				for i := 0; i < 100; i++ {
+					generator.metrics.RecordIDGeneration(time.Microsecond, false)
 				}
 				plugin.generator = generator
 
@@ -58,7 +64,9 @@ func TestPlugSnowflake_GetHealth(t *testing.T) {
 				require.True(t, ok)
 				assert.Equal(t, int64(100), metrics["ids_generated"])
 				assert.Equal(t, int64(0), metrics["generation_errors"])
-				assert.Equal(t, 1000.0, metrics["id_generation_rate"])
+				// Uptime is below one second, so the rate window is one second
+				assert.Equal(t, 100.0, metrics["id_generation_rate"])
+				assert.Equal(t, time.Microsecond.String(), metrics["p99_latency"])
 			},
 		},
 		{
@@ -259,3 +267,159 @@ func TestPlugSnowflake_GetHealth_ThreadSafety(t *testing.T) {
 		assert.Equal(t, "healthy", status)
 	}
 }
+
+func TestPlugSnowflake_GetHealth_DuringWorkerIDRecovery(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:          5,
+		WorkerRecoveryBackoff: durationpb.New(time.Millisecond),
+	})
+
+	// Health probes race the recovery that rewrites the worker ID; run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			plugin.GetHealth()
+		}
+	}()
+	stealWorkerID(t, allocator, mgr)
+	require.Eventually(t, func() bool { return mgr.IsRegistered() && !plugin.generator.IsFenced() },
+		2*time.Second, 5*time.Millisecond)
+	<-done
+
+	health := plugin.GetHealth()
+	assert.Equal(t, mgr.GetWorkerID(), health.Details["worker_id"])
+	assert.Equal(t, mgr.GetWorkerID(), health.Details["worker_manager_worker_id"])
+}
+
+func newProbeTestPlugin(t *testing.T, action string) (*PlugSnowflake, *Generator) {
+	t.Helper()
+	cfg := DefaultGeneratorConfig()
+	cfg.EnableMetrics = false
+	cfg.ClockDriftAction = action
+	generator, err := NewSnowflakeGeneratorCore(1, 1, cfg)
+	require.NoError(t, err)
+
+	plugin := NewSnowflakePlugin()
+	plugin.generator = generator
+	return plugin, generator
+}
+
+func TestPlugSnowflake_Probes_Ready(t *testing.T) {
+	plugin, _ := newProbeTestPlugin(t, ClockDriftActionWait)
+
+	assert.True(t, plugin.Liveness().OK)
+	assert.True(t, plugin.Readiness().OK)
+	assert.NoError(t, plugin.CheckHealth())
+
+	health := plugin.GetHealth()
+	assert.Equal(t, "healthy", health.Status)
+	assert.Equal(t, ProbeResult{OK: true}, health.Details["readiness"])
+	assert.NotEmpty(t, health.Details["epoch_remaining"])
+}
+
+func TestPlugSnowflake_Probes_NotInitialized(t *testing.T) {
+	plugin := NewSnowflakePlugin()
+
+	assert.False(t, plugin.Liveness().OK)
+	assert.False(t, plugin.Readiness().OK)
+	assert.Error(t, plugin.CheckHealth())
+}
+
+func TestPlugSnowflake_Probes_ShutDown(t *testing.T) {
+	plugin, generator := newProbeTestPlugin(t, ClockDriftActionWait)
+	require.NoError(t, generator.Shutdown(context.Background()))
+
+	assert.False(t, plugin.Liveness().OK)
+	assert.False(t, generator.IsHealthy())
+	assert.Equal(t, "unhealthy", plugin.GetHealth().Status)
+}
+
+func TestPlugSnowflake_Probes_WorkerNotRegistered(t *testing.T) {
+	plugin, _ := newProbeTestPlugin(t, ClockDriftActionWait)
+	plugin.workerManager = &WorkerIDManager{allocator: NewMemoryWorkerIDAllocator(), workerID: 1, datacenterID: 1}
+
+	readiness := plugin.Readiness()
+	assert.False(t, readiness.OK)
+	assert.Contains(t, readiness.Reasons, "worker ID not registered")
+	assert.True(t, plugin.Liveness().OK, "an unregistered worker is still live")
+
+	// Registered but heartbeat failing
+	plugin.workerManager.registered = true
+	assert.Equal(t, []string{"worker ID heartbeat failing"}, plugin.Readiness().Reasons)
+
+	atomic.StoreInt32(&plugin.workerManager.healthy, 1)
+	assert.True(t, plugin.Readiness().OK)
+
+	health := plugin.GetHealth()
+	assert.Equal(t, "healthy", health.Status)
+}
+
+// staticConfigRuntime serves a YAML configuration to InitializeResources; it has no shared resources
+type staticConfigRuntime struct {
+	plugins.Runtime
+	config config.Config
+}
+
+func (r staticConfigRuntime) GetLogger() log.Logger         { return log.DefaultLogger }
+func (r staticConfigRuntime) GetConfig() config.Config      { return r.config }
+func (r staticConfigRuntime) EmitEvent(plugins.PluginEvent) {}
+func (r staticConfigRuntime) GetSharedResource(string) (any, error) {
+	return nil, errors.New("no shared resources")
+}
+
+func TestPlugSnowflake_StaticWorkerIDIsReady(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path,
+		[]byte(`{"lynx": {"eon-id": {"datacenter_id": 1, "worker_id": 3, "auto_register_worker_id": false}}}`), 0o600))
+	cfg := config.New(config.WithSource(file.NewSource(path)))
+	require.NoError(t, cfg.Load())
+	t.Cleanup(func() { _ = cfg.Close() })
+
+	plugin := NewSnowflakePlugin()
+	require.NoError(t, plugin.InitializeResources(staticConfigRuntime{config: cfg}))
+	require.NoError(t, plugin.StartContext(context.Background(), plugin))
+	t.Cleanup(func() { _ = plugin.CleanupTasks() })
+
+	readiness := plugin.Readiness()
+	assert.True(t, readiness.OK, "reasons: %v", readiness.Reasons)
+	_, sid, err := plugin.GenerateIDWithMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), sid.WorkerID)
+}
+
+func TestPlugSnowflake_Probes_ClockBehind(t *testing.T) {
+	plugin, generator := newProbeTestPlugin(t, ClockDriftActionWait)
+	generator.lastTimestamp = time.Now().Add(time.Minute).UnixMilli()
+
+	readiness := plugin.Readiness()
+	require.False(t, readiness.OK)
+	assert.Contains(t, readiness.Reasons[0], "behind the last issued timestamp")
+	assert.False(t, generator.IsHealthy())
+	assert.Error(t, plugin.CheckHealth())
+
+	health := plugin.GetHealth()
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Contains(t, health.Message, "not ready")
+
+	// Ignore mode keeps issuing IDs from lastTimestamp, so it stays ready
+	plugin, generator = newProbeTestPlugin(t, ClockDriftActionIgnore)
+	generator.lastTimestamp = time.Now().Add(time.Minute).UnixMilli()
+	assert.True(t, plugin.Readiness().OK)
+}
+
+func TestPlugSnowflake_Probes_EpochExhaustion(t *testing.T) {
+	plugin, generator := newProbeTestPlugin(t, ClockDriftActionWait)
+	// Leave one hour before the timestamp bits overflow
+	generator.customEpoch = time.Now().UnixMilli() - ((int64(1) << generator.timestampBits) - 1) + time.Hour.Milliseconds()
+
+	remaining, ok := generator.EpochRemaining()
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), remaining.Seconds(), 5)
+
+	readiness := plugin.Readiness()
+	require.False(t, readiness.OK)
+	assert.Contains(t, readiness.Reasons[0], "epoch exhausted in")
+	assert.True(t, plugin.Liveness().OK)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/go-lynx/lynx"
+	"github.com/google/uuid"
 )
 
 // GetEonIdPlugin returns the eon-id plugin instance from the Lynx app (only available after the plugin is initialized and started).
@@ -46,6 +47,26 @@ func GenerateIDWithMetadata() (int64, *SID, error) {
 	return plugin.GenerateIDWithMetadata()
 }
 
+// GenerateUUIDv7 generates an RFC 9562 UUIDv7 using the global eon-id plugin.
+func GenerateUUIDv7() (uuid.UUID, error) {
+	plugin, err := GetEonIdPlugin()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return plugin.GenerateUUIDv7()
+}
+
+// GenerateULID generates a process-monotonic ULID using the global eon-id plugin.
+func GenerateULID() (ULID, error) {
+	plugin, err := GetEonIdPlugin()
+	if err != nil {
+		return ULID{}, err
+	}
+
+	return plugin.GenerateULID()
+}
+
 // ParseID parses an ID and returns its metadata using the global eon-id plugin.
 func ParseID(id int64) (*SID, error) {
 	plugin, err := GetEonIdPlugin()
@@ -0,0 +1,225 @@
+package eonId
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Latency sketch layout: a log-linear (HDR-style) histogram over nanoseconds.
+// Values below 2*sketchSubBuckets get one bucket each; above that every power of two is split into
+// sketchSubBuckets equal buckets, so a reported quantile is within 1/sketchSubBuckets (~1.6%) of the true value.
+const (
+	sketchSubBucketBits = 6
+	sketchSubBuckets    = 1 << sketchSubBucketBits
+	sketchLinearBuckets = 2 * sketchSubBuckets
+	sketchMaxShift      = 33 // covers values up to 2^40 ns (~18 minutes); larger values land in the last bucket
+	sketchBucketCount   = sketchLinearBuckets + sketchMaxShift*sketchSubBuckets
+)
+
+// LatencySketch is a fixed-size, lock-free latency histogram. Recording is a handful of atomic adds,
+// and sketches from different generators or instances can be combined with Merge before reading quantiles.
+// The zero value is ready to use.
+type LatencySketch struct {
+	counts [sketchBucketCount]int64
+	count  int64
+	sum    int64
+	min    int64 // min+1 so that zero means "no samples"
+	max    int64
+}
+
+// sketchBucket returns the bucket index for a value in nanoseconds
+func sketchBucket(v int64) int {
+	if v < sketchLinearBuckets {
+		if v < 0 {
+			return 0
+		}
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - (sketchSubBucketBits + 1)
+	if shift > sketchMaxShift {
+		return sketchBucketCount - 1
+	}
+	return sketchLinearBuckets + (shift-1)*sketchSubBuckets + int(v>>shift) - sketchSubBuckets
+}
+
+// sketchBucketValue returns the midpoint of a bucket in nanoseconds
+func sketchBucketValue(idx int) int64 {
+	if idx < sketchLinearBuckets {
+		return int64(idx)
+	}
+	j := idx - sketchLinearBuckets
+	shift := j/sketchSubBuckets + 1
+	lower := int64(j%sketchSubBuckets+sketchSubBuckets) << shift
+	return lower + int64(1)<<shift/2
+}
+
+// Record adds one latency sample
+func (s *LatencySketch) Record(latency time.Duration) {
+	v := int64(latency)
+	if v < 0 {
+		v = 0
+	}
+	atomic.AddInt64(&s.counts[sketchBucket(v)], 1)
+	atomic.AddInt64(&s.count, 1)
+	atomic.AddInt64(&s.sum, v)
+	s.updateMin(v + 1)
+	s.updateMax(v)
+}
+
+func (s *LatencySketch) updateMin(encoded int64) {
+	for {
+		cur := atomic.LoadInt64(&s.min)
+		if cur != 0 && cur <= encoded {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&s.min, cur, encoded) {
+			return
+		}
+	}
+}
+
+func (s *LatencySketch) updateMax(v int64) {
+	for {
+		cur := atomic.LoadInt64(&s.max)
+		if cur >= v {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&s.max, cur, v) {
+			return
+		}
+	}
+}
+
+// Merge adds all samples of other into s. other should not be recorded into concurrently
+// if an exact merge is required; concurrent recording only makes the merge slightly stale.
+func (s *LatencySketch) Merge(other *LatencySketch) {
+	if other == nil {
+		return
+	}
+	for i := range other.counts {
+		if c := atomic.LoadInt64(&other.counts[i]); c != 0 {
+			atomic.AddInt64(&s.counts[i], c)
+		}
+	}
+	atomic.AddInt64(&s.count, atomic.LoadInt64(&other.count))
+	atomic.AddInt64(&s.sum, atomic.LoadInt64(&other.sum))
+	if m := atomic.LoadInt64(&other.min); m != 0 {
+		s.updateMin(m)
+	}
+	s.updateMax(atomic.LoadInt64(&other.max))
+}
+
+// Clone returns an independent copy of the sketch
+func (s *LatencySketch) Clone() *LatencySketch {
+	c := &LatencySketch{}
+	c.Merge(s)
+	return c
+}
+
+// Reset discards all samples
+func (s *LatencySketch) Reset() {
+	for i := range s.counts {
+		atomic.StoreInt64(&s.counts[i], 0)
+	}
+	atomic.StoreInt64(&s.count, 0)
+	atomic.StoreInt64(&s.sum, 0)
+	atomic.StoreInt64(&s.min, 0)
+	atomic.StoreInt64(&s.max, 0)
+}
+
+// Count returns the number of recorded samples
+func (s *LatencySketch) Count() int64 {
+	return atomic.LoadInt64(&s.count)
+}
+
+// Min returns the smallest recorded latency, 0 if empty
+func (s *LatencySketch) Min() time.Duration {
+	if m := atomic.LoadInt64(&s.min); m != 0 {
+		return time.Duration(m - 1)
+	}
+	return 0
+}
+
+// Max returns the largest recorded latency
+func (s *LatencySketch) Max() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.max))
+}
+
+// Mean returns the exact average latency, 0 if empty
+func (s *LatencySketch) Mean() time.Duration {
+	n := atomic.LoadInt64(&s.count)
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&s.sum) / n)
+}
+
+// Quantile returns the latency at quantile q (0..1), clamped to the observed min and max; 0 if empty
+func (s *LatencySketch) Quantile(q float64) time.Duration {
+	return s.Quantiles(q)[0]
+}
+
+// Quantiles returns several quantiles from a single pass over the buckets; qs must be ascending
+func (s *LatencySketch) Quantiles(qs ...float64) []time.Duration {
+	out := make([]time.Duration, len(qs))
+	var counts [sketchBucketCount]int64
+	var total int64
+	for i := range counts {
+		counts[i] = atomic.LoadInt64(&s.counts[i])
+		total += counts[i]
+	}
+	if total == 0 || len(qs) == 0 {
+		return out
+	}
+
+	minV, maxV := s.Min(), s.Max()
+	next := 0
+	var cumulative int64
+	for i := 0; i < sketchBucketCount && next < len(qs); i++ {
+		cumulative += counts[i]
+		for next < len(qs) && cumulative >= quantileRank(qs[next], total) {
+			v := time.Duration(sketchBucketValue(i))
+			if v < minV {
+				v = minV
+			}
+			if v > maxV {
+				v = maxV
+			}
+			out[next] = v
+			next++
+		}
+	}
+	for ; next < len(qs); next++ {
+		out[next] = maxV
+	}
+	return out
+}
+
+// quantileRank returns the 1-based rank of quantile q among total samples
+func quantileRank(q float64, total int64) int64 {
+	rank := int64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > total {
+		rank = total
+	}
+	return rank
+}
+
+// rangeCount returns the number of samples whose bucket midpoint lies in [lo, hi)
+func (s *LatencySketch) rangeCount(lo, hi time.Duration) int64 {
+	var n int64
+	for i := sketchBucket(int64(lo)); i < sketchBucketCount; i++ {
+		v := time.Duration(sketchBucketValue(i))
+		if v >= hi {
+			break
+		}
+		if v >= lo {
+			n += atomic.LoadInt64(&s.counts[i])
+		}
+	}
+	return n
+}
@@ -103,39 +103,61 @@ func (p *PlugSnowflake) startupTasksContext(parentCtx context.Context) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.conf == nil || !p.conf.AutoRegisterWorkerId || p.workerManager == nil || p.redisClient == nil {
+	if p.conf == nil || !p.conf.AutoRegisterWorkerId || p.workerManager == nil || p.workerManager.allocator == nil {
 		return nil
 	}
 
-	ctx, cancel := p.createTimeoutContext(parentCtx, 10*time.Second)
+	ctx, cancel := createTimeoutContext(parentCtx, 10*time.Second)
 	defer cancel()
 
+	if redisAllocator, ok := p.workerManager.allocator.(*RedisWorkerIDAllocator); ok && p.conf.RedisMigrateKeyLayout {
+		report, err := redisAllocator.MigrateKeyLayout(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to migrate Redis key layout: %w", err)
+		}
+		lynxlog.Infof("migrated Redis key layout: %d claims copied, %d skipped, %d last timestamps",
+			report.Claims, report.ClaimsSkipped, report.LastTimestamps)
+	}
+
 	if p.conf.WorkerId > 0 {
 		if err := p.workerManager.RegisterSpecificWorkerID(ctx, int64(p.conf.WorkerId)); err == nil {
 			lynxlog.Infof("registered specific worker ID: %d", p.conf.WorkerId)
+			p.applyWorkerIDLocked(int64(p.conf.WorkerId))
+			p.startRegistryJanitorLocked()
 			return nil
 		} else {
 			lynxlog.Warnf("failed to register specific worker ID %d: %v, trying auto-register", p.conf.WorkerId, err)
 		}
 	}
 
-	maxWorkerID := int64((1 << p.conf.WorkerIdBits) - 1)
-	if maxWorkerID == 0 {
-		maxWorkerID = 31
-	}
-	workerID, err := p.workerManager.RegisterWorkerID(ctx, maxWorkerID)
+	workerID, err := p.workerManager.RegisterWorkerID(ctx, maxWorkerIDFromConfig(p.conf))
 	if err != nil {
 		return fmt.Errorf("failed to auto-register worker ID: %w", err)
 	}
-	if p.generator != nil {
-		p.generator.mu.Lock()
-		p.generator.workerID = workerID
-		p.generator.mu.Unlock()
-	}
+	p.applyWorkerIDLocked(workerID)
 	lynxlog.Infof("auto-registered worker ID: %d", workerID)
+	p.startRegistryJanitorLocked()
 	return nil
 }
 
+// applyWorkerIDLocked switches the generator to a registered worker ID and lifts a fence left by a lost lease.
+// If the worker ID was used before, the generator does not issue IDs until its clock passes the previous owner's
+// last timestamp plus the reuse safety margin. Caller must hold p.mu.
+func (p *PlugSnowflake) applyWorkerIDLocked(workerID int64) {
+	if p.generator == nil {
+		return
+	}
+	notBefore := p.workerManager.ReuseNotBefore()
+	p.generator.mu.Lock()
+	p.generator.workerID = workerID
+	p.generator.notBefore = notBefore
+	p.generator.fenced = false
+	p.generator.mu.Unlock()
+	if wait := time.Until(time.UnixMilli(notBefore)); wait > 0 {
+		lynxlog.Warnf("worker ID %d was used until recently, ID generation resumes in %v", workerID, wait.Round(time.Millisecond))
+	}
+}
+
 func (p *PlugSnowflake) cleanupTasksContext(parentCtx context.Context) error {
 	p.shutdownOnce.Do(func() { close(p.shutdownCh) })
 
@@ -146,33 +168,22 @@ func (p *PlugSnowflake) cleanupTasksContext(parentCtx context.Context) error {
 	return cleanupErr
 }
 
+// doStopCleanupContext drains the generator and releases the worker ID, see drainAndRelease. It runs without p.mu
+// held, so calls made during the drain fail at once instead of waiting for it.
 func (p *PlugSnowflake) doStopCleanupContext(parentCtx context.Context) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	var firstErr error
-	if p.workerManager != nil {
-		ctx, cancel := p.createTimeoutContext(parentCtx, 5*time.Second)
-		defer cancel()
-		if err := p.workerManager.UnregisterWorkerID(ctx); err != nil {
-			lynxlog.Warnf("failed to unregister worker ID: %v", err)
-			firstErr = err
-		} else {
-			lynxlog.Infof("unregistered worker ID")
-		}
-	}
-	if p.generator != nil {
-		ctx, cancel := p.createTimeoutContext(parentCtx, 5*time.Second)
-		defer cancel()
-		if err := p.generator.Shutdown(ctx); err != nil && firstErr == nil {
-			lynxlog.Warnf("failed to shutdown generator: %v", err)
-			firstErr = err
-		}
-	}
-	return firstErr
+	p.mu.RLock()
+	generator := p.generator
+	workerManager := p.workerManager
+	p.mu.RUnlock()
+
+	report := drainAndRelease(parentCtx, generator, workerManager)
+	p.reportDrain(report)
+	p.events.close()
+	return report.Err
 }
 
-func (p *PlugSnowflake) createTimeoutContext(parentCtx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+// createTimeoutContext bounds parentCtx by timeout unless its own deadline is earlier
+func createTimeoutContext(parentCtx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if deadline, ok := parentCtx.Deadline(); ok && time.Until(deadline) < timeout {
 		return parentCtx, func() {}
 	}
@@ -1,6 +1,7 @@
 package eonId
 
-// Lua scripts for eon-id Redis operations (worker ID counter, heartbeat)
+// Lua scripts for eon-id Redis operations (worker ID claims, slot index and counter, heartbeat, release, registry,
+// datacenter names)
 // All scripts are executed atomically by Redis
 
 // LuaScriptIncrWithReset atomically increments and wraps when exceeding max; returns value in [1, totalWorkerIDs] to avoid out-of-range workerID under concurrency.
@@ -19,12 +20,32 @@ return counter
 `
 
 // LuaScriptHeartbeat atomically verifies instance_id and refreshes TTL; uses cjson to parse JSON so values containing quotes do not break regex.
+// It also raises the worker ID's last-timestamp mark, which has no TTL, so the next owner of the worker ID can
+// wait until its clock has passed the last ID issued under it. The mark is raised even when the claim is lost:
+// IDs issued under the worker ID are real whoever holds the key now.
+// With a slot index (see LuaScriptAcquireSlot) a successful renewal also moves the worker ID's score to the new expiry,
+// and scores the worker's registry member with the heartbeat time.
+// A claim revoked by LuaScriptRevoke is left untouched, so it keeps its revocation and expires unless the owner
+// releases it first.
 // KEYS[1]: worker key
+// KEYS[2]: last-timestamp key
+// KEYS[3]: slot index (optional)
+// KEYS[4]: registry sorted set (optional, with KEYS[3])
 // ARGV[1]: new worker info JSON
 // ARGV[2]: expected instanceID
 // ARGV[3]: TTL in seconds
-// Returns: 1=success, 0=instanceID mismatch, -1=key not exist, -2=invalid format
+// ARGV[4]: last issued timestamp in Unix ms (0 if none)
+// ARGV[5]: worker ID, the slot index member (only with KEYS[3])
+// ARGV[6]: registry member (only with KEYS[4])
+// Returns: 1=success, 0=instanceID mismatch, -1=key not exist, -2=invalid format, -3=revoked
 const LuaScriptHeartbeat = `
+if redis.replicate_commands then
+    redis.replicate_commands()
+end
+local ts = tonumber(ARGV[4])
+if ts and ts > 0 and ts > (tonumber(redis.call('GET', KEYS[2])) or 0) then
+    redis.call('SET', KEYS[2], ARGV[4])
+end
 local current = redis.call('GET', KEYS[1])
 if not current then
     return -1
@@ -36,6 +57,271 @@ end
 if t.instance_id ~= ARGV[2] then
     return 0
 end
+if type(t.revocation) == 'table' then
+    return -3
+end
 redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+if KEYS[3] then
+    local time = redis.call('TIME')
+    local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+    redis.call('ZADD', KEYS[3], 'XX', now + tonumber(ARGV[3]) * 1000, ARGV[5])
+    redis.call('ZADD', KEYS[4], now, ARGV[6])
+end
+return 1
+`
+
+// LuaScriptRelease raises the last-timestamp mark like LuaScriptHeartbeat, then deletes the worker key only if
+// its instance_id matches. With a slot index and registry the worker ID is scored 0 (free) and leaves the
+// registry once its key is gone.
+// KEYS[1]: worker key
+// KEYS[2]: last-timestamp key
+// KEYS[3]: slot index (optional)
+// KEYS[4]: registry sorted set (optional, with KEYS[3])
+// ARGV[1]: expected instanceID
+// ARGV[2]: last issued timestamp in Unix ms (0 if none)
+// ARGV[3]: worker ID, the slot index member (only with KEYS[3])
+// ARGV[4]: registry member (only with KEYS[4])
+// Returns: 1=deleted, 0=instanceID mismatch, -1=key not exist, -2=invalid format
+const LuaScriptRelease = `
+local ts = tonumber(ARGV[2])
+if ts and ts > 0 and ts > (tonumber(redis.call('GET', KEYS[2])) or 0) then
+    redis.call('SET', KEYS[2], ARGV[2])
+end
+local current = redis.call('GET', KEYS[1])
+if not current then
+    if KEYS[3] then
+        redis.call('ZADD', KEYS[3], 'XX', 0, ARGV[3])
+        redis.call('ZREM', KEYS[4], ARGV[4])
+    end
+    return -1
+end
+local ok, t = pcall(cjson.decode, current)
+if not ok or not t or type(t.instance_id) ~= 'string' then
+    return -2
+end
+if t.instance_id ~= ARGV[1] then
+    return 0
+end
+redis.call('DEL', KEYS[1])
+if KEYS[3] then
+    redis.call('ZADD', KEYS[3], 'XX', 0, ARGV[3])
+    redis.call('ZREM', KEYS[4], ARGV[4])
+end
 return 1
 `
+
+// LuaScriptRevoke marks a claim revoked without ending it: the worker ID stays taken, with its remaining TTL,
+// until the owner sees the revocation in LuaScriptHeartbeat and releases it, or the claim expires. The
+// revocation is spliced into the stored JSON rather than re-encoded, so the other fields keep their exact form.
+// A claim that is already revoked keeps its first revocation.
+// KEYS[1]: worker key
+// ARGV[1]: expected instanceID ("" for any owner)
+// ARGV[2]: revocation JSON object
+// Returns: the revoked worker info JSON, or 0=instanceID mismatch, -1=key not exist, -2=invalid format
+const LuaScriptRevoke = `
+local current = redis.call('GET', KEYS[1])
+if not current then
+    return -1
+end
+local ok, t = pcall(cjson.decode, current)
+if not ok or not t or type(t.instance_id) ~= 'string' or string.sub(current, -1) ~= '}' then
+    return -2
+end
+if ARGV[1] ~= '' and t.instance_id ~= ARGV[1] then
+    return 0
+end
+if type(t.revocation) == 'table' then
+    return current
+end
+local revoked = string.sub(current, 1, -2) .. ',"revocation":' .. ARGV[2] .. '}'
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+    redis.call('SET', KEYS[1], revoked, 'PX', ttl)
+else
+    redis.call('SET', KEYS[1], revoked)
+end
+return revoked
+`
+
+// LuaScriptResolveDatacenter maps a datacenter name to a datacenter ID. A mapped name returns its ID; a new name
+// takes the requested ID, or the lowest ID no other name holds. Mappings have no TTL.
+// KEYS[1]: datacenter name hash (name -> datacenter ID)
+// ARGV[1]: datacenter name
+// ARGV[2]: total datacenter IDs (max datacenter ID + 1)
+// ARGV[3]: requested datacenter ID (-1 for any)
+// Returns: the datacenter ID of the name, or -1=every ID is mapped, -2=the requested ID is mapped to another name
+const LuaScriptResolveDatacenter = `
+local mapped = redis.call('HGET', KEYS[1], ARGV[1])
+if mapped then
+    return tonumber(mapped)
+end
+local used = {}
+for _, id in ipairs(redis.call('HVALS', KEYS[1])) do
+    used[tonumber(id)] = true
+end
+local requested = tonumber(ARGV[3])
+if requested >= 0 then
+    if used[requested] then
+        return -2
+    end
+    redis.call('HSET', KEYS[1], ARGV[1], requested)
+    return requested
+end
+for id = 0, tonumber(ARGV[2]) - 1 do
+    if not used[id] then
+        redis.call('HSET', KEYS[1], ARGV[1], id)
+        return id
+    end
+end
+return -1
+`
+
+// LuaScriptRaiseTimestamp raises a last-timestamp mark; it never lowers it.
+// KEYS[1]: last-timestamp key
+// ARGV[1]: last issued timestamp in Unix ms
+// Returns: 1=raised, 0=the mark was already at or above ARGV[1]
+const LuaScriptRaiseTimestamp = `
+local ts = tonumber(ARGV[1])
+if ts and ts > (tonumber(redis.call('GET', KEYS[1])) or 0) then
+    redis.call('SET', KEYS[1], ARGV[1])
+    return 1
+end
+return 0
+`
+
+// LuaScriptAcquireWorker claims one worker ID if its key does not exist: it writes the worker key, scores the
+// worker ID in the slot index, adds it to the registry and reads the previous owner's last timestamp.
+// KEYS[1]: worker key
+// KEYS[2]: last-timestamp key
+// KEYS[3]: slot index
+// KEYS[4]: registry sorted set
+// ARGV[1]: worker info JSON
+// ARGV[2]: TTL in milliseconds
+// ARGV[3]: worker ID, the slot index member
+// ARGV[4]: registry member
+// Returns: {1, last issued timestamp in Unix ms or 0} when claimed, {0, 0} when the worker ID is taken
+const LuaScriptAcquireWorker = `
+if redis.replicate_commands then
+    redis.replicate_commands()
+end
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+    return {0, 0}
+end
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call('ZADD', KEYS[3], 'XX', now + tonumber(ARGV[2]), ARGV[3])
+redis.call('ZADD', KEYS[4], now, ARGV[4])
+return {1, tonumber(redis.call('GET', KEYS[2])) or 0}
+`
+
+// LuaScriptAcquireSlot claims a free worker ID in one round trip. A sorted set per datacenter indexes every
+// worker ID by the expiry of its claim (Unix ms, 0 when free), so a free or expired slot is found with one
+// ZRANGEBYSCORE instead of probing worker keys one by one. The worker key stays the source of truth: a
+// candidate whose worker key still exists, e.g. one claimed by ID or renewed without the index, is re-scored
+// from its PTTL and skipped. The index is seeded with score 0 for every worker ID the first time it is used.
+// KEYS[1]: slot index (sorted set)
+// KEYS[2]: registry sorted set
+// ARGV[1]: worker key prefix; the worker key is ARGV[1] .. workerID
+// ARGV[2], ARGV[3]: last-timestamp key around the worker ID; the key is ARGV[2] .. workerID .. ARGV[3]
+// ARGV[4]: totalWorkerIDs (max worker ID + 1)
+// ARGV[5]: TTL in milliseconds
+// ARGV[6]: worker info JSON with "worker_id":-1, replaced by the claimed worker ID
+// ARGV[7]: registry member prefix ("<datacenterID>:")
+// Returns: {workerID, last issued timestamp in Unix ms or 0}, or {-1, 0} when every worker ID is taken
+const LuaScriptAcquireSlot = `
+if redis.replicate_commands then
+    redis.replicate_commands()
+end
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local total = tonumber(ARGV[4])
+local ttl = tonumber(ARGV[5])
+if redis.call('ZCARD', KEYS[1]) < total then
+    for first = 0, total - 1, 1000 do
+        local args = {}
+        for id = first, math.min(first + 999, total - 1) do
+            args[#args + 1] = 0
+            args[#args + 1] = id
+        end
+        redis.call('ZADD', KEYS[1], 'NX', unpack(args))
+    end
+end
+local offset = 0
+while true do
+    local candidates = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', offset, 16)
+    if #candidates == 0 then
+        return {-1, 0}
+    end
+    for _, member in ipairs(candidates) do
+        local id = tonumber(member)
+        if not id or id >= total then
+            offset = offset + 1
+        else
+            local key = ARGV[1] .. member
+            local pttl = redis.call('PTTL', key)
+            if pttl == -1 then
+                redis.call('ZADD', KEYS[1], now + ttl, member)
+            elseif pttl >= 0 then
+                redis.call('ZADD', KEYS[1], now + pttl, member)
+            else
+                local info = string.gsub(ARGV[6], '"worker_id":%-1,', '"worker_id":' .. member .. ',', 1)
+                redis.call('SET', key, info, 'PX', ttl)
+                redis.call('ZADD', KEYS[1], now + ttl, member)
+                redis.call('ZADD', KEYS[2], now, ARGV[7] .. member)
+                local last = tonumber(redis.call('GET', ARGV[2] .. member .. ARGV[3])) or 0
+                return {id, last}
+            end
+        end
+    end
+end
+`
+
+// LuaScriptTrimRegistry removes registry members whose last heartbeat is older than the cutoff and whose worker
+// key is gone. A member whose key still exists, e.g. one renewed with a longer TTL, is left alone.
+// KEYS[1]: registry sorted set
+// ARGV[1], ARGV[2]: worker key around the member's IDs; the key is ARGV[1] .. datacenterID .. ARGV[2] .. workerID
+// ARGV[3]: age in milliseconds after which a member is checked
+// Returns: number of members removed
+const LuaScriptTrimRegistry = `
+if redis.replicate_commands then
+    redis.replicate_commands()
+end
+local time = redis.call('TIME')
+local cutoff = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000) - tonumber(ARGV[3])
+local removed = 0
+local offset = 0
+while true do
+    local members = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', cutoff, 'LIMIT', offset, 100)
+    if #members == 0 then
+        return removed
+    end
+    for _, member in ipairs(members) do
+        local dc, id = string.match(member, '^(%d+):(%d+)$')
+        if dc and redis.call('EXISTS', ARGV[1] .. dc .. ARGV[2] .. id) == 1 then
+            offset = offset + 1
+        else
+            redis.call('ZREM', KEYS[1], member)
+            removed = removed + 1
+        end
+    end
+end
+`
+
+// LuaScriptListWorkers reads every registry member's worker info in one round trip. Members whose worker key
+// is gone are skipped; LuaScriptTrimRegistry removes them.
+// KEYS[1]: registry sorted set
+// ARGV[1], ARGV[2]: worker key around the member's IDs, as for LuaScriptTrimRegistry
+// Returns: the worker info JSON of each live member
+const LuaScriptListWorkers = `
+local workers = {}
+for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+    local dc, id = string.match(member, '^(%d+):(%d+)$')
+    if dc then
+        local info = redis.call('GET', ARGV[1] .. dc .. ARGV[2] .. id)
+        if info then
+            workers[#workers + 1] = info
+        end
+    end
+end
+return workers
+`
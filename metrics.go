@@ -1,61 +1,147 @@
 package eonId
 
 import (
-	"sort"
+	"sync/atomic"
 	"time"
 )
 
+// Sliding rate windows, in seconds
+const (
+	rateWindow1s = 1
+	rateWindow1m = 60
+	rateWindow5m = 300
+
+	// rateWindowSlots must exceed the longest window by at least one second
+	rateWindowSlots = 320
+)
+
+// latencyHistogramBuckets are the coarse ranges reported in Metrics.LatencyHistogram
+var latencyHistogramBuckets = []struct {
+	name   string
+	lo, hi time.Duration
+}{
+	{"0-1μs", 0, time.Microsecond},
+	{"1-10μs", time.Microsecond, 10 * time.Microsecond},
+	{"10-100μs", 10 * time.Microsecond, 100 * time.Microsecond},
+	{"100μs-1ms", 100 * time.Microsecond, time.Millisecond},
+	{"1-5ms", time.Millisecond, 5 * time.Millisecond},
+	{"5-10ms", 5 * time.Millisecond, 10 * time.Millisecond},
+	{"10-50ms", 10 * time.Millisecond, 50 * time.Millisecond},
+	{"50ms+", 50 * time.Millisecond, time.Duration(1<<63 - 1)},
+}
+
+// rateWindow counts events per wall-clock second in a ring of slots. Each slot packs the unix
+// second it belongs to (high 32 bits) with the count for that second (low 32 bits), so a slot is
+// claimed for a new second and incremented with a single CAS and never needs a lock.
+type rateWindow struct {
+	slots [rateWindowSlots]uint64
+	peak  int64 // highest count seen in a complete second
+}
+
+func (w *rateWindow) add(now time.Time) {
+	sec := uint64(now.Unix()) & 0xFFFFFFFF
+	slot := &w.slots[sec%rateWindowSlots]
+	for {
+		old := atomic.LoadUint64(slot)
+		next := sec<<32 | 1
+		if old>>32 == sec {
+			next = old + 1
+		}
+		if atomic.CompareAndSwapUint64(slot, old, next) {
+			if old>>32 != sec {
+				// The evicted second is complete; keep its count for the peak
+				w.notePeak(int64(old & 0xFFFFFFFF))
+			}
+			return
+		}
+	}
+}
+
+func (w *rateWindow) notePeak(count int64) {
+	for {
+		cur := atomic.LoadInt64(&w.peak)
+		if count <= cur || atomic.CompareAndSwapInt64(&w.peak, cur, count) {
+			return
+		}
+	}
+}
+
+// rate returns events per second over the last window seconds, including the current partial second.
+// The span is shortened to the uptime so that a freshly started generator is not under-reported.
+func (w *rateWindow) rate(window int64, now, start time.Time) float64 {
+	nowSec := now.Unix()
+	var total uint64
+	for s := nowSec - window; s <= nowSec; s++ {
+		v := atomic.LoadUint64(&w.slots[uint64(s)%rateWindowSlots])
+		if v>>32 == uint64(s)&0xFFFFFFFF {
+			total += v & 0xFFFFFFFF
+		}
+	}
+
+	span := now.Sub(time.Unix(nowSec-window, 0))
+	if !start.IsZero() {
+		if uptime := now.Sub(start); uptime < span {
+			span = uptime
+		}
+	}
+	if span < time.Second {
+		span = time.Second
+	}
+	return float64(total) / span.Seconds()
+}
+
+// peakRate folds every complete second still in the ring into the peak and returns it
+func (w *rateWindow) peakRate(now time.Time) float64 {
+	nowSec := uint64(now.Unix()) & 0xFFFFFFFF
+	for i := range w.slots {
+		v := atomic.LoadUint64(&w.slots[i])
+		if v != 0 && v>>32 != nowSec {
+			w.notePeak(int64(v & 0xFFFFFFFF))
+		}
+	}
+	return float64(atomic.LoadInt64(&w.peak))
+}
+
+// copyFrom loads the state of other so a snapshot can compute rates again later
+func (w *rateWindow) copyFrom(other *rateWindow) {
+	for i := range other.slots {
+		atomic.StoreUint64(&w.slots[i], atomic.LoadUint64(&other.slots[i]))
+	}
+	atomic.StoreInt64(&w.peak, atomic.LoadInt64(&other.peak))
+}
+
+func (w *rateWindow) reset() {
+	for i := range w.slots {
+		atomic.StoreUint64(&w.slots[i], 0)
+	}
+	atomic.StoreInt64(&w.peak, 0)
+}
+
 // NewSnowflakeMetrics creates a new metrics instance
 func NewSnowflakeMetrics() *Metrics {
 	return &Metrics{
 		StartTime:        time.Now(),
 		LatencyHistogram: make(map[string]int64),
-		MinLatency:       time.Hour, // Initialize with a large value
 	}
 }
 
-// RecordIDGeneration records metrics for ID generation
+// RecordIDGeneration records metrics for ID generation.
+// It is called for every ID and only uses atomics; derived values are computed in GetSnapshot.
 func (m *Metrics) RecordIDGeneration(latency time.Duration, cacheHit bool) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	now := time.Now()
 
-	m.IDsGenerated++
-	m.LastGenerationTime = time.Now()
-	m.UptimeDuration = m.LastGenerationTime.Sub(m.StartTime)
+	atomic.AddInt64(&m.IDsGenerated, 1)
+	atomic.StoreInt64(&m.lastGenerationNanos, now.UnixNano())
+	atomic.StoreInt64(&m.lastLatency, int64(latency))
 
-	// Update latency metrics
-	m.GenerationLatency = latency
-	if latency > m.MaxLatency {
-		m.MaxLatency = latency
-	}
-	if latency < m.MinLatency {
-		m.MinLatency = latency
-	}
-
-	// Update cache metrics
 	if cacheHit {
-		m.CacheHits++
+		atomic.AddInt64(&m.CacheHits, 1)
 	} else {
-		m.CacheMisses++
-	}
-
-	// Calculate cache hit rate
-	totalCacheAccess := m.CacheHits + m.CacheMisses
-	if totalCacheAccess > 0 {
-		m.CacheHitRate = float64(m.CacheHits) / float64(totalCacheAccess)
+		atomic.AddInt64(&m.CacheMisses, 1)
 	}
 
-	// Update latency histogram
-	m.updateLatencyHistogram(latency)
-
-	// Calculate generation rate (IDs per second)
-	if m.UptimeDuration.Seconds() > 0 {
-		m.IDGenerationRate = float64(m.IDsGenerated) / m.UptimeDuration.Seconds()
-		// Update peak generation rate if current rate is higher
-		if m.IDGenerationRate > m.PeakGenerationRate {
-			m.PeakGenerationRate = m.IDGenerationRate
-		}
-	}
+	m.latency.Record(latency)
+	m.rates.add(now)
 }
 
 // RecordCacheRefill records cache refill events
@@ -99,118 +185,112 @@ func (m *Metrics) RecordSequenceOverflow() {
 	m.SequenceOverflows++
 }
 
-// UpdateConnectionMetrics updates Redis connection metrics
-func (m *Metrics) UpdateConnectionMetrics(poolSize, active, idle int) {
+// RecordWorkerIDRecovery records an attempt to register a new worker ID after the lease was lost
+func (m *Metrics) RecordWorkerIDRecovery(success bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.RedisConnectionPool = poolSize
-	m.ActiveConnections = active
-	m.IdleConnections = idle
+	if success {
+		m.WorkerIDRecoveries++
+	} else {
+		m.WorkerIDRecoveryFailures++
+	}
 }
 
-// updateLatencyHistogram updates the latency histogram
-func (m *Metrics) updateLatencyHistogram(latency time.Duration) {
-	var bucket string
-
-	switch {
-	case latency < time.Microsecond:
-		bucket = "0-1μs"
-	case latency < 10*time.Microsecond:
-		bucket = "1-10μs"
-	case latency < 100*time.Microsecond:
-		bucket = "10-100μs"
-	case latency < time.Millisecond:
-		bucket = "100μs-1ms"
-	case latency < 5*time.Millisecond:
-		bucket = "1-5ms"
-	case latency < 10*time.Millisecond:
-		bucket = "5-10ms"
-	case latency < 50*time.Millisecond:
-		bucket = "10-50ms"
-	default:
-		bucket = "50ms+"
-	}
+// RecordSequenceOverflowWait records time spent waiting for the next tick after a sequence overflow
+func (m *Metrics) RecordSequenceOverflowWait(wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	m.LatencyHistogram[bucket]++
+	m.SequenceOverflowWait += wait
 }
 
-// CalculatePercentiles calculates P95 and P99 latencies
-func (m *Metrics) CalculatePercentiles(latencies []time.Duration) {
-	if len(latencies) == 0 {
-		return
-	}
-
+// UpdateConnectionMetrics updates Redis connection metrics
+func (m *Metrics) UpdateConnectionMetrics(poolSize, active, idle int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Sort latencies
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i] < latencies[j]
-	})
+	m.RedisConnectionPool = poolSize
+	m.ActiveConnections = active
+	m.IdleConnections = idle
+}
 
-	// Calculate average
-	var total time.Duration
+// CalculatePercentiles records externally measured latencies into the latency sketch.
+// Percentiles are no longer stored here; read them from GetSnapshot.
+func (m *Metrics) CalculatePercentiles(latencies []time.Duration) {
 	for _, latency := range latencies {
-		total += latency
-	}
-	m.AverageLatency = total / time.Duration(len(latencies))
-
-	// Calculate P95
-	p95Index := int(float64(len(latencies)) * 0.95)
-	if p95Index >= len(latencies) {
-		p95Index = len(latencies) - 1
+		m.latency.Record(latency)
 	}
-	m.P95Latency = latencies[p95Index]
+}
 
-	// Calculate P99
-	p99Index := int(float64(len(latencies)) * 0.99)
-	if p99Index >= len(latencies) {
-		p99Index = len(latencies) - 1
-	}
-	m.P99Latency = latencies[p99Index]
+// LatencySketch returns a copy of the latency sketch, e.g. to Merge sketches from several generators
+func (m *Metrics) LatencySketch() *LatencySketch {
+	return m.latency.Clone()
 }
 
 // GetSnapshot returns a snapshot of current metrics
 func (m *Metrics) GetSnapshot() *Metrics {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	now := time.Now()
 
-	// Create a deep copy of the metrics
+	m.mu.RLock()
 	snapshot := &Metrics{
-		IDsGenerated:        m.IDsGenerated,
-		ClockDriftEvents:    m.ClockDriftEvents,
-		WorkerIDConflicts:   m.WorkerIDConflicts,
-		SequenceOverflows:   m.SequenceOverflows,
-		GenerationLatency:   m.GenerationLatency,
-		AverageLatency:      m.AverageLatency,
-		P95Latency:          m.P95Latency,
-		P99Latency:          m.P99Latency,
-		MaxLatency:          m.MaxLatency,
-		MinLatency:          m.MinLatency,
-		CacheHitRate:        m.CacheHitRate,
-		CacheHits:           m.CacheHits,
-		CacheMisses:         m.CacheMisses,
-		CacheRefills:        m.CacheRefills,
-		IDGenerationRate:    m.IDGenerationRate,
-		PeakGenerationRate:  m.PeakGenerationRate,
-		GenerationErrors:    m.GenerationErrors,
-		RedisErrors:         m.RedisErrors,
-		TimeoutErrors:       m.TimeoutErrors,
-		ValidationErrors:    m.ValidationErrors,
-		RedisConnectionPool: m.RedisConnectionPool,
-		ActiveConnections:   m.ActiveConnections,
-		IdleConnections:     m.IdleConnections,
-		StartTime:           m.StartTime,
-		LastGenerationTime:  m.LastGenerationTime,
-		UptimeDuration:      m.UptimeDuration,
-		LatencyHistogram:    make(map[string]int64),
-	}
-
-	// Copy histogram
-	for k, v := range m.LatencyHistogram {
-		snapshot.LatencyHistogram[k] = v
+		ClockDriftEvents:         m.ClockDriftEvents,
+		WorkerIDConflicts:        m.WorkerIDConflicts,
+		SequenceOverflows:        m.SequenceOverflows,
+		WorkerIDRecoveries:       m.WorkerIDRecoveries,
+		WorkerIDRecoveryFailures: m.WorkerIDRecoveryFailures,
+		SequenceOverflowWait:     m.SequenceOverflowWait,
+		CacheRefills:             m.CacheRefills,
+		GenerationErrors:         m.GenerationErrors,
+		RedisErrors:              m.RedisErrors,
+		TimeoutErrors:            m.TimeoutErrors,
+		ValidationErrors:         m.ValidationErrors,
+		RedisConnectionPool:      m.RedisConnectionPool,
+		ActiveConnections:        m.ActiveConnections,
+		IdleConnections:          m.IdleConnections,
+		StartTime:                m.StartTime,
+		LastGenerationTime:       m.LastGenerationTime,
+		UptimeDuration:           m.UptimeDuration,
+		LatencyHistogram:         make(map[string]int64, len(latencyHistogramBuckets)),
 	}
+	m.mu.RUnlock()
+
+	snapshot.IDsGenerated = atomic.LoadInt64(&m.IDsGenerated)
+	snapshot.CacheHits = atomic.LoadInt64(&m.CacheHits)
+	snapshot.CacheMisses = atomic.LoadInt64(&m.CacheMisses)
+	if total := snapshot.CacheHits + snapshot.CacheMisses; total > 0 {
+		snapshot.CacheHitRate = float64(snapshot.CacheHits) / float64(total)
+	}
+
+	if last := atomic.LoadInt64(&m.lastGenerationNanos); last != 0 {
+		snapshot.lastGenerationNanos = last
+		snapshot.LastGenerationTime = time.Unix(0, last)
+	}
+	if !snapshot.StartTime.IsZero() {
+		snapshot.UptimeDuration = now.Sub(snapshot.StartTime)
+	}
+
+	// Latency
+	snapshot.latency.Merge(&m.latency)
+	snapshot.lastLatency = atomic.LoadInt64(&m.lastLatency)
+	snapshot.GenerationLatency = time.Duration(snapshot.lastLatency)
+	snapshot.AverageLatency = snapshot.latency.Mean()
+	snapshot.MinLatency = snapshot.latency.Min()
+	snapshot.MaxLatency = snapshot.latency.Max()
+	q := snapshot.latency.Quantiles(0.50, 0.95, 0.99, 0.999)
+	snapshot.P50Latency, snapshot.P95Latency, snapshot.P99Latency, snapshot.P999Latency = q[0], q[1], q[2], q[3]
+	for _, b := range latencyHistogramBuckets {
+		if n := snapshot.latency.rangeCount(b.lo, b.hi); n > 0 {
+			snapshot.LatencyHistogram[b.name] = n
+		}
+	}
+
+	// Throughput; the ring is copied so that a snapshot of a snapshot reports the same rates
+	snapshot.rates.copyFrom(&m.rates)
+	snapshot.IDGenerationRate = m.rates.rate(rateWindow1s, now, snapshot.StartTime)
+	snapshot.GenerationRate1m = m.rates.rate(rateWindow1m, now, snapshot.StartTime)
+	snapshot.GenerationRate5m = m.rates.rate(rateWindow5m, now, snapshot.StartTime)
+	snapshot.PeakGenerationRate = m.rates.peakRate(now)
 
 	return snapshot
 }
@@ -220,22 +300,21 @@ func (m *Metrics) Reset() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.IDsGenerated = 0
+	atomic.StoreInt64(&m.IDsGenerated, 0)
+	atomic.StoreInt64(&m.CacheHits, 0)
+	atomic.StoreInt64(&m.CacheMisses, 0)
+	atomic.StoreInt64(&m.lastLatency, 0)
+	atomic.StoreInt64(&m.lastGenerationNanos, 0)
+	m.latency.Reset()
+	m.rates.reset()
+
 	m.ClockDriftEvents = 0
 	m.WorkerIDConflicts = 0
 	m.SequenceOverflows = 0
-	m.GenerationLatency = 0
-	m.AverageLatency = 0
-	m.P95Latency = 0
-	m.P99Latency = 0
-	m.MaxLatency = 0
-	m.MinLatency = time.Hour
-	m.CacheHitRate = 0
-	m.CacheHits = 0
-	m.CacheMisses = 0
+	m.WorkerIDRecoveries = 0
+	m.WorkerIDRecoveryFailures = 0
+	m.SequenceOverflowWait = 0
 	m.CacheRefills = 0
-	m.IDGenerationRate = 0
-	m.PeakGenerationRate = 0
 	m.GenerationErrors = 0
 	m.RedisErrors = 0
 	m.TimeoutErrors = 0
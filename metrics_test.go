@@ -0,0 +1,169 @@
+package eonId
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencySketch_Quantiles(t *testing.T) {
+	var s LatencySketch
+	// 1µs .. 10ms in 1µs steps: the q-quantile is q*10ms
+	const n = 10000
+	for i := 1; i <= n; i++ {
+		s.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	require.Equal(t, int64(n), s.Count())
+	assert.Equal(t, time.Microsecond, s.Min())
+	assert.Equal(t, 10*time.Millisecond, s.Max())
+	assert.Equal(t, 5000500*time.Nanosecond, s.Mean())
+
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.50, 5 * time.Millisecond},
+		{0.95, 9500 * time.Microsecond},
+		{0.99, 9900 * time.Microsecond},
+		{0.999, 9990 * time.Microsecond},
+	} {
+		got := s.Quantile(tc.q)
+		assert.InEpsilon(t, float64(tc.want), float64(got), 0.02, "q=%v got %v", tc.q, got)
+	}
+	assert.Equal(t, 10*time.Millisecond, s.Quantile(1))
+}
+
+func TestLatencySketch_BucketRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 123456, int64(time.Second), int64(time.Minute)} {
+		idx := sketchBucket(v)
+		mid := sketchBucketValue(idx)
+		if v < sketchLinearBuckets {
+			assert.Equal(t, v, mid)
+			continue
+		}
+		assert.InEpsilon(t, float64(v), float64(mid), 1.0/sketchSubBuckets, "v=%d idx=%d mid=%d", v, idx, mid)
+	}
+	assert.Equal(t, sketchBucketCount-1, sketchBucket(1<<62))
+}
+
+func TestLatencySketch_Merge(t *testing.T) {
+	var a, b, all LatencySketch
+	for i := 1; i <= 1000; i++ {
+		d := time.Duration(i) * time.Microsecond
+		all.Record(d)
+		if i%2 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+	}
+
+	merged := a.Clone()
+	merged.Merge(&b)
+	assert.Equal(t, all.Count(), merged.Count())
+	assert.Equal(t, all.Min(), merged.Min())
+	assert.Equal(t, all.Max(), merged.Max())
+	assert.Equal(t, all.Mean(), merged.Mean())
+	assert.Equal(t, all.Quantiles(0.5, 0.99), merged.Quantiles(0.5, 0.99))
+}
+
+func TestRateWindow(t *testing.T) {
+	var w rateWindow
+	start := time.Unix(1_700_000_000, 0)
+
+	// 100 IDs per second for 120 seconds, then 1000 in one second
+	for sec := int64(0); sec < 120; sec++ {
+		for i := 0; i < 100; i++ {
+			w.add(start.Add(time.Duration(sec) * time.Second))
+		}
+	}
+	burst := start.Add(120 * time.Second)
+	for i := 0; i < 1000; i++ {
+		w.add(burst)
+	}
+
+	// Half-way through the burst second
+	now := burst.Add(500 * time.Millisecond)
+	assert.InDelta(t, (100+1000)/1.5, w.rate(rateWindow1s, now, start), 0.001)
+	assert.InDelta(t, (60*100+1000)/60.5, w.rate(rateWindow1m, now, start), 0.001)
+	// Uptime (120.5s) is shorter than 5 minutes
+	assert.InDelta(t, (120*100+1000)/120.5, w.rate(rateWindow5m, now, start), 0.001)
+
+	// The burst second is still in progress, so it does not count as a peak yet
+	assert.Equal(t, 100.0, w.peakRate(now))
+	assert.Equal(t, 1000.0, w.peakRate(burst.Add(time.Second)))
+
+	// Ten minutes later everything has aged out of the windows but the peak is kept
+	later := burst.Add(10 * time.Minute)
+	assert.Zero(t, w.rate(rateWindow5m, later, start))
+	assert.Equal(t, 1000.0, w.peakRate(later))
+}
+
+func TestMetrics_SnapshotDerivedValues(t *testing.T) {
+	m := NewSnowflakeMetrics()
+	for i := 0; i < 99; i++ {
+		m.RecordIDGeneration(time.Microsecond, i%3 == 0)
+	}
+	m.RecordIDGeneration(time.Millisecond, false)
+	m.RecordError("generation")
+
+	snap := m.GetSnapshot()
+	assert.Equal(t, int64(100), snap.IDsGenerated)
+	assert.Equal(t, int64(33), snap.CacheHits)
+	assert.InDelta(t, 0.33, snap.CacheHitRate, 0.0001)
+	assert.Equal(t, int64(1), snap.GenerationErrors)
+	assert.Equal(t, time.Millisecond, snap.GenerationLatency)
+	// Mixed samples report the bucket midpoint, within the sketch's relative error
+	assert.InEpsilon(t, float64(time.Microsecond), float64(snap.P50Latency), 1.0/sketchSubBuckets)
+	assert.InEpsilon(t, float64(time.Microsecond), float64(snap.P95Latency), 1.0/sketchSubBuckets)
+	assert.Equal(t, time.Millisecond, snap.P999Latency)
+	assert.Equal(t, time.Microsecond, snap.MinLatency)
+	assert.Equal(t, time.Millisecond, snap.MaxLatency)
+	assert.Equal(t, int64(99), snap.LatencyHistogram["1-10μs"])
+	assert.Equal(t, int64(1), snap.LatencyHistogram["1-5ms"])
+	assert.Equal(t, 100.0, snap.IDGenerationRate)
+	assert.False(t, snap.LastGenerationTime.IsZero())
+	assert.Equal(t, int64(100), snap.LatencySketch().Count())
+	assert.Equal(t, snap.IDGenerationRate, snap.GetSnapshot().IDGenerationRate)
+
+	m.Reset()
+	snap = m.GetSnapshot()
+	assert.Zero(t, snap.IDsGenerated)
+	assert.Zero(t, snap.P99Latency)
+	assert.Zero(t, snap.IDGenerationRate)
+	assert.Empty(t, snap.LatencyHistogram)
+}
+
+func TestMetrics_ConcurrentRecordAndSnapshot(t *testing.T) {
+	m := NewSnowflakeMetrics()
+	const workers, perWorker = 8, 5000
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				m.RecordIDGeneration(time.Duration(i%100)*time.Microsecond, false)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = m.GetSnapshot()
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	snap := m.GetSnapshot()
+	assert.Equal(t, int64(workers*perWorker), snap.IDsGenerated)
+	assert.Equal(t, int64(workers*perWorker), snap.LatencySketch().Count())
+	assert.GreaterOrEqual(t, snap.P99Latency, 97*time.Microsecond)
+}
@@ -0,0 +1,68 @@
+package eonId
+
+import (
+	"context"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/pkg/timex"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// registryJanitorInterval returns how often the registry janitor runs; 0 when it is disabled
+func registryJanitorInterval(conf *pb.EonId) time.Duration {
+	if conf.RegistryJanitorInterval == nil {
+		return DefaultRegistryJanitorInterval
+	}
+	interval := conf.RegistryJanitorInterval.AsDuration()
+	switch {
+	case interval < 0:
+		return 0
+	case interval == 0:
+		return DefaultRegistryJanitorInterval
+	}
+	return interval
+}
+
+// startRegistryJanitorLocked starts the registry janitor once, if the worker ID allocator keeps a registry that
+// can outlive claims (see WorkerRegistryTrimmer). It runs until shutdown. Caller must hold p.mu.
+func (p *PlugSnowflake) startRegistryJanitorLocked() {
+	if p.conf == nil || !p.conf.AutoRegisterWorkerId || p.workerManager == nil {
+		return
+	}
+	if _, ok := p.workerManager.allocator.(WorkerRegistryTrimmer); !ok {
+		return
+	}
+	interval := registryJanitorInterval(p.conf)
+	if interval <= 0 {
+		return
+	}
+	workerManager := p.workerManager
+	p.registryJanitorOnce.Do(func() { go p.registryJanitorLoop(workerManager, interval) })
+}
+
+// registryJanitorLoop trims the registry every interval. The first run is delayed by a random part of the
+// interval so that instances started together do not trim at the same moment.
+func (p *PlugSnowflake) registryJanitorLoop(workerManager *WorkerIDManager, interval time.Duration) {
+	timer := time.NewTimer(timex.RandomDuration(0, interval))
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.shutdownCh:
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		removed, err := workerManager.TrimRegistry(ctx)
+		cancel()
+		switch {
+		case err != nil:
+			lynxlog.Warnf("eon-id registry janitor failed: %v", err)
+		case removed > 0:
+			lynxlog.Infof("eon-id registry janitor removed %d expired worker(s)", removed)
+		}
+		timer.Reset(interval)
+	}
+}
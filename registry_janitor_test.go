@@ -0,0 +1,52 @@
+package eonId
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+func TestRegistryJanitor_TrimsExpiredWorkers(t *testing.T) {
+	allocator, advance := newMiniredisAllocator(t)
+	a := allocator.(*RedisWorkerIDAllocator)
+	ctx := context.Background()
+	crashed, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "crashed"}, 31, 10*time.Second)
+	require.NoError(t, err)
+	advance(2 * time.Hour) // past the plugin's worker ID TTL
+
+	plugin, mgr := newRecoveringPlugin(t, a, &pb.EonId{
+		WorkerIdBits:            5,
+		RegistryJanitorInterval: durationpb.New(10 * time.Millisecond),
+	})
+	require.True(t, mgr.IsHealthy())
+
+	member := registryMember(crashed.DatacenterID, crashed.WorkerID)
+	require.Eventually(t, func() bool {
+		return a.client.ZScore(ctx, a.registryKey(1), member).Err() != nil
+	}, 5*time.Second, 10*time.Millisecond, "the janitor removes the crashed worker's registry member")
+
+	// The plugin's own member is live and stays
+	_, err = a.client.ZScore(ctx, a.registryKey(1), registryMember(1, mgr.GetWorkerID())).Result()
+	require.NoError(t, err)
+	require.NoError(t, plugin.cleanupTasksContext(ctx))
+}
+
+func TestRegistryJanitorInterval(t *testing.T) {
+	assert.Equal(t, DefaultRegistryJanitorInterval, registryJanitorInterval(&pb.EonId{}))
+	assert.Equal(t, DefaultRegistryJanitorInterval, registryJanitorInterval(&pb.EonId{RegistryJanitorInterval: durationpb.New(0)}))
+	assert.Equal(t, 5*time.Second, registryJanitorInterval(&pb.EonId{RegistryJanitorInterval: durationpb.New(5 * time.Second)}))
+	assert.Zero(t, registryJanitorInterval(&pb.EonId{RegistryJanitorInterval: durationpb.New(-time.Second)}))
+}
+
+func TestWorkerIDManager_TrimRegistryWithoutRegistry(t *testing.T) {
+	mgr := NewWorkerIDManagerWithAllocator(NewMemoryWorkerIDAllocator(), 1, nil)
+	removed, err := mgr.TrimRegistry(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
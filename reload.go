@@ -0,0 +1,524 @@
+package eonId
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-lynx/lynx/plugins"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// RuntimeConfig holds the Generator settings that can change while it is issuing IDs.
+// The ID layout (epoch, bit widths) and the node IDs are fixed for the lifetime of a Generator.
+type RuntimeConfig struct {
+	EnableClockDriftProtection bool
+	MaxClockDrift              time.Duration
+	ClockCheckInterval         time.Duration // DefaultClockCheckInterval when zero
+	ClockDriftAction           string
+	EnableSequenceCache        bool
+	SequenceCacheSize          int
+	EnableMetrics              bool
+	SequenceOverflowStrategy   string
+	SequenceStartMode          string
+}
+
+// activeMetrics returns the metrics sink, or nil while metrics are disabled
+func (g *Generator) activeMetrics() *Metrics {
+	if atomic.LoadInt32(&g.metricsDisabled) != 0 {
+		return nil
+	}
+	return g.metrics
+}
+
+// RuntimeConfig returns the settings currently in effect
+func (g *Generator) RuntimeConfig() RuntimeConfig {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return RuntimeConfig{
+		EnableClockDriftProtection: g.enableClockDriftProtection,
+		MaxClockDrift:              g.maxClockDrift,
+		ClockCheckInterval:         g.clockCheckInterval,
+		ClockDriftAction:           g.clockDriftAction,
+		EnableSequenceCache:        g.enableSequenceCache,
+		SequenceCacheSize:          g.cacheSize,
+		EnableMetrics:              atomic.LoadInt32(&g.metricsDisabled) == 0,
+		SequenceOverflowStrategy:   g.sequenceOverflowStrategy,
+		SequenceStartMode:          g.sequenceStartMode,
+	}
+}
+
+// ApplyRuntimeConfig changes the live settings of a running generator. Empty strategy fields take their defaults.
+// A resized sequence cache starts empty, so the current millisecond continues with plain increments.
+func (g *Generator) ApplyRuntimeConfig(config RuntimeConfig) error {
+	if config.ClockDriftAction == "" {
+		config.ClockDriftAction = ClockDriftActionWait
+	}
+	if config.SequenceOverflowStrategy == "" {
+		config.SequenceOverflowStrategy = SequenceOverflowStrategySleep
+	}
+	if config.MaxClockDrift < 0 {
+		return fmt.Errorf("max clock drift cannot be negative")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Reuse GeneratorConfig validation for the live fields against the sequence width this generator was built with
+	check := &GeneratorConfig{
+		SequenceBits:               int(g.sequenceBits),
+		EnableClockDriftProtection: config.EnableClockDriftProtection,
+		MaxClockDrift:              config.MaxClockDrift,
+		ClockCheckInterval:         config.ClockCheckInterval,
+		ClockDriftAction:           config.ClockDriftAction,
+		EnableSequenceCache:        config.EnableSequenceCache,
+		SequenceCacheSize:          config.SequenceCacheSize,
+		SequenceOverflowStrategy:   config.SequenceOverflowStrategy,
+		SequenceStartMode:          config.SequenceStartMode,
+	}
+	if err := check.validateRuntime(); err != nil {
+		return err
+	}
+
+	g.enableClockDriftProtection = config.EnableClockDriftProtection
+	g.maxClockDrift = config.MaxClockDrift
+	g.clockCheckInterval = clockCheckIntervalOr(config.ClockCheckInterval)
+	g.clockDriftAction = config.ClockDriftAction
+	g.sequenceOverflowStrategy = config.SequenceOverflowStrategy
+	g.sequenceStartMode = config.SequenceStartMode
+
+	if config.EnableSequenceCache != g.enableSequenceCache || config.SequenceCacheSize != g.cacheSize {
+		g.enableSequenceCache = config.EnableSequenceCache
+		g.cacheSize = config.SequenceCacheSize
+		g.sequenceCache = nil
+		if g.enableSequenceCache {
+			g.sequenceCache = make([]int64, g.cacheSize)
+			for i := range g.sequenceCache {
+				g.sequenceCache[i] = -1
+			}
+		}
+		g.cacheIndex = 0
+	}
+
+	if config.EnableMetrics {
+		if g.metrics == nil {
+			g.metrics = NewSnowflakeMetrics()
+		}
+		atomic.StoreInt32(&g.metricsDisabled, 0)
+	} else {
+		atomic.StoreInt32(&g.metricsDisabled, 1)
+	}
+	return nil
+}
+
+// activeMetrics returns the metrics sink, or nil while metrics are disabled
+func (g *ULIDGenerator) activeMetrics() *Metrics {
+	if atomic.LoadInt32(&g.metricsDisabled) != 0 {
+		return nil
+	}
+	return g.metrics
+}
+
+// ApplyRuntimeConfig changes the clock drift policy and metrics collection of a running ULID generator
+func (g *ULIDGenerator) ApplyRuntimeConfig(clockDriftAction string, enableMetrics bool) error {
+	if clockDriftAction == "" {
+		clockDriftAction = ClockDriftActionWait
+	}
+	switch clockDriftAction {
+	case ClockDriftActionWait, ClockDriftActionError, ClockDriftActionIgnore:
+	default:
+		return fmt.Errorf("invalid clock drift action: %s", clockDriftAction)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.clockDriftAction = clockDriftAction
+	if enableMetrics {
+		if g.metrics == nil {
+			g.metrics = NewSnowflakeMetrics()
+		}
+		atomic.StoreInt32(&g.metricsDisabled, 0)
+	} else {
+		atomic.StoreInt32(&g.metricsDisabled, 1)
+	}
+	return nil
+}
+
+// configFieldClass tells UpdateConfiguration what to do when a field changes
+type configFieldClass int
+
+const (
+	configFieldLive    configFieldClass = iota // applied to the running generator and worker manager
+	configFieldLayout                          // changes the ID layout or node identity; would break uniqueness
+	configFieldRestart                         // only read during initialization
+)
+
+// configField describes one hot-reloadable configuration field. value returns the effective value
+// (defaults applied) so that, e.g., an unset epoch and the explicit default epoch compare equal;
+// keep copies the running value back when a change is refused.
+type configField struct {
+	name  string
+	class configFieldClass
+	value func(c *pb.EonId) string
+	keep  func(dst, src *pb.EonId)
+}
+
+func durationValue(d *durationpb.Duration, def time.Duration) string {
+	if d == nil {
+		return def.String()
+	}
+	return d.AsDuration().String()
+}
+
+func stringOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func int32Or(v, def int32) string {
+	if v == 0 {
+		v = def
+	}
+	return strconv.Itoa(int(v))
+}
+
+var configFields = []configField{
+	// Layout: a different epoch, bit split or datacenter can reproduce IDs that were already issued
+	{"datacenter_id", configFieldLayout,
+		func(c *pb.EonId) string { return strconv.Itoa(int(c.DatacenterId)) },
+		func(dst, src *pb.EonId) { dst.DatacenterId = src.DatacenterId }},
+	{"datacenter_name", configFieldLayout,
+		func(c *pb.EonId) string { return c.DatacenterName },
+		func(dst, src *pb.EonId) { dst.DatacenterName = src.DatacenterName }},
+	{"datacenter_name_env", configFieldLayout,
+		func(c *pb.EonId) string { return c.DatacenterNameEnv },
+		func(dst, src *pb.EonId) { dst.DatacenterNameEnv = src.DatacenterNameEnv }},
+	{"custom_epoch", configFieldLayout,
+		func(c *pb.EonId) string {
+			if c.CustomEpoch == 0 {
+				return strconv.FormatInt(DefaultEpoch, 10)
+			}
+			return strconv.FormatInt(c.CustomEpoch, 10)
+		},
+		func(dst, src *pb.EonId) { dst.CustomEpoch = src.CustomEpoch }},
+	{"worker_id_bits", configFieldLayout,
+		func(c *pb.EonId) string { return int32Or(c.WorkerIdBits, DefaultWorkerBits) },
+		func(dst, src *pb.EonId) { dst.WorkerIdBits = src.WorkerIdBits }},
+	{"sequence_bits", configFieldLayout,
+		func(c *pb.EonId) string { return int32Or(c.SequenceBits, DefaultSequenceBits) },
+		func(dst, src *pb.EonId) { dst.SequenceBits = src.SequenceBits }},
+
+	// Restart: consumed while registering the worker ID or building the ULID entropy source
+	{"worker_id", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.Itoa(int(c.WorkerId)) },
+		func(dst, src *pb.EonId) { dst.WorkerId = src.WorkerId }},
+	{"auto_register_worker_id", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.FormatBool(c.AutoRegisterWorkerId) },
+		func(dst, src *pb.EonId) { dst.AutoRegisterWorkerId = src.AutoRegisterWorkerId }},
+	{"worker_id_allocator", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.WorkerIdAllocator, WorkerIDAllocatorRedis) },
+		func(dst, src *pb.EonId) { dst.WorkerIdAllocator = src.WorkerIdAllocator }},
+	{"worker_reuse_safety_margin", configFieldRestart,
+		func(c *pb.EonId) string {
+			return durationValue(c.WorkerReuseSafetyMargin, DefaultWorkerReuseSafetyMargin)
+		},
+		func(dst, src *pb.EonId) { dst.WorkerReuseSafetyMargin = src.WorkerReuseSafetyMargin }},
+	{"worker_lease_safety_margin", configFieldRestart,
+		func(c *pb.EonId) string {
+			return durationValue(c.WorkerLeaseSafetyMargin, DefaultWorkerLeaseSafetyMargin)
+		},
+		func(dst, src *pb.EonId) { dst.WorkerLeaseSafetyMargin = src.WorkerLeaseSafetyMargin }},
+	{"etcd_plugin_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.EtcdPluginName, DefaultEtcdPluginName) },
+		func(dst, src *pb.EonId) { dst.EtcdPluginName = src.EtcdPluginName }},
+	{"etcd_key_prefix", configFieldRestart,
+		func(c *pb.EonId) string { return NormalizeEtcdKeyPrefix(c.EtcdKeyPrefix) },
+		func(dst, src *pb.EonId) { dst.EtcdKeyPrefix = src.EtcdKeyPrefix }},
+	{"zookeeper_plugin_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.ZookeeperPluginName, DefaultZooKeeperPluginName) },
+		func(dst, src *pb.EonId) { dst.ZookeeperPluginName = src.ZookeeperPluginName }},
+	{"zookeeper_key_prefix", configFieldRestart,
+		func(c *pb.EonId) string { return NormalizeZooKeeperKeyPrefix(c.ZookeeperKeyPrefix) },
+		func(dst, src *pb.EonId) { dst.ZookeeperKeyPrefix = src.ZookeeperKeyPrefix }},
+	{"kubernetes_plugin_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.KubernetesPluginName, DefaultKubernetesPluginName) },
+		func(dst, src *pb.EonId) { dst.KubernetesPluginName = src.KubernetesPluginName }},
+	{"kubernetes_namespace", configFieldRestart,
+		func(c *pb.EonId) string { return c.KubernetesNamespace },
+		func(dst, src *pb.EonId) { dst.KubernetesNamespace = src.KubernetesNamespace }},
+	{"kubernetes_lease_prefix", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.KubernetesLeasePrefix, DefaultKubernetesLeasePrefix) },
+		func(dst, src *pb.EonId) { dst.KubernetesLeasePrefix = src.KubernetesLeasePrefix }},
+	{"kubernetes_ordinal_env", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.KubernetesOrdinalEnv, DefaultKubernetesOrdinalEnv) },
+		func(dst, src *pb.EonId) { dst.KubernetesOrdinalEnv = src.KubernetesOrdinalEnv }},
+	{"sql_plugin_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.SqlPluginName, DefaultSQLPluginName) },
+		func(dst, src *pb.EonId) { dst.SqlPluginName = src.SqlPluginName }},
+	{"sql_dialect", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.SqlDialect, SQLDialectMySQL) },
+		func(dst, src *pb.EonId) { dst.SqlDialect = src.SqlDialect }},
+	{"sql_table_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.SqlTableName, DefaultSQLTableName) },
+		func(dst, src *pb.EonId) { dst.SqlTableName = src.SqlTableName }},
+	{"sql_safety_delay", configFieldRestart,
+		func(c *pb.EonId) string { return durationValue(c.SqlSafetyDelay, DefaultSQLSafetyDelay) },
+		func(dst, src *pb.EonId) { dst.SqlSafetyDelay = src.SqlSafetyDelay }},
+	{"file_lock_dir", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.FileLockDir, DefaultFileLockDir) },
+		func(dst, src *pb.EonId) { dst.FileLockDir = src.FileLockDir }},
+	{"file_lock_base_offset", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.FormatInt(c.FileLockBaseOffset, 10) },
+		func(dst, src *pb.EonId) { dst.FileLockBaseOffset = src.FileLockBaseOffset }},
+	{"file_lock_slots", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.Itoa(int(c.FileLockSlots)) },
+		func(dst, src *pb.EonId) { dst.FileLockSlots = src.FileLockSlots }},
+	{"redis_key_prefix", configFieldRestart,
+		func(c *pb.EonId) string { return NormalizeKeyPrefix(c.RedisKeyPrefix) },
+		func(dst, src *pb.EonId) { dst.RedisKeyPrefix = src.RedisKeyPrefix }},
+	{"redis_key_layout", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.RedisKeyLayout, RedisKeyLayoutLegacy) },
+		func(dst, src *pb.EonId) { dst.RedisKeyLayout = src.RedisKeyLayout }},
+	{"redis_migrate_key_layout", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.FormatBool(c.RedisMigrateKeyLayout) },
+		func(dst, src *pb.EonId) { dst.RedisMigrateKeyLayout = src.RedisMigrateKeyLayout }},
+	{"registry_janitor_interval", configFieldRestart,
+		func(c *pb.EonId) string {
+			return durationValue(c.RegistryJanitorInterval, DefaultRegistryJanitorInterval)
+		},
+		func(dst, src *pb.EonId) { dst.RegistryJanitorInterval = src.RegistryJanitorInterval }},
+	{"redis_plugin_name", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.RedisPluginName, RedisLegacyResourceName) },
+		func(dst, src *pb.EonId) { dst.RedisPluginName = src.RedisPluginName }},
+	{"redis_db", configFieldRestart,
+		func(c *pb.EonId) string { return strconv.Itoa(int(c.RedisDb)) },
+		func(dst, src *pb.EonId) { dst.RedisDb = src.RedisDb }},
+	{"ulid_entropy_source", configFieldRestart,
+		func(c *pb.EonId) string { return stringOr(c.UlidEntropySource, ULIDEntropyCrypto) },
+		func(dst, src *pb.EonId) { dst.UlidEntropySource = src.UlidEntropySource }},
+
+	// Live
+	{"worker_id_ttl", configFieldLive,
+		func(c *pb.EonId) string { return durationValue(c.WorkerIdTtl, DefaultWorkerIDTTL) }, nil},
+	{"heartbeat_interval", configFieldLive,
+		func(c *pb.EonId) string { return durationValue(c.HeartbeatInterval, DefaultHeartbeatInterval) }, nil},
+	{"worker_recovery_max_attempts", configFieldLive,
+		func(c *pb.EonId) string {
+			attempts, _ := workerRecoveryPolicy(c)
+			return strconv.Itoa(attempts)
+		}, nil},
+	{"worker_recovery_backoff", configFieldLive,
+		func(c *pb.EonId) string {
+			_, backoff := workerRecoveryPolicy(c)
+			return backoff.String()
+		}, nil},
+	{"worker_revoke_action", configFieldLive,
+		func(c *pb.EonId) string { return workerRevokeAction(c) }, nil},
+	{"enable_clock_drift_protection", configFieldLive,
+		func(c *pb.EonId) string { return strconv.FormatBool(c.EnableClockDriftProtection) }, nil},
+	{"max_clock_drift", configFieldLive,
+		func(c *pb.EonId) string { return durationValue(c.MaxClockDrift, DefaultMaxClockDrift) }, nil},
+	{"clock_check_interval", configFieldLive,
+		func(c *pb.EonId) string { return durationValue(c.ClockCheckInterval, DefaultClockCheckInterval) }, nil},
+	{"clock_drift_action", configFieldLive,
+		func(c *pb.EonId) string { return stringOr(c.ClockDriftAction, ClockDriftActionWait) }, nil},
+	{"enable_sequence_cache", configFieldLive,
+		func(c *pb.EonId) string { return strconv.FormatBool(c.EnableSequenceCache) }, nil},
+	{"sequence_cache_size", configFieldLive,
+		func(c *pb.EonId) string { return int32Or(c.SequenceCacheSize, DefaultSequenceCacheSize) }, nil},
+	{"enable_metrics", configFieldLive,
+		func(c *pb.EonId) string { return strconv.FormatBool(c.EnableMetrics) }, nil},
+	{"sequence_overflow_strategy", configFieldLive,
+		func(c *pb.EonId) string { return stringOr(c.SequenceOverflowStrategy, SequenceOverflowStrategySleep) }, nil},
+	{"sequence_start_mode", configFieldLive,
+		func(c *pb.EonId) string { return stringOr(c.SequenceStartMode, SequenceStartZero) }, nil},
+}
+
+// diffConfig splits the differences between the running and the new configuration into
+// changes that can be applied live and changes that must be refused
+func diffConfig(running, next *pb.EonId) (applied, refused []ConfigChange) {
+	for _, f := range configFields {
+		oldValue, newValue := f.value(running), f.value(next)
+		if oldValue == newValue {
+			continue
+		}
+		change := ConfigChange{Field: f.name, Old: oldValue, New: newValue}
+		switch f.class {
+		case configFieldLive:
+			applied = append(applied, change)
+		case configFieldLayout:
+			change.Reason = "would break ID uniqueness"
+			refused = append(refused, change)
+		case configFieldRestart:
+			change.Reason = "requires restart"
+			refused = append(refused, change)
+		}
+	}
+	return applied, refused
+}
+
+// runtimeConfigFromProto maps the live fields of the plugin configuration, with the same
+// defaults InitializeResources uses
+func runtimeConfigFromProto(conf *pb.EonId) RuntimeConfig {
+	rc := RuntimeConfig{
+		EnableClockDriftProtection: conf.EnableClockDriftProtection,
+		MaxClockDrift:              DefaultMaxClockDrift,
+		ClockCheckInterval:         DefaultClockCheckInterval,
+		ClockDriftAction:           conf.ClockDriftAction,
+		EnableSequenceCache:        conf.EnableSequenceCache,
+		SequenceCacheSize:          int(conf.SequenceCacheSize),
+		EnableMetrics:              conf.EnableMetrics,
+		SequenceOverflowStrategy:   conf.SequenceOverflowStrategy,
+		SequenceStartMode:          conf.SequenceStartMode,
+	}
+	if conf.MaxClockDrift != nil {
+		rc.MaxClockDrift = conf.MaxClockDrift.AsDuration()
+	}
+	if conf.ClockCheckInterval != nil {
+		rc.ClockCheckInterval = conf.ClockCheckInterval.AsDuration()
+	}
+	if rc.SequenceCacheSize == 0 {
+		rc.SequenceCacheSize = DefaultSequenceCacheSize
+	}
+	return rc
+}
+
+// reloadConfiguration applies the live part of conf to the running plugin and refuses the rest.
+// The stored configuration keeps the running values of refused fields, so it always describes what is in effect.
+func (p *PlugSnowflake) reloadConfiguration(conf *pb.EonId) error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	p.mu.RLock()
+	running := p.conf
+	generator := p.generator
+	workerManager := p.workerManager
+	ulidGenerator := p.ulidGenerator
+	p.mu.RUnlock()
+
+	if running == nil || generator == nil {
+		// Not initialized yet: InitializeResources reads the configuration
+		p.mu.Lock()
+		p.conf = conf
+		p.mu.Unlock()
+		return nil
+	}
+
+	applied, refused := diffConfig(running, conf)
+	next := proto.Clone(conf).(*pb.EonId)
+	for _, change := range refused {
+		for _, f := range configFields {
+			if f.name == change.Field {
+				f.keep(next, running)
+			}
+		}
+	}
+
+	if len(applied) > 0 {
+		if err := p.applyLiveConfig(next, generator, workerManager, ulidGenerator); err != nil {
+			p.emitRuntimeEvent(plugins.PluginEvent{
+				Type:     plugins.EventConfigurationInvalid,
+				Priority: plugins.PriorityHigh,
+				Source:   "UpdateConfiguration",
+				Category: "configuration",
+				Error:    err,
+			})
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.conf = next
+	p.mu.Unlock()
+
+	if len(applied) == 0 && len(refused) == 0 {
+		return nil
+	}
+
+	event := plugins.PluginEvent{
+		Type:     plugins.EventConfigurationApplied,
+		Priority: plugins.PriorityNormal,
+		Source:   "UpdateConfiguration",
+		Category: "configuration",
+		Metadata: map[string]any{
+			"applied": configChangeStrings(applied),
+			"refused": configChangeStrings(refused),
+		},
+	}
+	var err error
+	if len(refused) > 0 {
+		err = &UnsafeConfigChangeError{Refused: refused}
+		event.Priority = plugins.PriorityHigh
+		event.Error = err
+		if len(applied) == 0 {
+			event.Type = plugins.EventConfigurationInvalid
+		}
+	}
+	p.emitRuntimeEvent(event)
+	return err
+}
+
+// applyLiveConfig validates the live sections of conf and pushes them to the running components.
+// Everything is validated before the first component changes, so an invalid update changes nothing.
+func (p *PlugSnowflake) applyLiveConfig(conf *pb.EonId, generator *Generator, workerManager *WorkerIDManager, ulidGenerator *ULIDGenerator) error {
+	if err := validateClockDriftConfig(conf); err != nil {
+		return fmt.Errorf("clock drift protection validation failed: %w", err)
+	}
+	if err := validateWorkerIDTiming(conf); err != nil {
+		return fmt.Errorf("worker ID timing validation failed: %w", err)
+	}
+	ttl := DefaultWorkerIDTTL
+	if conf.WorkerIdTtl != nil {
+		ttl = conf.WorkerIdTtl.AsDuration()
+	}
+	heartbeatInterval := DefaultHeartbeatInterval
+	if conf.HeartbeatInterval != nil {
+		heartbeatInterval = conf.HeartbeatInterval.AsDuration()
+	}
+	if heartbeatInterval >= ttl {
+		return fmt.Errorf("heartbeat interval (%v) must be less than worker ID TTL (%v)", heartbeatInterval, ttl)
+	}
+
+	// ApplyRuntimeConfig validates the remaining fields before changing anything
+	if err := generator.ApplyRuntimeConfig(runtimeConfigFromProto(conf)); err != nil {
+		return fmt.Errorf("failed to apply generator configuration: %w", err)
+	}
+	if ulidGenerator != nil {
+		if err := ulidGenerator.ApplyRuntimeConfig(conf.ClockDriftAction, conf.EnableMetrics); err != nil {
+			return fmt.Errorf("failed to apply ULID generator configuration: %w", err)
+		}
+	}
+	if workerManager != nil {
+		if err := workerManager.UpdateTiming(ttl, heartbeatInterval); err != nil {
+			return fmt.Errorf("failed to apply worker ID timing: %w", err)
+		}
+	}
+	return nil
+}
+
+func configChangeStrings(changes []ConfigChange) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+// emitRuntimeEvent publishes a plugin event once the plugin is attached to a Lynx runtime
+func (p *PlugSnowflake) emitRuntimeEvent(event plugins.PluginEvent) {
+	p.mu.RLock()
+	rt := p.runtime
+	p.mu.RUnlock()
+	if rt == nil || p.BasePlugin == nil {
+		return
+	}
+	p.EmitEvent(event)
+}
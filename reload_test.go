@@ -0,0 +1,256 @@
+package eonId
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+func newReloadTestConf() *pb.EonId {
+	return &pb.EonId{
+		DatacenterId:               1,
+		WorkerId:                   3,
+		RedisKeyPrefix:             "eon:",
+		WorkerIdTtl:                durationpb.New(30 * time.Second),
+		HeartbeatInterval:          durationpb.New(10 * time.Second),
+		EnableClockDriftProtection: true,
+		ClockDriftAction:           ClockDriftActionWait,
+		EnableSequenceCache:        true,
+		SequenceCacheSize:          100,
+		EnableMetrics:              true,
+	}
+}
+
+// newReloadTestPlugin wires a plugin the way InitializeResources does, without a Lynx runtime
+func newReloadTestPlugin(t *testing.T, conf *pb.EonId) *PlugSnowflake {
+	t.Helper()
+	plugin := NewSnowflakePlugin()
+
+	generator, err := NewSnowflakeGeneratorCore(int64(conf.DatacenterId), int64(conf.WorkerId), &GeneratorConfig{
+		CustomEpoch:                DefaultEpoch,
+		DatacenterIDBits:           DefaultDatacenterBits,
+		WorkerIDBits:               DefaultWorkerBits,
+		SequenceBits:               DefaultSequenceBits,
+		EnableClockDriftProtection: conf.EnableClockDriftProtection,
+		MaxClockDrift:              DefaultMaxClockDrift,
+		ClockDriftAction:           conf.ClockDriftAction,
+		EnableSequenceCache:        conf.EnableSequenceCache,
+		SequenceCacheSize:          int(conf.SequenceCacheSize),
+		EnableMetrics:              conf.EnableMetrics,
+	})
+	require.NoError(t, err)
+	ulidGenerator, err := NewULIDGenerator(&ULIDGeneratorConfig{EnableMetrics: conf.EnableMetrics})
+	require.NoError(t, err)
+
+	plugin.conf = conf
+	plugin.generator = generator
+	plugin.ulidGenerator = ulidGenerator
+	plugin.workerManager = NewWorkerIDManager(nil, int64(conf.DatacenterId), &WorkerManagerConfig{
+		KeyPrefix:         conf.RedisKeyPrefix,
+		TTL:               conf.WorkerIdTtl.AsDuration(),
+		HeartbeatInterval: conf.HeartbeatInterval.AsDuration(),
+	})
+	return plugin
+}
+
+func TestUpdateConfiguration_BeforeInitStoresConfig(t *testing.T) {
+	plugin := NewSnowflakePlugin()
+	conf := newReloadTestConf()
+
+	require.NoError(t, plugin.UpdateConfiguration(conf))
+	assert.Same(t, conf, plugin.conf)
+
+	assert.Error(t, plugin.UpdateConfiguration("not a config"))
+}
+
+func TestUpdateConfiguration_AppliesLiveChanges(t *testing.T) {
+	plugin := newReloadTestPlugin(t, newReloadTestConf())
+
+	next := newReloadTestConf()
+	next.ClockDriftAction = ClockDriftActionError
+	next.MaxClockDrift = durationpb.New(2 * time.Second)
+	next.SequenceCacheSize = 200
+	next.EnableMetrics = false
+	next.SequenceOverflowStrategy = SequenceOverflowStrategyError
+	next.WorkerIdTtl = durationpb.New(60 * time.Second)
+	next.HeartbeatInterval = durationpb.New(15 * time.Second)
+	require.NoError(t, plugin.UpdateConfiguration(next))
+
+	rc := plugin.generator.RuntimeConfig()
+	assert.Equal(t, ClockDriftActionError, rc.ClockDriftAction)
+	assert.Equal(t, 2*time.Second, rc.MaxClockDrift)
+	assert.Equal(t, 200, rc.SequenceCacheSize)
+	assert.False(t, rc.EnableMetrics)
+	assert.Equal(t, SequenceOverflowStrategyError, rc.SequenceOverflowStrategy)
+	assert.Nil(t, plugin.generator.GetMetrics())
+	assert.Nil(t, plugin.ulidGenerator.GetMetrics())
+	assert.Equal(t, ClockDriftActionError, plugin.ulidGenerator.clockDriftAction)
+
+	assert.Equal(t, 60*time.Second, plugin.workerManager.ttl)
+	assert.Equal(t, 15*time.Second, plugin.workerManager.heartbeatInterval)
+	assert.True(t, proto.Equal(next, plugin.conf))
+
+	// IDs stay unique across the cache resize
+	seen := make(map[int64]struct{})
+	for i := 0; i < 5000; i++ {
+		id, err := plugin.generator.GenerateID()
+		require.NoError(t, err)
+		_, dup := seen[id]
+		require.False(t, dup)
+		seen[id] = struct{}{}
+	}
+}
+
+func TestUpdateConfiguration_ClockCheckInterval(t *testing.T) {
+	plugin := newReloadTestPlugin(t, newReloadTestConf())
+	generator := plugin.generator
+	assert.Equal(t, DefaultClockCheckInterval, generator.RuntimeConfig().ClockCheckInterval)
+
+	// staleCheck makes the next call see a forward drift far beyond max_clock_drift, last checked 2s ago
+	staleCheck := func() {
+		generator.mu.Lock()
+		generator.lastTimestamp = time.Now().Add(-time.Hour).UnixMilli()
+		generator.lastClockCheck = time.Now().Add(-2 * time.Second)
+		generator.mu.Unlock()
+	}
+
+	next := newReloadTestConf()
+	next.ClockDriftAction = ClockDriftActionError
+	next.ClockCheckInterval = durationpb.New(10 * time.Minute)
+	require.NoError(t, plugin.UpdateConfiguration(next))
+	assert.Equal(t, 10*time.Minute, generator.RuntimeConfig().ClockCheckInterval)
+	staleCheck()
+	_, err := generator.GenerateID()
+	require.NoError(t, err, "checked 2s ago, within the 10m interval")
+
+	next = newReloadTestConf()
+	next.ClockDriftAction = ClockDriftActionError
+	next.ClockCheckInterval = durationpb.New(time.Second)
+	require.NoError(t, plugin.UpdateConfiguration(next))
+	staleCheck()
+	_, err = generator.GenerateID()
+	var driftErr *ClockDriftError
+	assert.True(t, errors.As(err, &driftErr), "got %v", err)
+}
+
+func TestUpdateConfiguration_RefusesUnsafeChanges(t *testing.T) {
+	running := newReloadTestConf()
+	plugin := newReloadTestPlugin(t, running)
+
+	next := newReloadTestConf()
+	next.DatacenterId = 2
+	next.CustomEpoch = DefaultEpoch + 1
+	next.SequenceBits = 14
+	next.RedisKeyPrefix = "other:"
+	next.ClockDriftAction = ClockDriftActionIgnore
+
+	err := plugin.UpdateConfiguration(next)
+	var unsafeErr *UnsafeConfigChangeError
+	require.True(t, errors.As(err, &unsafeErr), "got %v", err)
+
+	refused := make(map[string]ConfigChange)
+	for _, c := range unsafeErr.Refused {
+		refused[c.Field] = c
+	}
+	assert.Len(t, refused, 4)
+	assert.Equal(t, "would break ID uniqueness", refused["datacenter_id"].Reason)
+	assert.Equal(t, "would break ID uniqueness", refused["custom_epoch"].Reason)
+	assert.Equal(t, "would break ID uniqueness", refused["sequence_bits"].Reason)
+	assert.Equal(t, "requires restart", refused["redis_key_prefix"].Reason)
+	assert.Contains(t, err.Error(), "datacenter_id: 1 -> 2")
+
+	// The safe part is applied, the stored config keeps the running layout
+	assert.Equal(t, ClockDriftActionIgnore, plugin.generator.RuntimeConfig().ClockDriftAction)
+	assert.Equal(t, int32(1), plugin.conf.DatacenterId)
+	assert.Zero(t, plugin.conf.CustomEpoch)
+	assert.Zero(t, plugin.conf.SequenceBits)
+	assert.Equal(t, "eon:", plugin.conf.RedisKeyPrefix)
+	assert.Equal(t, ClockDriftActionIgnore, plugin.conf.ClockDriftAction)
+	assert.Equal(t, int64(1), plugin.generator.datacenterID)
+}
+
+func TestUpdateConfiguration_DefaultsCompareEqual(t *testing.T) {
+	plugin := newReloadTestPlugin(t, newReloadTestConf())
+
+	// Spelling out the defaults is not a change
+	next := newReloadTestConf()
+	next.CustomEpoch = DefaultEpoch
+	next.WorkerIdBits = DefaultWorkerBits
+	next.SequenceBits = DefaultSequenceBits
+	next.MaxClockDrift = durationpb.New(DefaultMaxClockDrift)
+	assert.NoError(t, plugin.UpdateConfiguration(next))
+}
+
+func TestUpdateConfiguration_InvalidLiveChangeAppliesNothing(t *testing.T) {
+	plugin := newReloadTestPlugin(t, newReloadTestConf())
+	before := plugin.generator.RuntimeConfig()
+
+	next := newReloadTestConf()
+	next.EnableMetrics = false
+	next.ClockDriftAction = "sometimes"
+	err := plugin.UpdateConfiguration(next)
+	require.Error(t, err)
+	var unsafeErr *UnsafeConfigChangeError
+	assert.False(t, errors.As(err, &unsafeErr))
+
+	next = newReloadTestConf()
+	next.EnableMetrics = false
+	next.HeartbeatInterval = durationpb.New(40 * time.Second) // not below the 30s TTL
+	require.Error(t, plugin.UpdateConfiguration(next))
+
+	assert.Equal(t, before, plugin.generator.RuntimeConfig())
+	assert.True(t, plugin.conf.EnableMetrics)
+}
+
+func TestGenerator_ApplyRuntimeConfig_EnableMetrics(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.EnableMetrics = false
+	g, err := NewSnowflakeGeneratorCore(0, 0, cfg)
+	require.NoError(t, err)
+	assert.Nil(t, g.GetMetrics())
+
+	rc := g.RuntimeConfig()
+	rc.EnableMetrics = true
+	require.NoError(t, g.ApplyRuntimeConfig(rc))
+
+	_, err = g.GenerateID()
+	require.NoError(t, err)
+	metrics := g.GetMetrics()
+	require.NotNil(t, metrics)
+	assert.Equal(t, int64(1), metrics.IDsGenerated)
+
+	rc.EnableSequenceCache = true
+	rc.SequenceCacheSize = 1 << 20
+	assert.Error(t, g.ApplyRuntimeConfig(rc), "cache larger than the sequence space")
+}
+
+func TestWorkerIDManager_UpdateTimingRestartsHeartbeat(t *testing.T) {
+	mgr := NewWorkerIDManager(nil, 1, &WorkerManagerConfig{
+		KeyPrefix:         "eon:",
+		TTL:               2 * time.Hour,
+		HeartbeatInterval: time.Hour,
+	})
+	mgr.mu.Lock()
+	mgr.startHeartbeatLocked()
+	oldCtx := mgr.heartbeatCtx
+	mgr.mu.Unlock()
+
+	require.NoError(t, mgr.UpdateTiming(3*time.Hour, 30*time.Minute))
+
+	mgr.mu.Lock()
+	assert.True(t, mgr.heartbeatRunning)
+	assert.True(t, oldCtx != mgr.heartbeatCtx)
+	assert.Error(t, oldCtx.Err(), "old heartbeat loop is cancelled")
+	assert.Equal(t, 3*time.Hour, mgr.ttl)
+	mgr.heartbeatCancel()
+	mgr.mu.Unlock()
+
+	assert.Error(t, mgr.UpdateTiming(time.Minute, time.Minute))
+}
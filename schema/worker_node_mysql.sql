@@ -0,0 +1,16 @@
+-- Worker ID claims for worker_id_allocator: "sql" (MySQL 5.7+)
+-- Each start inserts a row; the worker ID is ID modulo the worker ID space.
+CREATE TABLE IF NOT EXISTS WORKER_NODE (
+    ID            BIGINT       NOT NULL AUTO_INCREMENT COMMENT 'auto-increment, worker ID = ID % (max worker ID + 1)',
+    HOST_NAME     VARCHAR(64)  NOT NULL COMMENT 'host IP',
+    PORT          VARCHAR(64)  NOT NULL COMMENT 'service port, or process ID',
+    SERVICE_NAME  VARCHAR(128) NOT NULL DEFAULT '',
+    INSTANCE_ID   VARCHAR(128) NOT NULL,
+    DATACENTER_ID BIGINT       NOT NULL,
+    WORKER_ID     BIGINT       NOT NULL DEFAULT -1,
+    LAUNCH_DATE   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    LEASE_EXPIRES BIGINT       NOT NULL COMMENT 'lease end, unix milliseconds',
+    INFO          TEXT         NOT NULL COMMENT 'WorkerInfo JSON',
+    PRIMARY KEY (ID),
+    KEY IDX_WORKER_NODE_WORKER (DATACENTER_ID, WORKER_ID, LEASE_EXPIRES)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,15 @@
+-- Worker ID claims for worker_id_allocator: "sql" (PostgreSQL 10+)
+-- Each start inserts a row; the worker ID is ID modulo the worker ID space.
+CREATE TABLE IF NOT EXISTS WORKER_NODE (
+    ID            BIGSERIAL    PRIMARY KEY,
+    HOST_NAME     VARCHAR(64)  NOT NULL,
+    PORT          VARCHAR(64)  NOT NULL,
+    SERVICE_NAME  VARCHAR(128) NOT NULL DEFAULT '',
+    INSTANCE_ID   VARCHAR(128) NOT NULL,
+    DATACENTER_ID BIGINT       NOT NULL,
+    WORKER_ID     BIGINT       NOT NULL DEFAULT -1,
+    LAUNCH_DATE   TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    LEASE_EXPIRES BIGINT       NOT NULL,
+    INFO          TEXT         NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IDX_WORKER_NODE_WORKER ON WORKER_NODE (DATACENTER_ID, WORKER_ID, LEASE_EXPIRES);
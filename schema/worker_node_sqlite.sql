@@ -0,0 +1,15 @@
+-- Worker ID claims for worker_id_allocator: "sql" (SQLite, for tests and single-host setups)
+-- Each start inserts a row; the worker ID is ID modulo the worker ID space.
+CREATE TABLE IF NOT EXISTS WORKER_NODE (
+    ID            INTEGER PRIMARY KEY AUTOINCREMENT,
+    HOST_NAME     TEXT    NOT NULL,
+    PORT          TEXT    NOT NULL,
+    SERVICE_NAME  TEXT    NOT NULL DEFAULT '',
+    INSTANCE_ID   TEXT    NOT NULL,
+    DATACENTER_ID INTEGER NOT NULL,
+    WORKER_ID     INTEGER NOT NULL DEFAULT -1,
+    LAUNCH_DATE   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    LEASE_EXPIRES INTEGER NOT NULL,
+    INFO          TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS IDX_WORKER_NODE_WORKER ON WORKER_NODE (DATACENTER_ID, WORKER_ID, LEASE_EXPIRES);
@@ -3,6 +3,7 @@ package eonId
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/go-lynx/lynx"
 	lynxlog "github.com/go-lynx/lynx/log"
 	"github.com/go-lynx/lynx/plugins"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 
 	pb "github.com/go-lynx/lynx-eon-id/conf"
@@ -28,12 +30,26 @@ type PlugSnowflake struct {
 	workerManager *WorkerIDManager
 	// ID generator
 	generator *Generator
+	// ULID generator (monotonic per process, no worker ID involved)
+	ulidGenerator *ULIDGenerator
 	// Shutdown channel
 	shutdownCh chan struct{}
 	// Ensure shutdown channel is closed only once
 	shutdownOnce sync.Once
 	// Ensure stop/cleanup logic runs only once (Stop and CleanupTasks are idempotent)
 	stopCleanupOnce sync.Once
+	// Serializes UpdateConfiguration calls
+	reloadMu sync.Mutex
+	// Last observed readiness (0=unknown, 1=ready, 2=not ready), used to emit health events on change
+	readinessState int32
+	// 1 while a goroutine is recovering from a lost worker ID lease, see onWorkerLeaseLost
+	recovering int32
+	// Ensure the registry janitor is started only once
+	registryJanitorOnce sync.Once
+	// Worker and clock lifecycle events for OnEvent handlers
+	events *eventBus
+	// Datacenter name to ID mapping of the registry, read at startup when datacenter_name is used
+	datacenterNames map[string]int64
 	// Mutex for thread safety
 	mu sync.RWMutex
 	// Plugin runtime
@@ -42,11 +58,12 @@ type PlugSnowflake struct {
 	logger log.Logger
 }
 
-// WorkerIDManager manages worker ID registration and heartbeat
-// Uses Redis INCR for lock-free worker ID allocation
+// WorkerIDManager manages worker ID registration and heartbeat.
+// Claims are stored by a WorkerIDAllocator backend (Redis by default).
 type WorkerIDManager struct {
-	redisClient       redis.UniversalClient
+	allocator         WorkerIDAllocator
 	datacenterID      int64
+	datacenterName    string // the name datacenterID was resolved from, see DatacenterResolver
 	keyPrefix         string
 	ttl               time.Duration
 	heartbeatInterval time.Duration
@@ -60,9 +77,22 @@ type WorkerIDManager struct {
 	// Registration info preserved for heartbeat
 	registerTime   time.Time
 	instanceID     string
-	localIP        string // Local IP address for troubleshooting
-	serviceName    string // Application name from lynx (e.g. betday-user)
-	serviceVersion string // Application version from lynx (e.g. v1.0.0)
+	localIP        string       // Local IP address for troubleshooting
+	serviceName    string       // Application name from lynx (e.g. betday-user)
+	serviceVersion string       // Application version from lynx (e.g. v1.0.0)
+	lastTimestamp  func() int64 // Unix ms of the last issued ID, stored with the claim
+	// Worker ID reuse: the previous owner's last issued timestamp (Unix ms, 0 if unknown) and the margin on top
+	previousTimestamp int64
+	reuseSafetyMargin time.Duration
+	// Self-fencing: IDs are refused once the monotonic clock passes leaseDeadline, see CheckLease
+	leaseDeadline     int64 // atomic: monotonicNow() value, 0 when no claim is tracked
+	leaseSafetyMargin time.Duration
+	// Called when re-registration finds the claim lost, see SetLeaseLostHandler
+	onLeaseLost func(workerID int64)
+	// Called when a heartbeat finds the claim revoked, see SetRevokedHandler
+	onRevoked func(revoked *WorkerRevokedError)
+	// Receives lifecycle events, see SetEventHandler
+	onEvent func(Event)
 	// Health state - used to stop ID generation when heartbeat fails
 	healthy int32 // atomic: 1=healthy, 0=unhealthy
 	// Mutex for state management
@@ -91,6 +121,11 @@ type Generator struct {
 	// State
 	lastTimestamp int64
 	sequence      int64
+	// Number of sequence values issued in the current millisecond (overflow detection with non-zero start)
+	sequenceCount int64
+	// Starting offset of each millisecond: zero, random or round_robin
+	sequenceStartMode string
+	sequenceRotation  int64
 
 	// Statistics
 	generatedCount     int64
@@ -99,6 +134,7 @@ type Generator struct {
 	// Clock drift protection
 	enableClockDriftProtection bool
 	maxClockDrift              time.Duration
+	clockCheckInterval         time.Duration // forward drift is checked at most this often
 	clockDriftAction           string
 	lastClockCheck             time.Time
 
@@ -108,23 +144,49 @@ type Generator struct {
 	cacheIndex          int
 	cacheSize           int
 
+	// What to do when the sequence is exhausted within one millisecond
+	sequenceOverflowStrategy string
+
 	// Shutdown state (isShuttingDownAtomic allows lock-free check in retry loop)
 	isShuttingDown       bool
 	isShuttingDownAtomic int32
+	// Generation calls in progress (atomic); Shutdown waits for them to return
+	inFlight int64
+
+	// No ID is issued at or before notBefore (Unix ms); set when a worker ID is reused
+	notBefore int64
+	// No ID is issued while fenced; set when the worker ID lease is lost, cleared when a new worker ID is applied
+	fenced bool
+	// Reports a passed worker ID lease deadline; checked when a slot is claimed, after any wait, see SetLeaseCheck
+	leaseExpired func() (time.Duration, bool)
 
 	// Timestamp bits for ParseID validation (64 - timestampShift)
 	timestampBits int64
 	// Ignore mode: reject if lastTimestamp drifts beyond real time by this much (ms)
 	maxIgnoreBackwardDriftMs int64
 
-	// Metrics collection
-	metrics *Metrics
+	// Lifecycle events, see SetEventHandler. A drift episode lasts from the first wait for the clock until an
+	// ID is issued again; overflows are counted per SequenceOverflowStormWindow.
+	onEvent           func(Event)
+	driftWaitReason   string // empty outside a drift episode
+	driftWaitStart    time.Time
+	clockBackward     bool // the current drift episode was reported as clock.backward
+	overflowWindow    time.Time
+	overflowsInWindow int64
+	overflowStormSent bool
+
+	// Metrics collection; metrics is only replaced while metricsDisabled is 1, so readers
+	// must go through activeMetrics
+	metrics         *Metrics
+	metricsDisabled int32 // atomic
 
 	// Mutex for thread safety
 	mu sync.Mutex
 }
 
 // Metrics holds detailed metrics for the Eon-ID generator.
+// Counters touched for every ID are updated atomically; latency percentiles, rates and the
+// histogram are derived when GetSnapshot is called, so read them from a snapshot.
 type Metrics struct {
 	// ID generation metrics
 	IDsGenerated      int64
@@ -132,11 +194,17 @@ type Metrics struct {
 	WorkerIDConflicts int64
 	SequenceOverflows int64
 
+	// Worker ID recovery after a lost lease: successful recoveries and failed registration attempts
+	WorkerIDRecoveries       int64
+	WorkerIDRecoveryFailures int64
+
 	// Performance metrics
 	GenerationLatency time.Duration
 	AverageLatency    time.Duration
+	P50Latency        time.Duration
 	P95Latency        time.Duration
 	P99Latency        time.Duration
+	P999Latency       time.Duration
 	MaxLatency        time.Duration
 	MinLatency        time.Duration
 
@@ -147,8 +215,13 @@ type Metrics struct {
 	CacheRefills int64
 
 	// Throughput metrics
-	IDGenerationRate   float64 // IDs per second
-	PeakGenerationRate float64 // Peak IDs per second
+	IDGenerationRate   float64 // IDs per second over the last second
+	GenerationRate1m   float64 // IDs per second over the last minute
+	GenerationRate5m   float64 // IDs per second over the last 5 minutes
+	PeakGenerationRate float64 // Highest IDs generated in one complete second
+
+	// Total time callers spent waiting for the next tick after a sequence overflow
+	SequenceOverflowWait time.Duration
 
 	// Error metrics
 	GenerationErrors int64
@@ -169,6 +242,12 @@ type Metrics struct {
 	// Latency histogram for detailed analysis
 	LatencyHistogram map[string]int64 // e.g., "0-1ms": count, "1-5ms": count
 
+	// Hot-path state, lock-free
+	latency             LatencySketch
+	rates               rateWindow
+	lastLatency         int64 // nanoseconds
+	lastGenerationNanos int64 // unix nanoseconds
+
 	mu sync.RWMutex
 }
 
@@ -184,6 +263,83 @@ func (e *ClockDriftError) Error() string {
 		e.CurrentTime, e.LastTimestamp, e.Drift)
 }
 
+// WorkerIDReuseError is returned while a reused worker ID must not issue IDs yet: the previous owner issued
+// IDs up to LastTimestamp, and this generator's clock has not passed NotBefore (LastTimestamp plus the
+// safety margin). The generator waits instead when the clock drift action is "wait" and Wait is short enough.
+type WorkerIDReuseError struct {
+	WorkerID  int64
+	NotBefore time.Time
+	Wait      time.Duration
+}
+
+func (e *WorkerIDReuseError) Error() string {
+	return fmt.Sprintf("worker ID %d was used by its previous owner until %v, refusing to issue IDs for another %v",
+		e.WorkerID, e.NotBefore, e.Wait)
+}
+
+// WorkerIDFencedError is returned while the generator is fenced: its worker ID lease was lost and no new worker
+// ID has been registered yet, so any ID issued now could duplicate one issued by the new owner of WorkerID
+type WorkerIDFencedError struct {
+	WorkerID int64
+}
+
+func (e *WorkerIDFencedError) Error() string {
+	return fmt.Sprintf("worker ID %d lease lost, refusing to issue IDs until a new worker ID is registered", e.WorkerID)
+}
+
+// WorkerLeaseExpiredError is returned by WorkerIDManager.CheckLease once the local lease deadline has passed:
+// no renewal has succeeded for the TTL minus the safety margin, so the claim may already be gone in the backend
+type WorkerLeaseExpiredError struct {
+	WorkerID   int64
+	ExpiredFor time.Duration
+}
+
+func (e *WorkerLeaseExpiredError) Error() string {
+	return fmt.Sprintf("worker ID %d lease deadline passed %v ago without a successful renewal, refusing to issue IDs",
+		e.WorkerID, e.ExpiredFor)
+}
+
+// SequenceOverflowError is returned when every sequence value of a millisecond has been issued
+// and the sequence overflow strategy is "error"
+type SequenceOverflowError struct {
+	Timestamp   time.Time
+	MaxSequence int64
+}
+
+func (e *SequenceOverflowError) Error() string {
+	return fmt.Sprintf("sequence overflow: all %d sequence values used at %v",
+		e.MaxSequence+1, e.Timestamp)
+}
+
+// ConfigChange describes one configuration field that differs between the running and an updated configuration
+type ConfigChange struct {
+	Field  string `json:"field"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Reason string `json:"reason,omitempty"` // set when the change was refused
+}
+
+func (c ConfigChange) String() string {
+	if c.Reason != "" {
+		return fmt.Sprintf("%s: %s -> %s (%s)", c.Field, c.Old, c.New, c.Reason)
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// UnsafeConfigChangeError is returned by UpdateConfiguration when changes cannot be applied to a running plugin.
+// Safe changes from the same update have still been applied.
+type UnsafeConfigChangeError struct {
+	Refused []ConfigChange
+}
+
+func (e *UnsafeConfigChangeError) Error() string {
+	parts := make([]string, 0, len(e.Refused))
+	for _, c := range e.Refused {
+		parts = append(parts, c.String())
+	}
+	return "refused configuration changes: " + strings.Join(parts, "; ")
+}
+
 // WorkerIDConflictError represents a worker ID conflict error
 type WorkerIDConflictError struct {
 	WorkerID     int64
@@ -203,6 +359,10 @@ type SID struct {
 	DatacenterID int64     `json:"datacenter_id"`
 	WorkerID     int64     `json:"worker_id"`
 	Sequence     int64     `json:"sequence"`
+	// UUID is set for IDs that do not fit in 64 bits (e.g. UUIDv7); ID is 0 in that case
+	UUID string `json:"uuid,omitempty"`
+	// ULID is set when the SID was derived from a ULID
+	ULID string `json:"ulid,omitempty"`
 }
 
 // IDComponents represents the components of a snowflake ID
@@ -237,6 +397,19 @@ const (
 	DefaultRedisKeyPrefix    = "lynx:eon-id:"
 	DefaultWorkerIDTTL       = 30 * time.Second
 	DefaultHeartbeatInterval = 10 * time.Second
+	// DefaultWorkerReuseSafetyMargin is added to the previous owner's last timestamp before a reused worker ID
+	// issues IDs
+	DefaultWorkerReuseSafetyMargin = time.Second
+	// DefaultWorkerLeaseSafetyMargin is subtracted from the TTL when computing the local lease deadline
+	DefaultWorkerLeaseSafetyMargin = 2 * time.Second
+	// DefaultWorkerRecoveryMaxAttempts is how often a new worker ID is tried after the lease is lost
+	DefaultWorkerRecoveryMaxAttempts = 10
+	// DefaultWorkerRecoveryBackoff is the wait before the second recovery attempt; it doubles after every failure
+	DefaultWorkerRecoveryBackoff = time.Second
+	// MaxWorkerRecoveryBackoff caps the wait between recovery attempts
+	MaxWorkerRecoveryBackoff = 30 * time.Second
+	// DefaultRegistryJanitorInterval is how often the registry entries of expired claims are removed
+	DefaultRegistryJanitorInterval = time.Minute
 )
 
 const (
@@ -256,11 +429,27 @@ const (
 
 	// MaxClockBackwardWait is the maximum drift we wait for in Wait mode; larger backward returns error
 	MaxClockBackwardWait = 5 * time.Second
+	// EpochExhaustionMargin is the minimum time left before timestamp overflow for the generator to report ready
+	EpochExhaustionMargin = 24 * time.Hour
 
 	// ClockDriftActionWait Clock drift actions
 	ClockDriftActionWait   = "wait"
 	ClockDriftActionError  = "error"
 	ClockDriftActionIgnore = "ignore"
+
+	// SequenceOverflowStrategySpin Sequence overflow strategies
+	SequenceOverflowStrategySpin  = "spin"
+	SequenceOverflowStrategySleep = "sleep"
+	SequenceOverflowStrategyError = "error"
+
+	// WorkerRevokeActionReregister Actions after the worker ID has been revoked
+	WorkerRevokeActionReregister = "reregister"
+	WorkerRevokeActionFence      = "fence"
+
+	// SequenceStartZero Per-millisecond sequence start modes
+	SequenceStartZero       = "zero"
+	SequenceStartRandom     = "random"
+	SequenceStartRoundRobin = "round_robin"
 )
 
 // NewSnowflakePlugin creates a new snowflake plugin instance
@@ -268,6 +457,7 @@ func NewSnowflakePlugin() *PlugSnowflake {
 	return &PlugSnowflake{
 		BasePlugin: plugins.NewBasePlugin(PluginName, PluginName, PluginDescription, PluginVersion, ConfPrefix, 100),
 		shutdownCh: make(chan struct{}),
+		events:     newEventBus(),
 	}
 }
 
@@ -288,13 +478,14 @@ func (p *PlugSnowflake) Weight() int {
 	return 100
 }
 
-// UpdateConfiguration updates the plugin configuration
+// UpdateConfiguration updates the plugin configuration. Before initialization the configuration is
+// simply stored. On a running plugin, clock drift, cache, metrics, overflow and heartbeat/TTL settings are
+// applied live; changes to the epoch, bit widths or datacenter ID (and to settings only read at startup)
+// are refused with *UnsafeConfigChangeError while the safe part of the update still takes effect.
+// A configuration event lists what was applied and what was refused.
 func (p *PlugSnowflake) UpdateConfiguration(config interface{}) error {
 	if conf, ok := config.(*pb.EonId); ok {
-		p.mu.Lock()
-		defer p.mu.Unlock()
-		p.conf = conf
-		return nil
+		return p.reloadConfiguration(conf)
 	}
 	return fmt.Errorf("invalid configuration type for eon-id plugin")
 }
@@ -344,23 +535,27 @@ func (p *PlugSnowflake) InitializeResources(rt plugins.Runtime) error {
 	}
 	p.conf = conf
 
+	var allocator WorkerIDAllocator
 	if conf.AutoRegisterWorkerId {
-		redisPluginName := conf.RedisPluginName
-		if redisPluginName == "" {
-			redisPluginName = "redis"
-		}
-		if redisClient, resolvedName, err := resolveRedisClientResource(rt, redisPluginName); err == nil {
-			p.redisClient = redisClient
-			lynxlog.Infof("successfully connected to Redis plugin resource: %s", resolvedName)
-		} else {
-			lynxlog.Warnf("failed to get Redis client from plugin resource %s: %v, disabling auto worker ID registration", redisPluginName, err)
+		var err error
+		if allocator, err = p.newWorkerIDAllocator(rt, conf); err != nil {
+			lynxlog.Warnf("%v, disabling auto worker ID registration", err)
 			conf.AutoRegisterWorkerId = false
 		}
 	}
+	datacenterID, datacenterName, err := resolveDatacenterID(context.Background(), allocator, conf)
+	if err != nil {
+		return err
+	}
+	var datacenterNames map[string]int64
+	if datacenterName != "" {
+		datacenterNames = registryDatacenters(context.Background(), allocator, datacenterName, datacenterID)
+	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.datacenterNames = datacenterNames
 	keyPrefix := NormalizeKeyPrefix(conf.RedisKeyPrefix)
 	ttl := DefaultWorkerIDTTL
 	if conf.WorkerIdTtl != nil {
@@ -370,6 +565,14 @@ func (p *PlugSnowflake) InitializeResources(rt plugins.Runtime) error {
 	if conf.HeartbeatInterval != nil {
 		heartbeatInterval = conf.HeartbeatInterval.AsDuration()
 	}
+	reuseSafetyMargin := DefaultWorkerReuseSafetyMargin
+	if conf.WorkerReuseSafetyMargin != nil {
+		reuseSafetyMargin = conf.WorkerReuseSafetyMargin.AsDuration()
+	}
+	leaseSafetyMargin := DefaultWorkerLeaseSafetyMargin
+	if conf.WorkerLeaseSafetyMargin != nil {
+		leaseSafetyMargin = conf.WorkerLeaseSafetyMargin.AsDuration()
+	}
 
 	localIP := getLocalIP()
 	if localIP == "" || localIP == "unknown" {
@@ -380,15 +583,18 @@ func (p *PlugSnowflake) InitializeResources(rt plugins.Runtime) error {
 		}
 	}
 	p.workerManager = &WorkerIDManager{
-		redisClient:       p.redisClient,
+		allocator:         allocator,
 		workerID:          int64(conf.WorkerId),
-		datacenterID:      int64(conf.DatacenterId),
+		datacenterID:      datacenterID,
+		datacenterName:    datacenterName,
 		keyPrefix:         keyPrefix,
 		ttl:               ttl,
 		heartbeatInterval: heartbeatInterval,
 		localIP:           localIP,
 		serviceName:       currentLynxName(),
 		serviceVersion:    currentLynxVersion(),
+		reuseSafetyMargin: reuseSafetyMargin,
+		leaseSafetyMargin: leaseSafetyMargin,
 	}
 	if !conf.AutoRegisterWorkerId {
 		atomic.StoreInt32(&p.workerManager.healthy, 1)
@@ -405,6 +611,8 @@ func (p *PlugSnowflake) InitializeResources(rt plugins.Runtime) error {
 		EnableSequenceCache:        conf.EnableSequenceCache,
 		SequenceCacheSize:          int(conf.SequenceCacheSize),
 		EnableMetrics:              conf.EnableMetrics,
+		SequenceOverflowStrategy:   conf.SequenceOverflowStrategy,
+		SequenceStartMode:          conf.SequenceStartMode,
 	}
 	if generatorConfig.CustomEpoch == 0 {
 		generatorConfig.CustomEpoch = DefaultEpoch
@@ -424,12 +632,34 @@ func (p *PlugSnowflake) InitializeResources(rt plugins.Runtime) error {
 	if conf.MaxClockDrift != nil {
 		generatorConfig.MaxClockDrift = conf.MaxClockDrift.AsDuration()
 	}
+	if conf.ClockCheckInterval != nil {
+		generatorConfig.ClockCheckInterval = conf.ClockCheckInterval.AsDuration()
+	}
 
-	var err error
-	p.generator, err = NewSnowflakeGeneratorCore(int64(conf.DatacenterId), int64(conf.WorkerId), generatorConfig)
+	p.generator, err = NewSnowflakeGeneratorCore(datacenterID, int64(conf.WorkerId), generatorConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create eon-id generator: %w", err)
 	}
+	generator := p.generator
+	p.workerManager.SetLastTimestampSource(func() int64 { return generator.GetStats().LastGeneratedTime })
+	p.workerManager.SetLeaseLostHandler(p.onWorkerLeaseLost)
+	p.workerManager.SetRevokedHandler(p.onWorkerRevoked)
+	p.workerManager.SetEventHandler(p.events.publish)
+	generator.SetLeaseCheck(p.workerManager.LeaseExpired)
+	generator.SetEventHandler(p.events.publish)
+
+	entropy, err := NewULIDEntropy(conf.UlidEntropySource)
+	if err != nil {
+		return fmt.Errorf("failed to create ULID entropy source: %w", err)
+	}
+	p.ulidGenerator, err = NewULIDGenerator(&ULIDGeneratorConfig{
+		Entropy:          entropy,
+		ClockDriftAction: generatorConfig.ClockDriftAction,
+		EnableMetrics:    conf.EnableMetrics,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ULID generator: %w", err)
+	}
 	return nil
 }
 
@@ -448,13 +678,104 @@ func (p *PlugSnowflake) CleanupTasks() error {
 	return p.cleanupTasksContext(context.Background())
 }
 
-// CheckHealth checks plugin health
-func (p *PlugSnowflake) CheckHealth() error {
+// ProbeResult is the outcome of a liveness or readiness probe
+type ProbeResult struct {
+	OK      bool     `json:"ok"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func newProbeResult(reasons []string) ProbeResult {
+	return ProbeResult{OK: len(reasons) == 0, Reasons: reasons}
+}
+
+// Liveness reports whether the plugin is running: the generator exists and has not been shut down.
+// A failed liveness probe means the instance should be restarted.
+func (p *PlugSnowflake) Liveness() ProbeResult {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
+	generator := p.generator
+	p.mu.RUnlock()
+
+	return newProbeResult(livenessReasons(generator))
+}
+
+// Readiness reports whether the plugin can safely issue IDs right now. Besides liveness it requires a
+// registered worker ID with a working heartbeat (when auto-registration is used), a clock that is not
+// behind the last issued timestamp and enough epoch left. A failed readiness probe means traffic
+// should be routed elsewhere until it recovers.
+func (p *PlugSnowflake) Readiness() ProbeResult {
+	p.mu.RLock()
+	generator := p.generator
+	workerManager := p.workerManager
+	p.mu.RUnlock()
+
+	readiness := newProbeResult(readinessReasons(generator, workerManager))
+	p.observeReadiness(readiness)
+	return readiness
+}
+
+// observeReadiness emits a Lynx health event when readiness changes, so the framework's
+// health system learns about a node that stopped (or resumed) being able to issue IDs
+func (p *PlugSnowflake) observeReadiness(readiness ProbeResult) {
+	state := int32(1)
+	if !readiness.OK {
+		state = 2
+	}
+	prev := atomic.SwapInt32(&p.readinessState, state)
+	if prev == state || (prev == 0 && readiness.OK) {
+		return
+	}
+
+	if readiness.OK {
+		p.emitRuntimeEvent(plugins.PluginEvent{
+			Type:     plugins.EventHealthStatusOK,
+			Priority: plugins.PriorityNormal,
+			Source:   "Readiness",
+			Category: "health",
+		})
+		return
+	}
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     plugins.EventHealthStatusCritical,
+		Priority: plugins.PriorityHigh,
+		Source:   "Readiness",
+		Category: "health",
+		Metadata: map[string]any{"reasons": readiness.Reasons},
+	})
+}
+
+func livenessReasons(generator *Generator) []string {
+	if generator == nil {
+		return []string{"generator not initialized"}
+	}
+	if !generator.IsAlive() {
+		return []string{"generator is shut down"}
+	}
+	return nil
+}
+
+func readinessReasons(generator *Generator, workerManager *WorkerIDManager) []string {
+	reasons := livenessReasons(generator)
+	if len(reasons) > 0 {
+		return reasons
+	}
+	// A fixed worker_id without auto-registration has no allocator, holds no claim and has nothing to renew
+	if workerManager != nil && workerManager.Allocator() != nil {
+		if !workerManager.IsRegistered() {
+			reasons = append(reasons, "worker ID not registered")
+		} else if !workerManager.IsHealthy() {
+			reasons = append(reasons, "worker ID heartbeat failing")
+		} else if err := workerManager.CheckLease(); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+	}
+	return append(reasons, generator.NotReadyReasons()...)
+}
 
-	if p.generator == nil {
-		return fmt.Errorf("eon-id generator not initialized")
+// CheckHealth checks plugin health; it fails whenever the plugin is not ready to issue IDs
+func (p *PlugSnowflake) CheckHealth() error {
+	readiness := p.Readiness()
+	if !readiness.OK {
+		return fmt.Errorf("eon-id not ready: %s", strings.Join(readiness.Reasons, "; "))
 	}
 
 	return nil
@@ -471,42 +792,26 @@ func (p *PlugSnowflake) GetHealth() plugins.HealthReport {
 	status := "healthy"
 	details := make(map[string]any)
 	message := "Eon-ID generator is operating normally"
-
-	if generator == nil {
-		status = "unhealthy"
-		message = "Eon-ID generator not initialized"
-		details["generator_status"] = "not_initialized"
-	} else {
-		details["generator_status"] = "initialized"
-		details["worker_id"] = generator.workerID
-		details["datacenter_id"] = generator.datacenterID
-		details["custom_epoch"] = generator.customEpoch
-		details["generated_count"] = atomic.LoadInt64(&generator.generatedCount)
-		details["clock_backward_count"] = atomic.LoadInt64(&generator.clockBackwardCount)
-		details["is_shutting_down"] = generator.isShuttingDown
-		if atomic.LoadInt64(&generator.clockBackwardCount) > 0 {
-			status = "degraded"
-			message = "Clock backward events detected"
+	details["events_dropped"] = p.events.Dropped()
+	if len(p.datacenterNames) > 0 {
+		datacenterNames := make(map[string]int64, len(p.datacenterNames))
+		for name, id := range p.datacenterNames {
+			datacenterNames[name] = id
 		}
+		details["datacenter_names"] = datacenterNames
 	}
-	if workerManager != nil {
-		details["worker_manager_status"] = "active"
-		details["worker_manager_worker_id"] = workerManager.workerID
-		details["worker_manager_datacenter_id"] = workerManager.datacenterID
-		details["worker_manager_key_prefix"] = workerManager.keyPrefix
-		details["worker_manager_ttl"] = workerManager.ttl.String()
-		details["worker_manager_heartbeat_interval"] = workerManager.heartbeatInterval.String()
-	} else {
-		details["worker_manager_status"] = "not_configured"
-	}
+
 	if conf != nil {
 		details["configuration"] = map[string]any{
 			"datacenter_id":           conf.DatacenterId,
+			"datacenter_name":         conf.DatacenterName,
+			"datacenter_name_env":     conf.DatacenterNameEnv,
 			"worker_id":               conf.WorkerId,
 			"custom_epoch":            conf.CustomEpoch,
 			"auto_register_worker_id": conf.AutoRegisterWorkerId,
 			"redis_plugin_name":       conf.RedisPluginName,
 			"redis_key_prefix":        conf.RedisKeyPrefix,
+			"worker_id_allocator":     conf.WorkerIdAllocator,
 			"worker_id_ttl":           conf.WorkerIdTtl,
 			"heartbeat_interval":      conf.HeartbeatInterval,
 			"enable_metrics":          conf.EnableMetrics,
@@ -523,6 +828,55 @@ func (p *PlugSnowflake) GetHealth() plugins.HealthReport {
 	}
 	p.mu.RUnlock()
 
+	// Worker ID recovery rewrites the worker ID under the generator and worker manager locks, so read
+	// through their accessors, after releasing p.mu like the probes below
+	if generator == nil {
+		status = "unhealthy"
+		message = "Eon-ID generator not initialized"
+		details["generator_status"] = "not_initialized"
+	} else {
+		stats := generator.GetStats()
+		details["generator_status"] = "initialized"
+		details["worker_id"] = stats.WorkerID
+		details["datacenter_id"] = stats.DatacenterID
+		details["custom_epoch"] = generator.customEpoch
+		details["generated_count"] = stats.GeneratedCount
+		details["clock_backward_count"] = stats.ClockBackwardCount
+		details["is_shutting_down"] = !generator.IsAlive()
+		if stats.ClockBackwardCount > 0 {
+			status = "degraded"
+			message = "Clock backward events detected"
+		}
+	}
+	if workerManager != nil {
+		details["worker_manager_status"] = "active"
+		details["worker_manager_worker_id"] = workerManager.GetWorkerID()
+		datacenterID := workerManager.DatacenterID()
+		details["worker_manager_datacenter_id"] = datacenterID
+		if name := workerManager.DatacenterName(); name != "" {
+			details["datacenter_name"] = name
+		}
+		details["worker_manager_key_prefix"] = workerManager.keyPrefix
+		if workerManager.allocator != nil {
+			details["worker_manager_allocator"] = workerManager.allocator.Name()
+		}
+		ttl, heartbeatInterval := workerManager.Timing()
+		details["worker_manager_ttl"] = ttl.String()
+		details["worker_manager_heartbeat_interval"] = heartbeatInterval.String()
+	} else {
+		details["worker_manager_status"] = "not_configured"
+	}
+	liveness := newProbeResult(livenessReasons(generator))
+	readiness := newProbeResult(readinessReasons(generator, workerManager))
+	p.observeReadiness(readiness)
+	details["liveness"] = liveness
+	details["readiness"] = readiness
+	if generator != nil {
+		if remaining, ok := generator.EpochRemaining(); ok {
+			details["epoch_remaining"] = remaining.Truncate(time.Second).String()
+		}
+	}
+
 	// Run Redis Ping outside lock to avoid blocking other callers.
 	if redisClient != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -551,18 +905,25 @@ func (p *PlugSnowflake) GetHealth() plugins.HealthReport {
 				lastGenStr = snap.LastGenerationTime.Format(time.RFC3339)
 			}
 			details["metrics"] = map[string]any{
-				"ids_generated":        snap.IDsGenerated,
-				"clock_drift_events":   snap.ClockDriftEvents,
-				"worker_id_conflicts":  snap.WorkerIDConflicts,
-				"sequence_overflows":   snap.SequenceOverflows,
-				"generation_errors":    snap.GenerationErrors,
-				"redis_errors":         snap.RedisErrors,
-				"timeout_errors":       snap.TimeoutErrors,
-				"validation_errors":    snap.ValidationErrors,
-				"id_generation_rate":   snap.IDGenerationRate,
-				"peak_generation_rate": snap.PeakGenerationRate,
-				"uptime_duration":      snap.UptimeDuration.String(),
-				"last_generation_time": lastGenStr,
+				"ids_generated":               snap.IDsGenerated,
+				"clock_drift_events":          snap.ClockDriftEvents,
+				"worker_id_conflicts":         snap.WorkerIDConflicts,
+				"sequence_overflows":          snap.SequenceOverflows,
+				"worker_id_recoveries":        snap.WorkerIDRecoveries,
+				"worker_id_recovery_failures": snap.WorkerIDRecoveryFailures,
+				"sequence_overflow_wait":      snap.SequenceOverflowWait.String(),
+				"generation_errors":           snap.GenerationErrors,
+				"redis_errors":                snap.RedisErrors,
+				"timeout_errors":              snap.TimeoutErrors,
+				"validation_errors":           snap.ValidationErrors,
+				"id_generation_rate":          snap.IDGenerationRate,
+				"generation_rate_1m":          snap.GenerationRate1m,
+				"generation_rate_5m":          snap.GenerationRate5m,
+				"peak_generation_rate":        snap.PeakGenerationRate,
+				"p50_latency":                 snap.P50Latency.String(),
+				"p99_latency":                 snap.P99Latency.String(),
+				"uptime_duration":             snap.UptimeDuration.String(),
+				"last_generation_time":        lastGenStr,
 			}
 			totalOperations := snap.IDsGenerated + snap.GenerationErrors
 			if totalOperations > 0 {
@@ -576,6 +937,15 @@ func (p *PlugSnowflake) GetHealth() plugins.HealthReport {
 		}
 	}
 
+	// A node that cannot safely issue IDs must not receive traffic, whatever else is healthy
+	if !liveness.OK {
+		status = "unhealthy"
+		message = "Eon-ID not live: " + strings.Join(liveness.Reasons, "; ")
+	} else if !readiness.OK {
+		status = "unhealthy"
+		message = "Eon-ID not ready: " + strings.Join(readiness.Reasons, "; ")
+	}
+
 	return plugins.HealthReport{
 		Status:    status,
 		Details:   details,
@@ -591,21 +961,38 @@ func (p *PlugSnowflake) GetHealth() plugins.HealthReport {
 const RedisPluginName = "redis.client"
 const RedisLegacyResourceName = "redis"
 
-// GetDependencies returns plugin dependencies so eon-id loads after redis and can use GetSharedResource("redis").
-// When conf is nil we still declare the dependency so load order is correct; when conf has AutoRegisterWorkerId we require redis.
+// GetDependencies returns plugin dependencies so eon-id loads after the plugin that provides the client of the
+// worker_id_allocator backend. When conf is nil we still declare Redis, the default backend, so load order is
+// correct; memory, file and statefulset need no other plugin, and nothing is required without auto-registration.
 func (p *PlugSnowflake) GetDependencies() []plugins.Dependency {
-	var deps []plugins.Dependency
-	needRedis := p.conf == nil || p.conf.AutoRegisterWorkerId
-	if !needRedis {
-		return deps
+	if p.conf != nil && !p.conf.AutoRegisterWorkerId {
+		return nil
 	}
-	deps = append(deps, plugins.Dependency{
-		Name:        RedisPluginName,
+	conf := p.conf
+	if conf == nil {
+		conf = &pb.EonId{}
+	}
+	var name, description string
+	switch stringOr(conf.WorkerIdAllocator, WorkerIDAllocatorRedis) {
+	case WorkerIDAllocatorRedis:
+		name, description = RedisPluginName, "Redis client for worker ID management"
+	case WorkerIDAllocatorEtcd:
+		name, description = stringOr(conf.EtcdPluginName, DefaultEtcdPluginName), "etcd client for worker ID management"
+	case WorkerIDAllocatorZooKeeper:
+		name, description = stringOr(conf.ZookeeperPluginName, DefaultZooKeeperPluginName), "ZooKeeper connection for worker ID management"
+	case WorkerIDAllocatorKubernetesLease:
+		name, description = stringOr(conf.KubernetesPluginName, DefaultKubernetesPluginName), "Kubernetes client for worker ID Leases"
+	case WorkerIDAllocatorSQL:
+		name, description = stringOr(conf.SqlPluginName, DefaultSQLPluginName), "database for worker ID management"
+	default:
+		return nil
+	}
+	return []plugins.Dependency{{
+		Name:        name,
 		Type:        plugins.DependencyTypeRequired,
 		Required:    true,
-		Description: "Redis client for worker ID management",
-	})
-	return deps
+		Description: description,
+	}}
 }
 
 func resolveRedisClientResource(rt plugins.Runtime, preferredName string) (redis.UniversalClient, string, error) {
@@ -668,16 +1055,28 @@ func (p *PlugSnowflake) GenerateID() (int64, error) {
 		return 0, fmt.Errorf("eon-id generator not initialized")
 	}
 
-	// Check worker manager health to prevent ID duplication
-	// when heartbeat fails and worker ID may have been taken by another instance
-	if workerManager != nil && !workerManager.IsHealthy() {
-		return 0, fmt.Errorf("worker ID registration unhealthy, cannot generate ID safely")
+	// Check the worker ID claim to prevent ID duplication when heartbeats fail or stall
+	// and the worker ID may have been taken by another instance
+	if err := workerIssueError(workerManager); err != nil {
+		return 0, err
 	}
 
 	// Generator.GenerateID() has its own mutex protection
 	return generator.GenerateID()
 }
 
+// workerIssueError returns why no ID may be issued under the worker ID right now, nil if IDs may be issued.
+// The lease deadline is checked on every call, so a hung or starved heartbeat cannot keep an expired claim alive.
+func workerIssueError(workerManager *WorkerIDManager) error {
+	if workerManager == nil {
+		return nil
+	}
+	if !workerManager.IsHealthy() {
+		return fmt.Errorf("worker ID registration unhealthy, cannot generate ID safely")
+	}
+	return workerManager.CheckLease()
+}
+
 // GenerateIDWithMetadata generates a new snowflake ID with metadata
 func (p *PlugSnowflake) GenerateIDWithMetadata() (int64, *SID, error) {
 	// Quick nil check with read lock
@@ -690,15 +1089,48 @@ func (p *PlugSnowflake) GenerateIDWithMetadata() (int64, *SID, error) {
 		return 0, nil, fmt.Errorf("snowflake generator not initialized")
 	}
 
-	// Check worker manager health to prevent ID duplication
-	if workerManager != nil && !workerManager.IsHealthy() {
-		return 0, nil, fmt.Errorf("worker ID registration unhealthy, cannot generate ID safely")
+	// Check the worker ID claim to prevent ID duplication
+	if err := workerIssueError(workerManager); err != nil {
+		return 0, nil, err
 	}
 
 	// Generator methods have their own mutex protection
 	return generator.GenerateIDWithMetadata()
 }
 
+// GenerateUUIDv7 generates an RFC 9562 UUIDv7 carrying this instance's datacenter and worker IDs
+func (p *PlugSnowflake) GenerateUUIDv7() (uuid.UUID, error) {
+	// Quick nil check with read lock
+	p.mu.RLock()
+	generator := p.generator
+	workerManager := p.workerManager
+	p.mu.RUnlock()
+
+	if generator == nil {
+		return uuid.Nil, fmt.Errorf("eon-id generator not initialized")
+	}
+
+	// UUIDs embed the worker ID, so they need the same registration guarantee as snowflake IDs
+	if err := workerIssueError(workerManager); err != nil {
+		return uuid.Nil, err
+	}
+
+	return generator.GenerateUUIDv7()
+}
+
+// GenerateULID generates a ULID that is monotonic within this process
+func (p *PlugSnowflake) GenerateULID() (ULID, error) {
+	p.mu.RLock()
+	ulidGenerator := p.ulidGenerator
+	p.mu.RUnlock()
+
+	if ulidGenerator == nil {
+		return ULID{}, fmt.Errorf("ULID generator not initialized")
+	}
+
+	return ulidGenerator.Generate()
+}
+
 func currentLynxApp() *lynx.LynxApp {
 	return lynx.Lynx()
 }
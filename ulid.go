@@ -0,0 +1,351 @@
+package eonId
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ULID is a 128-bit Universally Unique Lexicographically Sortable Identifier:
+// a 48-bit Unix millisecond timestamp followed by 80 bits of entropy, encoded as 26 Crockford base32 characters.
+type ULID [16]byte
+
+// ULID entropy sources accepted by the ulid_entropy_source configuration
+const (
+	ULIDEntropyCrypto = "crypto" // crypto/rand (default)
+	ULIDEntropyFast   = "fast"   // math/rand/v2 ChaCha8, not suitable when ULIDs must be unguessable
+)
+
+const (
+	ulidEncodedLen   = 26
+	ulidMaxTimestamp = int64(1)<<48 - 1
+	crockfordAlpha   = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// crockfordDecode maps an ASCII byte to its 5-bit value, 0xFF for invalid characters (case-insensitive)
+var crockfordDecode = func() [256]byte {
+	var table [256]byte
+	for i := range table {
+		table[i] = 0xFF
+	}
+	for i := 0; i < len(crockfordAlpha); i++ {
+		c := crockfordAlpha[i]
+		table[c] = byte(i)
+		if c >= 'A' && c <= 'Z' {
+			table[c+'a'-'A'] = byte(i)
+		}
+	}
+	return table
+}()
+
+// String returns the 26-character Crockford base32 form
+func (u ULID) String() string {
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	var dst [ulidEncodedLen]byte
+	for i := ulidEncodedLen - 1; i >= 0; i-- {
+		dst[i] = crockfordAlpha[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(dst[:])
+}
+
+// Time returns the timestamp component
+func (u ULID) Time() time.Time {
+	return time.UnixMilli(u.timestamp())
+}
+
+func (u ULID) timestamp() int64 {
+	return int64(binary.BigEndian.Uint64(u[:8]) >> 16)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u ULID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(text []byte) error {
+	parsed, err := ParseULID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// ParseULID decodes a 26-character Crockford base32 ULID (case-insensitive)
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != ulidEncodedLen {
+		return u, fmt.Errorf("invalid ULID length: %d", len(s))
+	}
+	// 26 * 5 = 130 bits; the first character may only carry 3 bits
+	if crockfordDecode[s[0]] > 7 {
+		return u, fmt.Errorf("invalid ULID %q: value overflows 128 bits", s)
+	}
+	var hi, lo uint64
+	for i := 0; i < ulidEncodedLen; i++ {
+		v := crockfordDecode[s[i]]
+		if v == 0xFF {
+			return u, fmt.Errorf("invalid ULID %q: bad character %q", s, s[i])
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(u[:8], hi)
+	binary.BigEndian.PutUint64(u[8:], lo)
+	return u, nil
+}
+
+// ULIDGeneratorConfig holds configuration for the ULID generator
+type ULIDGeneratorConfig struct {
+	// Entropy is read for the random part of each new millisecond; nil means crypto/rand
+	Entropy          io.Reader
+	ClockDriftAction string
+	EnableMetrics    bool
+}
+
+// DefaultULIDGeneratorConfig returns default ULID generator configuration
+func DefaultULIDGeneratorConfig() *ULIDGeneratorConfig {
+	return &ULIDGeneratorConfig{
+		Entropy:          crand.Reader,
+		ClockDriftAction: ClockDriftActionWait,
+		EnableMetrics:    true,
+	}
+}
+
+// NewULIDEntropy returns the entropy reader for a configured source name
+func NewULIDEntropy(source string) (io.Reader, error) {
+	switch source {
+	case "", ULIDEntropyCrypto:
+		return crand.Reader, nil
+	case ULIDEntropyFast:
+		var seed [32]byte
+		if _, err := crand.Read(seed[:]); err != nil {
+			return nil, fmt.Errorf("failed to seed fast ULID entropy: %w", err)
+		}
+		return rand.NewChaCha8(seed), nil
+	default:
+		return nil, fmt.Errorf("invalid ULID entropy source: %s (valid: crypto, fast)", source)
+	}
+}
+
+// ULIDGenerator produces ULIDs that are strictly monotonic within this process.
+// Within one millisecond the entropy is incremented instead of re-drawn; when the clock goes backward
+// the same clock_drift_action policies as Generator apply.
+type ULIDGenerator struct {
+	entropy          io.Reader
+	clockDriftAction string
+
+	// State
+	lastTimestamp int64
+	lastEntropy   [10]byte
+
+	// Statistics
+	generatedCount     int64
+	clockBackwardCount int64
+
+	// Ignore mode: reject if lastTimestamp drifts beyond real time by this much (ms)
+	maxIgnoreBackwardDriftMs int64
+
+	// metrics is only replaced while metricsDisabled is 1, so readers must go through activeMetrics
+	metrics         *Metrics
+	metricsDisabled int32 // atomic
+
+	// Mutex for thread safety (also guards entropy, which need not be safe for concurrent use)
+	mu sync.Mutex
+}
+
+// NewULIDGenerator creates a ULID generator
+func NewULIDGenerator(config *ULIDGeneratorConfig) (*ULIDGenerator, error) {
+	if config == nil {
+		config = DefaultULIDGeneratorConfig()
+	}
+	action := config.ClockDriftAction
+	if action == "" {
+		action = ClockDriftActionWait
+	}
+	switch action {
+	case ClockDriftActionWait, ClockDriftActionError, ClockDriftActionIgnore:
+	default:
+		return nil, fmt.Errorf("invalid clock drift action: %s", action)
+	}
+	entropy := config.Entropy
+	if entropy == nil {
+		entropy = crand.Reader
+	}
+
+	g := &ULIDGenerator{
+		entropy:                  entropy,
+		clockDriftAction:         action,
+		lastTimestamp:            -1,
+		maxIgnoreBackwardDriftMs: 3600000, // 1 hour, same bound as Generator
+	}
+	if config.EnableMetrics {
+		g.metrics = NewSnowflakeMetrics()
+	} else {
+		g.metricsDisabled = 1
+	}
+	return g, nil
+}
+
+// Generate returns the next ULID
+func (g *ULIDGenerator) Generate() (ULID, error) {
+	startTime := time.Now()
+	maxRetries := 10
+
+	for retry := 0; retry < maxRetries; retry++ {
+		u, needWait, waitDuration, err := g.tryGenerate()
+		if err != nil {
+			if m := g.activeMetrics(); m != nil {
+				m.RecordError("generation")
+			}
+			return ULID{}, err
+		}
+		if !needWait {
+			if m := g.activeMetrics(); m != nil {
+				m.RecordIDGeneration(time.Since(startTime), false)
+			}
+			return u, nil
+		}
+		time.Sleep(waitDuration)
+	}
+
+	if m := g.activeMetrics(); m != nil {
+		m.RecordError("generation")
+	}
+	return ULID{}, fmt.Errorf("failed to generate ULID after %d retries", maxRetries)
+}
+
+// tryGenerate attempts to generate a ULID, returns (ulid, needWait, waitDuration, error)
+func (g *ULIDGenerator) tryGenerate() (ULID, bool, time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	timestamp := time.Now().UnixMilli()
+	if timestamp > ulidMaxTimestamp {
+		return ULID{}, false, 0, fmt.Errorf("timestamp %d does not fit in 48 bits", timestamp)
+	}
+
+	if timestamp < g.lastTimestamp {
+		driftMs := g.lastTimestamp - timestamp
+		drift := time.Duration(driftMs) * time.Millisecond
+		atomic.AddInt64(&g.clockBackwardCount, 1)
+		if m := g.activeMetrics(); m != nil {
+			m.RecordClockDrift()
+		}
+		driftErr := &ClockDriftError{
+			CurrentTime:   time.UnixMilli(timestamp),
+			LastTimestamp: time.UnixMilli(g.lastTimestamp),
+			Drift:         drift,
+		}
+
+		switch g.clockDriftAction {
+		case ClockDriftActionError:
+			return ULID{}, false, 0, driftErr
+		case ClockDriftActionWait:
+			if drift > MaxClockBackwardWait {
+				return ULID{}, false, 0, driftErr
+			}
+			return ULID{}, true, drift + time.Millisecond, nil
+		case ClockDriftActionIgnore:
+			if driftMs > g.maxIgnoreBackwardDriftMs {
+				return ULID{}, false, 0, driftErr
+			}
+			// Stay on the last millisecond and keep incrementing, which preserves ordering
+			timestamp = g.lastTimestamp
+		default:
+			return ULID{}, false, 0, fmt.Errorf("unknown clock drift action: %s", g.clockDriftAction)
+		}
+	}
+
+	if timestamp == g.lastTimestamp {
+		if !incrementEntropy(&g.lastEntropy) {
+			// 80-bit entropy exhausted within one millisecond - wait for the next tick
+			if m := g.activeMetrics(); m != nil {
+				m.RecordSequenceOverflow()
+			}
+			return ULID{}, true, time.Millisecond, nil
+		}
+	} else {
+		if _, err := io.ReadFull(g.entropy, g.lastEntropy[:]); err != nil {
+			return ULID{}, false, 0, fmt.Errorf("failed to read ULID entropy: %w", err)
+		}
+		g.lastTimestamp = timestamp
+	}
+
+	var u ULID
+	binary.BigEndian.PutUint64(u[:8], uint64(timestamp)<<16)
+	copy(u[6:], g.lastEntropy[:])
+	atomic.AddInt64(&g.generatedCount, 1)
+	return u, false, 0, nil
+}
+
+// incrementEntropy adds one to the 80-bit big-endian entropy; returns false on overflow
+func incrementEntropy(e *[10]byte) bool {
+	for i := len(e) - 1; i >= 0; i-- {
+		e[i]++
+		if e[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMetrics returns detailed metrics about the ULID generator
+func (g *ULIDGenerator) GetMetrics() *Metrics {
+	m := g.activeMetrics()
+	if m == nil {
+		return nil
+	}
+	return m.GetSnapshot()
+}
+
+// GetStats returns statistics about the ULID generator
+func (g *ULIDGenerator) GetStats() *GeneratorStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return &GeneratorStats{
+		GeneratedCount:     atomic.LoadInt64(&g.generatedCount),
+		ClockBackwardCount: atomic.LoadInt64(&g.clockBackwardCount),
+		LastGeneratedTime:  g.lastTimestamp,
+	}
+}
+
+// ToULID converts a snowflake ID into a ULID with the same millisecond timestamp.
+// The 64-bit ID is stored in the low bits of the entropy (high 16 entropy bits are zero),
+// so the conversion is lossless and ULIDToSID can recover every SID field.
+func (g *Generator) ToULID(id int64) (ULID, error) {
+	sid, err := g.ParseID(id)
+	if err != nil {
+		return ULID{}, err
+	}
+	var u ULID
+	binary.BigEndian.PutUint64(u[:8], uint64(sid.Timestamp.UnixMilli())<<16)
+	binary.BigEndian.PutUint64(u[8:], uint64(id))
+	return u, nil
+}
+
+// ULIDToSID returns the SID view of a ULID. ULIDs created by ToULID map back to the full snowflake
+// fields; for any other ULID the layout carries no node identity, so only Timestamp and ULID are set.
+func (g *Generator) ULIDToSID(u ULID) *SID {
+	if binary.BigEndian.Uint16(u[6:8]) == 0 {
+		id := int64(binary.BigEndian.Uint64(u[8:]))
+		if sid, err := g.ParseID(id); err == nil && sid.Timestamp.UnixMilli() == u.timestamp() {
+			sid.ULID = u.String()
+			return sid
+		}
+	}
+	return &SID{
+		ULID:      u.String(),
+		Timestamp: u.Time(),
+	}
+}
@@ -0,0 +1,134 @@
+package eonId
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestULID_EncodeDecodeRoundTrip(t *testing.T) {
+	// Reference value from the ULID spec
+	const s = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	u, err := ParseULID(s)
+	require.NoError(t, err)
+	assert.Equal(t, s, u.String())
+	assert.Equal(t, int64(1469922850259), u.Time().UnixMilli())
+
+	lower, err := ParseULID(strings.ToLower(s))
+	require.NoError(t, err)
+	assert.Equal(t, u, lower)
+
+	text, err := u.MarshalText()
+	require.NoError(t, err)
+	var decoded ULID
+	require.NoError(t, decoded.UnmarshalText(text))
+	assert.Equal(t, u, decoded)
+}
+
+func TestParseULID_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",   // too short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAVX", // too long
+		"81ARZ3NDEKTSV4RRFFQ69G5FAV",  // overflows 128 bits
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU",  // U is not in the Crockford alphabet
+	} {
+		_, err := ParseULID(s)
+		assert.Error(t, err, "ParseULID(%q) should fail", s)
+	}
+}
+
+func TestULIDGenerator_MonotonicWithinProcess(t *testing.T) {
+	g, err := NewULIDGenerator(&ULIDGeneratorConfig{EnableMetrics: false})
+	require.NoError(t, err)
+
+	const count = 20000
+	prev, err := g.Generate()
+	require.NoError(t, err)
+	for i := 1; i < count; i++ {
+		u, err := g.Generate()
+		require.NoError(t, err)
+		require.Equal(t, 1, bytes.Compare(u[:], prev[:]), "ULID %s not after %s", u, prev)
+		require.Less(t, prev.String(), u.String())
+		prev = u
+	}
+}
+
+func TestULIDGenerator_ConfigurableEntropy(t *testing.T) {
+	// All-zero entropy makes consecutive ULIDs in one millisecond differ only in the last byte
+	g, err := NewULIDGenerator(&ULIDGeneratorConfig{Entropy: bytes.NewReader(make([]byte, 1<<16))})
+	require.NoError(t, err)
+	u, err := g.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 9), u[6:15])
+
+	_, err = NewULIDEntropy("bogus")
+	assert.Error(t, err)
+	fast, err := NewULIDEntropy(ULIDEntropyFast)
+	require.NoError(t, err)
+	g, err = NewULIDGenerator(&ULIDGeneratorConfig{Entropy: fast})
+	require.NoError(t, err)
+	_, err = g.Generate()
+	assert.NoError(t, err)
+}
+
+func TestULIDGenerator_ClockBackwardPolicies(t *testing.T) {
+	future := time.Now().Add(time.Minute).UnixMilli()
+
+	g, err := NewULIDGenerator(&ULIDGeneratorConfig{ClockDriftAction: ClockDriftActionError})
+	require.NoError(t, err)
+	g.lastTimestamp = future
+	_, err = g.Generate()
+	var driftErr *ClockDriftError
+	assert.ErrorAs(t, err, &driftErr)
+
+	// Drift beyond MaxClockBackwardWait is not waited out
+	g, err = NewULIDGenerator(&ULIDGeneratorConfig{ClockDriftAction: ClockDriftActionWait})
+	require.NoError(t, err)
+	g.lastTimestamp = future
+	_, err = g.Generate()
+	assert.ErrorAs(t, err, &driftErr)
+
+	g, err = NewULIDGenerator(&ULIDGeneratorConfig{ClockDriftAction: ClockDriftActionIgnore})
+	require.NoError(t, err)
+	first, err := g.Generate()
+	require.NoError(t, err)
+	g.lastTimestamp = future
+	u, err := g.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, future, u.Time().UnixMilli())
+	assert.Equal(t, 1, bytes.Compare(u[:], first[:]))
+	assert.Equal(t, int64(1), g.GetStats().ClockBackwardCount)
+}
+
+func TestGenerator_ULIDConversion(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.EnableMetrics = false
+	g, err := NewSnowflakeGeneratorCore(2, 9, cfg)
+	require.NoError(t, err)
+
+	id, err := g.GenerateID()
+	require.NoError(t, err)
+	u, err := g.ToULID(id)
+	require.NoError(t, err)
+
+	sid := g.ULIDToSID(u)
+	assert.Equal(t, id, sid.ID)
+	assert.Equal(t, int64(2), sid.DatacenterID)
+	assert.Equal(t, int64(9), sid.WorkerID)
+	assert.Equal(t, u.String(), sid.ULID)
+	assert.Equal(t, u.Time(), sid.Timestamp)
+
+	// A random ULID only carries a timestamp
+	ug, err := NewULIDGenerator(nil)
+	require.NoError(t, err)
+	random, err := ug.Generate()
+	require.NoError(t, err)
+	sid = g.ULIDToSID(random)
+	assert.Zero(t, sid.ID)
+	assert.Equal(t, random.Time(), sid.Timestamp)
+}
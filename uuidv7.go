@@ -0,0 +1,101 @@
+package eonId
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UUIDv7 layout (RFC 9562 section 5.7). The 74 "random" bits carry the same node identity and
+// per-millisecond sequence as the 64-bit ID, so uniqueness is guaranteed by worker registration
+// rather than by probability; only the trailing bits are random.
+//
+//	bits 0-47    unix_ts_ms
+//	bits 48-51   version (0b0111)
+//	bits 52-63   rand_a: sequence, low 12 bits
+//	bits 64-65   variant (0b10)
+//	bits 66-73   sequence, high 8 bits
+//	bits 74-83   datacenter ID (10 bits)
+//	bits 84-103  worker ID (20 bits)
+//	bits 104-127 random (24 bits)
+const (
+	uuidV7SequenceLowBits  = 12
+	uuidV7SequenceHighBits = 8
+	uuidV7DatacenterBits   = 10
+	uuidV7WorkerBits       = 20
+	uuidV7RandomBits       = 24
+
+	uuidV7SequenceHighShift = uuidV7DatacenterBits + uuidV7WorkerBits + uuidV7RandomBits
+	uuidV7DatacenterShift   = uuidV7WorkerBits + uuidV7RandomBits
+	uuidV7WorkerShift       = uuidV7RandomBits
+
+	uuidV7MaxTimestamp = int64(1)<<48 - 1
+)
+
+// GenerateUUIDv7 generates an RFC 9562 version 7 UUID from the next generator slot.
+// It shares the sequence, clock drift handling and worker ID with GenerateID, so a UUID and a
+// snowflake ID are never derived from the same slot.
+func (g *Generator) GenerateUUIDv7() (uuid.UUID, error) {
+	slot, err := g.nextSlot()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return composeUUIDv7(slot)
+}
+
+// composeUUIDv7 packs a slot into the UUIDv7 layout
+func composeUUIDv7(slot idSlot) (uuid.UUID, error) {
+	if slot.timestamp < 0 || slot.timestamp > uuidV7MaxTimestamp {
+		return uuid.Nil, fmt.Errorf("timestamp %d does not fit in 48 bits", slot.timestamp)
+	}
+
+	seq := uint64(slot.sequence)
+	hi := uint64(slot.timestamp)<<16 |
+		uint64(0x7)<<12 |
+		seq&(1<<uuidV7SequenceLowBits-1)
+	lo := uint64(0x2)<<62 |
+		(seq>>uuidV7SequenceLowBits)&(1<<uuidV7SequenceHighBits-1)<<uuidV7SequenceHighShift |
+		uint64(slot.datacenterID)&(1<<uuidV7DatacenterBits-1)<<uuidV7DatacenterShift |
+		uint64(slot.workerID)&(1<<uuidV7WorkerBits-1)<<uuidV7WorkerShift |
+		uint64(rand.Uint32())&(1<<uuidV7RandomBits-1)
+
+	var u uuid.UUID
+	for i := 0; i < 8; i++ {
+		u[i] = byte(hi >> (56 - 8*i))
+		u[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return u, nil
+}
+
+// ParseUUIDv7 extracts the timestamp, datacenter, worker and sequence fields from a UUID produced by GenerateUUIDv7.
+// SID.ID is always 0 because the value does not fit in 64 bits; SID.UUID carries the canonical string form.
+func ParseUUIDv7(u uuid.UUID) (*SID, error) {
+	if u.Version() != 7 {
+		return nil, fmt.Errorf("invalid UUIDv7: version is %d", u.Version())
+	}
+	if u.Variant() != uuid.RFC4122 {
+		return nil, fmt.Errorf("invalid UUIDv7: unexpected variant %s", u.Variant())
+	}
+
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(u[i])
+		lo = lo<<8 | uint64(u[8+i])
+	}
+
+	timestamp := int64(hi >> 16)
+	sequence := int64(hi&(1<<uuidV7SequenceLowBits-1)) |
+		int64((lo>>uuidV7SequenceHighShift)&(1<<uuidV7SequenceHighBits-1))<<uuidV7SequenceLowBits
+	datacenterID := int64((lo >> uuidV7DatacenterShift) & (1<<uuidV7DatacenterBits - 1))
+	workerID := int64((lo >> uuidV7WorkerShift) & (1<<uuidV7WorkerBits - 1))
+
+	return &SID{
+		UUID:         u.String(),
+		Timestamp:    time.UnixMilli(timestamp),
+		DatacenterID: datacenterID,
+		WorkerID:     workerID,
+		Sequence:     sequence,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package eonId
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUUIDv7_RoundTrip(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.EnableMetrics = false
+	g, err := NewSnowflakeGeneratorCore(3, 17, cfg)
+	require.NoError(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	u, err := g.GenerateUUIDv7()
+	require.NoError(t, err)
+
+	assert.Equal(t, uuid.Version(7), u.Version())
+	assert.Equal(t, uuid.RFC4122, u.Variant())
+
+	sid, err := ParseUUIDv7(u)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), sid.DatacenterID)
+	assert.Equal(t, int64(17), sid.WorkerID)
+	assert.Equal(t, u.String(), sid.UUID)
+	assert.Zero(t, sid.ID)
+	assert.False(t, sid.Timestamp.Before(before))
+	assert.WithinDuration(t, time.Now(), sid.Timestamp, time.Second)
+}
+
+func TestGenerateUUIDv7_UniqueAndOrdered(t *testing.T) {
+	cfg := DefaultGeneratorConfig()
+	cfg.EnableMetrics = false
+	g, err := NewSnowflakeGeneratorCore(1, 1, cfg)
+	require.NoError(t, err)
+
+	const count = 20000
+	seen := make(map[uuid.UUID]struct{}, count)
+	var prev uuid.UUID
+	for i := 0; i < count; i++ {
+		u, err := g.GenerateUUIDv7()
+		require.NoError(t, err)
+		_, dup := seen[u]
+		require.False(t, dup, "duplicate UUID %s", u)
+		seen[u] = struct{}{}
+
+		// Timestamp and sequence occupy the leading bits, so byte order follows generation order
+		// within a millisecond as long as the sequence high bits are unused (12-bit sequence).
+		if i > 0 {
+			require.Equal(t, 1, bytes.Compare(u[:8], prev[:8]), "UUID %s not after %s", u, prev)
+		}
+		prev = u
+	}
+}
+
+func TestComposeUUIDv7_WideSequenceAndWorker(t *testing.T) {
+	slot := idSlot{
+		timestamp:    time.Now().UnixMilli(),
+		sequence:     (1 << 20) - 1,
+		datacenterID: (1 << 10) - 1,
+		workerID:     (1 << 20) - 1,
+	}
+	u, err := composeUUIDv7(slot)
+	require.NoError(t, err)
+
+	sid, err := ParseUUIDv7(u)
+	require.NoError(t, err)
+	assert.Equal(t, slot.sequence, sid.Sequence)
+	assert.Equal(t, slot.datacenterID, sid.DatacenterID)
+	assert.Equal(t, slot.workerID, sid.WorkerID)
+	assert.Equal(t, slot.timestamp, sid.Timestamp.UnixMilli())
+}
+
+func TestParseUUIDv7_RejectsOtherVersions(t *testing.T) {
+	_, err := ParseUUIDv7(uuid.New())
+	assert.Error(t, err)
+	_, err = ParseUUIDv7(uuid.Nil)
+	assert.Error(t, err)
+}
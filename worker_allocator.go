@@ -0,0 +1,315 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+const (
+	// WorkerIDAllocatorRedis Worker ID allocator backends
+	WorkerIDAllocatorRedis           = "redis"
+	WorkerIDAllocatorMemory          = "memory"
+	WorkerIDAllocatorEtcd            = "etcd"
+	WorkerIDAllocatorZooKeeper       = "zookeeper"
+	WorkerIDAllocatorKubernetesLease = "kubernetes-lease"
+	WorkerIDAllocatorStatefulSet     = "statefulset"
+	WorkerIDAllocatorSQL             = "sql"
+	WorkerIDAllocatorFile            = "file"
+
+	// DefaultWorkerWatchInterval is how often polling backends list the registry to produce watch events
+	DefaultWorkerWatchInterval = 5 * time.Second
+)
+
+// WorkerIDAllocator stores worker ID claims for WorkerIDManager. The manager owns the lifecycle
+// (heartbeat, re-registration, health); a backend only has to make claims exclusive and expiring.
+// Claims are identified by WorkerInfo.DatacenterID, WorkerInfo.WorkerID and owned by WorkerInfo.InstanceID.
+type WorkerIDAllocator interface {
+	// Name returns the backend name, e.g. "redis"
+	Name() string
+	// Acquire claims a worker ID in info.DatacenterID for ttl. When info.WorkerID is non-negative only that ID is
+	// tried and *WorkerIDConflictError is returned if it is held; otherwise any free ID in [0, maxWorkerID] is taken.
+	// It returns the stored WorkerInfo with WorkerID set.
+	Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error)
+	// Renew stores info and extends the claim for another ttl if it still belongs to info.InstanceID.
+	// It returns *WorkerLeaseLostError when the claim has expired or another instance holds the worker ID.
+	Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error
+	// Release removes the claim if it still belongs to info.InstanceID; a claim that is already gone is not an error
+	Release(ctx context.Context, info WorkerInfo) error
+	// List returns the live claims of all datacenters
+	List(ctx context.Context) ([]WorkerInfo, error)
+	// Watch reports claims being added, replaced and removed until ctx is done, then closes the channel.
+	// Claims that exist when Watch is called are not reported; use List for the initial state.
+	Watch(ctx context.Context) (<-chan WorkerEvent, error)
+}
+
+// WorkerLeaseWatcher is implemented by allocators that notice a lost claim by themselves, e.g. when an
+// etcd lease keepalive stops. WorkerIDManager marks itself unhealthy as soon as the channel is closed,
+// without waiting for the next heartbeat.
+type WorkerLeaseWatcher interface {
+	// LeaseDone returns a channel that is closed when the claim described by info is lost or released
+	LeaseDone(info WorkerInfo) <-chan struct{}
+}
+
+// WorkerRegistryTrimmer is implemented by allocators whose registry can outlive a claim, e.g. when an instance
+// dies without releasing its worker ID. The plugin's registry janitor calls it periodically.
+type WorkerRegistryTrimmer interface {
+	// TrimRegistry removes registry entries whose claim has expired and whose last heartbeat is older than
+	// staleAfter, and returns how many it removed
+	TrimRegistry(ctx context.Context, staleAfter time.Duration) (int, error)
+}
+
+// WorkerRevoker is implemented by allocators that can revoke a claim whose owner is still renewing it, e.g. a
+// wedged instance that keeps heartbeating. The claim stays in place, marked revoked, so the worker ID is not
+// handed out again until the owner has seen the revocation and released it, or the claim has expired.
+type WorkerRevoker interface {
+	// RevokeWorker marks the claim on a worker ID revoked and returns it; the owner's next Renew fails with
+	// *WorkerRevokedError. It returns nil if the worker ID is free. Revoking a revoked claim keeps the first
+	// revocation.
+	RevokeWorker(ctx context.Context, datacenterID, workerID int64, revocation WorkerRevocation) (*WorkerInfo, error)
+}
+
+// DatacenterResolver is implemented by allocators that can map datacenter names, e.g. regions or zones, to
+// datacenter IDs. A new name takes the lowest datacenter ID no other name holds, first come first served, and
+// keeps it for good, so every instance started with the same name shares one datacenter ID.
+type DatacenterResolver interface {
+	// ResolveDatacenter returns the datacenter ID of name, mapping the name to a free ID in [0, maxDatacenterID]
+	// the first time it is seen. It returns *DatacenterIDsExhaustedError when every ID is taken.
+	ResolveDatacenter(ctx context.Context, name string, maxDatacenterID int64) (int64, error)
+	// Datacenters returns every datacenter name with its datacenter ID
+	Datacenters(ctx context.Context) (map[string]int64, error)
+}
+
+// WorkerEventType is the kind of change reported by WorkerIDAllocator.Watch
+type WorkerEventType string
+
+const (
+	// WorkerEventAdded Worker registry events
+	WorkerEventAdded    WorkerEventType = "added"
+	WorkerEventReplaced WorkerEventType = "replaced" // the worker ID is now held by a different instance
+	WorkerEventRemoved  WorkerEventType = "removed"
+)
+
+// WorkerEvent is a change in the worker registry
+type WorkerEvent struct {
+	Type   WorkerEventType `json:"type"`
+	Worker WorkerInfo      `json:"worker"`
+}
+
+// WorkerLeaseLostError is returned by WorkerIDAllocator.Renew when the claim no longer belongs to this instance
+type WorkerLeaseLostError struct {
+	WorkerID     int64
+	DatacenterID int64
+	Expired      bool // false: another instance holds the worker ID
+}
+
+func (e *WorkerLeaseLostError) Error() string {
+	if e.Expired {
+		return fmt.Sprintf("worker ID %d key has expired", e.WorkerID)
+	}
+	return fmt.Sprintf("worker ID %d was taken by another instance", e.WorkerID)
+}
+
+// WorkerRevokedError is returned by WorkerIDAllocator.Renew when an operator has revoked the claim (see
+// WorkerRevoker). The claim still belongs to this instance until it releases it, but no ID may be issued under
+// the worker ID any more.
+type WorkerRevokedError struct {
+	WorkerID     int64
+	DatacenterID int64
+	Revocation   WorkerRevocation
+}
+
+func (e *WorkerRevokedError) Error() string {
+	msg := fmt.Sprintf("worker ID %d was revoked", e.WorkerID)
+	if e.Revocation.By != "" {
+		msg += " by " + e.Revocation.By
+	}
+	if e.Revocation.Reason != "" {
+		msg += ": " + e.Revocation.Reason
+	}
+	return msg
+}
+
+// DatacenterIDsExhaustedError is returned by DatacenterResolver.ResolveDatacenter when a new name finds every
+// datacenter ID mapped to another name
+type DatacenterIDsExhaustedError struct {
+	Name            string
+	MaxDatacenterID int64
+}
+
+func (e *DatacenterIDsExhaustedError) Error() string {
+	return fmt.Sprintf("no datacenter ID left for %q: all %d are mapped to other names", e.Name, e.MaxDatacenterID+1)
+}
+
+// workerClaimKey identifies a claim independently of the backend's key layout
+type workerClaimKey struct {
+	datacenterID int64
+	workerID     int64
+}
+
+// pollWorkerEvents implements Watch for backends without change notifications by listing the registry
+// every interval and reporting the difference. The first listing happens before it returns, so a claim made
+// right after Watch returns is always reported.
+func pollWorkerEvents(ctx context.Context, interval time.Duration, list func(context.Context) ([]WorkerInfo, error)) (<-chan WorkerEvent, error) {
+	if interval <= 0 {
+		interval = DefaultWorkerWatchInterval
+	}
+	workers, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := indexWorkers(workers)
+
+	events := make(chan WorkerEvent, 16)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			workers, err := list(ctx)
+			if err != nil {
+				continue // keep the last known state and try again on the next tick
+			}
+			current := indexWorkers(workers)
+			for _, ev := range diffWorkers(known, current) {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			known = current
+		}
+	}()
+	return events, nil
+}
+
+func indexWorkers(workers []WorkerInfo) map[workerClaimKey]WorkerInfo {
+	index := make(map[workerClaimKey]WorkerInfo, len(workers))
+	for _, w := range workers {
+		index[workerClaimKey{w.DatacenterID, w.WorkerID}] = w
+	}
+	return index
+}
+
+// diffWorkers returns the events that turn known into current. Heartbeats alone are not reported.
+func diffWorkers(known, current map[workerClaimKey]WorkerInfo) []WorkerEvent {
+	var events []WorkerEvent
+	for key, w := range current {
+		prev, ok := known[key]
+		switch {
+		case !ok:
+			events = append(events, WorkerEvent{Type: WorkerEventAdded, Worker: w})
+		case prev.InstanceID != w.InstanceID:
+			events = append(events, WorkerEvent{Type: WorkerEventReplaced, Worker: w})
+		}
+	}
+	for key, w := range known {
+		if _, ok := current[key]; !ok {
+			events = append(events, WorkerEvent{Type: WorkerEventRemoved, Worker: w})
+		}
+	}
+	return events
+}
+
+// leaseSeconds converts a TTL to whole seconds, at least one, for backends whose leases count in seconds
+func leaseSeconds(ttl time.Duration) int64 {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// newWorkerIDAllocator creates the backend selected by worker_id_allocator, resolving its client from the runtime
+func (p *PlugSnowflake) newWorkerIDAllocator(rt plugins.Runtime, conf *pb.EonId) (WorkerIDAllocator, error) {
+	switch backend := stringOr(conf.WorkerIdAllocator, WorkerIDAllocatorRedis); backend {
+	case WorkerIDAllocatorRedis:
+		redisPluginName := conf.RedisPluginName
+		if redisPluginName == "" {
+			redisPluginName = "redis"
+		}
+		redisClient, resolvedName, err := resolveRedisClientResource(rt, redisPluginName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Redis client from plugin resource %s: %w", redisPluginName, err)
+		}
+		p.redisClient = redisClient
+		lynxlog.Infof("successfully connected to Redis plugin resource: %s", resolvedName)
+		return NewRedisWorkerIDAllocator(redisClient, &RedisWorkerIDAllocatorConfig{
+			KeyPrefix: conf.RedisKeyPrefix,
+			KeyLayout: conf.RedisKeyLayout,
+		}), nil
+	case WorkerIDAllocatorEtcd:
+		etcdPluginName := stringOr(conf.EtcdPluginName, DefaultEtcdPluginName)
+		etcdClient, err := resolveEtcdClientResource(rt, etcdPluginName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get etcd client from plugin resource %s: %w", etcdPluginName, err)
+		}
+		lynxlog.Infof("successfully connected to etcd plugin resource: %s", etcdPluginName)
+		return NewEtcdWorkerIDAllocator(etcdClient, conf.EtcdKeyPrefix), nil
+	case WorkerIDAllocatorZooKeeper:
+		zkPluginName := stringOr(conf.ZookeeperPluginName, DefaultZooKeeperPluginName)
+		zkConn, err := resolveZooKeeperConnResource(rt, zkPluginName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ZooKeeper connection from plugin resource %s: %w", zkPluginName, err)
+		}
+		lynxlog.Infof("successfully connected to ZooKeeper plugin resource: %s", zkPluginName)
+		return NewZooKeeperWorkerIDAllocator(zkConn, &ZooKeeperWorkerIDAllocatorConfig{KeyPrefix: conf.ZookeeperKeyPrefix}), nil
+	case WorkerIDAllocatorKubernetesLease:
+		k8sPluginName := stringOr(conf.KubernetesPluginName, DefaultKubernetesPluginName)
+		k8sClient, err := resolveKubernetesClientResource(rt, k8sPluginName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Kubernetes client from plugin resource %s: %w", k8sPluginName, err)
+		}
+		allocator := NewKubernetesLeaseWorkerIDAllocator(k8sClient, &KubernetesLeaseWorkerIDAllocatorConfig{
+			Namespace:   conf.KubernetesNamespace,
+			LeasePrefix: conf.KubernetesLeasePrefix,
+		})
+		lynxlog.Infof("claiming worker IDs with Leases in namespace %s", allocator.namespace)
+		return allocator, nil
+	case WorkerIDAllocatorStatefulSet:
+		ordinal, err := StatefulSetOrdinal(conf.KubernetesOrdinalEnv)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine StatefulSet ordinal: %w", err)
+		}
+		lynxlog.Infof("using StatefulSet ordinal %d as worker ID", ordinal)
+		return NewStatefulSetWorkerIDAllocator(ordinal), nil
+	case WorkerIDAllocatorSQL:
+		sqlPluginName := stringOr(conf.SqlPluginName, DefaultSQLPluginName)
+		db, err := resolveSQLDBResource(rt, sqlPluginName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database from plugin resource %s: %w", sqlPluginName, err)
+		}
+		lynxlog.Infof("successfully connected to database plugin resource: %s", sqlPluginName)
+		return NewSQLWorkerIDAllocator(db, &SQLWorkerIDAllocatorConfig{
+			Dialect:     conf.SqlDialect,
+			TableName:   conf.SqlTableName,
+			SafetyDelay: conf.SqlSafetyDelay.AsDuration(),
+		}), nil
+	case WorkerIDAllocatorFile:
+		allocator := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{
+			Dir:        conf.FileLockDir,
+			BaseOffset: conf.FileLockBaseOffset,
+			Slots:      int64(conf.FileLockSlots),
+		})
+		lynxlog.Infof("claiming worker IDs with slot files under %s from base offset %d", allocator.dir, allocator.baseOffset)
+		return allocator, nil
+	case WorkerIDAllocatorMemory:
+		lynxlog.Warnf("using the in-memory worker ID allocator; worker IDs are only unique within this process")
+		return NewMemoryWorkerIDAllocator(), nil
+	default:
+		return nil, fmt.Errorf("unknown worker ID allocator: %s", backend)
+	}
+}
@@ -0,0 +1,374 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// DefaultEtcdKeyPrefix is the etcd key prefix for worker ID claims; should end with "/"
+	DefaultEtcdKeyPrefix = "/lynx/eon-id/"
+	// DefaultEtcdPluginName is the shared resource name of the etcd client
+	DefaultEtcdPluginName = "etcd"
+)
+
+// EtcdWorkerIDAllocator claims worker IDs with etcd keys attached to a lease. A transaction on
+// CreateRevision == 0 makes a claim exclusive, and a lease KeepAlive replaces the Redis TTL refresh, so a
+// claim disappears together with the process. Values are the same WorkerInfo JSON as in Redis.
+type EtcdWorkerIDAllocator struct {
+	client    *clientv3.Client
+	keyPrefix string
+
+	mu     sync.Mutex
+	leases map[workerClaimKey]*etcdLease
+}
+
+// etcdLease is a claim held by this process
+type etcdLease struct {
+	id         clientv3.LeaseID
+	instanceID string
+	cancel     context.CancelFunc // stops the keepalive
+	done       chan struct{}      // closed when the keepalive ends: lease expired, revoked or released
+}
+
+// NewEtcdWorkerIDAllocator creates an etcd-backed worker ID allocator
+func NewEtcdWorkerIDAllocator(client *clientv3.Client, keyPrefix string) *EtcdWorkerIDAllocator {
+	return &EtcdWorkerIDAllocator{
+		client:    client,
+		keyPrefix: NormalizeEtcdKeyPrefix(keyPrefix),
+		leases:    make(map[workerClaimKey]*etcdLease),
+	}
+}
+
+// NormalizeEtcdKeyPrefix ensures the key prefix ends with "/"; empty means DefaultEtcdKeyPrefix
+func NormalizeEtcdKeyPrefix(prefix string) string {
+	if prefix == "" {
+		return DefaultEtcdKeyPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		return prefix + "/"
+	}
+	return prefix
+}
+
+// Name returns "etcd"
+func (a *EtcdWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorEtcd
+}
+
+// Acquire grants a lease for ttl and claims a worker ID with it. Free IDs are tried from a random
+// offset so that instances starting together do not all race for the same ID.
+func (a *EtcdWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("etcd client is nil")
+	}
+	if info.WorkerID < 0 && maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+
+	var candidates []int64
+	if info.WorkerID >= 0 {
+		candidates = []int64{info.WorkerID}
+	} else {
+		taken, err := a.takenWorkerIDs(ctx, info.DatacenterID)
+		if err != nil {
+			return nil, err
+		}
+		total := maxWorkerID + 1
+		start := secureInt63n(total)
+		for i := int64(0); i < total; i++ {
+			if id := (start + i) % total; !taken[id] {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", total)
+		}
+	}
+
+	lease, err := a.client.Grant(ctx, leaseSeconds(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant etcd lease: %w", err)
+	}
+
+	for _, workerID := range candidates {
+		claimed := info
+		claimed.WorkerID = workerID
+		key := a.workerKey(info.DatacenterID, workerID)
+		resp, err := a.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, claimed.String(), clientv3.WithLease(lease.ID))).
+			Commit()
+		if err != nil {
+			a.revoke(lease.ID)
+			return nil, fmt.Errorf("failed to claim worker ID %d: %w", workerID, err)
+		}
+		if !resp.Succeeded {
+			log.Debugf("worker ID %d is taken in etcd", workerID)
+			continue
+		}
+		if err := a.keepAlive(claimed, lease.ID); err != nil {
+			a.revoke(lease.ID)
+			return nil, err
+		}
+		return &claimed, nil
+	}
+
+	a.revoke(lease.ID)
+	if info.WorkerID >= 0 {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: "another instance",
+		}
+	}
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", maxWorkerID+1)
+}
+
+// takenWorkerIDs lists the worker IDs claimed in a datacenter
+func (a *EtcdWorkerIDAllocator) takenWorkerIDs(ctx context.Context, datacenterID int64) (map[int64]bool, error) {
+	prefix := a.datacenterPrefix(datacenterID)
+	resp, err := a.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker IDs: %w", err)
+	}
+	taken := make(map[int64]bool, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if id, err := strconv.ParseInt(strings.TrimPrefix(string(kv.Key), prefix), 10, 64); err == nil {
+			taken[id] = true
+		}
+	}
+	return taken, nil
+}
+
+// keepAlive starts refreshing the lease in the background and records the claim
+func (a *EtcdWorkerIDAllocator) keepAlive(info WorkerInfo, leaseID clientv3.LeaseID) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	responses, err := a.client.KeepAlive(ctx, leaseID)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to keep etcd lease alive: %w", err)
+	}
+
+	lease := &etcdLease{id: leaseID, instanceID: info.InstanceID, cancel: cancel, done: make(chan struct{})}
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	a.mu.Lock()
+	if old, ok := a.leases[key]; ok {
+		old.cancel()
+	}
+	a.leases[key] = lease
+	a.mu.Unlock()
+
+	go func() {
+		defer close(lease.done)
+		// The channel is closed when the lease expires or is revoked, or when the keepalive is cancelled
+		for range responses {
+		}
+		if ctx.Err() == nil {
+			log.Warnf("etcd lease of worker ID %d (datacenter %d) was lost", info.WorkerID, info.DatacenterID)
+		}
+	}()
+	return nil
+}
+
+// localLease returns the lease this process holds for the claim, if info.InstanceID owns it
+func (a *EtcdWorkerIDAllocator) localLease(info WorkerInfo) *etcdLease {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lease, ok := a.leases[workerClaimKey{info.DatacenterID, info.WorkerID}]
+	if !ok || lease.instanceID != info.InstanceID {
+		return nil
+	}
+	return lease
+}
+
+// revoke drops a lease and with it any key attached to it
+func (a *EtcdWorkerIDAllocator) revoke(leaseID clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = a.client.Revoke(ctx, leaseID)
+}
+
+// Renew stores info under the claim's lease. The lease itself is refreshed by its keepalive, so ttl only
+// applies to the next Acquire.
+func (a *EtcdWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if a.client == nil {
+		return fmt.Errorf("etcd client is nil")
+	}
+
+	key := a.workerKey(info.DatacenterID, info.WorkerID)
+	lease := a.localLease(info)
+	if lease == nil {
+		// Not ours: report whether somebody else holds it or it is gone
+		resp, err := a.client.Get(ctx, key, clientv3.WithKeysOnly())
+		if err != nil {
+			return fmt.Errorf("failed to read worker ID %d: %w", info.WorkerID, err)
+		}
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: len(resp.Kvs) == 0}
+	}
+
+	resp, err := a.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.LeaseValue(key), "=", lease.id)).
+		Then(clientv3.OpPut(key, info.String(), clientv3.WithLease(lease.id))).
+		Else(clientv3.OpGet(key, clientv3.WithKeysOnly())).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to renew worker ID %d: %w", info.WorkerID, err)
+	}
+	if resp.Succeeded {
+		return nil
+	}
+	current := resp.Responses[0].GetResponseRange()
+	return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: current == nil || len(current.Kvs) == 0}
+}
+
+// Release revokes the claim's lease, which deletes the key. Claims held by other instances are left alone.
+func (a *EtcdWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	if a.client == nil {
+		return fmt.Errorf("etcd client is nil")
+	}
+
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	a.mu.Lock()
+	lease, ok := a.leases[key]
+	if ok && lease.instanceID == info.InstanceID {
+		delete(a.leases, key)
+	} else {
+		lease = nil
+	}
+	a.mu.Unlock()
+	if lease == nil {
+		return nil
+	}
+
+	lease.cancel()
+	if _, err := a.client.Revoke(ctx, lease.id); err != nil && !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		return fmt.Errorf("failed to revoke etcd lease of worker ID %d: %w", info.WorkerID, err)
+	}
+	return nil
+}
+
+// List returns the claims of all datacenters
+func (a *EtcdWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("etcd client is nil")
+	}
+
+	resp, err := a.client.Get(ctx, a.keyPrefix+"dc/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	workers := make([]WorkerInfo, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		info, err := ParseWorkerInfo(string(kv.Value))
+		if err != nil {
+			continue
+		}
+		workers = append(workers, *info)
+	}
+	return workers, nil
+}
+
+// Watch uses an etcd watch starting right after the current revision, so no change is missed between
+// Watch returning and the watch being established
+func (a *EtcdWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("etcd client is nil")
+	}
+
+	prefix := a.keyPrefix + "dc/"
+	resp, err := a.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read etcd revision: %w", err)
+	}
+	watch := a.client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(resp.Header.Revision+1))
+
+	events := make(chan WorkerEvent, 16)
+	go func() {
+		defer close(events)
+		for wr := range watch {
+			for _, ev := range wr.Events {
+				event, ok := etcdWorkerEvent(ev)
+				if !ok {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// etcdWorkerEvent maps a watch event to a registry event; heartbeats of the same instance are skipped
+func etcdWorkerEvent(ev *clientv3.Event) (WorkerEvent, bool) {
+	var prev *WorkerInfo
+	if ev.PrevKv != nil {
+		prev, _ = ParseWorkerInfo(string(ev.PrevKv.Value))
+	}
+
+	switch ev.Type {
+	case clientv3.EventTypeDelete:
+		if prev == nil {
+			return WorkerEvent{}, false
+		}
+		return WorkerEvent{Type: WorkerEventRemoved, Worker: *prev}, true
+	case clientv3.EventTypePut:
+		info, err := ParseWorkerInfo(string(ev.Kv.Value))
+		if err != nil {
+			return WorkerEvent{}, false
+		}
+		switch {
+		case prev == nil:
+			return WorkerEvent{Type: WorkerEventAdded, Worker: *info}, true
+		case prev.InstanceID != info.InstanceID:
+			return WorkerEvent{Type: WorkerEventReplaced, Worker: *info}, true
+		}
+	}
+	return WorkerEvent{}, false
+}
+
+// LeaseDone implements WorkerLeaseWatcher
+func (a *EtcdWorkerIDAllocator) LeaseDone(info WorkerInfo) <-chan struct{} {
+	if lease := a.localLease(info); lease != nil {
+		return lease.done
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// Key layout: <prefix>dc/<datacenter>/worker/<worker>
+func (a *EtcdWorkerIDAllocator) workerKey(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s%d", a.datacenterPrefix(datacenterID), workerID)
+}
+
+func (a *EtcdWorkerIDAllocator) datacenterPrefix(datacenterID int64) string {
+	return fmt.Sprintf("%sdc/%d/worker/", a.keyPrefix, datacenterID)
+}
+
+// resolveEtcdClientResource looks up the etcd client shared by another plugin
+func resolveEtcdClientResource(rt plugins.Runtime, name string) (*clientv3.Client, error) {
+	resource, err := rt.GetSharedResource(name)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := resource.(*clientv3.Client)
+	if !ok || client == nil {
+		return nil, fmt.Errorf("shared resource %s is %T, not an etcd client", name, resource)
+	}
+	return client, nil
+}
@@ -0,0 +1,128 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEmbeddedEtcd runs a single-node etcd server for the duration of the test
+func startEmbeddedEtcd(t *testing.T) *clientv3.Client {
+	t.Helper()
+
+	freeURL := func() url.URL {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		return url.URL{Scheme: "http", Host: l.Addr().String()}
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, peerURL := freeURL(), freeURL()
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		t.Fatal("embedded etcd did not start")
+	}
+
+	client, err := clientv3.New(clientv3.Config{Endpoints: []string{clientURL.String()}, DialTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// expireEtcdLeases revokes every lease that would run out within d without keepalives,
+// which is what the server does when a client is cut off for that long
+func expireEtcdLeases(t *testing.T, client *clientv3.Client, d time.Duration) {
+	ctx := context.Background()
+	leases, err := client.Leases(ctx)
+	require.NoError(t, err)
+	for _, l := range leases.Leases {
+		ttl, err := client.TimeToLive(ctx, l.ID)
+		if err != nil || ttl.TTL < 0 {
+			continue
+		}
+		if time.Duration(ttl.TTL)*time.Second < d {
+			_, _ = client.Revoke(ctx, l.ID)
+		}
+	}
+}
+
+func TestEtcdWorkerIDAllocator_Conformance(t *testing.T) {
+	client := startEmbeddedEtcd(t)
+	var n int64
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		// Each subtest gets its own key space on the shared server
+		prefix := fmt.Sprintf("/test/eon-id-%d", atomic.AddInt64(&n, 1))
+		allocator := NewEtcdWorkerIDAllocator(client, prefix)
+		t.Cleanup(func() {
+			for _, lease := range allocator.leases {
+				lease.cancel()
+			}
+		})
+		return allocator, func(d time.Duration) { expireEtcdLeases(t, client, d) }
+	})
+}
+
+func TestEtcdWorkerIDAllocator_KeyLayout(t *testing.T) {
+	client := startEmbeddedEtcd(t)
+	a := NewEtcdWorkerIDAllocator(client, "/eon")
+	ctx := context.Background()
+
+	info := WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "inst", ServiceName: "orders"}
+	_, err := a.Acquire(ctx, info, 31, 30*time.Second)
+	require.NoError(t, err)
+	defer func() { _ = a.Release(ctx, info) }()
+
+	resp, err := client.Get(ctx, "/eon/dc/2/worker/9")
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.NotZero(t, resp.Kvs[0].Lease, "claims are attached to a lease")
+	stored, err := ParseWorkerInfo(string(resp.Kvs[0].Value))
+	require.NoError(t, err)
+	assert.Equal(t, info, *stored, "same WorkerInfo JSON as the Redis backend")
+}
+
+func TestWorkerIDManager_EtcdLeaseLossMarksUnhealthy(t *testing.T) {
+	client := startEmbeddedEtcd(t)
+	allocator := NewEtcdWorkerIDAllocator(client, "/eon")
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{
+		TTL:               3 * time.Second, // keepalives go out every TTL/3
+		HeartbeatInterval: time.Hour,
+	})
+
+	workerID, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	require.True(t, mgr.IsHealthy())
+
+	// Revoke the lease behind the manager's back; it must notice without waiting for a heartbeat
+	expireEtcdLeases(t, client, time.Hour)
+	assert.Eventually(t, func() bool { return !mgr.IsHealthy() }, 5*time.Second, 10*time.Millisecond)
+
+	var lost *WorkerLeaseLostError
+	require.ErrorAs(t, mgr.sendHeartbeat(), &lost)
+	assert.True(t, lost.Expired)
+	assert.Equal(t, workerID, lost.WorkerID)
+
+	require.NoError(t, mgr.UnregisterWorkerID(context.Background()))
+}
@@ -0,0 +1,402 @@
+package eonId
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFileLockDir is the directory that holds the worker ID slot files
+	DefaultFileLockDir = "/var/run/eon-id"
+
+	fileLockSlotPrefix = "worker-"
+	fileLockSlotSuffix = ".lock"
+	maxSlotFileSize    = 64 << 10
+)
+
+// FileWorkerIDAllocator claims worker IDs with flock(2) on slot files, for processes that share a host but
+// no coordination service:
+//
+//	<dir>/dc-<n>/worker-<id>.lock
+//
+// A slot is claimed while this process holds an exclusive lock on its file, and the kernel drops the lock
+// when the process dies, so a crashed instance never strands its worker ID. The file keeps the WorkerInfo
+// of the last holder, including the timestamp of the last ID it issued, after the lock is gone.
+//
+// Slot files only coordinate processes on one host. Hosts are kept apart by a static base offset: each host
+// hands out worker IDs base_offset .. base_offset+slots-1, and the ranges must not overlap.
+type FileWorkerIDAllocator struct {
+	dir           string
+	baseOffset    int64
+	slots         int64
+	watchInterval time.Duration
+
+	mu   sync.Mutex
+	held map[workerClaimKey]*fileSlot
+}
+
+// fileSlot is a slot file locked by this process
+type fileSlot struct {
+	file *os.File
+	info WorkerInfo
+}
+
+// FileWorkerIDAllocatorConfig holds configuration for the file lock allocator
+type FileWorkerIDAllocatorConfig struct {
+	Dir           string        // slot file directory (default: DefaultFileLockDir)
+	BaseOffset    int64         // first worker ID of this host
+	Slots         int64         // number of worker IDs of this host (default: up to the max worker ID)
+	WatchInterval time.Duration // registry polling interval for Watch (default: DefaultWorkerWatchInterval)
+}
+
+// NewFileWorkerIDAllocator creates a file lock worker ID allocator
+func NewFileWorkerIDAllocator(config *FileWorkerIDAllocatorConfig) *FileWorkerIDAllocator {
+	if config == nil {
+		config = &FileWorkerIDAllocatorConfig{}
+	}
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = DefaultWorkerWatchInterval
+	}
+	return &FileWorkerIDAllocator{
+		dir:           stringOr(config.Dir, DefaultFileLockDir),
+		baseOffset:    config.BaseOffset,
+		slots:         config.Slots,
+		watchInterval: watchInterval,
+		held:          make(map[workerClaimKey]*fileSlot),
+	}
+}
+
+// Name implements WorkerIDAllocator
+func (a *FileWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorFile
+}
+
+// slotRange returns the worker IDs this host may claim
+func (a *FileWorkerIDAllocator) slotRange(maxWorkerID int64) (first, last int64, err error) {
+	if a.baseOffset < 0 || a.baseOffset > maxWorkerID {
+		return 0, 0, fmt.Errorf("file lock base offset %d is outside worker IDs 0..%d", a.baseOffset, maxWorkerID)
+	}
+	last = maxWorkerID
+	if a.slots > 0 && a.baseOffset+a.slots-1 < last {
+		last = a.baseOffset + a.slots - 1
+	}
+	return a.baseOffset, last, nil
+}
+
+// Acquire locks the first free slot file of this host's range, or the one for info.WorkerID if it is set.
+// A specific worker ID outside the range is an error, since it belongs to another host.
+func (a *FileWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	first, last, err := a.slotRange(maxWorkerID)
+	if err != nil {
+		return nil, err
+	}
+	if info.WorkerID >= 0 && (info.WorkerID < first || info.WorkerID > last) {
+		return nil, fmt.Errorf("worker ID %d is outside this host's slots %d..%d", info.WorkerID, first, last)
+	}
+	if err := os.MkdirAll(a.datacenterDir(info.DatacenterID), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create slot directory: %w", err)
+	}
+
+	candidates := []int64{info.WorkerID}
+	if info.WorkerID < 0 {
+		candidates = candidates[:0]
+		for id := first; id <= last; id++ {
+			candidates = append(candidates, id)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, workerID := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		key := workerClaimKey{info.DatacenterID, workerID}
+		if slot, ok := a.held[key]; ok {
+			if info.WorkerID >= 0 {
+				return nil, &WorkerIDConflictError{WorkerID: workerID, DatacenterID: info.DatacenterID, ConflictWith: slot.info.InstanceID}
+			}
+			continue
+		}
+
+		claimed := info
+		claimed.WorkerID = workerID
+		slot, err := a.lockSlot(claimed)
+		if err != nil {
+			return nil, err
+		}
+		if slot == nil {
+			if info.WorkerID >= 0 {
+				return nil, &WorkerIDConflictError{
+					WorkerID:     workerID,
+					DatacenterID: info.DatacenterID,
+					ConflictWith: a.holderOf(info.DatacenterID, workerID),
+				}
+			}
+			continue
+		}
+		a.held[key] = slot
+		result := slot.info
+		return &result, nil
+	}
+	return nil, fmt.Errorf("no available worker ID in datacenter %d (slots %d..%d)", info.DatacenterID, first, last)
+}
+
+// lockSlot opens and locks the slot file for info and writes info to it. It returns nil without an error if
+// another process holds the slot.
+func (a *FileWorkerIDAllocator) lockSlot(info WorkerInfo) (*fileSlot, error) {
+	path := a.slotPath(info.DatacenterID, info.WorkerID)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open slot file %s: %w", path, err)
+	}
+	locked, err := tryLockFile(f, true)
+	if err != nil || !locked {
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock slot file %s: %w", path, err)
+		}
+		return nil, nil
+	}
+	// The file may have been removed and recreated between open and flock, in which case the lock guards
+	// nothing. Treat the slot as busy; the next attempt opens the new file.
+	if !a.isSlotFile(f, path) {
+		_ = f.Close()
+		return nil, nil
+	}
+
+	// The last-issued timestamp belongs to the worker ID rather than the holder, so a new holder keeps the
+	// previous mark until it issues a later one
+	if previous, err := readSlotInfo(f); err == nil && previous.LastTimestamp > info.LastTimestamp {
+		info.LastTimestamp = previous.LastTimestamp
+	}
+	slot := &fileSlot{file: f, info: info}
+	if err := slot.write(info); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to write slot file %s: %w", path, err)
+	}
+	return slot, nil
+}
+
+// Renew rewrites the slot file with info. The lock itself does not expire; the claim is lost only if this
+// process no longer holds the file, or the file was removed from the directory.
+func (a *FileWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	slot, ok := a.held[key]
+	if !ok {
+		return &WorkerLeaseLostError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			Expired:      a.holderOf(info.DatacenterID, info.WorkerID) == "",
+		}
+	}
+	if slot.info.InstanceID != info.InstanceID {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	if !a.isSlotFile(slot.file, a.slotPath(info.DatacenterID, info.WorkerID)) {
+		_ = slot.file.Close()
+		delete(a.held, key)
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	if info.LastTimestamp < slot.info.LastTimestamp {
+		info.LastTimestamp = slot.info.LastTimestamp
+	}
+	if err := slot.write(info); err != nil {
+		return fmt.Errorf("failed to write slot file: %w", err)
+	}
+	slot.info = info
+	return nil
+}
+
+// Release writes the final WorkerInfo to the slot file and unlocks it if info.InstanceID holds it. The file
+// stays in place so the next holder sees the last-issued timestamp.
+func (a *FileWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	slot, ok := a.held[key]
+	if !ok || slot.info.InstanceID != info.InstanceID {
+		return nil
+	}
+	delete(a.held, key)
+	if info.LastTimestamp < slot.info.LastTimestamp {
+		info.LastTimestamp = slot.info.LastTimestamp
+	}
+	writeErr := slot.write(info)
+	_ = unlockFile(slot.file)
+	if err := slot.file.Close(); err != nil {
+		return fmt.Errorf("failed to close slot file: %w", err)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write slot file: %w", writeErr)
+	}
+	return nil
+}
+
+// List returns the WorkerInfo of every locked slot file under the directory
+func (a *FileWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	datacenters, err := os.ReadDir(a.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read slot directory: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var workers []WorkerInfo
+	for _, dc := range datacenters {
+		datacenterID, ok := parseDatacenterDir(dc.Name())
+		if !ok || !dc.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(a.datacenterDir(datacenterID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slot directory: %w", err)
+		}
+		for _, entry := range entries {
+			workerID, ok := parseSlotFileName(entry.Name())
+			if !ok {
+				continue
+			}
+			if slot, ok := a.held[workerClaimKey{datacenterID, workerID}]; ok {
+				workers = append(workers, slot.info)
+				continue
+			}
+			if info, locked := a.probeSlot(datacenterID, workerID); locked && info != nil {
+				workers = append(workers, *info)
+			}
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].DatacenterID != workers[j].DatacenterID {
+			return workers[i].DatacenterID < workers[j].DatacenterID
+		}
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	return workers, nil
+}
+
+// Watch polls List
+func (a *FileWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// LastReported returns the WorkerInfo last written to a slot file, whether or not the slot is locked now
+func (a *FileWorkerIDAllocator) LastReported(datacenterID, workerID int64) (*WorkerInfo, error) {
+	f, err := os.Open(a.slotPath(datacenterID, workerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read slot of worker ID %d: %w", workerID, err)
+	}
+	defer f.Close()
+	return readSlotInfo(f)
+}
+
+// holderOf returns the instance ID in a slot file that another process holds locked, or "" if the slot is free
+func (a *FileWorkerIDAllocator) holderOf(datacenterID, workerID int64) string {
+	info, locked := a.probeSlot(datacenterID, workerID)
+	switch {
+	case !locked:
+		return ""
+	case info == nil:
+		return "unknown"
+	default:
+		return info.InstanceID
+	}
+}
+
+// probeSlot reports whether another open file description holds the slot locked, and if so, what it wrote.
+// The probe takes a shared lock for a moment, so a concurrent Acquire may skip the slot.
+func (a *FileWorkerIDAllocator) probeSlot(datacenterID, workerID int64) (*WorkerInfo, bool) {
+	f, err := os.Open(a.slotPath(datacenterID, workerID))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	free, err := tryLockFile(f, false)
+	if err != nil {
+		return nil, false
+	}
+	if free {
+		_ = unlockFile(f)
+		return nil, false
+	}
+	info, err := readSlotInfo(f)
+	if err != nil {
+		return nil, true // locked, but the holder has not written its record yet
+	}
+	return info, true
+}
+
+// isSlotFile reports whether f is still the file at path
+func (a *FileWorkerIDAllocator) isSlotFile(f *os.File, path string) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(held, current)
+}
+
+func (a *FileWorkerIDAllocator) datacenterDir(datacenterID int64) string {
+	return filepath.Join(a.dir, fmt.Sprintf("dc-%d", datacenterID))
+}
+
+func (a *FileWorkerIDAllocator) slotPath(datacenterID, workerID int64) string {
+	return filepath.Join(a.datacenterDir(datacenterID), fmt.Sprintf("%s%02d%s", fileLockSlotPrefix, workerID, fileLockSlotSuffix))
+}
+
+// write replaces the contents of the slot file with info
+func (s *fileSlot) write(info WorkerInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// readSlotInfo parses the WorkerInfo stored in a slot file
+func readSlotInfo(f *os.File) (*WorkerInfo, error) {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, maxSlotFileSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("slot file %s is empty", f.Name())
+	}
+	return ParseWorkerInfo(string(data))
+}
+
+// parseDatacenterDir parses a dc-<n> directory name
+func parseDatacenterDir(name string) (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(name, "dc-"), 10, 64)
+	return id, err == nil && strings.HasPrefix(name, "dc-") && id >= 0
+}
+
+// parseSlotFileName parses a worker-<id>.lock file name
+func parseSlotFileName(name string) (int64, bool) {
+	if !strings.HasPrefix(name, fileLockSlotPrefix) || !strings.HasSuffix(name, fileLockSlotSuffix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, fileLockSlotPrefix), fileLockSlotSuffix), 10, 64)
+	return id, err == nil && id >= 0
+}
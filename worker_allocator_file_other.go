@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package eonId
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// flockSupported reports whether FileWorkerIDAllocator works on this platform
+const flockSupported = false
+
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	return false, fmt.Errorf("file lock worker ID allocator is not supported on %s", runtime.GOOS)
+}
+
+func unlockFile(f *os.File) error {
+	return fmt.Errorf("file lock worker ID allocator is not supported on %s", runtime.GOOS)
+}
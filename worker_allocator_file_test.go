@@ -0,0 +1,167 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package eonId
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// abandonSlots closes the slot files without unlocking or rewriting them, which is what the kernel does
+// when the process dies
+func abandonSlots(a *FileWorkerIDAllocator) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, slot := range a.held {
+		_ = slot.file.Close()
+		delete(a.held, key)
+	}
+}
+
+func TestFileWorkerIDAllocator_Conformance(t *testing.T) {
+	const ttl = 10 * time.Second // matches the suite's TTL
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		allocator := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{
+			Dir:           t.TempDir(),
+			WatchInterval: 20 * time.Millisecond,
+		})
+		t.Cleanup(func() { abandonSlots(allocator) })
+		return allocator, func(d time.Duration) {
+			// Slot locks do not expire; a holder silent for longer than the TTL stands in for a dead process
+			if d > ttl {
+				abandonSlots(allocator)
+			}
+		}
+	})
+}
+
+func TestFileWorkerIDAllocator_SlotFile(t *testing.T) {
+	dir := t.TempDir()
+	a := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: dir})
+	t.Cleanup(func() { abandonSlots(a) })
+	ctx := context.Background()
+
+	info := WorkerInfo{WorkerID: 7, DatacenterID: 1, InstanceID: "inst-a", ServiceName: "orders", LastTimestamp: 1_700_000_000_000}
+	_, err := a.Acquire(ctx, info, 31, time.Minute)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "dc-1", "worker-07.lock")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	stored, err := ParseWorkerInfo(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, info, *stored)
+
+	// A second allocator stands in for another process on the same host
+	other := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: dir})
+	t.Cleanup(func() { abandonSlots(other) })
+	var conflict *WorkerIDConflictError
+	_, err = other.Acquire(ctx, WorkerInfo{WorkerID: 7, DatacenterID: 1, InstanceID: "inst-b"}, 31, time.Minute)
+	require.True(t, errors.As(err, &conflict), "got %v", err)
+	assert.Equal(t, "inst-a", conflict.ConflictWith)
+	workers, err := other.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []WorkerInfo{info}, workers)
+
+	info.LastTimestamp = 1_700_000_005_000
+	require.NoError(t, a.Renew(ctx, info, time.Minute))
+	require.NoError(t, a.Release(ctx, info))
+	_, err = os.Stat(path)
+	require.NoError(t, err, "the slot file outlives the claim")
+
+	// The next holder inherits the last-issued timestamp of the worker ID
+	claimed, err := other.Acquire(ctx, WorkerInfo{WorkerID: 7, DatacenterID: 1, InstanceID: "inst-b"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_700_000_005_000), claimed.LastTimestamp)
+	last, err := other.LastReported(1, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "inst-b", last.InstanceID)
+	assert.Equal(t, int64(1_700_000_005_000), last.LastTimestamp)
+}
+
+func TestFileWorkerIDAllocator_ProcessDeath(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	a := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: dir})
+	_, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "inst-a"}, 31, time.Minute)
+	require.NoError(t, err)
+	abandonSlots(a)
+
+	b := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: dir})
+	t.Cleanup(func() { abandonSlots(b) })
+	claimed, err := b.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "inst-b"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), claimed.WorkerID, "the dead process's slot is free again")
+
+	// A slot file removed from under its holder no longer guards the worker ID
+	require.NoError(t, os.Remove(filepath.Join(dir, "dc-1", "worker-00.lock")))
+	var lost *WorkerLeaseLostError
+	require.True(t, errors.As(b.Renew(ctx, *claimed, time.Minute), &lost))
+	assert.False(t, lost.Expired)
+}
+
+func TestFileWorkerIDAllocator_BaseOffset(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	a := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: dir, BaseOffset: 8, Slots: 2})
+	t.Cleanup(func() { abandonSlots(a) })
+
+	var workerIDs []int64
+	for _, instance := range []string{"inst-a", "inst-b"} {
+		claimed, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: instance}, 31, time.Minute)
+		require.NoError(t, err)
+		workerIDs = append(workerIDs, claimed.WorkerID)
+	}
+	assert.Equal(t, []int64{8, 9}, workerIDs)
+
+	_, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "inst-c"}, 31, time.Minute)
+	assert.Error(t, err, "the host's slots are exhausted")
+	_, err = a.Acquire(ctx, WorkerInfo{WorkerID: 3, DatacenterID: 1, InstanceID: "inst-c"}, 31, time.Minute)
+	assert.Error(t, err, "worker ID 3 belongs to another host")
+
+	_, err = a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "inst-c"}, 7, time.Minute)
+	assert.Error(t, err, "base offset above max worker ID")
+}
+
+func TestValidateSnowflakeConfig_FileLock(t *testing.T) {
+	conf := newReloadTestConf()
+	conf.AutoRegisterWorkerId = true
+	conf.WorkerIdBits = DefaultWorkerBits
+	conf.SequenceBits = DefaultSequenceBits
+	conf.WorkerIdAllocator = WorkerIDAllocatorFile
+	assert.NoError(t, ValidateSnowflakeConfig(conf), "file allocator needs no Redis settings")
+
+	conf.FileLockBaseOffset = 16
+	conf.FileLockSlots = 16
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.FileLockSlots = 1 << DefaultWorkerBits
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.FileLockSlots = -1
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.FileLockSlots = 0
+	conf.FileLockBaseOffset = 1 << DefaultWorkerBits
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+}
+
+func TestWorkerIDManager_FileLockRecordsLastTimestamp(t *testing.T) {
+	a := NewFileWorkerIDAllocator(&FileWorkerIDAllocatorConfig{Dir: t.TempDir()})
+	mgr := NewWorkerIDManagerWithAllocator(a, 1, &WorkerManagerConfig{TTL: time.Minute, HeartbeatInterval: time.Hour})
+	ctx := context.Background()
+
+	workerID, err := mgr.RegisterWorkerID(ctx, 31)
+	require.NoError(t, err)
+	mgr.SetLastTimestampSource(func() int64 { return 1_700_000_000_123 })
+	require.NoError(t, mgr.sendHeartbeat())
+	require.NoError(t, mgr.UnregisterWorkerID(ctx))
+
+	last, err := a.LastReported(1, workerID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_700_000_000_123), last.LastTimestamp)
+}
@@ -0,0 +1,479 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultKubernetesPluginName is the shared resource name of the Kubernetes client
+	DefaultKubernetesPluginName = "kubernetes"
+	// DefaultKubernetesLeasePrefix prefixes the names of worker ID Lease objects
+	DefaultKubernetesLeasePrefix = "eon-id"
+	// DefaultKubernetesOrdinalEnv is the environment variable read for the StatefulSet ordinal. Set it from the
+	// downward API to either metadata.name or the apps.kubernetes.io/pod-index label.
+	DefaultKubernetesOrdinalEnv = "POD_NAME"
+
+	// Lease labels and annotations
+	kubernetesManagedByLabel   = "app.kubernetes.io/managed-by"
+	kubernetesManagedByValue   = "lynx-eon-id"
+	kubernetesLeasePrefixLabel = "eon-id.go-lynx.io/prefix"
+	kubernetesWorkerInfoKey    = "eon-id.go-lynx.io/worker-info"
+	kubernetesNamespaceFile    = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	kubernetesNamespaceEnv     = "POD_NAMESPACE"
+	kubernetesDefaultNamespace = "default"
+)
+
+// kubernetesLeasePrefixPattern is a DNS label, which keeps the generated Lease names valid
+var kubernetesLeasePrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateKubernetesLeasePrefix checks that Lease names built from the prefix are valid object names
+func ValidateKubernetesLeasePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if len(prefix) > 63 || !kubernetesLeasePrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("kubernetes lease prefix must be a lowercase DNS label, got %q", prefix)
+	}
+	return nil
+}
+
+// KubernetesNamespace returns the namespace to create Leases in: the configured one, else POD_NAMESPACE,
+// else the service account namespace, else "default"
+func KubernetesNamespace(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if ns := os.Getenv(kubernetesNamespaceEnv); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile(kubernetesNamespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return kubernetesDefaultNamespace
+}
+
+// KubernetesLeaseWorkerIDAllocator claims worker IDs with coordination.k8s.io/v1 Lease objects, one per
+// worker ID, named <prefix>-dc-<n>-worker-<id>. The holder identity is the instance ID, renewals move
+// renewTime forward, and a Lease whose renewTime is older than its duration may be taken over. Updates carry
+// the resourceVersion that was read, so two instances cannot take over the same Lease.
+type KubernetesLeaseWorkerIDAllocator struct {
+	client        kubernetes.Interface
+	namespace     string
+	prefix        string
+	watchInterval time.Duration
+	now           func() time.Time
+}
+
+// KubernetesLeaseWorkerIDAllocatorConfig holds configuration for the Kubernetes Lease allocator
+type KubernetesLeaseWorkerIDAllocatorConfig struct {
+	Namespace     string        // default: see KubernetesNamespace
+	LeasePrefix   string        // default: DefaultKubernetesLeasePrefix
+	WatchInterval time.Duration // registry polling interval for Watch (default: DefaultWorkerWatchInterval)
+}
+
+// NewKubernetesLeaseWorkerIDAllocator creates a Lease-backed worker ID allocator
+func NewKubernetesLeaseWorkerIDAllocator(client kubernetes.Interface, config *KubernetesLeaseWorkerIDAllocatorConfig) *KubernetesLeaseWorkerIDAllocator {
+	if config == nil {
+		config = &KubernetesLeaseWorkerIDAllocatorConfig{}
+	}
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = DefaultWorkerWatchInterval
+	}
+	return &KubernetesLeaseWorkerIDAllocator{
+		client:        client,
+		namespace:     KubernetesNamespace(config.Namespace),
+		prefix:        stringOr(config.LeasePrefix, DefaultKubernetesLeasePrefix),
+		watchInterval: watchInterval,
+		now:           time.Now,
+	}
+}
+
+// Name returns "kubernetes-lease"
+func (a *KubernetesLeaseWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorKubernetesLease
+}
+
+// Acquire creates the Lease of a free worker ID, or takes over one that has expired. Free IDs are tried
+// from a random offset so that pods starting together do not all race for the same ID.
+func (a *KubernetesLeaseWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("kubernetes client is nil")
+	}
+	if info.WorkerID < 0 && maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+
+	var candidates []int64
+	if info.WorkerID >= 0 {
+		candidates = []int64{info.WorkerID}
+	} else {
+		held, err := a.list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		taken := make(map[int64]bool, len(held))
+		for _, w := range held {
+			if w.DatacenterID == info.DatacenterID {
+				taken[w.WorkerID] = true
+			}
+		}
+		total := maxWorkerID + 1
+		start := secureInt63n(total)
+		for i := int64(0); i < total; i++ {
+			if id := (start + i) % total; !taken[id] {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", total)
+		}
+	}
+
+	leases := a.client.CoordinationV1().Leases(a.namespace)
+	for _, workerID := range candidates {
+		claimed := info
+		claimed.WorkerID = workerID
+		lease := a.newLease(claimed, ttl)
+
+		_, err := leases.Create(ctx, lease, metav1.CreateOptions{})
+		if err == nil {
+			return &claimed, nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create lease for worker ID %d: %w", workerID, err)
+		}
+
+		existing, err := leases.Get(ctx, lease.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue // released in between; another candidate is as good
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lease for worker ID %d: %w", workerID, err)
+		}
+		if !a.expired(existing) {
+			log.Debugf("worker ID %d is held by lease %s", workerID, lease.Name)
+			continue
+		}
+
+		// Take over the expired Lease; the resourceVersion makes this fail if anybody else got there first
+		takeover := existing.DeepCopy()
+		takeover.Labels = lease.Labels
+		takeover.Annotations = lease.Annotations
+		takeover.Spec = lease.Spec
+		transitions := int32(1)
+		if existing.Spec.LeaseTransitions != nil {
+			transitions = *existing.Spec.LeaseTransitions + 1
+		}
+		takeover.Spec.LeaseTransitions = &transitions
+		if _, err := leases.Update(ctx, takeover, metav1.UpdateOptions{}); err != nil {
+			if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to take over lease for worker ID %d: %w", workerID, err)
+		}
+		log.Infof("took over expired lease %s", lease.Name)
+		return &claimed, nil
+	}
+
+	if info.WorkerID >= 0 {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: "another instance",
+		}
+	}
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", maxWorkerID+1)
+}
+
+// Renew moves renewTime forward and stores info if the Lease is still held by info.InstanceID and has
+// not expired
+func (a *KubernetesLeaseWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if a.client == nil {
+		return fmt.Errorf("kubernetes client is nil")
+	}
+
+	leases := a.client.CoordinationV1().Leases(a.namespace)
+	lease, err := leases.Get(ctx, a.leaseName(info.DatacenterID, info.WorkerID), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lease for worker ID %d: %w", info.WorkerID, err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != info.InstanceID {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	if a.expired(lease) {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+
+	renewed := lease.DeepCopy()
+	renewed.Annotations = a.newLease(info, ttl).Annotations
+	renewTime := metav1.NewMicroTime(a.now())
+	duration := int32(leaseSeconds(ttl))
+	renewed.Spec.RenewTime = &renewTime
+	renewed.Spec.LeaseDurationSeconds = &duration
+	if _, err := leases.Update(ctx, renewed, metav1.UpdateOptions{}); err != nil {
+		switch {
+		case apierrors.IsNotFound(err):
+			return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+		case apierrors.IsConflict(err):
+			return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+		}
+		return fmt.Errorf("failed to renew lease for worker ID %d: %w", info.WorkerID, err)
+	}
+	return nil
+}
+
+// Release deletes the Lease if it is still held by info.InstanceID
+func (a *KubernetesLeaseWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	if a.client == nil {
+		return fmt.Errorf("kubernetes client is nil")
+	}
+
+	leases := a.client.CoordinationV1().Leases(a.namespace)
+	name := a.leaseName(info.DatacenterID, info.WorkerID)
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read lease for worker ID %d: %w", info.WorkerID, err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != info.InstanceID {
+		log.Warnf("lease %s is held by another instance, not releasing", name)
+		return nil
+	}
+
+	preconditions := metav1.Preconditions{ResourceVersion: &lease.ResourceVersion}
+	err = leases.Delete(ctx, name, metav1.DeleteOptions{Preconditions: &preconditions})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		return fmt.Errorf("failed to delete lease for worker ID %d: %w", info.WorkerID, err)
+	}
+	return nil
+}
+
+// List returns the unexpired claims of all datacenters
+func (a *KubernetesLeaseWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("kubernetes client is nil")
+	}
+	return a.list(ctx)
+}
+
+func (a *KubernetesLeaseWorkerIDAllocator) list(ctx context.Context) ([]WorkerInfo, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%s", kubernetesManagedByLabel, kubernetesManagedByValue, kubernetesLeasePrefixLabel, a.prefix)
+	leases, err := a.client.CoordinationV1().Leases(a.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+
+	workers := make([]WorkerInfo, 0, len(leases.Items))
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if a.expired(lease) {
+			continue
+		}
+		info, err := ParseWorkerInfo(lease.Annotations[kubernetesWorkerInfoKey])
+		if err != nil {
+			continue
+		}
+		workers = append(workers, *info)
+	}
+	return workers, nil
+}
+
+// Watch polls the registry
+func (a *KubernetesLeaseWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// newLease builds the Lease object that represents info's claim
+func (a *KubernetesLeaseWorkerIDAllocator) newLease(info WorkerInfo, ttl time.Duration) *coordinationv1.Lease {
+	now := metav1.NewMicroTime(a.now())
+	holder := info.InstanceID
+	duration := int32(leaseSeconds(ttl))
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      a.leaseName(info.DatacenterID, info.WorkerID),
+			Namespace: a.namespace,
+			Labels: map[string]string{
+				kubernetesManagedByLabel:   kubernetesManagedByValue,
+				kubernetesLeasePrefixLabel: a.prefix,
+			},
+			Annotations: map[string]string{kubernetesWorkerInfoKey: info.String()},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+}
+
+// expired reports whether the Lease was not renewed within its duration
+func (a *KubernetesLeaseWorkerIDAllocator) expired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	deadline := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return !a.now().Before(deadline)
+}
+
+// Lease name: <prefix>-dc-<datacenter>-worker-<worker>
+func (a *KubernetesLeaseWorkerIDAllocator) leaseName(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s-dc-%d-worker-%d", a.prefix, datacenterID, workerID)
+}
+
+// StatefulSetWorkerIDAllocator uses the pod's StatefulSet ordinal as its worker ID. The StatefulSet
+// controller already guarantees that only one pod has a given ordinal, so nothing is stored: renewals
+// always succeed and List only knows about this pod. Give every StatefulSet that shares a datacenter ID
+// its own datacenter ID, otherwise their ordinals collide.
+type StatefulSetWorkerIDAllocator struct {
+	ordinal       int64
+	watchInterval time.Duration
+
+	mu   sync.Mutex
+	held *WorkerInfo
+}
+
+// NewStatefulSetWorkerIDAllocator creates an allocator that hands out the given ordinal
+func NewStatefulSetWorkerIDAllocator(ordinal int64) *StatefulSetWorkerIDAllocator {
+	return &StatefulSetWorkerIDAllocator{ordinal: ordinal, watchInterval: DefaultWorkerWatchInterval}
+}
+
+// StatefulSetOrdinal reads the pod ordinal from the environment variable envName, which may hold the
+// ordinal itself (apps.kubernetes.io/pod-index) or the pod name. Without it the hostname is used, which
+// is the pod name in a StatefulSet.
+func StatefulSetOrdinal(envName string) (int64, error) {
+	value := os.Getenv(stringOr(envName, DefaultKubernetesOrdinalEnv))
+	if value == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return -1, fmt.Errorf("failed to read hostname: %w", err)
+		}
+		value = hostname
+	}
+	if ordinal, err := strconv.ParseInt(value, 10, 64); err == nil && ordinal >= 0 {
+		return ordinal, nil
+	}
+	return ParseStatefulSetOrdinal(value)
+}
+
+// ParseStatefulSetOrdinal returns the ordinal suffix of a StatefulSet pod name, e.g. 3 for "orders-3"
+func ParseStatefulSetOrdinal(podName string) (int64, error) {
+	i := strings.LastIndex(podName, "-")
+	if i < 0 || i == len(podName)-1 {
+		return -1, fmt.Errorf("%q is not a StatefulSet pod name", podName)
+	}
+	ordinal, err := strconv.ParseInt(podName[i+1:], 10, 64)
+	if err != nil || ordinal < 0 {
+		return -1, fmt.Errorf("%q is not a StatefulSet pod name", podName)
+	}
+	return ordinal, nil
+}
+
+// Name returns "statefulset"
+func (a *StatefulSetWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorStatefulSet
+}
+
+// Acquire returns the ordinal as worker ID. A specific worker ID other than the ordinal is refused with
+// *WorkerIDConflictError, and an ordinal above maxWorkerID is an error.
+func (a *StatefulSetWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if info.WorkerID >= 0 && info.WorkerID != a.ordinal {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: fmt.Sprintf("StatefulSet ordinal %d", a.ordinal),
+		}
+	}
+	if info.WorkerID < 0 && a.ordinal > maxWorkerID {
+		return nil, fmt.Errorf("StatefulSet ordinal %d exceeds max worker ID %d", a.ordinal, maxWorkerID)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.held != nil && a.held.InstanceID != info.InstanceID {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     a.ordinal,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: a.held.InstanceID,
+		}
+	}
+	claimed := info
+	claimed.WorkerID = a.ordinal
+	a.held = &claimed
+	return &claimed, nil
+}
+
+// Renew stores info if info.InstanceID holds the ordinal
+func (a *StatefulSetWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.held == nil || info.WorkerID != a.ordinal {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+	if a.held.InstanceID != info.InstanceID {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	renewed := info
+	a.held = &renewed
+	return nil
+}
+
+// Release gives the ordinal back if info.InstanceID holds it
+func (a *StatefulSetWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.held != nil && a.held.InstanceID == info.InstanceID {
+		a.held = nil
+	}
+	return nil
+}
+
+// List returns this pod's claim, if any
+func (a *StatefulSetWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.held == nil {
+		return nil, nil
+	}
+	return []WorkerInfo{*a.held}, nil
+}
+
+// Watch polls List
+func (a *StatefulSetWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// resolveKubernetesClientResource looks up the Kubernetes client shared by another plugin
+func resolveKubernetesClientResource(rt plugins.Runtime, name string) (kubernetes.Interface, error) {
+	resource, err := rt.GetSharedResource(name)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := resource.(kubernetes.Interface)
+	if !ok || client == nil {
+		return nil, fmt.Errorf("shared resource %s is %T, not a Kubernetes client", name, resource)
+	}
+	return client, nil
+}
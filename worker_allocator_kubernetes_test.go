@@ -0,0 +1,127 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeLeaseAllocator(t *testing.T) (*KubernetesLeaseWorkerIDAllocator, *fake.Clientset, func(time.Duration)) {
+	client := fake.NewClientset()
+	allocator := NewKubernetesLeaseWorkerIDAllocator(client, &KubernetesLeaseWorkerIDAllocatorConfig{
+		Namespace:     "ids",
+		WatchInterval: 20 * time.Millisecond,
+	})
+	var offset int64
+	allocator.now = func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) }
+	return allocator, client, func(d time.Duration) { atomic.AddInt64(&offset, int64(d)) }
+}
+
+func TestKubernetesLeaseWorkerIDAllocator_Conformance(t *testing.T) {
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		allocator, _, advance := newFakeLeaseAllocator(t)
+		return allocator, advance
+	})
+}
+
+func TestKubernetesLeaseWorkerIDAllocator_LeaseObject(t *testing.T) {
+	allocator, client, advance := newFakeLeaseAllocator(t)
+	ctx := context.Background()
+
+	info := WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "pod-a", ServiceName: "orders"}
+	_, err := allocator.Acquire(ctx, info, 31, 30*time.Second)
+	require.NoError(t, err)
+
+	lease, err := client.CoordinationV1().Leases("ids").Get(ctx, "eon-id-dc-2-worker-9", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "pod-a", *lease.Spec.HolderIdentity)
+	assert.Equal(t, int32(30), *lease.Spec.LeaseDurationSeconds)
+	assert.Equal(t, kubernetesManagedByValue, lease.Labels[kubernetesManagedByLabel])
+	stored, err := ParseWorkerInfo(lease.Annotations[kubernetesWorkerInfoKey])
+	require.NoError(t, err)
+	assert.Equal(t, info, *stored)
+
+	// An expired Lease is taken over in place and counts as a transition
+	advance(time.Minute)
+	_, err = allocator.Acquire(ctx, WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "pod-b"}, 31, 30*time.Second)
+	require.NoError(t, err)
+	lease, err = client.CoordinationV1().Leases("ids").Get(ctx, "eon-id-dc-2-worker-9", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "pod-b", *lease.Spec.HolderIdentity)
+	assert.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+
+	var lost *WorkerLeaseLostError
+	require.True(t, errors.As(allocator.Renew(ctx, info, 30*time.Second), &lost))
+	assert.False(t, lost.Expired)
+}
+
+func TestValidateKubernetesLeasePrefix(t *testing.T) {
+	assert.NoError(t, ValidateKubernetesLeasePrefix(""))
+	assert.NoError(t, ValidateKubernetesLeasePrefix("orders-ids"))
+	assert.Error(t, ValidateKubernetesLeasePrefix("Orders"))
+	assert.Error(t, ValidateKubernetesLeasePrefix("orders-"))
+	assert.Error(t, ValidateKubernetesLeasePrefix("lynx:eon-id"))
+}
+
+func TestStatefulSetOrdinal(t *testing.T) {
+	ordinal, err := ParseStatefulSetOrdinal("orders-12")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), ordinal)
+	for _, name := range []string{"orders", "orders-", "orders-x", "orders-1a"} {
+		_, err := ParseStatefulSetOrdinal(name)
+		assert.Error(t, err, name)
+	}
+
+	t.Setenv("EON_TEST_POD", "web-ids-7")
+	ordinal, err = StatefulSetOrdinal("EON_TEST_POD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), ordinal)
+
+	// The pod-index label is exposed as the bare ordinal
+	t.Setenv("EON_TEST_POD", "4")
+	ordinal, err = StatefulSetOrdinal("EON_TEST_POD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), ordinal)
+}
+
+func TestStatefulSetWorkerIDAllocator(t *testing.T) {
+	ctx := context.Background()
+	a := NewStatefulSetWorkerIDAllocator(5)
+
+	_, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "pod"}, 3, time.Minute)
+	assert.Error(t, err, "ordinal above max worker ID")
+
+	var conflict *WorkerIDConflictError
+	_, err = a.Acquire(ctx, WorkerInfo{WorkerID: 2, DatacenterID: 1, InstanceID: "pod"}, 31, time.Minute)
+	require.True(t, errors.As(err, &conflict), "a configured worker ID must match the ordinal")
+
+	info, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "pod"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), info.WorkerID)
+	require.NoError(t, a.Renew(ctx, *info, time.Minute))
+	workers, err := a.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []WorkerInfo{*info}, workers)
+
+	_, err = a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "other"}, 31, time.Minute)
+	require.True(t, errors.As(err, &conflict), "one holder per process")
+
+	require.NoError(t, a.Release(ctx, *info))
+	var lost *WorkerLeaseLostError
+	require.True(t, errors.As(a.Renew(ctx, *info, time.Minute), &lost))
+	assert.True(t, lost.Expired)
+
+	// Through the manager the ordinal becomes the worker ID
+	mgr := NewWorkerIDManagerWithAllocator(a, 1, &WorkerManagerConfig{TTL: time.Minute, HeartbeatInterval: 10 * time.Second})
+	workerID, err := mgr.RegisterWorkerID(ctx, 31)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), workerID)
+	require.NoError(t, mgr.UnregisterWorkerID(ctx))
+}
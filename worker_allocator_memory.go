@@ -0,0 +1,249 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryWorkerIDAllocator keeps worker ID claims in process memory. It only coordinates generators
+// that share the allocator, so it suits single-instance deployments and tests.
+type MemoryWorkerIDAllocator struct {
+	mu            sync.Mutex
+	claims        map[workerClaimKey]memoryClaim
+	next          map[int64]int64          // per-datacenter round-robin cursor, like the Redis counter
+	lastTimestamp map[workerClaimKey]int64 // last issued timestamp per worker ID, kept after the claim ends
+	datacenters   map[string]int64         // datacenter name -> datacenter ID, see ResolveDatacenter
+	watchInterval time.Duration
+	now           func() time.Time
+}
+
+type memoryClaim struct {
+	info    WorkerInfo
+	expires time.Time
+}
+
+// NewMemoryWorkerIDAllocator creates an in-process worker ID allocator
+func NewMemoryWorkerIDAllocator() *MemoryWorkerIDAllocator {
+	return &MemoryWorkerIDAllocator{
+		claims:        make(map[workerClaimKey]memoryClaim),
+		next:          make(map[int64]int64),
+		lastTimestamp: make(map[workerClaimKey]int64),
+		datacenters:   make(map[string]int64),
+		watchInterval: 100 * time.Millisecond,
+		now:           time.Now,
+	}
+}
+
+// Name returns "memory"
+func (a *MemoryWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorMemory
+}
+
+// Acquire claims a worker ID
+func (a *MemoryWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if info.WorkerID >= 0 {
+		if a.heldLocked(info.DatacenterID, info.WorkerID, now) {
+			return nil, &WorkerIDConflictError{
+				WorkerID:     info.WorkerID,
+				DatacenterID: info.DatacenterID,
+				ConflictWith: "another instance",
+			}
+		}
+		a.previousTimestampLocked(&info)
+		a.claims[workerClaimKey{info.DatacenterID, info.WorkerID}] = memoryClaim{info: info, expires: now.Add(ttl)}
+		return &info, nil
+	}
+	if maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+
+	total := maxWorkerID + 1
+	for i := int64(0); i < total; i++ {
+		workerID := (a.next[info.DatacenterID] + i) % total
+		if a.heldLocked(info.DatacenterID, workerID, now) {
+			continue
+		}
+		a.next[info.DatacenterID] = (workerID + 1) % total
+		claimed := info
+		claimed.WorkerID = workerID
+		a.previousTimestampLocked(&claimed)
+		a.claims[workerClaimKey{info.DatacenterID, workerID}] = memoryClaim{info: claimed, expires: now.Add(ttl)}
+		return &claimed, nil
+	}
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", total)
+}
+
+// heldLocked reports whether a live claim exists and drops it if it has expired. Caller must hold a.mu.
+func (a *MemoryWorkerIDAllocator) heldLocked(datacenterID, workerID int64, now time.Time) bool {
+	key := workerClaimKey{datacenterID, workerID}
+	claim, ok := a.claims[key]
+	if ok && !now.Before(claim.expires) {
+		delete(a.claims, key)
+		return false
+	}
+	return ok
+}
+
+// recordTimestampLocked raises the last issued timestamp of info's worker ID. Caller must hold a.mu.
+func (a *MemoryWorkerIDAllocator) recordTimestampLocked(info WorkerInfo) {
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	if info.LastTimestamp > a.lastTimestamp[key] {
+		a.lastTimestamp[key] = info.LastTimestamp
+	}
+}
+
+// previousTimestampLocked sets info.LastTimestamp to the timestamp recorded for its worker ID if that is later.
+// Caller must hold a.mu.
+func (a *MemoryWorkerIDAllocator) previousTimestampLocked(info *WorkerInfo) {
+	if last := a.lastTimestamp[workerClaimKey{info.DatacenterID, info.WorkerID}]; last > info.LastTimestamp {
+		info.LastTimestamp = last
+	}
+}
+
+// Renew extends a claim held by info.InstanceID and records info.LastTimestamp
+func (a *MemoryWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recordTimestampLocked(info)
+
+	now := a.now()
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	if !a.heldLocked(info.DatacenterID, info.WorkerID, now) {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+	claim := a.claims[key]
+	if claim.info.InstanceID != info.InstanceID {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	if claim.info.Revocation != nil {
+		return &WorkerRevokedError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Revocation: *claim.info.Revocation}
+	}
+	a.claims[key] = memoryClaim{info: info, expires: now.Add(ttl)}
+	return nil
+}
+
+// RevokeWorker marks a live claim revoked; it keeps its expiry until the owner releases it
+func (a *MemoryWorkerIDAllocator) RevokeWorker(ctx context.Context, datacenterID, workerID int64, revocation WorkerRevocation) (*WorkerInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.heldLocked(datacenterID, workerID, a.now()) {
+		return nil, nil
+	}
+	key := workerClaimKey{datacenterID, workerID}
+	claim := a.claims[key]
+	if claim.info.Revocation == nil {
+		if revocation.At == 0 {
+			revocation.At = a.now().UnixMilli()
+		}
+		claim.info.Revocation = &revocation
+		a.claims[key] = claim
+	}
+	revoked := claim.info
+	revocationCopy := *claim.info.Revocation
+	revoked.Revocation = &revocationCopy
+	return &revoked, nil
+}
+
+// ResolveDatacenter returns the datacenter ID of name, mapping a new name to the lowest free datacenter ID
+func (a *MemoryWorkerIDAllocator) ResolveDatacenter(ctx context.Context, name string, maxDatacenterID int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if datacenterID, ok := a.datacenters[name]; ok {
+		return datacenterID, nil
+	}
+	used := make(map[int64]bool, len(a.datacenters))
+	for _, datacenterID := range a.datacenters {
+		used[datacenterID] = true
+	}
+	for datacenterID := int64(0); datacenterID <= maxDatacenterID; datacenterID++ {
+		if !used[datacenterID] {
+			a.datacenters[name] = datacenterID
+			return datacenterID, nil
+		}
+	}
+	return -1, &DatacenterIDsExhaustedError{Name: name, MaxDatacenterID: maxDatacenterID}
+}
+
+// Datacenters returns every datacenter name with its datacenter ID
+func (a *MemoryWorkerIDAllocator) Datacenters(ctx context.Context) (map[string]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	datacenters := make(map[string]int64, len(a.datacenters))
+	for name, datacenterID := range a.datacenters {
+		datacenters[name] = datacenterID
+	}
+	return datacenters, nil
+}
+
+// Release records info.LastTimestamp and removes a claim held by info.InstanceID
+func (a *MemoryWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recordTimestampLocked(info)
+
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	if claim, ok := a.claims[key]; ok && claim.info.InstanceID == info.InstanceID {
+		delete(a.claims, key)
+	}
+	return nil
+}
+
+// List returns the live claims ordered by datacenter and worker ID
+func (a *MemoryWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	workers := make([]WorkerInfo, 0, len(a.claims))
+	for key, claim := range a.claims {
+		if a.heldLocked(key.datacenterID, key.workerID, now) {
+			workers = append(workers, claim.info)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].DatacenterID != workers[j].DatacenterID {
+			return workers[i].DatacenterID < workers[j].DatacenterID
+		}
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	return workers, nil
+}
+
+// Watch polls the claims
+func (a *MemoryWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
@@ -0,0 +1,689 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/pkg/timex"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisWorkerIDAllocator claims worker IDs with Redis keys that expire after the TTL.
+// A Lua script finds and claims a free worker ID in one round trip using a sorted set per datacenter that
+// indexes worker IDs by claim expiry; the worker keys remain the source of truth. A Lua heartbeat script only
+// refreshes keys whose instance_id matches. The registry is a sorted set scored by each worker's last heartbeat;
+// List reads it in one round trip and TrimRegistry removes the members of expired claims.
+//
+// Claims, registry membership and releases are single scripts when all keys of a datacenter hash to one slot.
+// That always holds on a single Redis node, and on Redis Cluster with RedisKeyLayoutCluster or a hash-tagged key
+// prefix. Otherwise the allocator falls back to probing worker IDs with a Lua INCR counter and SetNX, and
+// updates the registry with separate commands.
+type RedisWorkerIDAllocator struct {
+	client        redis.UniversalClient
+	keyPrefix     string
+	layout        string
+	watchInterval time.Duration
+	singleSlot    bool // multi-key scripts over a datacenter's keys are safe
+	// now scores registry members that cannot be scored by a script with the server's TIME
+	now func() time.Time
+}
+
+// RedisWorkerIDAllocatorConfig holds configuration for the Redis allocator
+type RedisWorkerIDAllocatorConfig struct {
+	KeyPrefix     string
+	KeyLayout     string        // RedisKeyLayoutLegacy (default) or RedisKeyLayoutCluster
+	WatchInterval time.Duration // registry polling interval for Watch (default: DefaultWorkerWatchInterval)
+}
+
+const (
+	// RedisKeyLayoutLegacy keeps keys as <prefix>dc:<n>:worker:<id> with one <prefix>registry set
+	RedisKeyLayoutLegacy = "legacy"
+	// RedisKeyLayoutCluster hash-tags every key with its datacenter, e.g. {<prefix>dc:<n>}:worker:<id>, and keeps
+	// one registry per datacenter, so that a datacenter's keys share a Redis Cluster slot
+	RedisKeyLayoutCluster = "cluster"
+)
+
+// NewRedisWorkerIDAllocator creates a Redis-backed worker ID allocator
+func NewRedisWorkerIDAllocator(client redis.UniversalClient, config *RedisWorkerIDAllocatorConfig) *RedisWorkerIDAllocator {
+	if config == nil {
+		config = &RedisWorkerIDAllocatorConfig{}
+	}
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = DefaultWorkerWatchInterval
+	}
+	keyPrefix := NormalizeKeyPrefix(config.KeyPrefix)
+	layout := stringOr(config.KeyLayout, RedisKeyLayoutLegacy)
+	return &RedisWorkerIDAllocator{
+		client:        client,
+		keyPrefix:     keyPrefix,
+		layout:        layout,
+		watchInterval: watchInterval,
+		singleSlot:    !isShardedClient(client) || layout == RedisKeyLayoutCluster || hasHashTag(keyPrefix),
+		now:           time.Now,
+	}
+}
+
+// ValidateRedisKeyLayout checks a redis_key_layout value
+func ValidateRedisKeyLayout(layout string) error {
+	switch layout {
+	case "", RedisKeyLayoutLegacy, RedisKeyLayoutCluster:
+		return nil
+	}
+	return fmt.Errorf("unsupported Redis key layout %q, supported: %s, %s", layout, RedisKeyLayoutLegacy, RedisKeyLayoutCluster)
+}
+
+// isShardedClient reports whether client spreads keys over several nodes
+func isShardedClient(client redis.UniversalClient) bool {
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		return true
+	}
+	return false
+}
+
+// Name returns "redis"
+func (a *RedisWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorRedis
+}
+
+// Client returns the underlying Redis client
+func (a *RedisWorkerIDAllocator) Client() redis.UniversalClient {
+	return a.client
+}
+
+// Acquire claims a worker ID: the requested one if info.WorkerID >= 0, otherwise any free one in [0, maxWorkerID]
+func (a *RedisWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if info.WorkerID >= 0 {
+		return a.acquireSpecific(ctx, info, ttl)
+	}
+	if maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+	if a.singleSlot {
+		return a.acquireFromIndex(ctx, info, maxWorkerID, ttl)
+	}
+	return a.acquireByProbing(ctx, info, maxWorkerID, ttl)
+}
+
+// acquireFromIndex claims the first free or expired worker ID of the slot index with LuaScriptAcquireSlot
+func (a *RedisWorkerIDAllocator) acquireFromIndex(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	totalWorkerIDs := maxWorkerID + 1
+	template := info
+	template.WorkerID = -1 // replaced by the script
+	lastTimestampBefore, lastTimestampAfter := a.lastTimestampKeyParts(info.DatacenterID)
+
+	result, err := a.client.Eval(ctx, LuaScriptAcquireSlot, []string{a.slotIndexKey(info.DatacenterID), a.registryKey(info.DatacenterID)},
+		a.workerKeyPrefix(info.DatacenterID), lastTimestampBefore, lastTimestampAfter,
+		totalWorkerIDs, ttl.Milliseconds(), template.String(), fmt.Sprintf("%d:", info.DatacenterID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute acquire script: %w", err)
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, fmt.Errorf("acquire script returned unexpected result %v", result)
+	}
+	workerID, err := redisResultToInt64(values[0])
+	if err != nil {
+		return nil, fmt.Errorf("acquire script result: %w", err)
+	}
+	if workerID < 0 {
+		return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", totalWorkerIDs)
+	}
+	last, err := redisResultToInt64(values[1])
+	if err != nil {
+		return nil, fmt.Errorf("acquire script result: %w", err)
+	}
+
+	claimed := info
+	claimed.WorkerID = workerID
+	if last > claimed.LastTimestamp {
+		claimed.LastTimestamp = last
+	}
+	a.addDatacenter(ctx, info.DatacenterID)
+	log.Debugf("claimed worker ID %d in Redis from the slot index", workerID)
+	return &claimed, nil
+}
+
+// acquireByProbing claims a worker ID without the slot index.
+// Flow: INCR to get workerID -> if exceeds max, reset to 0 -> SetNX to verify -> retry until full cycle
+func (a *RedisWorkerIDAllocator) acquireByProbing(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	counterKey := a.counterKey(info.DatacenterID)
+	totalWorkerIDs := maxWorkerID + 1 // Total available worker IDs (0 to maxWorkerID)
+	maxRetries := int(totalWorkerIDs) // Try each worker ID at most once (full cycle)
+
+	// Loop to try acquiring an available worker ID
+	for retryCount := 0; retryCount < maxRetries; retryCount++ {
+		// Check if context is cancelled
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		// 1. Atomic INCR with auto-reset using Lua script
+		// If counter exceeds max, reset to 1 and return 1
+		// This prevents race condition when multiple instances try to reset simultaneously
+		result, err := a.client.Eval(ctx, LuaScriptIncrWithReset, []string{counterKey}, totalWorkerIDs).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute INCR script: %w", err)
+		}
+		seq, err := redisResultToInt64(result)
+		if err != nil {
+			return nil, fmt.Errorf("INCR script result: %w", err)
+		}
+
+		// 2. workerID = seq - 1 (0-based), SetNX to verify this worker ID is available
+		candidate := info
+		candidate.WorkerID = seq - 1
+		ok, err := a.setNX(ctx, &candidate, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			log.Debugf("claimed worker ID %d in Redis after %d attempt(s)", candidate.WorkerID, retryCount+1)
+			return &candidate, nil
+		}
+
+		// SetNX failed, this worker ID is already taken
+		log.Debugf("worker ID %d is taken, attempt %d/%d", candidate.WorkerID, retryCount+1, maxRetries)
+
+		// Backoff sleep: random 10-50ms to prevent retry storms.
+		backoff := timex.RandomDuration(10*time.Millisecond, 50*time.Millisecond)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	// All worker IDs are taken after a full cycle
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", totalWorkerIDs)
+}
+
+func (a *RedisWorkerIDAllocator) acquireSpecific(ctx context.Context, info WorkerInfo, ttl time.Duration) (*WorkerInfo, error) {
+	ok, err := a.setNX(ctx, &info, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: "another instance",
+		}
+	}
+	return &info, nil
+}
+
+// setNX writes the worker key if it does not exist and adds the worker to the registry set (for monitoring).
+// On success info.LastTimestamp is set to the timestamp recorded by the previous owner of the worker ID.
+func (a *RedisWorkerIDAllocator) setNX(ctx context.Context, info *WorkerInfo, ttl time.Duration) (bool, error) {
+	if a.singleSlot {
+		return a.claimWorker(ctx, info, ttl)
+	}
+
+	key := a.workerKey(info.DatacenterID, info.WorkerID)
+	ok, err := a.client.SetNX(ctx, key, info.String(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to SetNX worker ID %d: %w", info.WorkerID, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	a.touchRegistry(ctx, *info)
+
+	last, err := a.LastTimestamp(ctx, info.DatacenterID, info.WorkerID)
+	if err != nil {
+		// Without the previous owner's timestamp the worker ID cannot be reused safely; give it back
+		_ = a.Release(ctx, *info)
+		return false, err
+	}
+	if last > info.LastTimestamp {
+		info.LastTimestamp = last
+	}
+	return true, nil
+}
+
+// claimWorker is setNX as one script: the claim, its slot index score, registry membership and the previous
+// owner's last timestamp
+func (a *RedisWorkerIDAllocator) claimWorker(ctx context.Context, info *WorkerInfo, ttl time.Duration) (bool, error) {
+	dc, id := info.DatacenterID, info.WorkerID
+	result, err := a.client.Eval(ctx, LuaScriptAcquireWorker,
+		[]string{a.workerKey(dc, id), a.lastTimestampKey(dc, id), a.slotIndexKey(dc), a.registryKey(dc)},
+		info.String(), ttl.Milliseconds(), id, registryMember(dc, id)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim worker ID %d: %w", id, err)
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, fmt.Errorf("claim script returned unexpected result %v", result)
+	}
+	claimed, err := redisResultToInt64(values[0])
+	if err != nil {
+		return false, fmt.Errorf("claim script result: %w", err)
+	}
+	if claimed != 1 {
+		return false, nil
+	}
+	last, err := redisResultToInt64(values[1])
+	if err != nil {
+		return false, fmt.Errorf("claim script result: %w", err)
+	}
+	if last > info.LastTimestamp {
+		info.LastTimestamp = last
+	}
+	a.addDatacenter(ctx, dc)
+	return true, nil
+}
+
+// Renew atomically verifies instance_id and refreshes the key and its TTL, recording info.LastTimestamp
+func (a *RedisWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if a.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	keys := []string{a.workerKey(info.DatacenterID, info.WorkerID), a.lastTimestampKey(info.DatacenterID, info.WorkerID)}
+	args := []interface{}{info.String(), info.InstanceID, int64(ttl.Seconds()), info.LastTimestamp}
+	if a.singleSlot {
+		keys = append(keys, a.slotIndexKey(info.DatacenterID), a.registryKey(info.DatacenterID))
+		args = append(args, info.WorkerID, registryMember(info.DatacenterID, info.WorkerID))
+	}
+	result, err := a.client.Eval(ctx, LuaScriptHeartbeat, keys, args...).Result()
+	if err != nil {
+		return fmt.Errorf("heartbeat script execution failed: %w", err)
+	}
+
+	code, err := redisResultToInt64(result)
+	if err != nil {
+		return fmt.Errorf("heartbeat script result: %w", err)
+	}
+	switch code {
+	case 1:
+		if !a.singleSlot {
+			a.touchRegistry(ctx, info)
+		}
+		return nil // Success
+	case 0:
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	case -1:
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	case -2:
+		return fmt.Errorf("worker ID %d has invalid JSON format", info.WorkerID)
+	case -3:
+		revoked := &WorkerRevokedError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+		// The details are informational; the script's verdict stands even if they cannot be read
+		if value, err := a.client.Get(ctx, a.workerKey(info.DatacenterID, info.WorkerID)).Result(); err == nil {
+			if claim, err := ParseWorkerInfo(value); err == nil && claim.Revocation != nil {
+				revoked.Revocation = *claim.Revocation
+			}
+		}
+		return revoked
+	default:
+		return fmt.Errorf("heartbeat returned unknown status: %d", code)
+	}
+}
+
+// Release records the last issued timestamp, then deletes the worker key and removes it from the registry only
+// when the key's instance_id matches (or the key is already gone)
+func (a *RedisWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	if a.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	keys := []string{a.workerKey(info.DatacenterID, info.WorkerID), a.lastTimestampKey(info.DatacenterID, info.WorkerID)}
+	args := []interface{}{info.InstanceID, info.LastTimestamp}
+	if a.singleSlot {
+		keys = append(keys, a.slotIndexKey(info.DatacenterID), a.registryKey(info.DatacenterID))
+		args = append(args, info.WorkerID, registryMember(info.DatacenterID, info.WorkerID))
+	}
+	result, err := a.client.Eval(ctx, LuaScriptRelease, keys, args...).Result()
+	if err != nil {
+		return fmt.Errorf("release script execution failed: %w", err)
+	}
+	code, err := redisResultToInt64(result)
+	if err != nil {
+		return fmt.Errorf("release script result: %w", err)
+	}
+	switch code {
+	case 1, -1:
+		// Deleted, or already expired; remove from registry either way (idempotent); the script did it already
+		// when the registry shares the worker key's slot
+		if !a.singleSlot {
+			_ = a.client.ZRem(ctx, a.registryKey(info.DatacenterID), registryMember(info.DatacenterID, info.WorkerID)).Err()
+		}
+	}
+	// 0: another instance took this worker ID; do not delete or ZRem
+	return nil
+}
+
+// LastTimestamp returns the last issued timestamp (Unix ms) recorded for a worker ID, or 0 if none is.
+// The record outlives the worker key.
+func (a *RedisWorkerIDAllocator) LastTimestamp(ctx context.Context, datacenterID, workerID int64) (int64, error) {
+	if a.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	ts, err := a.client.Get(ctx, a.lastTimestampKey(datacenterID, workerID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read last timestamp of worker ID %d: %w", workerID, err)
+	}
+	return ts, nil
+}
+
+// List returns all registered workers in one round trip per node: a script reads each registry and its worker
+// keys, or, when the registry and worker keys are on different cluster nodes, one pipeline GETs the worker keys.
+// Members whose worker key is gone are skipped; TrimRegistry removes them.
+func (a *RedisWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+
+	registries, err := a.registryKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	if a.singleSlot {
+		beforeDC, beforeID := a.workerKeyParts()
+		pipe := a.client.Pipeline()
+		cmds := make([]*redis.Cmd, len(registries))
+		for i, registry := range registries {
+			cmds[i] = pipe.Eval(ctx, LuaScriptListWorkers, []string{registry}, beforeDC, beforeID)
+		}
+		if len(cmds) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return nil, fmt.Errorf("failed to list workers: %w", err)
+			}
+		}
+		for _, cmd := range cmds {
+			workers, err := cmd.StringSlice()
+			if err != nil && err != redis.Nil {
+				return nil, fmt.Errorf("failed to list workers: %w", err)
+			}
+			values = append(values, workers...)
+		}
+	} else {
+		members, err := a.client.ZRange(ctx, registries[0], 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get registry members: %w", err)
+		}
+		pipe := a.client.Pipeline()
+		var cmds []*redis.StringCmd
+		for _, member := range members {
+			if datacenterID, workerID, ok := parseRegistryMember(member); ok {
+				cmds = append(cmds, pipe.Get(ctx, a.workerKey(datacenterID, workerID)))
+			}
+		}
+		if len(cmds) > 0 {
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return nil, fmt.Errorf("failed to read workers: %w", err)
+			}
+		}
+		for _, cmd := range cmds {
+			if value, err := cmd.Result(); err == nil {
+				values = append(values, value)
+			}
+		}
+	}
+
+	var workers []WorkerInfo
+	for _, value := range values {
+		workerInfo, err := ParseWorkerInfo(value)
+		if err != nil {
+			continue
+		}
+		workers = append(workers, *workerInfo)
+	}
+	return workers, nil
+}
+
+// TrimRegistry removes the registry members of claims that have expired: members whose last heartbeat is older
+// than staleAfter and whose worker key is gone. It returns the number of members removed.
+func (a *RedisWorkerIDAllocator) TrimRegistry(ctx context.Context, staleAfter time.Duration) (int, error) {
+	if a.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+
+	registries, err := a.registryKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !a.singleSlot {
+		return a.trimRegistryByCommands(ctx, registries[0], staleAfter)
+	}
+	beforeDC, beforeID := a.workerKeyParts()
+	removed := 0
+	for _, registry := range registries {
+		n, err := a.client.Eval(ctx, LuaScriptTrimRegistry, []string{registry}, beforeDC, beforeID, staleAfter.Milliseconds()).Int()
+		if err != nil {
+			return removed, fmt.Errorf("failed to trim registry %s: %w", registry, err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// trimRegistryByCommands is TrimRegistry for a registry whose worker keys are on other cluster nodes. The
+// cutoff is taken from the local clock.
+func (a *RedisWorkerIDAllocator) trimRegistryByCommands(ctx context.Context, registry string, staleAfter time.Duration) (int, error) {
+	cutoff := a.now().Add(-staleAfter).UnixMilli()
+	members, err := a.client.ZRangeByScore(ctx, registry, &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(cutoff, 10)}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stale registry members: %w", err)
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+	pipe := a.client.Pipeline()
+	exists := make([]*redis.IntCmd, len(members))
+	for i, member := range members {
+		if datacenterID, workerID, ok := parseRegistryMember(member); ok {
+			exists[i] = pipe.Exists(ctx, a.workerKey(datacenterID, workerID))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to check stale registry members: %w", err)
+	}
+	var stale []interface{}
+	for i, member := range members {
+		if exists[i] == nil || exists[i].Val() == 0 {
+			stale = append(stale, member)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	// A heartbeat between the check and ZREM only re-adds its member on the next renewal
+	removed, err := a.client.ZRem(ctx, registry, stale...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale registry members: %w", err)
+	}
+	return int(removed), nil
+}
+
+// Watch polls the registry; Redis keyspace notifications are not enabled by default
+func (a *RedisWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// registryKeys returns every registry: the single legacy one, or one per datacenter listed in the datacenters set
+func (a *RedisWorkerIDAllocator) registryKeys(ctx context.Context) ([]string, error) {
+	if a.layout != RedisKeyLayoutCluster {
+		return []string{a.registryKey(0)}, nil
+	}
+	datacenters, err := a.client.SMembers(ctx, a.datacentersKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get datacenters: %w", err)
+	}
+	var keys []string
+	for _, dc := range datacenters {
+		if datacenterID, err := strconv.ParseInt(dc, 10, 64); err == nil {
+			keys = append(keys, a.registryKey(datacenterID))
+		}
+	}
+	return keys, nil
+}
+
+// touchRegistry scores a worker's registry member with the local time, for claims and heartbeats that cannot
+// update the registry in their script
+func (a *RedisWorkerIDAllocator) touchRegistry(ctx context.Context, info WorkerInfo) {
+	member := redis.Z{Score: float64(a.now().UnixMilli()), Member: registryMember(info.DatacenterID, info.WorkerID)}
+	_ = a.client.ZAdd(ctx, a.registryKey(info.DatacenterID), member).Err()
+}
+
+// addDatacenter records a datacenter with claims in the cluster layout, so that List finds its registry
+func (a *RedisWorkerIDAllocator) addDatacenter(ctx context.Context, datacenterID int64) {
+	if a.layout == RedisKeyLayoutCluster {
+		_ = a.client.SAdd(ctx, a.datacentersKey(), datacenterID).Err()
+	}
+}
+
+// ResolveDatacenter returns the datacenter ID of name, mapping a new name to the lowest free datacenter ID with
+// LuaScriptResolveDatacenter
+func (a *RedisWorkerIDAllocator) ResolveDatacenter(ctx context.Context, name string, maxDatacenterID int64) (int64, error) {
+	return a.mapDatacenter(ctx, name, maxDatacenterID, -1)
+}
+
+// mapDatacenter runs LuaScriptResolveDatacenter; requested is the datacenter ID for a new name, or -1 for any
+func (a *RedisWorkerIDAllocator) mapDatacenter(ctx context.Context, name string, maxDatacenterID, requested int64) (int64, error) {
+	if a.client == nil {
+		return -1, fmt.Errorf("redis client is nil")
+	}
+	if maxDatacenterID < 0 {
+		return -1, fmt.Errorf("max datacenter ID must be non-negative, got %d", maxDatacenterID)
+	}
+	result, err := a.client.Eval(ctx, LuaScriptResolveDatacenter, []string{a.datacenterNamesKey()},
+		name, maxDatacenterID+1, requested).Result()
+	if err != nil {
+		return -1, fmt.Errorf("failed to execute datacenter script: %w", err)
+	}
+	datacenterID, err := redisResultToInt64(result)
+	if err != nil {
+		return -1, fmt.Errorf("datacenter script result: %w", err)
+	}
+	switch datacenterID {
+	case -1:
+		return -1, &DatacenterIDsExhaustedError{Name: name, MaxDatacenterID: maxDatacenterID}
+	case -2:
+		return -1, fmt.Errorf("datacenter ID %d is already mapped to another name", requested)
+	}
+	return datacenterID, nil
+}
+
+// Datacenters returns every datacenter name with its datacenter ID
+func (a *RedisWorkerIDAllocator) Datacenters(ctx context.Context) (map[string]int64, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	values, err := a.client.HGetAll(ctx, a.datacenterNamesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read datacenter names: %w", err)
+	}
+	datacenters := make(map[string]int64, len(values))
+	for name, value := range values {
+		datacenterID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("datacenter %q has invalid ID %q", name, value)
+		}
+		datacenters[name] = datacenterID
+	}
+	return datacenters, nil
+}
+
+// Key layout (keyPrefix is normalized via NormalizeKeyPrefix at creation time)
+func (a *RedisWorkerIDAllocator) workerKey(datacenterID, workerID int64) string {
+	return a.workerKeyPrefix(datacenterID) + strconv.FormatInt(workerID, 10)
+}
+
+func (a *RedisWorkerIDAllocator) workerKeyPrefix(datacenterID int64) string {
+	return a.datacenterKeyPrefix(datacenterID) + "worker:"
+}
+
+// datacenterKeyPrefix starts every key of a datacenter; the cluster layout hash-tags it
+func (a *RedisWorkerIDAllocator) datacenterKeyPrefix(datacenterID int64) string {
+	if a.layout == RedisKeyLayoutCluster {
+		return fmt.Sprintf("{%sdc:%d}:", a.keyPrefix, datacenterID)
+	}
+	return fmt.Sprintf("%sdc:%d:", a.keyPrefix, datacenterID)
+}
+
+// lastTimestampKey holds the last issued timestamp of a worker ID and has no TTL. The hash tag keeps it in the
+// worker key's slot so that one script can update both on Redis Cluster; a prefix with its own hash tag already
+// does that.
+func (a *RedisWorkerIDAllocator) lastTimestampKey(datacenterID, workerID int64) string {
+	before, after := a.lastTimestampKeyParts(datacenterID)
+	return before + strconv.FormatInt(workerID, 10) + after
+}
+
+// lastTimestampKeyParts returns the parts of lastTimestampKey around the worker ID
+func (a *RedisWorkerIDAllocator) lastTimestampKeyParts(datacenterID int64) (before, after string) {
+	if a.layout == RedisKeyLayoutCluster || hasHashTag(a.keyPrefix) {
+		return a.workerKeyPrefix(datacenterID), ":last_ts"
+	}
+	return "{" + a.workerKeyPrefix(datacenterID), "}:last_ts"
+}
+
+// slotIndexKey is the sorted set that scores every worker ID of a datacenter by the expiry of its claim
+func (a *RedisWorkerIDAllocator) slotIndexKey(datacenterID int64) string {
+	return a.datacenterKeyPrefix(datacenterID) + "slots"
+}
+
+func (a *RedisWorkerIDAllocator) counterKey(datacenterID int64) string {
+	return a.datacenterKeyPrefix(datacenterID) + "counter"
+}
+
+// registryKey is the sorted set of "<datacenterID>:<workerID>" members with a claim, scored by the last
+// heartbeat in Unix ms; the legacy layout keeps one for all datacenters. It replaces the <prefix>registry set
+// of earlier versions.
+func (a *RedisWorkerIDAllocator) registryKey(datacenterID int64) string {
+	if a.layout == RedisKeyLayoutCluster {
+		return a.datacenterKeyPrefix(datacenterID) + "workers"
+	}
+	return a.keyPrefix + "workers"
+}
+
+// workerKeyParts returns the parts of workerKey before the datacenter ID and before the worker ID
+func (a *RedisWorkerIDAllocator) workerKeyParts() (beforeDC, beforeID string) {
+	if a.layout == RedisKeyLayoutCluster {
+		return "{" + a.keyPrefix + "dc:", "}:worker:"
+	}
+	return a.keyPrefix + "dc:", ":worker:"
+}
+
+// datacentersKey lists the datacenters that have a registry in the cluster layout
+func (a *RedisWorkerIDAllocator) datacentersKey() string {
+	return a.keyPrefix + "datacenters"
+}
+
+// datacenterNamesKey is the hash that maps datacenter names to datacenter IDs; it is shared by all datacenters
+func (a *RedisWorkerIDAllocator) datacenterNamesKey() string {
+	return a.keyPrefix + "datacenter_names"
+}
+
+// hasHashTag reports whether Redis Cluster hashes key by a {...} section rather than the whole key
+func hasHashTag(key string) bool {
+	open := strings.Index(key, "{")
+	if open < 0 {
+		return false
+	}
+	return strings.Index(key[open+1:], "}") > 0
+}
+
+func registryMember(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%d:%d", datacenterID, workerID)
+}
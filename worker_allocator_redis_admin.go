@@ -0,0 +1,226 @@
+package eonId
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisWorkerRecord is everything the Redis backend stores about one worker ID, for inspection by operators
+type RedisWorkerRecord struct {
+	DatacenterID int64 `json:"datacenter_id"`
+	WorkerID     int64 `json:"worker_id"`
+	// Claim is the stored WorkerInfo, nil when the worker ID is free
+	Claim *WorkerInfo `json:"claim,omitempty"`
+	// TTL is the time left on the claim
+	TTL time.Duration `json:"ttl"`
+	// Registered reports whether the worker ID is in the registry; LastHeartbeat is its registry score (Unix ms)
+	Registered    bool  `json:"registered"`
+	LastHeartbeat int64 `json:"last_heartbeat,omitempty"`
+	// LastTimestamp is the last-timestamp mark (Unix ms), which outlives the claim
+	LastTimestamp int64 `json:"last_timestamp"`
+}
+
+// Workers returns the record of every registered worker, read with one pipeline after List
+func (a *RedisWorkerIDAllocator) Workers(ctx context.Context) ([]RedisWorkerRecord, error) {
+	workers, err := a.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]RedisWorkerRecord, 0, len(workers))
+	for _, info := range workers {
+		records = append(records, RedisWorkerRecord{DatacenterID: info.DatacenterID, WorkerID: info.WorkerID})
+	}
+	if err := a.readRecords(ctx, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// InspectWorker returns the record of one worker ID, whether or not it is claimed
+func (a *RedisWorkerIDAllocator) InspectWorker(ctx context.Context, datacenterID, workerID int64) (*RedisWorkerRecord, error) {
+	records := []RedisWorkerRecord{{DatacenterID: datacenterID, WorkerID: workerID}}
+	if err := a.readRecords(ctx, records); err != nil {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// readRecords fills in the claim, TTL, registry score and last-timestamp mark of each record in one pipeline
+func (a *RedisWorkerIDAllocator) readRecords(ctx context.Context, records []RedisWorkerRecord) error {
+	if a.client == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	type recordCmds struct {
+		claim, last *redis.StringCmd
+		ttl         *redis.DurationCmd
+		score       *redis.FloatCmd
+	}
+	cmds := make([]recordCmds, len(records))
+	pipe := a.client.Pipeline()
+	for i, r := range records {
+		cmds[i] = recordCmds{
+			claim: pipe.Get(ctx, a.workerKey(r.DatacenterID, r.WorkerID)),
+			ttl:   pipe.PTTL(ctx, a.workerKey(r.DatacenterID, r.WorkerID)),
+			score: pipe.ZScore(ctx, a.registryKey(r.DatacenterID), registryMember(r.DatacenterID, r.WorkerID)),
+			last:  pipe.Get(ctx, a.lastTimestampKey(r.DatacenterID, r.WorkerID)),
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to read worker records: %w", err)
+	}
+
+	for i := range records {
+		r, c := &records[i], cmds[i]
+		if value, err := c.claim.Result(); err == nil {
+			claim, err := ParseWorkerInfo(value)
+			if err != nil {
+				return fmt.Errorf("worker ID %d in datacenter %d: %w", r.WorkerID, r.DatacenterID, err)
+			}
+			r.Claim = claim
+			if ttl := c.ttl.Val(); ttl > 0 {
+				r.TTL = ttl
+			}
+		}
+		if score, err := c.score.Result(); err == nil {
+			r.Registered = true
+			r.LastHeartbeat = int64(score)
+		}
+		if last, err := c.last.Int64(); err == nil {
+			r.LastTimestamp = last
+		}
+	}
+	return nil
+}
+
+// EvictWorker deletes the claim on a worker ID whoever holds it and returns the evicted WorkerInfo, or nil if
+// the worker ID was free. The last-timestamp mark is kept, so the next owner still waits for the evicted
+// owner's clock. An owner that is still running sees its claim expired at the next heartbeat and registers
+// again, possibly with the same worker ID; evict claims of instances that are gone.
+func (a *RedisWorkerIDAllocator) EvictWorker(ctx context.Context, datacenterID, workerID int64) (*WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	value, err := a.client.Get(ctx, a.workerKey(datacenterID, workerID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker ID %d: %w", workerID, err)
+	}
+	owner, err := ParseWorkerInfo(value)
+	if err != nil {
+		return nil, fmt.Errorf("worker ID %d in datacenter %d: %w", workerID, datacenterID, err)
+	}
+	owner.DatacenterID, owner.WorkerID = datacenterID, workerID
+
+	// Release deletes the claim only if it still belongs to the owner read above
+	if err := a.Release(ctx, *owner); err != nil {
+		return nil, err
+	}
+	current, err := a.client.Get(ctx, a.workerKey(datacenterID, workerID)).Result()
+	if err == nil {
+		if info, err := ParseWorkerInfo(current); err == nil && info.InstanceID != owner.InstanceID {
+			return nil, &WorkerIDConflictError{WorkerID: workerID, DatacenterID: datacenterID, ConflictWith: info.InstanceID}
+		}
+		return nil, fmt.Errorf("worker ID %d in datacenter %d is still claimed", workerID, datacenterID)
+	}
+	if err != redis.Nil {
+		return nil, fmt.Errorf("failed to read worker ID %d: %w", workerID, err)
+	}
+	return owner, nil
+}
+
+// RevokeWorker marks the claim on a worker ID revoked, whoever holds it, and returns the revoked claim, or nil
+// if the worker ID is free. Unlike EvictWorker the claim stays until its owner has seen the revocation at its
+// next heartbeat, stopped issuing IDs and released the worker ID, or until the claim expires; no other instance
+// can take the worker ID in the meantime.
+func (a *RedisWorkerIDAllocator) RevokeWorker(ctx context.Context, datacenterID, workerID int64, revocation WorkerRevocation) (*WorkerInfo, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if revocation.At == 0 {
+		revocation.At = time.Now().UnixMilli()
+	}
+	encoded, err := json.Marshal(revocation)
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.client.Eval(ctx, LuaScriptRevoke, []string{a.workerKey(datacenterID, workerID)}, "", string(encoded)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("revoke script execution failed: %w", err)
+	}
+	if value, ok := result.(string); ok {
+		return ParseWorkerInfo(value)
+	}
+	code, err := redisResultToInt64(result)
+	if err != nil {
+		return nil, fmt.Errorf("revoke script result: %w", err)
+	}
+	switch code {
+	case -1:
+		return nil, nil
+	case -2:
+		return nil, fmt.Errorf("worker ID %d has invalid JSON format", workerID)
+	default:
+		return nil, fmt.Errorf("revoke returned unknown status: %d", code)
+	}
+}
+
+// AssignDatacenter maps a new datacenter name to a chosen datacenter ID, e.g. to reserve the ID of a deployment
+// that still sets datacenter_id by hand. It fails if the name is mapped to another ID or the ID to another name;
+// assigning an existing mapping again is not an error.
+func (a *RedisWorkerIDAllocator) AssignDatacenter(ctx context.Context, name string, datacenterID, maxDatacenterID int64) error {
+	if datacenterID < 0 || datacenterID > maxDatacenterID {
+		return fmt.Errorf("datacenter ID must be between 0 and %d, got %d", maxDatacenterID, datacenterID)
+	}
+	mapped, err := a.mapDatacenter(ctx, name, maxDatacenterID, datacenterID)
+	if err != nil {
+		return err
+	}
+	if mapped != datacenterID {
+		return fmt.Errorf("datacenter %q is already mapped to datacenter ID %d", name, mapped)
+	}
+	return nil
+}
+
+// ResetCounters deletes the INCR counters that probing registration starts from, in every datacenter, and
+// returns how many it deleted. Probing then starts again at worker ID 0.
+func (a *RedisWorkerIDAllocator) ResetCounters(ctx context.Context) (int, error) {
+	if a.client == nil {
+		return 0, fmt.Errorf("redis client is nil")
+	}
+	dcPrefix := strings.TrimSuffix(a.counterKey(0), "0"+a.counterKeySuffix())
+	pattern := escapeRedisPattern(dcPrefix) + "*" + escapeRedisPattern(a.counterKeySuffix())
+	deleted := 0
+	err := scanRedisKeys(ctx, a.client, pattern, func(key string) error {
+		dc := strings.TrimSuffix(strings.TrimPrefix(key, dcPrefix), a.counterKeySuffix())
+		datacenterID, err := strconv.ParseInt(dc, 10, 64)
+		if err != nil || a.counterKey(datacenterID) != key {
+			return nil
+		}
+		n, err := a.client.Del(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+		deleted += int(n)
+		return nil
+	})
+	return deleted, err
+}
+
+// counterKeySuffix is the part of counterKey after the datacenter ID
+func (a *RedisWorkerIDAllocator) counterKeySuffix() string {
+	if a.layout == RedisKeyLayoutCluster {
+		return "}:counter"
+	}
+	return ":counter"
+}
@@ -0,0 +1,247 @@
+package eonId
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clusterSlotRanges splits the 16384 Redis Cluster slots over the nodes of newMiniredisCluster
+var clusterSlotRanges = [][2]int{{0, 5460}, {5461, 10922}, {10923, 16383}}
+
+// newMiniredisCluster stands in for a three-master Redis Cluster: the client routes every command by key slot,
+// and each node keeps only the keys of its own slots. Miniredis does not reject cross-slot scripts, so tests
+// check afterwards that every key sits on the node that owns its slot.
+func newMiniredisCluster(t *testing.T) (*redis.ClusterClient, []*miniredis.Miniredis, func(time.Duration)) {
+	nodes := make([]*miniredis.Miniredis, len(clusterSlotRanges))
+	slots := make([]redis.ClusterSlot, len(clusterSlotRanges))
+	now := time.Now()
+	for i, r := range clusterSlotRanges {
+		nodes[i] = miniredis.RunT(t)
+		nodes[i].SetTime(now)
+		slots[i] = redis.ClusterSlot{Start: r[0], End: r[1], Nodes: []redis.ClusterNode{{Addr: nodes[i].Addr()}}}
+	}
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) { return slots, nil },
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	return client, nodes, func(d time.Duration) {
+		now = now.Add(d)
+		for _, node := range nodes {
+			node.SetTime(now)
+			node.FastForward(d)
+		}
+	}
+}
+
+// assertKeysOnOwningNodes fails if a key was written to a node that does not own its slot, which on a real
+// cluster would have been a CROSSSLOT or MOVED error
+func assertKeysOnOwningNodes(t *testing.T, nodes []*miniredis.Miniredis) {
+	t.Helper()
+	for i, node := range nodes {
+		for _, key := range node.Keys() {
+			slot := clusterKeySlot(key)
+			assert.True(t, slot >= clusterSlotRanges[i][0] && slot <= clusterSlotRanges[i][1],
+				"key %q (slot %d) is on node %d", key, slot, i)
+		}
+	}
+}
+
+// clusterKeySlot is the Redis Cluster slot of key: CRC16 (XMODEM) of its hash tag, or of the whole key
+func clusterKeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+func TestClusterKeySlot(t *testing.T) {
+	// Values from the Redis Cluster specification and CLUSTER KEYSLOT
+	assert.Equal(t, 12739, clusterKeySlot("123456789"))
+	assert.Equal(t, clusterKeySlot("user1000"), clusterKeySlot("{user1000}.following"))
+	assert.Equal(t, clusterKeySlot("{}x"), clusterKeySlot("{}x"))
+	assert.NotEqual(t, clusterKeySlot("{lynx:eon-id:dc:1}:worker:5"), clusterKeySlot("lynx:eon-id:dc:1:worker:5"))
+}
+
+func TestRedisWorkerIDAllocator_ClusterConformance(t *testing.T) {
+	for _, layout := range []string{RedisKeyLayoutCluster, RedisKeyLayoutLegacy} {
+		t.Run(layout, func(t *testing.T) {
+			runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+				client, nodes, advance := newMiniredisCluster(t)
+				t.Cleanup(func() { assertKeysOnOwningNodes(t, nodes) })
+				return NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{
+					KeyPrefix:     "lynx:eon-id",
+					KeyLayout:     layout,
+					WatchInterval: 20 * time.Millisecond,
+				}), advance
+			})
+		})
+	}
+}
+
+func TestRedisWorkerIDAllocator_ClusterKeyLayout(t *testing.T) {
+	client, nodes, _ := newMiniredisCluster(t)
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "lynx:eon-id", KeyLayout: RedisKeyLayoutCluster})
+	require.True(t, a.singleSlot)
+	ctx := context.Background()
+
+	var claimed []WorkerInfo
+	for dc := int64(0); dc < 4; dc++ {
+		info, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: dc, InstanceID: "inst"}, 31, time.Minute)
+		require.NoError(t, err)
+		info.LastTimestamp = 1_700_000_000_000
+		require.NoError(t, a.Renew(ctx, *info, time.Minute))
+		claimed = append(claimed, *info)
+	}
+
+	// Every key of a datacenter shares the datacenter's hash tag, so each script touches a single slot
+	assert.Equal(t, "{lynx:eon-id:dc:1}:worker:0", a.workerKey(1, 0))
+	for _, key := range []string{a.lastTimestampKey(1, 0), a.slotIndexKey(1), a.registryKey(1), a.counterKey(1)} {
+		assert.True(t, strings.HasPrefix(key, "{lynx:eon-id:dc:1}:"), key)
+	}
+	assertKeysOnOwningNodes(t, nodes)
+
+	listed, err := a.List(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, claimed, listed)
+
+	for _, info := range claimed {
+		require.NoError(t, a.Release(ctx, info))
+	}
+	listed, err = a.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	for dc := int64(0); dc < 4; dc++ {
+		members, err := client.ZCard(ctx, a.registryKey(dc)).Result()
+		require.NoError(t, err)
+		assert.Zero(t, members, "release removes the registry member in the same script")
+		last, err := a.LastTimestamp(ctx, dc, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1_700_000_000_000), last)
+	}
+	assertKeysOnOwningNodes(t, nodes)
+}
+
+func TestRedisWorkerIDAllocator_MigrateKeyLayout(t *testing.T) {
+	client, nodes, _ := newMiniredisCluster(t)
+	ctx := context.Background()
+	legacy := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"})
+	cluster := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon", KeyLayout: RedisKeyLayoutCluster})
+
+	_, err := legacy.MigrateKeyLayout(ctx)
+	require.Error(t, err, "only a cluster layout allocator migrates")
+
+	// Two live legacy claims with marks, and a released worker ID whose mark must survive
+	live := WorkerInfo{WorkerID: 3, DatacenterID: 1, InstanceID: "old-a", LastTimestamp: 1_700_000_000_000}
+	contested := WorkerInfo{WorkerID: 5, DatacenterID: 2, InstanceID: "old-b", LastTimestamp: 1_700_000_000_100}
+	released := WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "old-c", LastTimestamp: 1_700_000_000_200}
+	for _, info := range []WorkerInfo{live, contested, released} {
+		_, err := legacy.Acquire(ctx, info, 31, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, legacy.Renew(ctx, info, time.Minute))
+	}
+	require.NoError(t, legacy.Release(ctx, released))
+	// An instance already on the cluster layout holds the contested worker ID
+	_, err = cluster.Acquire(ctx, WorkerInfo{WorkerID: 5, DatacenterID: 2, InstanceID: "new"}, 31, time.Minute)
+	require.NoError(t, err)
+
+	report, err := cluster.MigrateKeyLayout(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, KeyLayoutMigration{Claims: 1, ClaimsSkipped: 1, LastTimestamps: 3}, *report)
+	assertKeysOnOwningNodes(t, nodes)
+
+	// The copied claim keeps its owner and TTL and blocks the worker ID in the cluster layout
+	_, err = cluster.Acquire(ctx, WorkerInfo{WorkerID: 3, DatacenterID: 1, InstanceID: "new"}, 31, time.Minute)
+	require.Error(t, err)
+	ttl, err := client.PTTL(ctx, cluster.workerKey(1, 3)).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Second)
+	listed, err := cluster.List(ctx)
+	require.NoError(t, err)
+	owners := map[string]string{}
+	for _, info := range listed {
+		owners[registryMember(info.DatacenterID, info.WorkerID)] = info.InstanceID
+	}
+	assert.Equal(t, map[string]string{"1:3": "old-a", "2:5": "new"}, owners)
+
+	// Marks carry over, so the next owner of a migrated worker ID waits for the previous one's clock
+	for _, info := range []WorkerInfo{live, contested, released} {
+		last, err := cluster.LastTimestamp(ctx, info.DatacenterID, info.WorkerID)
+		require.NoError(t, err)
+		assert.Equal(t, info.LastTimestamp, last)
+	}
+
+	// Running it again changes nothing
+	report, err = cluster.MigrateKeyLayout(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, KeyLayoutMigration{Claims: 0, ClaimsSkipped: 2, LastTimestamps: 3}, *report)
+	assert.True(t, nodes[0].Exists(legacy.workerKey(1, 3)) || nodes[1].Exists(legacy.workerKey(1, 3)) ||
+		nodes[2].Exists(legacy.workerKey(1, 3)), "legacy keys are left in place")
+}
+
+func TestRedisWorkerIDAllocator_ClusterAdmin(t *testing.T) {
+	client, _, _ := newMiniredisCluster(t)
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon", KeyLayout: RedisKeyLayoutCluster})
+	ctx := context.Background()
+
+	var claimed []WorkerInfo
+	for dc := int64(0); dc < 3; dc++ {
+		info, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: dc, InstanceID: "inst"}, 31, time.Minute)
+		require.NoError(t, err)
+		info.LastTimestamp = 1_700_000_000_000
+		require.NoError(t, a.Renew(ctx, *info, time.Minute))
+		claimed = append(claimed, *info)
+		require.NoError(t, client.Set(ctx, a.counterKey(dc), 5, 0).Err())
+	}
+
+	records, err := a.Workers(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	for _, r := range records {
+		require.NotNil(t, r.Claim)
+		assert.Equal(t, "inst", r.Claim.InstanceID)
+		assert.Greater(t, r.TTL, 50*time.Second)
+		assert.True(t, r.Registered)
+		assert.Positive(t, r.LastHeartbeat)
+		assert.Equal(t, int64(1_700_000_000_000), r.LastTimestamp)
+	}
+
+	evicted, err := a.EvictWorker(ctx, 1, claimed[1].WorkerID)
+	require.NoError(t, err)
+	require.NotNil(t, evicted)
+	assert.Equal(t, "inst", evicted.InstanceID)
+	record, err := a.InspectWorker(ctx, 1, claimed[1].WorkerID)
+	require.NoError(t, err)
+	assert.Nil(t, record.Claim)
+	assert.False(t, record.Registered)
+	assert.Equal(t, int64(1_700_000_000_000), record.LastTimestamp, "eviction keeps the last-timestamp mark")
+	evicted, err = a.EvictWorker(ctx, 1, claimed[1].WorkerID)
+	require.NoError(t, err)
+	assert.Nil(t, evicted)
+
+	reset, err := a.ResetCounters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reset)
+	for dc := int64(0); dc < 3; dc++ {
+		assert.Zero(t, client.Exists(ctx, a.counterKey(dc)).Val())
+	}
+}
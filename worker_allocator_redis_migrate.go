@@ -0,0 +1,190 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyLayoutMigration reports what MigrateKeyLayout copied
+type KeyLayoutMigration struct {
+	Claims         int // live claims copied with their remaining TTL
+	ClaimsSkipped  int // live claims whose worker ID is already claimed in the cluster layout
+	LastTimestamps int // last-timestamp marks copied or raised
+}
+
+// MigrateKeyLayout copies the legacy key layout into the cluster layout of this allocator: live claims keep
+// their WorkerInfo and remaining TTL, and last-timestamp marks are raised to the legacy value, so worker ID
+// reuse protection carries over. Legacy keys are left in place. It is idempotent and may run while instances
+// on the cluster layout are registering; a worker ID claimed in both layouts keeps its cluster layout claim.
+//
+// Instances on different layouts do not see each other's claims. Switch the whole fleet at once: stop the
+// instances on the legacy layout, migrate, then start them on the cluster layout.
+func (a *RedisWorkerIDAllocator) MigrateKeyLayout(ctx context.Context) (*KeyLayoutMigration, error) {
+	if a.client == nil {
+		return nil, fmt.Errorf("redis client is nil")
+	}
+	if a.layout != RedisKeyLayoutCluster {
+		return nil, fmt.Errorf("key layout migration needs the %s layout, this allocator uses %s", RedisKeyLayoutCluster, a.layout)
+	}
+	legacy := NewRedisWorkerIDAllocator(a.client, &RedisWorkerIDAllocatorConfig{KeyPrefix: a.keyPrefix, KeyLayout: RedisKeyLayoutLegacy})
+	report := &KeyLayoutMigration{}
+
+	// Worker keys are scanned rather than read from the registry, which older versions kept as a plain set
+	beforeDC, beforeID := legacy.workerKeyParts()
+	pattern := escapeRedisPattern(beforeDC) + "*" + escapeRedisPattern(beforeID) + "*"
+	err := scanRedisKeys(ctx, a.client, pattern, func(key string) error {
+		datacenterID, workerID, ok := parseDatacenterKey(key, beforeDC, "", legacy.workerKey)
+		if !ok {
+			return nil
+		}
+		copied, err := a.migrateClaim(ctx, legacy, datacenterID, workerID)
+		if err != nil {
+			return err
+		}
+		if copied {
+			report.Claims++
+		} else {
+			report.ClaimsSkipped++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	before, after := legacy.lastTimestampKeyParts(0)
+	dcPrefix := strings.TrimSuffix(before, "0:worker:")
+	pattern = escapeRedisPattern(dcPrefix) + "*" + escapeRedisPattern(after)
+	err = scanRedisKeys(ctx, a.client, pattern, func(key string) error {
+		datacenterID, workerID, ok := parseDatacenterKey(key, dcPrefix, after, legacy.lastTimestampKey)
+		if !ok {
+			return nil
+		}
+		ts, err := a.client.Get(ctx, key).Int64()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		if err := a.client.Eval(ctx, LuaScriptRaiseTimestamp, []string{a.lastTimestampKey(datacenterID, workerID)}, ts).Err(); err != nil {
+			return fmt.Errorf("failed to copy last timestamp of worker ID %d: %w", workerID, err)
+		}
+		report.LastTimestamps++
+		return nil
+	})
+	return report, err
+}
+
+// migrateClaim copies one legacy claim; it returns false if the claim is gone or the worker ID is taken
+func (a *RedisWorkerIDAllocator) migrateClaim(ctx context.Context, legacy *RedisWorkerIDAllocator, datacenterID, workerID int64) (bool, error) {
+	key := legacy.workerKey(datacenterID, workerID)
+	value, err := a.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	ttl, err := a.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read the TTL of %s: %w", key, err)
+	}
+	switch {
+	case ttl == -2:
+		return false, nil // expired between GET and PTTL
+	case ttl <= 0:
+		ttl = DefaultWorkerIDTTL // no expiry
+	}
+	info, err := ParseWorkerInfo(value)
+	if err != nil {
+		return false, nil
+	}
+	info.DatacenterID, info.WorkerID = datacenterID, workerID
+	return a.claimWorker(ctx, info, ttl)
+}
+
+// parseDatacenterKey extracts the datacenter and worker IDs from a key "<dcPrefix><dc>...:worker:<id><suffix>"
+// that key(dc, id) builds; other keys matched by a SCAN pattern are rejected
+func parseDatacenterKey(key, dcPrefix, suffix string, build func(datacenterID, workerID int64) string) (datacenterID, workerID int64, ok bool) {
+	if !strings.HasPrefix(key, dcPrefix) || !strings.HasSuffix(key, suffix) {
+		return 0, 0, false
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(key, dcPrefix), suffix)
+	dc, worker, found := strings.Cut(rest, ":worker:")
+	if !found {
+		return 0, 0, false
+	}
+	datacenterID, err := strconv.ParseInt(dc, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	workerID, err = strconv.ParseInt(worker, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return datacenterID, workerID, build(datacenterID, workerID) == key
+}
+
+func parseRegistryMember(member string) (datacenterID, workerID int64, ok bool) {
+	dc, worker, found := strings.Cut(member, ":")
+	if !found {
+		return 0, 0, false
+	}
+	datacenterID, err := strconv.ParseInt(dc, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	workerID, err = strconv.ParseInt(worker, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return datacenterID, workerID, true
+}
+
+// escapeRedisPattern escapes the glob characters of a SCAN MATCH pattern
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// scanRedisKeys calls fn for every key matching pattern, on every master of a cluster or shard of a ring.
+// Nodes are scanned concurrently, but fn is never called concurrently.
+func scanRedisKeys(ctx context.Context, client redis.UniversalClient, pattern string, fn func(key string) error) error {
+	var mu sync.Mutex
+	scan := func(ctx context.Context, node redis.Cmdable) error {
+		iter := node.Scan(ctx, 0, pattern, 500).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			err := fn(iter.Val())
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", pattern, err)
+		}
+		return nil
+	}
+
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error { return scan(ctx, node) })
+	case *redis.Ring:
+		return c.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error { return scan(ctx, node) })
+	}
+	return scan(ctx, client)
+}
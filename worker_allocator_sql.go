@@ -0,0 +1,364 @@
+package eonId
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+)
+
+const (
+	// SQLDialectMySQL SQL dialects supported by the SQL allocator
+	SQLDialectMySQL    = "mysql"
+	SQLDialectPostgres = "postgres"
+	SQLDialectSQLite   = "sqlite"
+
+	// DefaultSQLPluginName is the shared resource name of the *sql.DB
+	DefaultSQLPluginName = "sql"
+	// DefaultSQLTableName is the worker node table, named after Baidu uid-generator's WORKER_NODE
+	DefaultSQLTableName = "WORKER_NODE"
+	// DefaultSQLSafetyDelay is how long an expired row keeps its worker ID before it can be reused
+	DefaultSQLSafetyDelay = time.Minute
+)
+
+//go:embed schema/worker_node_*.sql
+var workerNodeSchema embed.FS
+
+// sqlTableNamePattern allows an optionally schema-qualified identifier
+var sqlTableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// sqlDefaultTablePattern matches the table name in the shipped DDL but not the index names built from it
+var sqlDefaultTablePattern = regexp.MustCompile(`\bWORKER_NODE\b`)
+
+// ValidateSQLTableName checks that the table name can be used in queries without quoting
+func ValidateSQLTableName(table string) error {
+	if table != "" && !sqlTableNamePattern.MatchString(table) {
+		return fmt.Errorf("sql table name must be an identifier, got %q", table)
+	}
+	return nil
+}
+
+// ValidateSQLDialect checks that the dialect is supported; empty means mysql
+func ValidateSQLDialect(dialect string) error {
+	switch dialect {
+	case "", SQLDialectMySQL, SQLDialectPostgres, SQLDialectSQLite:
+		return nil
+	}
+	return fmt.Errorf("sql dialect must be %s, %s or %s, got %q", SQLDialectMySQL, SQLDialectPostgres, SQLDialectSQLite, dialect)
+}
+
+// WorkerNodeDDL returns the CREATE statements of the worker node table for a dialect, with table as the
+// table name. The same DDL ships in schema/worker_node_<dialect>.sql for DBAs.
+func WorkerNodeDDL(dialect, table string) ([]string, error) {
+	if err := ValidateSQLDialect(dialect); err != nil {
+		return nil, err
+	}
+	if err := ValidateSQLTableName(table); err != nil {
+		return nil, err
+	}
+	data, err := workerNodeSchema.ReadFile(fmt.Sprintf("schema/worker_node_%s.sql", stringOr(dialect, SQLDialectMySQL)))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	table = stringOr(table, DefaultSQLTableName)
+	ddl := strings.ReplaceAll(strings.Join(lines, "\n"), "IDX_WORKER_NODE_", "IDX_"+strings.ReplaceAll(table, ".", "_")+"_")
+	ddl = sqlDefaultTablePattern.ReplaceAllLiteralString(ddl, table)
+
+	var statements []string
+	for _, stmt := range strings.Split(ddl, ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements, nil
+}
+
+// SQLWorkerIDAllocator claims worker IDs with rows of a WORKER_NODE table, modelled on Baidu uid-generator.
+// Every Acquire inserts a row (host, port, service name, launch time) and takes the auto-increment ID
+// modulo the worker ID space as worker ID. A row holds its worker ID while LEASE_EXPIRES is in the future,
+// and for a safety delay after that, so a paused or partitioned instance cannot share the ID with its
+// successor. Lease times come from the local clock; the safety delay also absorbs clock skew between hosts.
+type SQLWorkerIDAllocator struct {
+	db            *sql.DB
+	dialect       string
+	table         string
+	port          string
+	safetyDelay   time.Duration
+	watchInterval time.Duration
+	now           func() time.Time
+}
+
+// SQLWorkerIDAllocatorConfig holds configuration for the SQL allocator
+type SQLWorkerIDAllocatorConfig struct {
+	Dialect       string        // "mysql" (default), "postgres" or "sqlite"
+	TableName     string        // default: DefaultSQLTableName
+	Port          string        // stored in PORT (default: the process ID)
+	SafetyDelay   time.Duration // default: DefaultSQLSafetyDelay
+	WatchInterval time.Duration // registry polling interval for Watch (default: DefaultWorkerWatchInterval)
+}
+
+// NewSQLWorkerIDAllocator creates a database/sql-backed worker ID allocator. The table must exist; see
+// WorkerNodeDDL and CreateTable.
+func NewSQLWorkerIDAllocator(db *sql.DB, config *SQLWorkerIDAllocatorConfig) *SQLWorkerIDAllocator {
+	if config == nil {
+		config = &SQLWorkerIDAllocatorConfig{}
+	}
+	safetyDelay := config.SafetyDelay
+	if safetyDelay <= 0 {
+		safetyDelay = DefaultSQLSafetyDelay
+	}
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = DefaultWorkerWatchInterval
+	}
+	return &SQLWorkerIDAllocator{
+		db:            db,
+		dialect:       stringOr(config.Dialect, SQLDialectMySQL),
+		table:         stringOr(config.TableName, DefaultSQLTableName),
+		port:          stringOr(config.Port, strconv.Itoa(os.Getpid())),
+		safetyDelay:   safetyDelay,
+		watchInterval: watchInterval,
+		now:           time.Now,
+	}
+}
+
+// Name returns "sql"
+func (a *SQLWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorSQL
+}
+
+// CreateTable creates the worker node table and its index if they do not exist
+func (a *SQLWorkerIDAllocator) CreateTable(ctx context.Context) error {
+	if a.db == nil {
+		return fmt.Errorf("sql database is nil")
+	}
+	statements, err := WorkerNodeDDL(a.dialect, a.table)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range statements {
+		if _, err := a.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", a.table, err)
+		}
+	}
+	return nil
+}
+
+// Acquire inserts a row and keeps it if no other row holds the same worker ID. Otherwise the row is deleted
+// and another one inserted, which moves on to the next worker ID, until every ID has been tried. For a
+// specific worker ID there is a single attempt.
+//
+// The row is written with its worker ID before the check, so of two instances racing for the same ID the
+// second one to check always sees the first; at worst both back off and try the next ID.
+func (a *SQLWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if a.db == nil {
+		return nil, fmt.Errorf("sql database is nil")
+	}
+	if info.WorkerID < 0 && maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+
+	attempts := maxWorkerID + 1
+	if info.WorkerID >= 0 {
+		attempts = 1
+	}
+	for i := int64(0); i < attempts; i++ {
+		now := a.now()
+		rowID, err := a.insertRow(ctx, info, now, ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		claimed := info
+		if claimed.WorkerID < 0 {
+			claimed.WorkerID = rowID % (maxWorkerID + 1)
+		}
+		if _, err := a.db.ExecContext(ctx, a.query(`UPDATE %s SET WORKER_ID = ?, INFO = ? WHERE ID = ?`),
+			claimed.WorkerID, claimed.String(), rowID); err != nil {
+			a.deleteRow(rowID)
+			return nil, fmt.Errorf("failed to claim worker ID %d: %w", claimed.WorkerID, err)
+		}
+
+		var holders int64
+		err = a.db.QueryRowContext(ctx, a.query(`SELECT COUNT(*) FROM %s WHERE DATACENTER_ID = ? AND WORKER_ID = ? AND ID <> ? AND LEASE_EXPIRES > ?`),
+			claimed.DatacenterID, claimed.WorkerID, rowID, a.holdingSince(now)).Scan(&holders)
+		if err != nil {
+			a.deleteRow(rowID)
+			return nil, fmt.Errorf("failed to check worker ID %d: %w", claimed.WorkerID, err)
+		}
+		if holders == 0 {
+			return &claimed, nil
+		}
+
+		log.Debugf("worker ID %d is held by another row, trying the next one", claimed.WorkerID)
+		a.deleteRow(rowID)
+	}
+
+	if info.WorkerID >= 0 {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: "another instance",
+		}
+	}
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", maxWorkerID+1)
+}
+
+// insertRow inserts a row without a worker ID and returns its auto-increment ID
+func (a *SQLWorkerIDAllocator) insertRow(ctx context.Context, info WorkerInfo, now time.Time, ttl time.Duration) (int64, error) {
+	insert := a.query(`INSERT INTO %s (HOST_NAME, PORT, SERVICE_NAME, INSTANCE_ID, DATACENTER_ID, WORKER_ID, LAUNCH_DATE, LEASE_EXPIRES, INFO) VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?)`)
+	args := []any{info.IP, a.port, info.ServiceName, info.InstanceID, info.DatacenterID, now.UTC(), now.Add(ttl).UnixMilli(), info.String()}
+
+	if a.dialect == SQLDialectPostgres {
+		// lib/pq and pgx do not implement LastInsertId
+		var rowID int64
+		if err := a.db.QueryRowContext(ctx, insert+" RETURNING ID", args...).Scan(&rowID); err != nil {
+			return 0, fmt.Errorf("failed to insert worker node: %w", err)
+		}
+		return rowID, nil
+	}
+	res, err := a.db.ExecContext(ctx, insert, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert worker node: %w", err)
+	}
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read worker node ID: %w", err)
+	}
+	return rowID, nil
+}
+
+// deleteRow removes a row that lost its claim; a failure only leaves a row behind that expires by itself
+func (a *SQLWorkerIDAllocator) deleteRow(rowID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := a.db.ExecContext(ctx, a.query(`DELETE FROM %s WHERE ID = ?`), rowID); err != nil {
+		log.Warnf("failed to delete worker node %d: %v", rowID, err)
+	}
+}
+
+// Renew moves LEASE_EXPIRES forward and stores info if the row of info.InstanceID has not expired
+func (a *SQLWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if a.db == nil {
+		return fmt.Errorf("sql database is nil")
+	}
+
+	now := a.now()
+	res, err := a.db.ExecContext(ctx, a.query(`UPDATE %s SET LEASE_EXPIRES = ?, INFO = ? WHERE DATACENTER_ID = ? AND WORKER_ID = ? AND INSTANCE_ID = ? AND LEASE_EXPIRES > ?`),
+		now.Add(ttl).UnixMilli(), info.String(), info.DatacenterID, info.WorkerID, info.InstanceID, now.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to renew worker ID %d: %w", info.WorkerID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	// Not renewed: report whether another instance holds the worker ID or our lease ran out
+	var holders int64
+	err = a.db.QueryRowContext(ctx, a.query(`SELECT COUNT(*) FROM %s WHERE DATACENTER_ID = ? AND WORKER_ID = ? AND INSTANCE_ID <> ? AND LEASE_EXPIRES > ?`),
+		info.DatacenterID, info.WorkerID, info.InstanceID, now.UnixMilli()).Scan(&holders)
+	if err != nil {
+		return fmt.Errorf("failed to read worker ID %d: %w", info.WorkerID, err)
+	}
+	return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: holders == 0}
+}
+
+// Release deletes the row of info.InstanceID, which makes the worker ID available right away
+func (a *SQLWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	if a.db == nil {
+		return fmt.Errorf("sql database is nil")
+	}
+	if _, err := a.db.ExecContext(ctx, a.query(`DELETE FROM %s WHERE DATACENTER_ID = ? AND WORKER_ID = ? AND INSTANCE_ID = ?`),
+		info.DatacenterID, info.WorkerID, info.InstanceID); err != nil {
+		return fmt.Errorf("failed to release worker ID %d: %w", info.WorkerID, err)
+	}
+	return nil
+}
+
+// List returns the rows whose lease has not expired
+func (a *SQLWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if a.db == nil {
+		return nil, fmt.Errorf("sql database is nil")
+	}
+
+	rows, err := a.db.QueryContext(ctx, a.query(`SELECT INFO FROM %s WHERE WORKER_ID >= 0 AND LEASE_EXPIRES > ? ORDER BY DATACENTER_ID, WORKER_ID`),
+		a.now().UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	defer rows.Close()
+
+	var workers []WorkerInfo
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list workers: %w", err)
+		}
+		info, err := ParseWorkerInfo(data)
+		if err != nil {
+			continue
+		}
+		workers = append(workers, *info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	return workers, nil
+}
+
+// Watch polls the registry
+func (a *SQLWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// holdingSince is the LEASE_EXPIRES bound above which a row still holds its worker ID
+func (a *SQLWorkerIDAllocator) holdingSince(now time.Time) int64 {
+	return now.Add(-a.safetyDelay).UnixMilli()
+}
+
+// query fills in the table name and rewrites ? placeholders for PostgreSQL
+func (a *SQLWorkerIDAllocator) query(format string) string {
+	q := fmt.Sprintf(format, a.table)
+	if a.dialect != SQLDialectPostgres {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// resolveSQLDBResource looks up the *sql.DB shared by another plugin
+func resolveSQLDBResource(rt plugins.Runtime, name string) (*sql.DB, error) {
+	resource, err := rt.GetSharedResource(name)
+	if err != nil {
+		return nil, err
+	}
+	db, ok := resource.(*sql.DB)
+	if !ok || db == nil {
+		return nil, fmt.Errorf("shared resource %s is %T, not a *sql.DB", name, resource)
+	}
+	return db, nil
+}
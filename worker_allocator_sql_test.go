@@ -0,0 +1,113 @@
+package eonId
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newSQLiteAllocator(t *testing.T, safetyDelay time.Duration) (*SQLWorkerIDAllocator, *sql.DB, func(time.Duration)) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "ids.db")+"?_pragma=busy_timeout(5000)")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	allocator := NewSQLWorkerIDAllocator(db, &SQLWorkerIDAllocatorConfig{
+		Dialect:       SQLDialectSQLite,
+		Port:          "8080",
+		SafetyDelay:   safetyDelay,
+		WatchInterval: 20 * time.Millisecond,
+	})
+	require.NoError(t, allocator.CreateTable(context.Background()))
+	require.NoError(t, allocator.CreateTable(context.Background()), "DDL is idempotent")
+
+	var offset int64
+	allocator.now = func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) }
+	return allocator, db, func(d time.Duration) { atomic.AddInt64(&offset, int64(d)) }
+}
+
+func TestSQLWorkerIDAllocator_Conformance(t *testing.T) {
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		// The suite advances one second past the TTL before reusing a worker ID
+		allocator, _, advance := newSQLiteAllocator(t, 500*time.Millisecond)
+		return allocator, advance
+	})
+}
+
+func TestSQLWorkerIDAllocator_WorkerNodeRows(t *testing.T) {
+	allocator, db, _ := newSQLiteAllocator(t, time.Minute)
+	ctx := context.Background()
+
+	info := WorkerInfo{WorkerID: -1, DatacenterID: 1, IP: "10.0.0.7", ServiceName: "orders", InstanceID: "inst-a"}
+	var workerIDs []int64
+	for i := 0; i < 3; i++ {
+		info.InstanceID = "inst-" + string(rune('a'+i))
+		claimed, err := allocator.Acquire(ctx, info, 31, time.Minute)
+		require.NoError(t, err)
+		workerIDs = append(workerIDs, claimed.WorkerID)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, workerIDs, "worker ID is the auto-increment ID modulo the worker ID space")
+
+	var host, port, service string
+	var workerID int64
+	require.NoError(t, db.QueryRow(`SELECT HOST_NAME, PORT, SERVICE_NAME, WORKER_ID FROM WORKER_NODE WHERE INSTANCE_ID = 'inst-a'`).
+		Scan(&host, &port, &service, &workerID))
+	assert.Equal(t, "10.0.0.7", host)
+	assert.Equal(t, "8080", port)
+	assert.Equal(t, "orders", service)
+	assert.Equal(t, int64(1), workerID)
+}
+
+func TestSQLWorkerIDAllocator_SafetyDelay(t *testing.T) {
+	allocator, _, advance := newSQLiteAllocator(t, time.Minute)
+	ctx := context.Background()
+
+	owner := WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "inst-a"}
+	_, err := allocator.Acquire(ctx, owner, 31, 10*time.Second)
+	require.NoError(t, err)
+
+	// The lease has run out, but the row keeps the worker ID until the safety delay has passed too
+	advance(30 * time.Second)
+	var lost *WorkerLeaseLostError
+	require.ErrorAs(t, allocator.Renew(ctx, owner, 10*time.Second), &lost)
+	assert.True(t, lost.Expired)
+
+	var conflict *WorkerIDConflictError
+	_, err = allocator.Acquire(ctx, WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "inst-b"}, 31, 10*time.Second)
+	require.ErrorAs(t, err, &conflict)
+
+	advance(time.Minute)
+	_, err = allocator.Acquire(ctx, WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "inst-b"}, 31, 10*time.Second)
+	assert.NoError(t, err)
+}
+
+func TestWorkerNodeDDL(t *testing.T) {
+	for _, dialect := range []string{SQLDialectMySQL, SQLDialectPostgres, SQLDialectSQLite} {
+		statements, err := WorkerNodeDDL(dialect, "ids.WORKER_NODE_ORDERS")
+		require.NoError(t, err, dialect)
+		require.NotEmpty(t, statements)
+		for _, stmt := range statements {
+			assert.NotContains(t, stmt, "--", "comments are stripped")
+			assert.True(t, strings.HasPrefix(stmt, "CREATE"), stmt)
+		}
+		assert.Contains(t, statements[0], "ids.WORKER_NODE_ORDERS (")
+		assert.Contains(t, strings.Join(statements, "\n"), "IDX_ids_WORKER_NODE_ORDERS_WORKER")
+	}
+
+	_, err := WorkerNodeDDL("oracle", "")
+	assert.Error(t, err)
+	_, err = WorkerNodeDDL(SQLDialectMySQL, "nodes; DROP TABLE x")
+	assert.Error(t, err)
+}
+
+func TestSQLWorkerIDAllocator_PostgresPlaceholders(t *testing.T) {
+	a := NewSQLWorkerIDAllocator(nil, &SQLWorkerIDAllocatorConfig{Dialect: SQLDialectPostgres})
+	assert.Equal(t, "DELETE FROM WORKER_NODE WHERE ID = $1 AND PORT = $2", a.query(`DELETE FROM %s WHERE ID = ? AND PORT = ?`))
+}
@@ -0,0 +1,763 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// allocatorFactory returns a fresh, empty backend and a function that moves the backend's clock forward
+type allocatorFactory func(t *testing.T) (WorkerIDAllocator, func(time.Duration))
+
+// runWorkerIDAllocatorConformance checks the WorkerIDAllocator contract that WorkerIDManager relies on.
+// Every backend runs the same suite.
+func runWorkerIDAllocatorConformance(t *testing.T, newAllocator allocatorFactory) {
+	ctx := context.Background()
+	const ttl = 10 * time.Second
+
+	claim := func(instance string, datacenterID, workerID int64) WorkerInfo {
+		return WorkerInfo{
+			WorkerID:       workerID,
+			DatacenterID:   datacenterID,
+			IP:             "10.0.0.1",
+			ServiceName:    "orders",
+			ServiceVersion: "v1.2.3",
+			RegisterTime:   1_700_000_000,
+			LastHeartbeat:  1_700_000_000,
+			InstanceID:     instance,
+		}
+	}
+
+	t.Run("AcquireAnyUntilExhausted", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		const maxWorkerID = 3
+
+		seen := make(map[int64]bool)
+		for i := 0; i <= maxWorkerID; i++ {
+			info, err := a.Acquire(ctx, claim(fmt.Sprintf("inst-%d", i), 1, -1), maxWorkerID, ttl)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, info.WorkerID, int64(0))
+			assert.LessOrEqual(t, info.WorkerID, int64(maxWorkerID))
+			assert.False(t, seen[info.WorkerID], "worker ID %d handed out twice", info.WorkerID)
+			assert.Equal(t, fmt.Sprintf("inst-%d", i), info.InstanceID)
+			seen[info.WorkerID] = true
+		}
+
+		_, err := a.Acquire(ctx, claim("inst-late", 1, -1), maxWorkerID, ttl)
+		require.Error(t, err)
+		var conflict *WorkerIDConflictError
+		assert.False(t, errors.As(err, &conflict))
+
+		// Datacenters have separate ID spaces
+		_, err = a.Acquire(ctx, claim("inst-dc2", 2, -1), maxWorkerID, ttl)
+		assert.NoError(t, err)
+	})
+
+	t.Run("AcquireSpecific", func(t *testing.T) {
+		a, _ := newAllocator(t)
+
+		info, err := a.Acquire(ctx, claim("inst-a", 1, 2), 31, ttl)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), info.WorkerID)
+
+		_, err = a.Acquire(ctx, claim("inst-b", 1, 2), 31, ttl)
+		var conflict *WorkerIDConflictError
+		require.True(t, errors.As(err, &conflict), "got %v", err)
+		assert.Equal(t, int64(2), conflict.WorkerID)
+		assert.Equal(t, int64(1), conflict.DatacenterID)
+
+		_, err = a.Acquire(ctx, claim("inst-b", 2, 2), 31, ttl)
+		assert.NoError(t, err)
+	})
+
+	t.Run("RenewOnlyByOwner", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		owner := claim("inst-a", 1, 5)
+		_, err := a.Acquire(ctx, owner, 31, ttl)
+		require.NoError(t, err)
+
+		owner.LastHeartbeat++
+		require.NoError(t, a.Renew(ctx, owner, ttl))
+
+		var lost *WorkerLeaseLostError
+		err = a.Renew(ctx, claim("inst-b", 1, 5), ttl)
+		require.True(t, errors.As(err, &lost), "got %v", err)
+		assert.False(t, lost.Expired)
+		assert.Equal(t, int64(5), lost.WorkerID)
+
+		require.NoError(t, a.Release(ctx, owner))
+		err = a.Renew(ctx, owner, ttl)
+		require.True(t, errors.As(err, &lost), "got %v", err)
+		assert.True(t, lost.Expired)
+	})
+
+	t.Run("ReleaseOnlyByOwner", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		owner := claim("inst-a", 1, 7)
+		_, err := a.Acquire(ctx, owner, 31, ttl)
+		require.NoError(t, err)
+
+		require.NoError(t, a.Release(ctx, claim("inst-b", 1, 7)))
+		workers, err := a.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, workers, 1)
+
+		require.NoError(t, a.Release(ctx, owner))
+		require.NoError(t, a.Release(ctx, owner), "releasing twice is not an error")
+		workers, err = a.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, workers)
+
+		_, err = a.Acquire(ctx, claim("inst-b", 1, 7), 31, ttl)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ClaimsExpire", func(t *testing.T) {
+		a, advance := newAllocator(t)
+		owner := claim("inst-a", 1, 3)
+		_, err := a.Acquire(ctx, owner, 31, ttl)
+		require.NoError(t, err)
+
+		// A renewal restarts the TTL
+		advance(ttl / 2)
+		require.NoError(t, a.Renew(ctx, owner, ttl))
+		advance(ttl / 2)
+		require.NoError(t, a.Renew(ctx, owner, ttl))
+
+		advance(ttl + time.Second)
+		var lost *WorkerLeaseLostError
+		err = a.Renew(ctx, owner, ttl)
+		require.True(t, errors.As(err, &lost), "got %v", err)
+		assert.True(t, lost.Expired)
+
+		workers, err := a.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, workers)
+		_, err = a.Acquire(ctx, claim("inst-b", 1, 3), 31, ttl)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ListReturnsStoredInfo", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		_, err := a.Acquire(ctx, claim("inst-a", 1, 1), 31, ttl)
+		require.NoError(t, err)
+		_, err = a.Acquire(ctx, claim("inst-b", 3, 4), 31, ttl)
+		require.NoError(t, err)
+
+		renewed := claim("inst-a", 1, 1)
+		renewed.LastHeartbeat = 1_700_000_100
+		require.NoError(t, a.Renew(ctx, renewed, ttl))
+
+		workers, err := a.List(ctx)
+		require.NoError(t, err)
+		byInstance := make(map[string]WorkerInfo)
+		for _, w := range workers {
+			byInstance[w.InstanceID] = w
+		}
+		require.Len(t, byInstance, 2)
+		assert.Equal(t, renewed, byInstance["inst-a"])
+		assert.Equal(t, claim("inst-b", 3, 4), byInstance["inst-b"])
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		a, advance := newAllocator(t)
+		revoker, ok := a.(WorkerRevoker)
+		if !ok {
+			t.Skip("allocator cannot revoke claims")
+		}
+		owner := claim("inst-a", 1, 4)
+		_, err := a.Acquire(ctx, owner, 31, ttl)
+		require.NoError(t, err)
+
+		revocation := WorkerRevocation{At: 1_700_000_000_000, By: "ops", Reason: "wedged"}
+		revoked, err := revoker.RevokeWorker(ctx, 1, 4, revocation)
+		require.NoError(t, err)
+		require.NotNil(t, revoked)
+		assert.Equal(t, "inst-a", revoked.InstanceID)
+		assert.Equal(t, &revocation, revoked.Revocation)
+		again, err := revoker.RevokeWorker(ctx, 1, 4, WorkerRevocation{By: "someone else"})
+		require.NoError(t, err)
+		assert.Equal(t, &revocation, again.Revocation, "a second revocation keeps the first")
+
+		// The owner learns of the revocation at its next renewal; the claim stays listed until it is released
+		var revokedErr *WorkerRevokedError
+		err = a.Renew(ctx, owner, ttl)
+		require.True(t, errors.As(err, &revokedErr), "got %v", err)
+		assert.Equal(t, int64(4), revokedErr.WorkerID)
+		assert.Equal(t, int64(1), revokedErr.DatacenterID)
+		assert.Equal(t, revocation, revokedErr.Revocation)
+		workers, err := a.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, workers, 1)
+		assert.Equal(t, &revocation, workers[0].Revocation)
+		assert.Equal(t, "orders", workers[0].ServiceName)
+
+		var conflict *WorkerIDConflictError
+		_, err = a.Acquire(ctx, claim("inst-b", 1, 4), 31, ttl)
+		require.True(t, errors.As(err, &conflict), "a revoked worker ID is not handed out before release, got %v", err)
+		require.NoError(t, a.Release(ctx, owner))
+		_, err = a.Acquire(ctx, claim("inst-b", 1, 4), 31, ttl)
+		require.NoError(t, err)
+
+		// Without a release the claim expires on its original TTL; renewals do not extend it
+		wedged := claim("inst-c", 1, 9)
+		_, err = a.Acquire(ctx, wedged, 31, ttl)
+		require.NoError(t, err)
+		_, err = revoker.RevokeWorker(ctx, 1, 9, revocation)
+		require.NoError(t, err)
+		advance(ttl / 2)
+		require.True(t, errors.As(a.Renew(ctx, wedged, ttl), &revokedErr))
+		advance(ttl/2 + time.Second)
+		_, err = a.Acquire(ctx, claim("inst-d", 1, 9), 31, ttl)
+		require.NoError(t, err)
+
+		revoked, err = revoker.RevokeWorker(ctx, 1, 20, revocation)
+		require.NoError(t, err)
+		assert.Nil(t, revoked, "a free worker ID is not revoked")
+	})
+
+	t.Run("ResolveDatacenter", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		resolver, ok := a.(DatacenterResolver)
+		if !ok {
+			t.Skip("allocator cannot resolve datacenter names")
+		}
+		east, err := resolver.ResolveDatacenter(ctx, "us-east-1", 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), east)
+		west, err := resolver.ResolveDatacenter(ctx, "us-west-2", 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), west)
+		again, err := resolver.ResolveDatacenter(ctx, "us-east-1", 2)
+		require.NoError(t, err)
+		assert.Equal(t, east, again, "a name keeps its datacenter ID")
+
+		_, err = resolver.ResolveDatacenter(ctx, "eu-west-1", 2)
+		require.NoError(t, err)
+		var exhausted *DatacenterIDsExhaustedError
+		_, err = resolver.ResolveDatacenter(ctx, "ap-south-1", 2)
+		require.True(t, errors.As(err, &exhausted), "got %v", err)
+		assert.Equal(t, "ap-south-1", exhausted.Name)
+
+		datacenters, err := resolver.Datacenters(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"us-east-1": 0, "us-west-2": 1, "eu-west-1": 2}, datacenters)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		a, _ := newAllocator(t)
+		_, err := a.Acquire(ctx, claim("inst-old", 1, 0), 31, ttl)
+		require.NoError(t, err)
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events, err := a.Watch(watchCtx)
+		require.NoError(t, err)
+
+		next := func() WorkerEvent {
+			t.Helper()
+			select {
+			case ev := <-events:
+				return ev
+			case <-time.After(5 * time.Second):
+				t.Fatal("no watch event")
+				return WorkerEvent{}
+			}
+		}
+
+		_, err = a.Acquire(ctx, claim("inst-a", 1, 6), 31, ttl)
+		require.NoError(t, err)
+		ev := next()
+		assert.Equal(t, WorkerEventAdded, ev.Type)
+		assert.Equal(t, "inst-a", ev.Worker.InstanceID)
+		assert.Equal(t, int64(6), ev.Worker.WorkerID)
+
+		require.NoError(t, a.Release(ctx, claim("inst-a", 1, 6)))
+		ev = next()
+		assert.Equal(t, WorkerEventRemoved, ev.Type)
+		assert.Equal(t, int64(6), ev.Worker.WorkerID)
+
+		cancel()
+		for range events {
+		}
+	})
+}
+
+func newMiniredisAllocator(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	allocator := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{
+		KeyPrefix:     "test:eon-id",
+		WatchInterval: 20 * time.Millisecond,
+	})
+	// The slot index scores claims with TIME, so the server clock moves along with key expiry
+	now := time.Now()
+	mr.SetTime(now)
+	return allocator, func(d time.Duration) {
+		now = now.Add(d)
+		mr.SetTime(now)
+		mr.FastForward(d)
+	}
+}
+
+func newTestMemoryAllocator(t *testing.T) (*MemoryWorkerIDAllocator, func(time.Duration)) {
+	allocator := NewMemoryWorkerIDAllocator()
+	allocator.watchInterval = 20 * time.Millisecond
+	var offset int64
+	allocator.now = func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) }
+	return allocator, func(d time.Duration) { atomic.AddInt64(&offset, int64(d)) }
+}
+
+func TestRedisWorkerIDAllocator_Conformance(t *testing.T) {
+	runWorkerIDAllocatorConformance(t, newMiniredisAllocator)
+}
+
+func TestMemoryWorkerIDAllocator_Conformance(t *testing.T) {
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		return newTestMemoryAllocator(t)
+	})
+}
+
+func TestRedisWorkerIDAllocator_KeyLayout(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"})
+
+	_, err := a.Acquire(context.Background(), WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "inst"}, 31, time.Minute)
+	require.NoError(t, err)
+
+	// Keys stay where existing deployments and dashboards expect them
+	assert.True(t, mr.Exists("eon:dc:2:worker:9"))
+	members, err := mr.ZMembers("eon:workers")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2:9"}, members)
+	assert.Equal(t, time.Minute, mr.TTL("eon:dc:2:worker:9"))
+}
+
+func TestRedisWorkerIDAllocator_SlotIndex(t *testing.T) {
+	allocator, advance := newMiniredisAllocator(t)
+	a := allocator.(*RedisWorkerIDAllocator)
+	require.True(t, a.singleSlot)
+	ctx := context.Background()
+	const maxWorkerID = 7
+	info := func(instance string, workerID int64) WorkerInfo {
+		return WorkerInfo{WorkerID: workerID, DatacenterID: 1, InstanceID: instance}
+	}
+
+	// A worker ID claimed by number is skipped, and the index learns its expiry
+	_, err := a.Acquire(ctx, info("pinned", 0), maxWorkerID, time.Minute)
+	require.NoError(t, err)
+	first, err := a.Acquire(ctx, info("a", -1), maxWorkerID, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.WorkerID)
+	score, err := a.client.ZScore(ctx, a.slotIndexKey(1), "0").Result()
+	require.NoError(t, err)
+	assert.Greater(t, score, float64(time.Now().UnixMilli()))
+
+	for i := 2; i <= maxWorkerID; i++ {
+		_, err = a.Acquire(ctx, info(fmt.Sprintf("inst-%d", i), -1), maxWorkerID, time.Minute)
+		require.NoError(t, err)
+	}
+	_, err = a.Acquire(ctx, info("late", -1), maxWorkerID, time.Minute)
+	require.Error(t, err)
+
+	// A released worker ID is free again at once
+	require.NoError(t, a.Release(ctx, *first))
+	again, err := a.Acquire(ctx, info("b", -1), maxWorkerID, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.WorkerID, again.WorkerID)
+	stored, err := a.client.Get(ctx, a.workerKey(1, again.WorkerID)).Result()
+	require.NoError(t, err)
+	parsed, err := ParseWorkerInfo(stored)
+	require.NoError(t, err)
+	assert.Equal(t, *again, *parsed)
+
+	// Renewals keep a claim out of reach; an expired one is reclaimed by the same script
+	renewed := info("inst-2", 2)
+	advance(50 * time.Second)
+	require.NoError(t, a.Renew(ctx, renewed, time.Minute))
+	advance(20 * time.Second)
+	reclaimed, err := a.Acquire(ctx, info("c", -1), maxWorkerID, time.Minute)
+	require.NoError(t, err)
+	assert.NotEqual(t, int64(2), reclaimed.WorkerID)
+}
+
+func TestRedisWorkerIDAllocator_ShardedClientFallsBackToProbing(t *testing.T) {
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:0"}})
+	defer cluster.Close()
+	assert.False(t, NewRedisWorkerIDAllocator(cluster, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"}).singleSlot)
+	assert.True(t, NewRedisWorkerIDAllocator(cluster, &RedisWorkerIDAllocatorConfig{KeyPrefix: "{eon}"}).singleSlot)
+	assert.True(t, NewRedisWorkerIDAllocator(cluster, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon", KeyLayout: RedisKeyLayoutCluster}).singleSlot)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"})
+	a.singleSlot = false
+	info, err := a.Acquire(context.Background(), WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "inst"}, 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, mr.Exists(a.workerKey(1, info.WorkerID)))
+	assert.False(t, mr.Exists(a.slotIndexKey(1)))
+	require.NoError(t, a.Renew(context.Background(), *info, time.Minute))
+	require.NoError(t, a.Release(context.Background(), *info))
+}
+
+func TestRedisWorkerIDAllocator_Registry(t *testing.T) {
+	setups := map[string]func(t *testing.T) (*RedisWorkerIDAllocator, func(time.Duration)){
+		"single node": func(t *testing.T) (*RedisWorkerIDAllocator, func(time.Duration)) {
+			allocator, advance := newMiniredisAllocator(t)
+			return allocator.(*RedisWorkerIDAllocator), advance
+		},
+		"cluster layout": func(t *testing.T) (*RedisWorkerIDAllocator, func(time.Duration)) {
+			client, _, advance := newMiniredisCluster(t)
+			return NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon", KeyLayout: RedisKeyLayoutCluster}), advance
+		},
+		"legacy layout on a cluster": func(t *testing.T) (*RedisWorkerIDAllocator, func(time.Duration)) {
+			client, _, advance := newMiniredisCluster(t)
+			a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"})
+			// Without scripts the registry is scored with the local clock
+			var offset time.Duration
+			a.now = func() time.Time { return time.Now().Add(offset) }
+			return a, func(d time.Duration) {
+				offset += d
+				advance(d)
+			}
+		},
+	}
+	for name, setup := range setups {
+		t.Run(name, func(t *testing.T) {
+			a, advance := setup(t)
+			ctx := context.Background()
+			heartbeat := func(info WorkerInfo) float64 {
+				score, err := a.client.ZScore(ctx, a.registryKey(info.DatacenterID), registryMember(info.DatacenterID, info.WorkerID)).Result()
+				require.NoError(t, err)
+				return score
+			}
+
+			crashed, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "crashed"}, 7, 10*time.Second)
+			require.NoError(t, err)
+			live, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 2, InstanceID: "live"}, 7, 10*time.Second)
+			require.NoError(t, err)
+			slow, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 2, InstanceID: "slow"}, 7, time.Hour)
+			require.NoError(t, err)
+
+			// Heartbeats move the member's score to the heartbeat time
+			before := heartbeat(*live)
+			advance(8 * time.Second)
+			require.NoError(t, a.Renew(ctx, *live, 10*time.Second))
+			assert.Greater(t, heartbeat(*live), before)
+
+			// The crashed instance's claim expires; List skips it at once, the registry keeps it until trimmed
+			advance(8 * time.Second)
+			listed, err := a.List(ctx)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []WorkerInfo{*live, *slow}, listed)
+			registries, err := a.registryKeys(ctx)
+			require.NoError(t, err)
+			var members int64
+			for _, registry := range registries {
+				members += a.client.ZCard(ctx, registry).Val()
+			}
+			assert.Equal(t, int64(3), members)
+
+			// A member whose heartbeat is old but whose key lives on (a longer TTL) is kept
+			removed, err := a.TrimRegistry(ctx, 10*time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, 1, removed)
+			_, err = a.client.ZScore(ctx, a.registryKey(1), registryMember(1, crashed.WorkerID)).Result()
+			assert.ErrorIs(t, err, redis.Nil)
+			heartbeat(*slow)
+
+			removed, err = a.TrimRegistry(ctx, 10*time.Second)
+			require.NoError(t, err)
+			assert.Zero(t, removed)
+		})
+	}
+}
+
+// BenchmarkRedisWorkerIDAllocator_AcquireNearlyFull registers into a 12-bit worker ID space where one
+// worker ID is free. Probing would collide about 2000 times per registration, each followed by a 10-50ms backoff.
+func BenchmarkRedisWorkerIDAllocator_AcquireNearlyFull(b *testing.B) {
+	mr := miniredis.RunT(b)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "bench"})
+	ctx := context.Background()
+	const maxWorkerID = 1<<12 - 1
+
+	pipe := client.Pipeline()
+	for id := int64(0); id < maxWorkerID; id++ {
+		filler := WorkerInfo{WorkerID: id, DatacenterID: 1, InstanceID: "filler"}
+		pipe.Set(ctx, a.workerKey(1, id), filler.String(), time.Hour)
+	}
+	_, err := pipe.Exec(ctx)
+	require.NoError(b, err)
+	// The first acquisition seeds the index and learns every filler's expiry
+	info, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "bench"}, maxWorkerID, time.Hour)
+	require.NoError(b, err)
+	require.Equal(b, int64(maxWorkerID), info.WorkerID)
+	require.NoError(b, a.Release(ctx, *info))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		info, err := a.Acquire(ctx, WorkerInfo{WorkerID: -1, DatacenterID: 1, InstanceID: "bench"}, maxWorkerID, time.Hour)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := a.Release(ctx, *info); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRedisWorkerIDAllocator_LastTimestamp(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	a := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "eon"})
+	ctx := context.Background()
+
+	owner := WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "inst-a"}
+	claimed, err := a.Acquire(ctx, owner, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, claimed.LastTimestamp, "a fresh worker ID has no previous owner")
+
+	// Heartbeats record the last issued timestamp in a key without TTL, in the worker key's cluster slot
+	owner.LastTimestamp = 1_700_000_000_000
+	require.NoError(t, a.Renew(ctx, owner, time.Minute))
+	mark, err := mr.Get("{eon:dc:2:worker:9}:last_ts")
+	require.NoError(t, err)
+	assert.Equal(t, "1700000000000", mark)
+	assert.Zero(t, mr.TTL("{eon:dc:2:worker:9}:last_ts"))
+
+	// The worker key expires; the next owner learns where the previous one stopped
+	mr.FastForward(2 * time.Minute)
+	next, err := a.Acquire(ctx, WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "inst-b"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_700_000_000_000), next.LastTimestamp)
+
+	// The expired owner's late heartbeat still raises the mark, but a lower value never lowers it
+	owner.LastTimestamp = 1_700_000_001_000
+	var lost *WorkerLeaseLostError
+	require.ErrorAs(t, a.Renew(ctx, owner, time.Minute), &lost)
+	next.LastTimestamp = 1_700_000_000_500
+	require.NoError(t, a.Release(ctx, *next))
+	last, err := a.LastTimestamp(ctx, 2, 9)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_700_000_001_000), last)
+	assert.False(t, mr.Exists("eon:dc:2:worker:9"))
+
+	// A prefix with its own hash tag already keeps both keys in one slot
+	tagged := NewRedisWorkerIDAllocator(client, &RedisWorkerIDAllocatorConfig{KeyPrefix: "{eon}"})
+	assert.Equal(t, "{eon}:dc:2:worker:9:last_ts", tagged.lastTimestampKey(2, 9))
+}
+
+func TestWorkerIDManager_ReuseNotBefore(t *testing.T) {
+	allocator, advance := newTestMemoryAllocator(t)
+	ctx := context.Background()
+	config := &WorkerManagerConfig{TTL: time.Minute, HeartbeatInterval: time.Hour, ReuseSafetyMargin: 2 * time.Second}
+
+	first := NewWorkerIDManagerWithAllocator(allocator, 1, config)
+	first.SetLastTimestampSource(func() int64 { return 1_700_000_000_000 })
+	require.NoError(t, first.RegisterSpecificWorkerID(ctx, 4))
+	assert.Zero(t, first.ReuseNotBefore())
+	require.NoError(t, first.sendHeartbeat())
+
+	// The first owner vanishes without unregistering; its claim expires
+	first.mu.Lock()
+	first.heartbeatCancel()
+	first.mu.Unlock()
+	advance(2 * time.Minute)
+
+	second := NewWorkerIDManagerWithAllocator(allocator, 1, config)
+	require.NoError(t, second.RegisterSpecificWorkerID(ctx, 4))
+	assert.Equal(t, int64(1_700_000_002_000), second.ReuseNotBefore())
+	require.NoError(t, second.UnregisterWorkerID(ctx))
+	assert.Zero(t, second.ReuseNotBefore())
+}
+
+func TestWorkerIDManager_WithAllocator(t *testing.T) {
+	allocator, _ := newTestMemoryAllocator(t)
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{
+		TTL:               time.Hour,
+		HeartbeatInterval: time.Hour,
+		ServiceName:       "orders",
+	})
+	defer func() { _ = mgr.UnregisterWorkerID(context.Background()) }()
+
+	workerID, err := mgr.RegisterWorkerID(context.Background(), 31)
+	require.NoError(t, err)
+	assert.True(t, mgr.IsRegistered())
+	require.NoError(t, mgr.sendHeartbeat())
+
+	workers, err := mgr.GetRegisteredWorkers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.Equal(t, workerID, workers[0].WorkerID)
+	assert.Equal(t, "orders", workers[0].ServiceName)
+
+	// Another instance takes the worker ID over: heartbeats fail and re-registration gives it up
+	stolen := workers[0]
+	require.NoError(t, allocator.Release(context.Background(), stolen))
+	stolen.InstanceID = "other"
+	_, err = allocator.Acquire(context.Background(), stolen, 31, time.Hour)
+	require.NoError(t, err)
+
+	var lost *WorkerLeaseLostError
+	require.True(t, errors.As(mgr.sendHeartbeat(), &lost))
+	require.Error(t, mgr.tryReRegister(context.Background()))
+	assert.False(t, mgr.IsRegistered())
+	assert.Equal(t, int64(-1), mgr.GetWorkerID())
+
+	// Unregistering must not release the other instance's claim
+	require.NoError(t, mgr.UnregisterWorkerID(context.Background()))
+	workers, err = allocator.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.Equal(t, "other", workers[0].InstanceID)
+}
+
+func TestWorkerIDManager_NoAllocator(t *testing.T) {
+	mgr := NewWorkerIDManager(nil, 1, nil)
+	assert.Nil(t, mgr.Allocator())
+	_, err := mgr.RegisterWorkerID(context.Background(), 31)
+	assert.Error(t, err)
+	_, err = mgr.WatchWorkers(context.Background())
+	assert.Error(t, err)
+}
+
+func TestValidateSnowflakeConfig_WorkerIDAllocator(t *testing.T) {
+	conf := newReloadTestConf()
+	conf.AutoRegisterWorkerId = true
+	conf.RedisPluginName = "redis"
+	conf.WorkerIdBits = DefaultWorkerBits
+	conf.SequenceBits = DefaultSequenceBits
+	require.NoError(t, ValidateSnowflakeConfig(conf))
+
+	conf.RedisKeyLayout = RedisKeyLayoutCluster
+	conf.RedisMigrateKeyLayout = true
+	require.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.RedisKeyLayout = RedisKeyLayoutLegacy
+	assert.Error(t, ValidateSnowflakeConfig(conf), "migration needs the cluster layout")
+	conf.RedisKeyLayout = "sharded"
+	conf.RedisMigrateKeyLayout = false
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.RedisKeyLayout = ""
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorMemory
+	conf.RedisPluginName = ""
+	assert.NoError(t, ValidateSnowflakeConfig(conf), "memory allocator needs no Redis settings")
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorEtcd
+	assert.NoError(t, ValidateSnowflakeConfig(conf), "etcd allocator needs no Redis settings")
+	conf.EtcdKeyPrefix = "/bad prefix/"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorZooKeeper
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.ZookeeperKeyPrefix = "/bad prefix"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorStatefulSet
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerIdAllocator = WorkerIDAllocatorKubernetesLease
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.KubernetesLeasePrefix = "Eon_ID"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorSQL
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.SqlDialect = "oracle"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.SqlDialect = SQLDialectPostgres
+	conf.SqlTableName = "worker-node"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+
+	conf.WorkerIdAllocator = WorkerIDAllocatorMemory
+	conf.WorkerReuseSafetyMargin = durationpb.New(-time.Second)
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerReuseSafetyMargin = durationpb.New(5 * time.Second)
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerLeaseSafetyMargin = durationpb.New(-time.Second)
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerLeaseSafetyMargin = durationpb.New(3 * time.Second)
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerRecoveryBackoff = durationpb.New(-time.Second)
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerRecoveryBackoff = durationpb.New(500 * time.Millisecond)
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerRevokeAction = "restart"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.WorkerRevokeAction = WorkerRevokeActionFence
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+
+	datacenterID := conf.DatacenterId
+	conf.DatacenterId = 0
+	conf.DatacenterName = "us-east-1"
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.DatacenterName = "us east"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+	conf.DatacenterName = "us-east-1"
+	conf.DatacenterId = 3
+	assert.Error(t, ValidateSnowflakeConfig(conf), "a name replaces datacenter_id")
+	conf.DatacenterId = 0
+	conf.WorkerIdAllocator = WorkerIDAllocatorEtcd
+	assert.Error(t, ValidateSnowflakeConfig(conf), "etcd cannot resolve datacenter names")
+	conf.WorkerIdAllocator = WorkerIDAllocatorMemory
+	conf.DatacenterName = ""
+	conf.DatacenterNameEnv = "REGION"
+	assert.NoError(t, ValidateSnowflakeConfig(conf))
+	conf.DatacenterNameEnv = ""
+	conf.DatacenterId = datacenterID
+
+	conf.WorkerIdAllocator = "consul"
+	assert.Error(t, ValidateSnowflakeConfig(conf))
+}
+
+func TestPlugin_GetDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		conf *pb.EonId
+		want string // empty: no dependency
+	}{
+		{"NoConfig", nil, RedisPluginName},
+		{"NoAutoRegister", &pb.EonId{WorkerIdAllocator: WorkerIDAllocatorEtcd}, ""},
+		{"Default", &pb.EonId{AutoRegisterWorkerId: true}, RedisPluginName},
+		{"Redis", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorRedis, RedisPluginName: "cache"}, RedisPluginName},
+		{"Etcd", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorEtcd}, DefaultEtcdPluginName},
+		{"EtcdNamed", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorEtcd, EtcdPluginName: "etcd.registry"}, "etcd.registry"},
+		{"ZooKeeper", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorZooKeeper, ZookeeperPluginName: "zk"}, "zk"},
+		{"KubernetesLease", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorKubernetesLease}, DefaultKubernetesPluginName},
+		{"SQL", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorSQL, SqlPluginName: "mysql"}, "mysql"},
+		{"StatefulSet", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorStatefulSet}, ""},
+		{"File", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorFile}, ""},
+		{"Memory", &pb.EonId{AutoRegisterWorkerId: true, WorkerIdAllocator: WorkerIDAllocatorMemory}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := NewSnowflakePlugin()
+			plugin.conf = tt.conf
+			deps := plugin.GetDependencies()
+			if tt.want == "" {
+				assert.Empty(t, deps)
+				return
+			}
+			require.Len(t, deps, 1)
+			assert.Equal(t, tt.want, deps[0].Name)
+			assert.True(t, deps[0].Required)
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+	"github.com/go-zookeeper/zk"
+)
+
+const (
+	// DefaultZooKeeperKeyPrefix is the root znode for worker ID claims
+	DefaultZooKeeperKeyPrefix = "/lynx/eon-id"
+	// DefaultZooKeeperPluginName is the shared resource name of the ZooKeeper connection
+	DefaultZooKeeperPluginName = "zookeeper"
+)
+
+// ZooKeeperConn is the subset of *zk.Conn used by ZooKeeperWorkerIDAllocator
+type ZooKeeperConn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Delete(path string, version int32) error
+	Children(path string) ([]string, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	SessionID() int64
+}
+
+// ZooKeeperWorkerIDAllocator claims worker IDs with ephemeral znodes, the way Meituan Leaf does:
+//
+//	<prefix>/dc-<n>/workers/<id>     ephemeral, owned by the session that holds the worker ID
+//	<prefix>/dc-<n>/timestamps/<id>  persistent, the WorkerInfo last reported for the worker ID
+//
+// A claim lives as long as the ZooKeeper session, so the session timeout takes the place of worker_id_ttl.
+// When the session expires the server deletes the claim and the manager goes through its usual unhealthy
+// and re-register flow. The worker ID is the znode name rather than an EPHEMERAL_SEQUENTIAL suffix, because
+// sequence numbers keep growing and would not stay within the worker ID bits.
+type ZooKeeperWorkerIDAllocator struct {
+	conn          ZooKeeperConn
+	root          string
+	watchInterval time.Duration
+
+	mu     sync.Mutex
+	claims map[workerClaimKey]*zkClaim
+}
+
+// zkClaim is a claim held by this process
+type zkClaim struct {
+	instanceID string
+	stop       chan struct{} // closed on Release
+	done       chan struct{} // closed when the znode is gone, the session expired or the claim was released
+}
+
+// ZooKeeperWorkerIDAllocatorConfig holds configuration for the ZooKeeper allocator
+type ZooKeeperWorkerIDAllocatorConfig struct {
+	KeyPrefix     string
+	WatchInterval time.Duration // registry polling interval for Watch (default: DefaultWorkerWatchInterval)
+}
+
+// zkACL is used for every znode the allocator creates
+var zkACL = zk.WorldACL(zk.PermAll)
+
+// NewZooKeeperWorkerIDAllocator creates a ZooKeeper-backed worker ID allocator
+func NewZooKeeperWorkerIDAllocator(conn ZooKeeperConn, config *ZooKeeperWorkerIDAllocatorConfig) *ZooKeeperWorkerIDAllocator {
+	if config == nil {
+		config = &ZooKeeperWorkerIDAllocatorConfig{}
+	}
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = DefaultWorkerWatchInterval
+	}
+	return &ZooKeeperWorkerIDAllocator{
+		conn:          conn,
+		root:          NormalizeZooKeeperKeyPrefix(config.KeyPrefix),
+		watchInterval: watchInterval,
+		claims:        make(map[workerClaimKey]*zkClaim),
+	}
+}
+
+// NormalizeZooKeeperKeyPrefix turns the prefix into an absolute znode path without a trailing "/";
+// empty means DefaultZooKeeperKeyPrefix and "/" means the ZooKeeper root
+func NormalizeZooKeeperKeyPrefix(prefix string) string {
+	if prefix == "" {
+		return DefaultZooKeeperKeyPrefix
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return strings.TrimRight(prefix, "/")
+}
+
+// Name returns "zookeeper"
+func (a *ZooKeeperWorkerIDAllocator) Name() string {
+	return WorkerIDAllocatorZooKeeper
+}
+
+// Acquire creates the ephemeral znode of a worker ID. Free IDs are tried from a random offset so that
+// instances starting together do not all race for the same ID. ttl is not used; see the type comment.
+func (a *ZooKeeperWorkerIDAllocator) Acquire(ctx context.Context, info WorkerInfo, maxWorkerID int64, ttl time.Duration) (*WorkerInfo, error) {
+	if a.conn == nil {
+		return nil, fmt.Errorf("zookeeper connection is nil")
+	}
+	if info.WorkerID < 0 && maxWorkerID < 0 {
+		return nil, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
+	}
+
+	workersPath := a.workersPath(info.DatacenterID)
+	if err := a.ensurePath(workersPath); err != nil {
+		return nil, err
+	}
+
+	var candidates []int64
+	if info.WorkerID >= 0 {
+		candidates = []int64{info.WorkerID}
+	} else {
+		children, _, err := a.conn.Children(workersPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list worker IDs: %w", err)
+		}
+		taken := make(map[int64]bool, len(children))
+		for _, child := range children {
+			if id, err := strconv.ParseInt(child, 10, 64); err == nil {
+				taken[id] = true
+			}
+		}
+		total := maxWorkerID + 1
+		start := secureInt63n(total)
+		for i := int64(0); i < total; i++ {
+			if id := (start + i) % total; !taken[id] {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", total)
+		}
+	}
+
+	for _, workerID := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		claimed := info
+		claimed.WorkerID = workerID
+		_, err := a.conn.Create(a.workerPath(info.DatacenterID, workerID), []byte(claimed.String()), zk.FlagEphemeral, zkACL)
+		if errors.Is(err, zk.ErrNodeExists) {
+			log.Debugf("worker ID %d is taken in ZooKeeper", workerID)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim worker ID %d: %w", workerID, err)
+		}
+		if last, err := a.LastReported(info.DatacenterID, workerID); err == nil && last.LastTimestamp > claimed.LastTimestamp {
+			claimed.LastTimestamp = last.LastTimestamp
+		} else if err != nil && !errors.Is(err, zk.ErrNoNode) {
+			log.Warnf("failed to read last timestamp of worker ID %d: %v", workerID, err)
+		}
+		a.recordTimestamp(claimed)
+		a.watchClaim(claimed)
+		return &claimed, nil
+	}
+
+	if info.WorkerID >= 0 {
+		return nil, &WorkerIDConflictError{
+			WorkerID:     info.WorkerID,
+			DatacenterID: info.DatacenterID,
+			ConflictWith: "another instance",
+		}
+	}
+	return nil, fmt.Errorf("all %d worker IDs are occupied, registration failed", maxWorkerID+1)
+}
+
+// Renew stores info in the claim's znode and its timestamp record. The claim itself is kept alive by the
+// session, so ttl is not used.
+func (a *ZooKeeperWorkerIDAllocator) Renew(ctx context.Context, info WorkerInfo, ttl time.Duration) error {
+	if a.conn == nil {
+		return fmt.Errorf("zookeeper connection is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	nodePath := a.workerPath(info.DatacenterID, info.WorkerID)
+	data, stat, err := a.conn.Get(nodePath)
+	if errors.Is(err, zk.ErrNoNode) {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read worker ID %d: %w", info.WorkerID, err)
+	}
+	if current, err := ParseWorkerInfo(string(data)); err != nil || current.InstanceID != info.InstanceID {
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+	}
+	if stat.EphemeralOwner != a.conn.SessionID() {
+		// Left over from a session of ours that has expired; the server is about to delete it
+		return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+	}
+
+	if _, err := a.conn.Set(nodePath, []byte(info.String()), stat.Version); err != nil {
+		switch {
+		case errors.Is(err, zk.ErrNoNode):
+			return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID, Expired: true}
+		case errors.Is(err, zk.ErrBadVersion):
+			return &WorkerLeaseLostError{WorkerID: info.WorkerID, DatacenterID: info.DatacenterID}
+		}
+		return fmt.Errorf("failed to renew worker ID %d: %w", info.WorkerID, err)
+	}
+	a.recordTimestamp(info)
+	return nil
+}
+
+// Release records info.LastTimestamp and deletes the claim's znode. Claims held by other instances are
+// left alone, and the timestamp record is kept for the next holder.
+func (a *ZooKeeperWorkerIDAllocator) Release(ctx context.Context, info WorkerInfo) error {
+	if a.conn == nil {
+		return fmt.Errorf("zookeeper connection is nil")
+	}
+	a.stopWatch(info)
+
+	nodePath := a.workerPath(info.DatacenterID, info.WorkerID)
+	data, stat, err := a.conn.Get(nodePath)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read worker ID %d: %w", info.WorkerID, err)
+	}
+	if current, err := ParseWorkerInfo(string(data)); err == nil && current.InstanceID != info.InstanceID {
+		log.Warnf("worker ID %d is held by instance %s, not releasing", info.WorkerID, current.InstanceID)
+		return nil
+	}
+	a.recordTimestamp(info)
+	if err := a.conn.Delete(nodePath, stat.Version); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return fmt.Errorf("failed to release worker ID %d: %w", info.WorkerID, err)
+	}
+	return nil
+}
+
+// List returns the claims of all datacenters
+func (a *ZooKeeperWorkerIDAllocator) List(ctx context.Context) ([]WorkerInfo, error) {
+	if a.conn == nil {
+		return nil, fmt.Errorf("zookeeper connection is nil")
+	}
+
+	datacenters, _, err := a.conn.Children(a.rootPath())
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list datacenters: %w", err)
+	}
+
+	var workers []WorkerInfo
+	for _, dc := range datacenters {
+		datacenterID, err := strconv.ParseInt(strings.TrimPrefix(dc, "dc-"), 10, 64)
+		if err != nil || !strings.HasPrefix(dc, "dc-") {
+			continue
+		}
+		workersPath := a.workersPath(datacenterID)
+		children, _, err := a.conn.Children(workersPath)
+		if errors.Is(err, zk.ErrNoNode) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workers: %w", err)
+		}
+		for _, child := range children {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			data, _, err := a.conn.Get(workersPath + "/" + child)
+			if err != nil {
+				continue // released while listing
+			}
+			info, err := ParseWorkerInfo(string(data))
+			if err != nil {
+				continue
+			}
+			workers = append(workers, *info)
+		}
+	}
+	return workers, nil
+}
+
+// Watch polls the registry; ZooKeeper watches are one-shot and per znode, so polling is simpler and
+// does not miss changes made while a watch is being re-armed
+func (a *ZooKeeperWorkerIDAllocator) Watch(ctx context.Context) (<-chan WorkerEvent, error) {
+	return pollWorkerEvents(ctx, a.watchInterval, a.List)
+}
+
+// LastReported returns the WorkerInfo last stored for a worker ID, whether or not it is claimed now
+func (a *ZooKeeperWorkerIDAllocator) LastReported(datacenterID, workerID int64) (*WorkerInfo, error) {
+	if a.conn == nil {
+		return nil, fmt.Errorf("zookeeper connection is nil")
+	}
+	data, _, err := a.conn.Get(a.timestampPath(datacenterID, workerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamp of worker ID %d: %w", workerID, err)
+	}
+	return ParseWorkerInfo(string(data))
+}
+
+// LeaseDone implements WorkerLeaseWatcher
+func (a *ZooKeeperWorkerIDAllocator) LeaseDone(info WorkerInfo) <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if claim, ok := a.claims[workerClaimKey{info.DatacenterID, info.WorkerID}]; ok && claim.instanceID == info.InstanceID {
+		return claim.done
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// recordTimestamp writes info to the persistent timestamp znode, keeping the later of its LastTimestamp
+// and the stored one. A failure is only logged: the claim is still valid, and the next renewal writes
+// the record again.
+func (a *ZooKeeperWorkerIDAllocator) recordTimestamp(info WorkerInfo) {
+	nodePath := a.timestampPath(info.DatacenterID, info.WorkerID)
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		var data []byte
+		var stat *zk.Stat
+		data, stat, err = a.conn.Get(nodePath)
+		if errors.Is(err, zk.ErrNoNode) {
+			if err = a.ensurePath(path.Dir(nodePath)); err != nil {
+				break
+			}
+			_, err = a.conn.Create(nodePath, []byte(info.String()), 0, zkACL)
+			if errors.Is(err, zk.ErrNodeExists) {
+				continue // written concurrently; compare against it
+			}
+			break
+		}
+		if err != nil {
+			break
+		}
+		if stored, perr := ParseWorkerInfo(string(data)); perr == nil && stored.LastTimestamp > info.LastTimestamp {
+			info.LastTimestamp = stored.LastTimestamp
+		}
+		_, err = a.conn.Set(nodePath, []byte(info.String()), stat.Version)
+		if !errors.Is(err, zk.ErrBadVersion) && !errors.Is(err, zk.ErrNoNode) {
+			break
+		}
+	}
+	if err != nil {
+		log.Warnf("failed to record timestamp of worker ID %d: %v", info.WorkerID, err)
+	}
+}
+
+// watchClaim closes the claim's done channel once its znode is gone
+func (a *ZooKeeperWorkerIDAllocator) watchClaim(info WorkerInfo) {
+	claim := &zkClaim{instanceID: info.InstanceID, stop: make(chan struct{}), done: make(chan struct{})}
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	a.mu.Lock()
+	if old, ok := a.claims[key]; ok {
+		close(old.stop)
+	}
+	a.claims[key] = claim
+	a.mu.Unlock()
+
+	go func() {
+		defer close(claim.done)
+		nodePath := a.workerPath(info.DatacenterID, info.WorkerID)
+		for {
+			exists, _, events, err := a.conn.ExistsW(nodePath)
+			if err != nil {
+				if errors.Is(err, zk.ErrSessionExpired) || errors.Is(err, zk.ErrClosing) || errors.Is(err, zk.ErrConnectionClosed) {
+					log.Warnf("ZooKeeper session of worker ID %d (datacenter %d) ended: %v", info.WorkerID, info.DatacenterID, err)
+					return
+				}
+				// Disconnected: the session may still be alive, so try again
+				select {
+				case <-claim.stop:
+					return
+				case <-time.After(time.Second):
+					continue
+				}
+			}
+			if !exists {
+				log.Warnf("ZooKeeper claim of worker ID %d (datacenter %d) was lost", info.WorkerID, info.DatacenterID)
+				return
+			}
+
+			select {
+			case <-claim.stop:
+				return
+			case ev := <-events:
+				if ev.Type == zk.EventNodeDataChanged {
+					continue // our own renewal
+				}
+				// Deleted, or the watch was dropped because the session expired
+				log.Warnf("ZooKeeper claim of worker ID %d (datacenter %d) was lost: %v", info.WorkerID, info.DatacenterID, ev.Type)
+				return
+			}
+		}
+	}()
+}
+
+// stopWatch stops watching a claim of info.InstanceID
+func (a *ZooKeeperWorkerIDAllocator) stopWatch(info WorkerInfo) {
+	key := workerClaimKey{info.DatacenterID, info.WorkerID}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if claim, ok := a.claims[key]; ok && claim.instanceID == info.InstanceID {
+		delete(a.claims, key)
+		close(claim.stop)
+	}
+}
+
+// ensurePath creates the persistent znodes of p that do not exist yet
+func (a *ZooKeeperWorkerIDAllocator) ensurePath(p string) error {
+	current := ""
+	for _, part := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+		current += "/" + part
+		if _, err := a.conn.Create(current, nil, 0, zkACL); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return fmt.Errorf("failed to create znode %s: %w", current, err)
+		}
+	}
+	return nil
+}
+
+// Znode layout: <prefix>/dc-<datacenter>/{workers,timestamps}/<worker>
+func (a *ZooKeeperWorkerIDAllocator) rootPath() string {
+	if a.root == "" {
+		return "/"
+	}
+	return a.root
+}
+
+func (a *ZooKeeperWorkerIDAllocator) workersPath(datacenterID int64) string {
+	return fmt.Sprintf("%s/dc-%d/workers", a.root, datacenterID)
+}
+
+func (a *ZooKeeperWorkerIDAllocator) workerPath(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s/%d", a.workersPath(datacenterID), workerID)
+}
+
+func (a *ZooKeeperWorkerIDAllocator) timestampPath(datacenterID, workerID int64) string {
+	return fmt.Sprintf("%s/dc-%d/timestamps/%d", a.root, datacenterID, workerID)
+}
+
+// resolveZooKeeperConnResource looks up the ZooKeeper connection shared by another plugin
+func resolveZooKeeperConnResource(rt plugins.Runtime, name string) (ZooKeeperConn, error) {
+	resource, err := rt.GetSharedResource(name)
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := resource.(ZooKeeperConn)
+	if !ok || conn == nil {
+		return nil, fmt.Errorf("shared resource %s is %T, not a ZooKeeper connection", name, resource)
+	}
+	return conn, nil
+}
@@ -0,0 +1,293 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeZooKeeper is an in-process stand-in for a ZooKeeper ensemble with a single client session.
+// It implements the znode semantics the allocator relies on: ephemeral owners, versions and one-shot watches.
+type fakeZooKeeper struct {
+	mu      sync.Mutex
+	nodes   map[string]*fakeZNode
+	watches map[string][]chan zk.Event
+	session int64
+}
+
+type fakeZNode struct {
+	data    []byte
+	version int32
+	owner   int64
+}
+
+func newFakeZooKeeper() *fakeZooKeeper {
+	return &fakeZooKeeper{
+		nodes:   map[string]*fakeZNode{"/": {}},
+		watches: make(map[string][]chan zk.Event),
+		session: 1,
+	}
+}
+
+func (f *fakeZooKeeper) Create(p string, data []byte, flags int32, _ []zk.ACL) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	parent, ok := f.nodes[path.Dir(p)]
+	if !ok {
+		return "", zk.ErrNoNode
+	}
+	if parent.owner != 0 {
+		return "", zk.ErrNoChildrenForEphemerals
+	}
+	node := &fakeZNode{data: append([]byte(nil), data...)}
+	if flags&zk.FlagEphemeral != 0 {
+		node.owner = f.session
+	}
+	f.nodes[p] = node
+	f.fireLocked(p, zk.EventNodeCreated)
+	return p, nil
+}
+
+func (f *fakeZooKeeper) Get(p string) ([]byte, *zk.Stat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return append([]byte(nil), node.data...), node.stat(), nil
+}
+
+func (f *fakeZooKeeper) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != node.version {
+		return nil, zk.ErrBadVersion
+	}
+	node.data = append([]byte(nil), data...)
+	node.version++
+	f.fireLocked(p, zk.EventNodeDataChanged)
+	return node.stat(), nil
+}
+
+func (f *fakeZooKeeper) Delete(p string, version int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[p]
+	if !ok {
+		return zk.ErrNoNode
+	}
+	if version != -1 && version != node.version {
+		return zk.ErrBadVersion
+	}
+	if len(f.childrenLocked(p)) > 0 {
+		return zk.ErrNotEmpty
+	}
+	delete(f.nodes, p)
+	f.fireLocked(p, zk.EventNodeDeleted)
+	return nil
+}
+
+func (f *fakeZooKeeper) Children(p string) ([]string, *zk.Stat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return f.childrenLocked(p), node.stat(), nil
+}
+
+func (f *fakeZooKeeper) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan zk.Event, 1)
+	f.watches[p] = append(f.watches[p], ch)
+	node, ok := f.nodes[p]
+	if !ok {
+		return false, nil, ch, nil
+	}
+	return true, node.stat(), ch, nil
+}
+
+func (f *fakeZooKeeper) SessionID() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.session
+}
+
+// expireSession does what the server and go-zookeeper do when the session times out: the session's
+// ephemeral znodes are deleted, its watches are dropped with ErrSessionExpired and the client reconnects
+// with a new session
+func (f *fakeZooKeeper) expireSession() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for p, chans := range f.watches {
+		for _, ch := range chans {
+			ch <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: p, Err: zk.ErrSessionExpired}
+		}
+	}
+	f.watches = make(map[string][]chan zk.Event)
+	for p, node := range f.nodes {
+		if node.owner == f.session {
+			delete(f.nodes, p)
+		}
+	}
+	f.session++
+}
+
+func (f *fakeZooKeeper) childrenLocked(p string) []string {
+	var children []string
+	for child := range f.nodes {
+		if child != "/" && path.Dir(child) == p {
+			children = append(children, path.Base(child))
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func (f *fakeZooKeeper) fireLocked(p string, eventType zk.EventType) {
+	for _, ch := range f.watches[p] {
+		ch <- zk.Event{Type: eventType, State: zk.StateHasSession, Path: p}
+	}
+	delete(f.watches, p)
+}
+
+func (n *fakeZNode) stat() *zk.Stat {
+	return &zk.Stat{Version: n.version, EphemeralOwner: n.owner}
+}
+
+func newFakeZooKeeperAllocator(t *testing.T) (*ZooKeeperWorkerIDAllocator, *fakeZooKeeper) {
+	fake := newFakeZooKeeper()
+	allocator := NewZooKeeperWorkerIDAllocator(fake, &ZooKeeperWorkerIDAllocatorConfig{
+		KeyPrefix:     "/test/eon-id",
+		WatchInterval: 20 * time.Millisecond,
+	})
+	return allocator, fake
+}
+
+func TestZooKeeperWorkerIDAllocator_Conformance(t *testing.T) {
+	const sessionTimeout = 10 * time.Second // matches the suite's TTL
+	runWorkerIDAllocatorConformance(t, func(t *testing.T) (WorkerIDAllocator, func(time.Duration)) {
+		allocator, fake := newFakeZooKeeperAllocator(t)
+		return allocator, func(d time.Duration) {
+			// A client cut off for longer than the session timeout loses its session
+			if d > sessionTimeout {
+				fake.expireSession()
+			}
+		}
+	})
+}
+
+func TestZooKeeperWorkerIDAllocator_Layout(t *testing.T) {
+	allocator, fake := newFakeZooKeeperAllocator(t)
+	ctx := context.Background()
+
+	info := WorkerInfo{WorkerID: 9, DatacenterID: 2, InstanceID: "inst", ServiceName: "orders", LastHeartbeat: 100}
+	_, err := allocator.Acquire(ctx, info, 31, time.Minute)
+	require.NoError(t, err)
+
+	data, stat, err := fake.Get("/test/eon-id/dc-2/workers/9")
+	require.NoError(t, err)
+	assert.Equal(t, fake.SessionID(), stat.EphemeralOwner, "claims are ephemeral")
+	stored, err := ParseWorkerInfo(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, info, *stored, "same WorkerInfo JSON as the Redis backend")
+
+	info.LastHeartbeat = 200
+	require.NoError(t, allocator.Renew(ctx, info, time.Minute))
+	require.NoError(t, allocator.Release(ctx, info))
+
+	// The timestamp record is persistent and outlives the claim
+	_, stat, err = fake.Get("/test/eon-id/dc-2/timestamps/9")
+	require.NoError(t, err)
+	assert.Zero(t, stat.EphemeralOwner)
+	last, err := allocator.LastReported(2, 9)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), last.LastHeartbeat)
+
+	_, err = allocator.LastReported(2, 10)
+	assert.True(t, errors.Is(err, zk.ErrNoNode))
+}
+
+func TestZooKeeperWorkerIDAllocator_ReuseTimestamp(t *testing.T) {
+	allocator, _ := newFakeZooKeeperAllocator(t)
+	ctx := context.Background()
+
+	first := WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "first"}
+	_, err := allocator.Acquire(ctx, first, 31, time.Minute)
+	require.NoError(t, err)
+	first.LastTimestamp = 5000
+	require.NoError(t, allocator.Renew(ctx, first, time.Minute))
+	// A late write with an older timestamp must not lower the record
+	first.LastTimestamp = 3000
+	require.NoError(t, allocator.Release(ctx, first))
+
+	last, err := allocator.LastReported(1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), last.LastTimestamp)
+
+	claimed, err := allocator.Acquire(ctx, WorkerInfo{WorkerID: 4, DatacenterID: 1, InstanceID: "second"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), claimed.LastTimestamp, "the new owner learns the previous owner's last timestamp")
+
+	// A fresh worker ID has no record to seed from
+	fresh, err := allocator.Acquire(ctx, WorkerInfo{WorkerID: 5, DatacenterID: 1, InstanceID: "second"}, 31, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, fresh.LastTimestamp)
+}
+
+func TestNormalizeZooKeeperKeyPrefix(t *testing.T) {
+	assert.Equal(t, DefaultZooKeeperKeyPrefix, NormalizeZooKeeperKeyPrefix(""))
+	assert.Equal(t, "/a/b", NormalizeZooKeeperKeyPrefix("a/b/"))
+	assert.Equal(t, "/a", NormalizeZooKeeperKeyPrefix("/a"))
+
+	a := NewZooKeeperWorkerIDAllocator(newFakeZooKeeper(), &ZooKeeperWorkerIDAllocatorConfig{KeyPrefix: "/"})
+	assert.Equal(t, "/dc-1/workers/3", a.workerPath(1, 3))
+	assert.Equal(t, "/", a.rootPath())
+}
+
+func TestWorkerIDManager_ZooKeeperSessionExpiry(t *testing.T) {
+	allocator, fake := newFakeZooKeeperAllocator(t)
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{
+		TTL:               10 * time.Second,
+		HeartbeatInterval: time.Hour,
+	})
+	ctx := context.Background()
+
+	workerID, err := mgr.RegisterWorkerID(ctx, 31)
+	require.NoError(t, err)
+	require.True(t, mgr.IsHealthy())
+
+	// The manager notices the expired session without waiting for a heartbeat
+	fake.expireSession()
+	assert.Eventually(t, func() bool { return !mgr.IsHealthy() }, 5*time.Second, 10*time.Millisecond)
+
+	// The next heartbeat goes through the usual re-register flow: state is cleared and registration starts over
+	var lost *WorkerLeaseLostError
+	require.ErrorAs(t, mgr.tryReRegister(ctx), &lost)
+	assert.True(t, lost.Expired)
+	assert.Equal(t, workerID, lost.WorkerID)
+	assert.False(t, mgr.IsRegistered())
+
+	_, err = mgr.RegisterWorkerID(ctx, 31)
+	require.NoError(t, err)
+	assert.True(t, mgr.IsHealthy())
+	require.NoError(t, mgr.UnregisterWorkerID(ctx))
+}
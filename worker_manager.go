@@ -4,17 +4,15 @@ import (
 	"context"
 	crand "crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"net"
 	"os"
-	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-lynx/lynx/log"
-	"github.com/go-lynx/lynx/pkg/timex"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -48,9 +46,21 @@ func getLocalIP() string {
 	return "unknown"
 }
 
-// NewWorkerIDManager creates a new worker ID manager
-// Uses Redis INCR for lock-free worker ID allocation
+// NewWorkerIDManager creates a new worker ID manager backed by Redis.
+// Uses Redis INCR for lock-free worker ID allocation; see NewWorkerIDManagerWithAllocator for other backends.
 func NewWorkerIDManager(redisClient redis.UniversalClient, datacenterID int64, config *WorkerManagerConfig) *WorkerIDManager {
+	if config == nil {
+		config = DefaultWorkerManagerConfig()
+	}
+	var allocator WorkerIDAllocator
+	if redisClient != nil {
+		allocator = NewRedisWorkerIDAllocator(redisClient, &RedisWorkerIDAllocatorConfig{KeyPrefix: config.KeyPrefix})
+	}
+	return NewWorkerIDManagerWithAllocator(allocator, datacenterID, config)
+}
+
+// NewWorkerIDManagerWithAllocator creates a new worker ID manager that stores its claim in allocator
+func NewWorkerIDManagerWithAllocator(allocator WorkerIDAllocator, datacenterID int64, config *WorkerManagerConfig) *WorkerIDManager {
 	if config == nil {
 		config = DefaultWorkerManagerConfig()
 	}
@@ -63,10 +73,19 @@ func NewWorkerIDManager(redisClient redis.UniversalClient, datacenterID int64, c
 	if heartbeatInterval <= 0 {
 		heartbeatInterval = DefaultHeartbeatInterval
 	}
+	reuseSafetyMargin := config.ReuseSafetyMargin
+	if reuseSafetyMargin <= 0 {
+		reuseSafetyMargin = DefaultWorkerReuseSafetyMargin
+	}
+	leaseSafetyMargin := config.LeaseSafetyMargin
+	if leaseSafetyMargin <= 0 {
+		leaseSafetyMargin = DefaultWorkerLeaseSafetyMargin
+	}
 
 	mgr := &WorkerIDManager{
-		redisClient:       redisClient,
+		allocator:         allocator,
 		datacenterID:      datacenterID,
+		datacenterName:    config.DatacenterName,
 		keyPrefix:         keyPrefix,
 		ttl:               ttl,
 		heartbeatInterval: heartbeatInterval,
@@ -77,14 +96,20 @@ func NewWorkerIDManager(redisClient redis.UniversalClient, datacenterID int64, c
 		localIP:           getLocalIP(),
 		serviceName:       config.ServiceName,
 		serviceVersion:    config.ServiceVersion,
+		reuseSafetyMargin: reuseSafetyMargin,
+		leaseSafetyMargin: leaseSafetyMargin,
 	}
 	atomic.StoreInt32(&mgr.healthy, 1) // Initially healthy
 	return mgr
 }
 
-// RegisterWorkerID registers a worker ID
-// Flow: INCR to get workerID -> if exceeds max, reset to 0 -> SetNX to verify -> retry until full cycle
-// Heartbeat maintains key TTL to ensure worker ID exclusivity during instance lifetime
+// Allocator returns the backend that stores the worker ID claim
+func (w *WorkerIDManager) Allocator() WorkerIDAllocator {
+	return w.allocator
+}
+
+// RegisterWorkerID registers any free worker ID in [0, maxWorkerID].
+// Heartbeat maintains the claim to ensure worker ID exclusivity during instance lifetime
 func (w *WorkerIDManager) RegisterWorkerID(ctx context.Context, maxWorkerID int64) (int64, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -93,100 +118,30 @@ func (w *WorkerIDManager) RegisterWorkerID(ctx context.Context, maxWorkerID int6
 		atomic.StoreInt32(&w.healthy, 0)
 		return -1, fmt.Errorf("max worker ID must be non-negative, got %d", maxWorkerID)
 	}
-	if w.redisClient == nil {
+	if w.allocator == nil {
 		atomic.StoreInt32(&w.healthy, 0)
-		return -1, fmt.Errorf("redis client is nil")
+		return -1, fmt.Errorf("worker ID allocator is nil")
 	}
 	if w.registered {
 		return w.workerID, nil // Already registered (workerID can be 0)
 	}
 
-	counterKey := w.getCounterKey()
-	totalWorkerIDs := maxWorkerID + 1 // Total available worker IDs (0 to maxWorkerID)
-	maxRetries := int(totalWorkerIDs) // Try each worker ID at most once (full cycle)
-
-	// Loop to try acquiring an available worker ID
-	for retryCount := 0; retryCount < maxRetries; retryCount++ {
-		// Check if context is cancelled
-		select {
-		case <-ctx.Done():
-			return -1, ctx.Err()
-		default:
-		}
-
-		// 1. Atomic INCR with auto-reset using Lua script
-		// If counter exceeds max, reset to 1 and return 1
-		// This prevents race condition when multiple instances try to reset simultaneously
-		result, err := w.redisClient.Eval(ctx, LuaScriptIncrWithReset, []string{counterKey}, totalWorkerIDs).Result()
-		if err != nil {
-			return -1, fmt.Errorf("failed to execute INCR script: %w", err)
-		}
-		seq, err := redisResultToInt64(result)
-		if err != nil {
-			return -1, fmt.Errorf("INCR script result: %w", err)
-		}
-
-		// 2. workerID = seq - 1 (0-based)
-		workerID := seq - 1
-
-		// 4. SetNX to verify this worker ID is available
-		now := time.Now()
-		w.instanceID = w.generateInstanceID()
-		workerInfo := WorkerInfo{
-			WorkerID:       workerID,
-			DatacenterID:   w.datacenterID,
-			IP:             w.localIP,
-			ServiceName:    w.serviceName,
-			ServiceVersion: w.serviceVersion,
-			RegisterTime:   now.Unix(),
-			LastHeartbeat:  now.Unix(),
-			InstanceID:     w.instanceID,
-		}
-
-		key := w.getWorkerKey(workerID)
-		success, err := w.redisClient.SetNX(ctx, key, workerInfo.String(), w.ttl).Result()
-		if err != nil {
-			return -1, fmt.Errorf("failed to SetNX worker ID %d: %w", workerID, err)
-		}
-
-		if success {
-			// Registration successful
-			w.workerID = workerID
-			w.registered = true
-			w.registerTime = now
-
-			// Add to registry set (for monitoring)
-			registryKey := w.getRegistryKey()
-			_ = w.redisClient.SAdd(ctx, registryKey, fmt.Sprintf("%d:%d", w.datacenterID, workerID))
-
-			atomic.StoreInt32(&w.healthy, 1)
-			w.startHeartbeatLocked() // Start heartbeat to maintain key TTL
-
-			log.Infof("successfully registered worker ID %d (datacenter: %d, attempts: %d)", workerID, w.datacenterID, retryCount+1)
-			return workerID, nil
-		}
-
-		// SetNX failed, this worker ID is already taken
-		log.Debugf("worker ID %d is taken, attempt %d/%d", workerID, retryCount+1, maxRetries)
-
-		// Backoff sleep: random 10-50ms to prevent retry storms.
-		backoff := timex.RandomDuration(10*time.Millisecond, 50*time.Millisecond)
-		timer := time.NewTimer(backoff)
-		select {
-		case <-timer.C:
-		case <-ctx.Done():
-			timer.Stop()
-			return -1, ctx.Err()
+	acquireStart := monotonicNow()
+	info, err := w.allocator.Acquire(ctx, w.newWorkerInfoLocked(-1), maxWorkerID, w.ttl)
+	if err != nil {
+		if ctx.Err() == nil {
+			atomic.StoreInt32(&w.healthy, 0)
 		}
+		return -1, err
 	}
+	w.registeredLocked(info, acquireStart)
 
-	// All worker IDs are taken after a full cycle
-	atomic.StoreInt32(&w.healthy, 0)
-	return -1, fmt.Errorf("all %d worker IDs are occupied, registration failed", totalWorkerIDs)
+	log.Infof("successfully registered worker ID %d (datacenter: %d, allocator: %s)", info.WorkerID, w.datacenterID, w.allocator.Name())
+	return info.WorkerID, nil
 }
 
 // RegisterSpecificWorkerID registers a specific worker ID
-// Uses SetNX to verify worker ID availability, returns error if already taken
+// Returns *WorkerIDConflictError if the worker ID is already taken
 func (w *WorkerIDManager) RegisterSpecificWorkerID(ctx context.Context, workerID int64) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -195,9 +150,9 @@ func (w *WorkerIDManager) RegisterSpecificWorkerID(ctx context.Context, workerID
 		atomic.StoreInt32(&w.healthy, 0)
 		return fmt.Errorf("worker ID must be non-negative, got %d", workerID)
 	}
-	if w.redisClient == nil {
+	if w.allocator == nil {
 		atomic.StoreInt32(&w.healthy, 0)
-		return fmt.Errorf("redis client is nil")
+		return fmt.Errorf("worker ID allocator is nil")
 	}
 	if w.registered {
 		if w.workerID == workerID {
@@ -206,11 +161,30 @@ func (w *WorkerIDManager) RegisterSpecificWorkerID(ctx context.Context, workerID
 		return fmt.Errorf("already registered with worker ID %d", w.workerID)
 	}
 
+	acquireStart := monotonicNow()
+	info, err := w.allocator.Acquire(ctx, w.newWorkerInfoLocked(workerID), workerID, w.ttl)
+	if err != nil {
+		var conflict *WorkerIDConflictError
+		if errors.As(err, &conflict) {
+			atomic.StoreInt32(&w.healthy, 0) // Mark as unhealthy
+		}
+		return err
+	}
+	w.registeredLocked(info, acquireStart)
+
+	log.Infof("successfully registered specific worker ID %d (datacenter: %d, allocator: %s)", workerID, w.datacenterID, w.allocator.Name())
+	return nil
+}
+
+// newWorkerInfoLocked builds the claim for a new registration with a fresh instance ID.
+// Caller must hold w.mu.
+func (w *WorkerIDManager) newWorkerInfoLocked(workerID int64) WorkerInfo {
 	now := time.Now()
 	w.instanceID = w.generateInstanceID()
-	workerInfo := WorkerInfo{
+	return WorkerInfo{
 		WorkerID:       workerID,
 		DatacenterID:   w.datacenterID,
+		DatacenterName: w.datacenterName,
 		IP:             w.localIP,
 		ServiceName:    w.serviceName,
 		ServiceVersion: w.serviceVersion,
@@ -218,34 +192,57 @@ func (w *WorkerIDManager) RegisterSpecificWorkerID(ctx context.Context, workerID
 		LastHeartbeat:  now.Unix(),
 		InstanceID:     w.instanceID,
 	}
+}
 
-	// SetNX to verify worker ID availability
-	key := w.getWorkerKey(workerID)
-	success, err := w.redisClient.SetNX(ctx, key, workerInfo.String(), w.ttl).Result()
-	if err != nil {
-		return fmt.Errorf("failed to SetNX worker ID %d: %w", workerID, err)
-	}
-	if !success {
-		atomic.StoreInt32(&w.healthy, 0) // Mark as unhealthy
-		return &WorkerIDConflictError{
-			WorkerID:     workerID,
-			DatacenterID: w.datacenterID,
-			ConflictWith: "another instance",
-		}
+// registeredLocked records a successful claim made at acquireStart and starts the heartbeat.
+// Caller must hold w.mu.
+func (w *WorkerIDManager) registeredLocked(info *WorkerInfo, acquireStart int64) {
+	atomic.StoreInt64(&w.leaseDeadline, acquireStart+int64(w.leaseDurationLocked()))
+	w.workerID = info.WorkerID
+	w.registered = true
+	w.registerTime = time.Unix(info.RegisterTime, 0)
+	w.previousTimestamp = info.LastTimestamp
+	if info.LastTimestamp > 0 {
+		log.Infof("worker ID %d was last used at %s", info.WorkerID, time.UnixMilli(info.LastTimestamp).Format(time.RFC3339Nano))
 	}
 
-	w.workerID = workerID
-	w.registered = true
-	w.registerTime = now
+	atomic.StoreInt32(&w.healthy, 1)
+	w.startHeartbeatLocked() // Start heartbeat to maintain the claim
+	w.emitLocked(EventWorkerRegistered, info.WorkerID, WorkerRegisteredPayload{
+		InstanceID:        info.InstanceID,
+		DatacenterName:    w.datacenterName,
+		Allocator:         w.allocator.Name(),
+		PreviousTimestamp: info.LastTimestamp,
+	})
+
+	if watcher, ok := w.allocator.(WorkerLeaseWatcher); ok {
+		go w.watchLease(watcher.LeaseDone(*info), info.InstanceID, info.WorkerID)
+	}
+}
 
-	registryKey := w.getRegistryKey()
-	_ = w.redisClient.SAdd(ctx, registryKey, fmt.Sprintf("%d:%d", w.datacenterID, workerID))
+// watchLease marks the manager unhealthy as soon as the allocator reports the claim lost, the same way a
+// failed heartbeat does; the heartbeat loop then re-registers. It ends when the claim is released.
+func (w *WorkerIDManager) watchLease(done <-chan struct{}, instanceID string, workerID int64) {
+	<-done
 
-	atomic.StoreInt32(&w.healthy, 1)
-	w.startHeartbeatLocked()
+	w.mu.RLock()
+	current := w.registered && w.instanceID == instanceID
+	if current {
+		w.emitLocked(EventLeaseLost, workerID, LeaseLostPayload{Reason: LeaseLostWatch})
+	}
+	w.mu.RUnlock()
+	if !current {
+		return // released or replaced by a newer registration
+	}
+	atomic.StoreInt32(&w.healthy, 0)
+	log.Warnf("eon-id worker ID %d lease lost, marking unhealthy", workerID)
+}
 
-	log.Infof("successfully registered specific worker ID %d (datacenter: %d)", workerID, w.datacenterID)
-	return nil
+// IsRegistered returns whether a worker ID is currently held
+func (w *WorkerIDManager) IsRegistered() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.registered
 }
 
 // IsHealthy returns whether the worker manager is healthy (heartbeat is working)
@@ -253,6 +250,34 @@ func (w *WorkerIDManager) IsHealthy() bool {
 	return atomic.LoadInt32(&w.healthy) == 1
 }
 
+// UpdateTiming changes the registration TTL and heartbeat interval of a running manager.
+// The new TTL is applied by the next heartbeat; a running heartbeat loop is restarted with the new interval.
+func (w *WorkerIDManager) UpdateTiming(ttl, heartbeatInterval time.Duration) error {
+	if ttl <= 0 || heartbeatInterval <= 0 {
+		return fmt.Errorf("worker ID TTL and heartbeat interval must be positive")
+	}
+	if heartbeatInterval >= ttl {
+		return fmt.Errorf("heartbeat interval (%v) must be less than worker ID TTL (%v)", heartbeatInterval, ttl)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	intervalChanged := w.heartbeatInterval != heartbeatInterval
+	w.ttl = ttl
+	w.heartbeatInterval = heartbeatInterval
+
+	if intervalChanged && w.heartbeatRunning {
+		// The old loop only clears state that still belongs to its own context, so it cannot undo this restart
+		w.heartbeatCancel()
+		w.heartbeatCtx = nil
+		w.heartbeatCancel = nil
+		w.heartbeatRunning = false
+		w.startHeartbeatLocked()
+	}
+	return nil
+}
+
 // startHeartbeatLocked starts the heartbeat if not running.
 // Caller must hold w.mu.
 func (w *WorkerIDManager) startHeartbeatLocked() {
@@ -267,12 +292,12 @@ func (w *WorkerIDManager) startHeartbeatLocked() {
 	w.heartbeatCtx = ctx
 	w.heartbeatCancel = cancel
 	w.heartbeatRunning = true
-	go w.heartbeatLoop(ctx)
+	go w.heartbeatLoop(ctx, w.heartbeatInterval)
 }
 
 // heartbeatLoop starts the heartbeat process with context cancellation.
-func (w *WorkerIDManager) heartbeatLoop(ctx context.Context) {
-	ticker := time.NewTicker(w.heartbeatInterval)
+func (w *WorkerIDManager) heartbeatLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	defer func() {
 		w.mu.Lock()
@@ -292,10 +317,23 @@ func (w *WorkerIDManager) heartbeatLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := w.sendHeartbeat(); err != nil {
+			if ctx.Err() != nil {
+				return // stopped while both cases were ready, e.g. by tryReRegister on a lost lease
+			}
+			err := w.sendHeartbeat()
+			var revoked *WorkerRevokedError
+			if errors.As(err, &revoked) {
+				continue // sendHeartbeat dropped the worker ID and stopped this loop; a new registration starts another
+			}
+			if err != nil {
 				consecutiveFailures++
 				log.Warnf("eon-id worker heartbeat failed (attempt %d/%d): %v",
 					consecutiveFailures, maxConsecutiveFailures, err)
+				w.emit(EventHeartbeatDegraded, HeartbeatDegradedPayload{
+					ConsecutiveFailures: consecutiveFailures,
+					ReregisterAfter:     maxConsecutiveFailures,
+					Error:               err.Error(),
+				})
 
 				// Mark as unhealthy after first failure to prevent ID generation
 				if consecutiveFailures >= 1 {
@@ -329,22 +367,19 @@ func (w *WorkerIDManager) heartbeatLoop(ctx context.Context) {
 }
 
 // tryReRegister attempts to re-register the current worker ID.
-// Only updates Redis if the key still belongs to this instance (same instance_id);
+// The allocator only renews the claim if it still belongs to this instance (same instance_id);
 // avoids overwriting another instance that took the same worker ID after expiry.
 // On failure, clears local state so caller can attempt full re-registration.
 func (w *WorkerIDManager) tryReRegister(ctx context.Context) error {
-	if w.redisClient == nil {
-		return fmt.Errorf("redis client is nil")
+	if w.allocator == nil {
+		return fmt.Errorf("worker ID allocator is nil")
 	}
 
 	w.mu.RLock()
 	workerID := w.workerID
 	registered := w.registered
-	registerTime := w.registerTime
-	instanceID := w.instanceID
-	localIP := w.localIP
-	serviceName := w.serviceName
-	serviceVersion := w.serviceVersion
+	info := w.currentWorkerInfoLocked()
+	ttl := w.ttl
 	w.mu.RUnlock()
 
 	if !registered || workerID < 0 {
@@ -354,140 +389,307 @@ func (w *WorkerIDManager) tryReRegister(ctx context.Context) error {
 	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	key := w.getWorkerKey(workerID)
-	workerInfo := WorkerInfo{
-		WorkerID:       workerID,
-		DatacenterID:   w.datacenterID,
-		IP:             localIP,
-		ServiceName:    serviceName,
-		ServiceVersion: serviceVersion,
-		RegisterTime:   registerTime.Unix(),
-		LastHeartbeat:  time.Now().Unix(),
-		InstanceID:     instanceID,
-	}
-
-	result, err := w.redisClient.Eval(timeoutCtx, LuaScriptHeartbeat, []string{key},
-		workerInfo.String(), instanceID, int64(w.ttl.Seconds())).Result()
-	if err != nil {
-		return fmt.Errorf("re-register script failed: %w", err)
-	}
-	code, err := redisResultToInt64(result)
-	if err != nil {
-		return err
-	}
-	switch code {
-	case 1:
-		return nil
-	case 0:
-		// Another instance took our worker ID; clear state for full re-registration
-		w.mu.Lock()
-		w.workerID = -1
-		w.registered = false
-		w.mu.Unlock()
-		return fmt.Errorf("worker ID %d was taken by another instance", workerID)
-	case -1:
-		// Key expired; clear state for full re-registration
+	renewStart := monotonicNow()
+	err := w.allocator.Renew(timeoutCtx, info, ttl)
+	var lost *WorkerLeaseLostError
+	if errors.As(err, &lost) {
+		// Another instance took our worker ID or the claim expired; clear state for full re-registration
 		w.mu.Lock()
-		w.workerID = -1
-		w.registered = false
+		cleared := w.registered && w.workerID == workerID
+		if cleared {
+			// Stop the heartbeat: there is nothing left to renew, and its failures would mark the manager
+			// unhealthy after recovery registered a new worker ID, which starts a fresh loop
+			if w.heartbeatCancel != nil {
+				w.heartbeatCancel()
+				w.heartbeatCancel = nil
+				w.heartbeatCtx = nil
+				w.heartbeatRunning = false
+			}
+			w.workerID = -1
+			w.registered = false
+			atomic.StoreInt64(&w.leaseDeadline, 0)
+			reason := LeaseLostTaken
+			if lost.Expired {
+				reason = LeaseLostExpired
+			}
+			w.emitLocked(EventLeaseLost, workerID, LeaseLostPayload{Reason: reason, Error: lost.Error()})
+		}
+		onLeaseLost := w.onLeaseLost
 		w.mu.Unlock()
-		return fmt.Errorf("worker ID %d key has expired", workerID)
-	case -2:
-		return fmt.Errorf("worker ID %d has invalid JSON format", workerID)
-	default:
-		return fmt.Errorf("re-register returned unknown status: %d", code)
+		if cleared && onLeaseLost != nil {
+			onLeaseLost(workerID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("re-register failed: %w", err)
 	}
+	w.extendLease(info.InstanceID, renewStart)
+	w.emit(EventWorkerReregistered, WorkerReregisteredPayload{PreviousWorkerID: workerID, SameWorkerID: true})
+	return nil
 }
 
-// sendHeartbeat sends heartbeat to maintain worker ID key TTL
-// Uses Lua script to atomically verify instanceID and refresh TTL
+// sendHeartbeat renews the claim so that it outlives the next heartbeat interval
 func (w *WorkerIDManager) sendHeartbeat() error {
-	if w.redisClient == nil {
-		return fmt.Errorf("redis client is nil")
+	if w.allocator == nil {
+		return fmt.Errorf("worker ID allocator is nil")
 	}
 
 	w.mu.RLock()
 	workerID := w.workerID
-	registerTime := w.registerTime
-	instanceID := w.instanceID
-	localIP := w.localIP
-	serviceName := w.serviceName
-	serviceVersion := w.serviceVersion
+	info := w.currentWorkerInfoLocked()
+	ttl := w.ttl
+	parent := w.heartbeatCtx
 	w.mu.RUnlock()
 
 	if workerID == -1 {
 		return fmt.Errorf("worker ID not registered")
 	}
 
-	parent := w.heartbeatCtx
 	if parent == nil {
 		parent = context.Background()
 	}
 	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
 	defer cancel()
 
-	key := w.getWorkerKey(workerID)
+	renewStart := monotonicNow()
+	if err := w.allocator.Renew(ctx, info, ttl); err != nil {
+		var revoked *WorkerRevokedError
+		if errors.As(err, &revoked) {
+			w.dropRevoked(info, revoked)
+		}
+		return err
+	}
+	w.extendLease(info.InstanceID, renewStart)
+	return nil
+}
 
-	workerInfo := WorkerInfo{
-		WorkerID:       workerID,
+// dropRevoked gives up a worker ID whose claim an operator has revoked. The manager turns unhealthy and stops
+// its heartbeat at once; the revoked handler then fences ID issuance, and only after that is the last issued
+// timestamp read and the claim released, so the next owner of the worker ID waits for every ID issued under it.
+func (w *WorkerIDManager) dropRevoked(info WorkerInfo, revoked *WorkerRevokedError) {
+	w.mu.Lock()
+	if !w.registered || w.instanceID != info.InstanceID {
+		w.mu.Unlock()
+		return // released or replaced by a newer registration
+	}
+	atomic.StoreInt32(&w.healthy, 0)
+	if w.heartbeatCancel != nil {
+		w.heartbeatCancel()
+		w.heartbeatCancel = nil
+		w.heartbeatCtx = nil
+		w.heartbeatRunning = false
+	}
+	w.workerID = -1
+	w.registered = false
+	atomic.StoreInt64(&w.leaseDeadline, 0)
+	w.emitLocked(EventLeaseLost, info.WorkerID, LeaseLostPayload{Reason: LeaseLostRevoked, Error: revoked.Error()})
+	onRevoked, onLeaseLost := w.onRevoked, w.onLeaseLost
+	lastTimestamp := w.lastTimestamp
+	w.mu.Unlock()
+
+	log.Errorf("eon-id %v, dropping the worker ID", revoked)
+	switch {
+	case onRevoked != nil:
+		onRevoked(revoked)
+	case onLeaseLost != nil:
+		onLeaseLost(info.WorkerID)
+	}
+	if lastTimestamp != nil {
+		if ts := lastTimestamp(); ts > info.LastTimestamp {
+			info.LastTimestamp = ts
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.allocator.Release(ctx, info); err != nil {
+		log.Warnf("failed to release revoked worker ID %d, it is freed when the claim expires: %v", info.WorkerID, err)
+	}
+}
+
+// monotonicBase anchors monotonicNow; durations measured from it are immune to wall clock steps
+var monotonicBase = time.Now()
+
+// monotonicNow returns the monotonic clock in nanoseconds since process start
+func monotonicNow() int64 {
+	return int64(time.Since(monotonicBase))
+}
+
+// leaseDurationLocked is how long a renewal keeps the local lease: the TTL minus the safety margin, where the
+// margin is capped at a third of the TTL so that the deadline always lies beyond the next heartbeat.
+// Caller must hold w.mu (read or write).
+func (w *WorkerIDManager) leaseDurationLocked() time.Duration {
+	margin := w.leaseSafetyMargin
+	if margin > w.ttl/3 {
+		margin = w.ttl / 3
+	}
+	return w.ttl - margin
+}
+
+// extendLease moves the local lease deadline after a renewal that started at renewStart succeeded, unless the
+// claim has changed hands in the meantime. The deadline never moves backwards, so a slow renewal that
+// completes after a newer one cannot shorten the lease.
+func (w *WorkerIDManager) extendLease(instanceID string, renewStart int64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.registered || w.instanceID != instanceID {
+		return
+	}
+	deadline := renewStart + int64(w.leaseDurationLocked())
+	for {
+		current := atomic.LoadInt64(&w.leaseDeadline)
+		if current >= deadline || atomic.CompareAndSwapInt64(&w.leaseDeadline, current, deadline) {
+			return
+		}
+	}
+}
+
+// CheckLease returns *WorkerLeaseExpiredError once the local lease deadline has passed, i.e. when no renewal
+// started within the TTL minus the safety margin has succeeded. The claim may already have expired in the
+// backend and been handed to another instance, so IDs must not be issued, whatever the heartbeat loop reports:
+// a renewal call can hang, and the heartbeat goroutine can be starved by GC pauses or CPU throttling.
+// It returns nil when no claim is held.
+func (w *WorkerIDManager) CheckLease() error {
+	expiredFor, expired := w.LeaseExpired()
+	if !expired {
+		return nil
+	}
+	w.mu.RLock()
+	workerID := w.workerID
+	w.mu.RUnlock()
+	return &WorkerLeaseExpiredError{WorkerID: workerID, ExpiredFor: expiredFor}
+}
+
+// LeaseExpired reports whether the local lease deadline has passed and by how much; expired is false when no
+// claim is held. It takes no lock, so the generator can call it while holding its own, see Generator.SetLeaseCheck.
+func (w *WorkerIDManager) LeaseExpired() (expiredFor time.Duration, expired bool) {
+	deadline := atomic.LoadInt64(&w.leaseDeadline)
+	if deadline == 0 {
+		return 0, false
+	}
+	now := monotonicNow()
+	if now < deadline {
+		return 0, false
+	}
+	return time.Duration(now - deadline), true
+}
+
+// LeaseRemaining returns how long IDs may still be issued without another successful renewal;
+// ok is false when no claim is held
+func (w *WorkerIDManager) LeaseRemaining() (remaining time.Duration, ok bool) {
+	deadline := atomic.LoadInt64(&w.leaseDeadline)
+	if deadline == 0 {
+		return 0, false
+	}
+	return time.Duration(deadline - monotonicNow()), true
+}
+
+// currentWorkerInfoLocked returns the claim of the current registration with a fresh heartbeat time.
+// Caller must hold w.mu (read or write).
+func (w *WorkerIDManager) currentWorkerInfoLocked() WorkerInfo {
+	return WorkerInfo{
+		WorkerID:       w.workerID,
 		DatacenterID:   w.datacenterID,
-		IP:             localIP,
-		ServiceName:    serviceName,
-		ServiceVersion: serviceVersion,
-		RegisterTime:   registerTime.Unix(),
+		DatacenterName: w.datacenterName,
+		IP:             w.localIP,
+		ServiceName:    w.serviceName,
+		ServiceVersion: w.serviceVersion,
+		RegisterTime:   w.registerTime.Unix(),
 		LastHeartbeat:  time.Now().Unix(),
-		InstanceID:     instanceID,
+		InstanceID:     w.instanceID,
+		LastTimestamp:  w.lastIssuedTimestampLocked(),
 	}
+}
 
-	result, err := w.redisClient.Eval(ctx, LuaScriptHeartbeat, []string{key},
-		workerInfo.String(), instanceID, int64(w.ttl.Seconds())).Result()
-	if err != nil {
-		return fmt.Errorf("heartbeat script execution failed: %w", err)
+// SetLastTimestampSource sets the function that reports the Unix ms timestamp of the last ID issued under
+// the worker ID. Allocators store it with the claim, e.g. in a file lock slot.
+func (w *WorkerIDManager) SetLastTimestampSource(source func() int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastTimestamp = source
+}
+
+// SetLeaseLostHandler sets the function called when re-registration finds the claim expired or taken by
+// another instance. The manager has then dropped the worker ID; the handler runs on the heartbeat goroutine
+// and should fence ID issuance and start recovery without blocking.
+func (w *WorkerIDManager) SetLeaseLostHandler(handler func(workerID int64)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onLeaseLost = handler
+}
+
+// SetRevokedHandler sets the function called when a heartbeat finds the claim revoked by an operator (see
+// RevokeWorkerID). The manager has then dropped the worker ID; the handler must fence ID issuance before it
+// returns, because the claim is released right after. Without a revoked handler the lease-lost handler is called.
+func (w *WorkerIDManager) SetRevokedHandler(handler func(revoked *WorkerRevokedError)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onRevoked = handler
+}
+
+// SetEventHandler sets the function that receives worker lifecycle events: registered, heartbeat degraded,
+// lease lost and re-registered with the same worker ID. It is called with the manager's lock held and must not
+// block or call back into the manager; the plugin passes events on through PlugSnowflake.OnEvent.
+func (w *WorkerIDManager) SetEventHandler(handler func(Event)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onEvent = handler
+}
+
+// emit sends an event about the current worker ID to the event handler
+func (w *WorkerIDManager) emit(eventType EventType, payload any) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.emitLocked(eventType, w.workerID, payload)
+}
+
+// emitLocked sends an event about workerID to the event handler.
+// Caller must hold w.mu (read or write).
+func (w *WorkerIDManager) emitLocked(eventType EventType, workerID int64, payload any) {
+	if w.onEvent == nil {
+		return
+	}
+	w.onEvent(Event{
+		Type:         eventType,
+		Time:         time.Now(),
+		DatacenterID: w.datacenterID,
+		WorkerID:     workerID,
+		Payload:      payload,
+	})
+}
+
+func (w *WorkerIDManager) lastIssuedTimestampLocked() int64 {
+	if w.lastTimestamp == nil {
+		return 0
+	}
+	if ts := w.lastTimestamp(); ts > 0 {
+		return ts
 	}
+	return 0
+}
 
-	code, err := redisResultToInt64(result)
-	if err != nil {
-		return fmt.Errorf("heartbeat script result: %w", err)
-	}
-	switch code {
-	case 1:
-		return nil // Success
-	case 0:
-		return fmt.Errorf("worker ID %d was taken by another instance", workerID)
-	case -1:
-		return fmt.Errorf("worker ID %d key has expired", workerID)
-	case -2:
-		return fmt.Errorf("worker ID %d has invalid JSON format", workerID)
-	default:
-		return fmt.Errorf("heartbeat returned unknown status: %d", code)
+// ReuseNotBefore returns the Unix ms timestamp the generator must pass before issuing IDs with the registered
+// worker ID: the last timestamp the allocator recorded for the worker ID, from its previous owner, plus the
+// reuse safety margin. It is 0 when the allocator has no record, i.e. the worker ID is fresh.
+func (w *WorkerIDManager) ReuseNotBefore() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.registered || w.previousTimestamp <= 0 {
+		return 0
 	}
+	return w.previousTimestamp + w.reuseSafetyMargin.Milliseconds()
 }
 
 // UnregisterWorkerID unregisters the worker ID.
-// Only deletes the worker key and removes from registry when the key's instance_id matches this instance (safe for graceful shutdown).
+// The allocator only releases the claim when it still belongs to this instance (safe for graceful shutdown).
 func (w *WorkerIDManager) UnregisterWorkerID(ctx context.Context) error {
 	w.mu.Lock()
 	if !w.registered {
 		w.mu.Unlock()
 		return nil // Not registered
 	}
-	if w.redisClient == nil {
-		atomic.StoreInt32(&w.healthy, 0)
-		if w.heartbeatCancel != nil {
-			w.heartbeatCancel()
-			w.heartbeatCancel = nil
-			w.heartbeatCtx = nil
-			w.heartbeatRunning = false
-		}
-		w.workerID = -1
-		w.registered = false
-		w.mu.Unlock()
-		return fmt.Errorf("redis client is nil")
-	}
 
-	workerID := w.workerID
-	instanceID := w.instanceID
-	registryMember := fmt.Sprintf("%d:%d", w.datacenterID, w.workerID)
+	info := w.currentWorkerInfoLocked()
 
 	// Mark as unhealthy and stop heartbeat first
 	atomic.StoreInt32(&w.healthy, 0)
@@ -499,31 +701,13 @@ func (w *WorkerIDManager) UnregisterWorkerID(ctx context.Context) error {
 	}
 	w.workerID = -1
 	w.registered = false
+	atomic.StoreInt64(&w.leaseDeadline, 0)
 	w.mu.Unlock()
 
-	key := w.getWorkerKey(workerID)
-	registryKey := w.getRegistryKey()
-
-	// Only delete if the key still belongs to this instance (instance_id match)
-	infoStr, err := w.redisClient.Get(ctx, key).Result()
-	if err != nil {
-		if err == redis.Nil {
-			// Key already expired or deleted; still remove from registry if present (idempotent)
-			_ = w.redisClient.SRem(ctx, registryKey, registryMember).Err()
-		}
-		return nil
-	}
-	info, err := ParseWorkerInfo(infoStr)
-	if err != nil {
-		return nil
+	if w.allocator == nil {
+		return fmt.Errorf("worker ID allocator is nil")
 	}
-	if info.InstanceID != instanceID {
-		// Another instance took this worker ID; do not delete or SRem
-		return nil
-	}
-	_ = w.redisClient.Del(ctx, key).Err()
-	_ = w.redisClient.SRem(ctx, registryKey, registryMember).Err()
-	return nil
+	return w.allocator.Release(ctx, info)
 }
 
 // GetWorkerID returns the current worker ID
@@ -533,57 +717,85 @@ func (w *WorkerIDManager) GetWorkerID() int64 {
 	return w.workerID
 }
 
-// GetRegisteredWorkers returns all registered workers.
-// Stale registry members (worker key expired or missing, e.g. after power loss) are removed from the set (lazy cleanup).
-func (w *WorkerIDManager) GetRegisteredWorkers(ctx context.Context) ([]WorkerInfo, error) {
-	if w.redisClient == nil {
-		return nil, fmt.Errorf("redis client is nil")
-	}
-
-	registryKey := w.getRegistryKey()
-
-	members, err := w.redisClient.SMembers(ctx, registryKey).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get registry members: %w", err)
-	}
+// DatacenterID returns the datacenter ID the worker ID is registered under
+func (w *WorkerIDManager) DatacenterID() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.datacenterID
+}
 
-	var workers []WorkerInfo
-	for _, member := range members {
-		parts := strings.Split(member, ":")
-		if len(parts) != 2 {
-			continue
-		}
+// Timing returns the registration TTL and heartbeat interval, see UpdateTiming
+func (w *WorkerIDManager) Timing() (ttl, heartbeatInterval time.Duration) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.ttl, w.heartbeatInterval
+}
 
-		datacenterID, err := strconv.ParseInt(parts[0], 10, 64)
-		if err != nil {
-			continue
-		}
+// DatacenterName returns the name the datacenter ID was resolved from, or "" for a configured datacenter ID
+func (w *WorkerIDManager) DatacenterName() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.datacenterName
+}
 
-		workerID, err := strconv.ParseInt(parts[1], 10, 64)
-		if err != nil {
-			continue
-		}
+// GetRegisteredWorkers returns all registered workers
+func (w *WorkerIDManager) GetRegisteredWorkers(ctx context.Context) ([]WorkerInfo, error) {
+	if w.allocator == nil {
+		return nil, fmt.Errorf("worker ID allocator is nil")
+	}
+	return w.allocator.List(ctx)
+}
 
-		// Get worker info; worker key has TTL and may have expired (e.g. instance died without Stop)
-		key := w.getWorkerKeyForDatacenter(datacenterID, workerID)
-		infoStr, err := w.redisClient.Get(ctx, key).Result()
-		if err != nil {
-			if err == redis.Nil {
-				// Key expired or missing: remove stale member from registry (lazy cleanup)
-				_ = w.redisClient.SRem(ctx, registryKey, member).Err()
-			}
-			continue
-		}
+// TrimRegistry removes the registry entries of expired claims if the allocator keeps a separate registry
+// (see WorkerRegistryTrimmer); entries are checked once their last heartbeat is older than the worker ID TTL
+func (w *WorkerIDManager) TrimRegistry(ctx context.Context) (int, error) {
+	trimmer, ok := w.allocator.(WorkerRegistryTrimmer)
+	if !ok {
+		return 0, nil
+	}
+	w.mu.RLock()
+	ttl := w.ttl
+	w.mu.RUnlock()
+	return trimmer.TrimRegistry(ctx, ttl)
+}
 
-		workerInfo, err := ParseWorkerInfo(infoStr)
-		if err != nil {
-			continue
-		}
+// RevokeWorkerID revokes the claim on a worker ID in the manager's datacenter, whichever instance holds it, if
+// the allocator supports it (see WorkerRevoker). The owner stops issuing IDs at its next heartbeat and releases
+// the worker ID; until then no other instance can claim it. It returns the revoked claim, or nil if the worker ID
+// is free, and writes the revocation to the audit log.
+func (w *WorkerIDManager) RevokeWorkerID(ctx context.Context, workerID int64, reason string) (*WorkerInfo, error) {
+	revoker, ok := w.allocator.(WorkerRevoker)
+	if !ok {
+		return nil, fmt.Errorf("worker ID allocator cannot revoke claims")
+	}
+	w.mu.RLock()
+	by := w.instanceID
+	if by == "" {
+		by = w.localIP
+	}
+	w.mu.RUnlock()
 
-		workers = append(workers, *workerInfo)
+	revocation := WorkerRevocation{At: time.Now().UnixMilli(), By: by, Reason: reason}
+	info, err := revoker.RevokeWorker(ctx, w.datacenterID, workerID, revocation)
+	result, holder := "success", ""
+	switch {
+	case err != nil:
+		result = "failure: " + err.Error()
+	case info == nil:
+		result = "not claimed"
+	default:
+		holder = info.InstanceID
 	}
+	auditWorkerAction("worker_id.revoke", w.localIP, w.datacenterID, workerID, result, revocationDetails(revocation, holder))
+	return info, err
+}
 
-	return workers, nil
+// WatchWorkers reports workers joining, leaving and taking over worker IDs until ctx is done
+func (w *WorkerIDManager) WatchWorkers(ctx context.Context) (<-chan WorkerEvent, error) {
+	if w.allocator == nil {
+		return nil, fmt.Errorf("worker ID allocator is nil")
+	}
+	return w.allocator.Watch(ctx)
 }
 
 // NormalizeKeyPrefix ensures the key prefix ends with ":" or "_" for dc/worker/registry concatenation; aligns with DefaultRedisKeyPrefix.
@@ -597,23 +809,6 @@ func NormalizeKeyPrefix(prefix string) string {
 	return prefix
 }
 
-// Helper methods (keyPrefix is normalized via NormalizeKeyPrefix at creation time)
-func (w *WorkerIDManager) getWorkerKey(workerID int64) string {
-	return fmt.Sprintf("%sdc:%d:worker:%d", w.keyPrefix, w.datacenterID, workerID)
-}
-
-func (w *WorkerIDManager) getWorkerKeyForDatacenter(datacenterID, workerID int64) string {
-	return fmt.Sprintf("%sdc:%d:worker:%d", w.keyPrefix, datacenterID, workerID)
-}
-
-func (w *WorkerIDManager) getCounterKey() string {
-	return fmt.Sprintf("%sdc:%d:counter", w.keyPrefix, w.datacenterID)
-}
-
-func (w *WorkerIDManager) getRegistryKey() string {
-	return fmt.Sprintf("%sregistry", w.keyPrefix)
-}
-
 func (w *WorkerIDManager) generateInstanceID() string {
 	// Include PID and random to reduce collision probability under high concurrency
 	pid := os.Getpid()
@@ -636,12 +831,23 @@ func secureInt63n(max int64) int64 {
 type WorkerInfo struct {
 	WorkerID       int64  `json:"worker_id"`
 	DatacenterID   int64  `json:"datacenter_id"`
+	DatacenterName string `json:"datacenter_name,omitempty"` // the name datacenter_id was resolved from, if any
 	IP             string `json:"ip"`
 	ServiceName    string `json:"service_name"`
 	ServiceVersion string `json:"service_version"`
 	RegisterTime   int64  `json:"register_time"`
 	LastHeartbeat  int64  `json:"last_heartbeat"`
 	InstanceID     string `json:"instance_id"`
+	LastTimestamp  int64  `json:"last_timestamp,omitempty"` // Unix ms of the last ID issued under this worker ID
+	// Revocation is set once an operator has revoked the claim, see WorkerRevoker
+	Revocation *WorkerRevocation `json:"revocation,omitempty"`
+}
+
+// WorkerRevocation records who revoked a claim, when and why
+type WorkerRevocation struct {
+	At     int64  `json:"at"` // Unix ms
+	By     string `json:"by,omitempty"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // String returns JSON representation of WorkerInfo
@@ -676,6 +882,13 @@ type WorkerManagerConfig struct {
 	HeartbeatInterval time.Duration
 	ServiceName       string // Application name (e.g. from lynx.GetName())
 	ServiceVersion    string // Application version (e.g. from lynx.GetVersion())
+	DatacenterName    string // Name the datacenter ID was resolved from, stored with the claim (optional)
+	// ReuseSafetyMargin is added to the previous owner's last timestamp, see ReuseNotBefore
+	// (default: DefaultWorkerReuseSafetyMargin)
+	ReuseSafetyMargin time.Duration
+	// LeaseSafetyMargin is subtracted from the TTL for the local lease deadline, see CheckLease
+	// (default: DefaultWorkerLeaseSafetyMargin)
+	LeaseSafetyMargin time.Duration
 }
 
 // DefaultWorkerManagerConfig returns default worker manager configuration
@@ -684,5 +897,7 @@ func DefaultWorkerManagerConfig() *WorkerManagerConfig {
 		KeyPrefix:         DefaultRedisKeyPrefix,
 		TTL:               DefaultWorkerIDTTL,
 		HeartbeatInterval: DefaultHeartbeatInterval,
+		ReuseSafetyMargin: DefaultWorkerReuseSafetyMargin,
+		LeaseSafetyMargin: DefaultWorkerLeaseSafetyMargin,
 	}
 }
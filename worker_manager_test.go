@@ -91,7 +91,7 @@ func TestWorkerIDManager_HeartbeatLoopClearsRunningState(t *testing.T) {
 
 	done := make(chan struct{})
 	go func() {
-		mgr.heartbeatLoop(ctx)
+		mgr.heartbeatLoop(ctx, mgr.heartbeatInterval)
 		close(done)
 	}()
 	cancel()
@@ -0,0 +1,163 @@
+package eonId
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// maxWorkerIDFromConfig returns the largest worker ID the configured layout can encode
+func maxWorkerIDFromConfig(conf *pb.EonId) int64 {
+	maxWorkerID := int64((1 << conf.WorkerIdBits) - 1)
+	if maxWorkerID == 0 {
+		maxWorkerID = 31
+	}
+	return maxWorkerID
+}
+
+// workerRecoveryPolicy returns the number of attempts and the initial backoff for worker ID recovery;
+// attempts is 0 when recovery is disabled
+func workerRecoveryPolicy(conf *pb.EonId) (attempts int, backoff time.Duration) {
+	attempts = DefaultWorkerRecoveryMaxAttempts
+	switch {
+	case conf.WorkerRecoveryMaxAttempts < 0:
+		attempts = 0
+	case conf.WorkerRecoveryMaxAttempts > 0:
+		attempts = int(conf.WorkerRecoveryMaxAttempts)
+	}
+	backoff = DefaultWorkerRecoveryBackoff
+	if conf.WorkerRecoveryBackoff != nil && conf.WorkerRecoveryBackoff.AsDuration() > 0 {
+		backoff = conf.WorkerRecoveryBackoff.AsDuration()
+	}
+	return attempts, backoff
+}
+
+// onWorkerLeaseLost is the WorkerIDManager lease-lost handler. It fences the generator before returning, so no
+// ID is issued under the lost worker ID from here on, and starts recovery unless it is already running.
+func (p *PlugSnowflake) onWorkerLeaseLost(workerID int64) {
+	p.mu.RLock()
+	generator := p.generator
+	p.mu.RUnlock()
+	if generator != nil {
+		generator.Fence()
+	}
+	lynxlog.Errorf("eon-id worker ID %d lease lost, ID generation fenced", workerID)
+
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     plugins.EventHealthStatusCritical,
+		Priority: plugins.PriorityHigh,
+		Source:   "WorkerIDRecovery",
+		Category: "worker_id",
+		Metadata: map[string]any{"worker_id": workerID, "reason": "lease lost"},
+	})
+
+	if atomic.CompareAndSwapInt32(&p.recovering, 0, 1) {
+		go p.recoverWorkerID(workerID)
+	}
+}
+
+// recoverWorkerID registers a new worker ID after the lease on lostWorkerID was lost and swaps it into the
+// generator, lifting the fence. Attempts back off exponentially; recovery stops at shutdown, and after the
+// configured number of attempts the plugin stays fenced and not ready.
+func (p *PlugSnowflake) recoverWorkerID(lostWorkerID int64) {
+	defer atomic.StoreInt32(&p.recovering, 0)
+
+	p.mu.RLock()
+	conf := p.conf
+	workerManager := p.workerManager
+	generator := p.generator
+	p.mu.RUnlock()
+	if conf == nil || workerManager == nil {
+		return
+	}
+
+	maxAttempts, backoff := workerRecoveryPolicy(conf)
+	maxWorkerID := maxWorkerIDFromConfig(conf)
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-p.shutdownCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff *= 2
+			if backoff > MaxWorkerRecoveryBackoff {
+				backoff = MaxWorkerRecoveryBackoff
+			}
+		}
+		select {
+		case <-p.shutdownCh:
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		workerID, err := workerManager.RegisterWorkerID(ctx, maxWorkerID)
+		cancel()
+		if err != nil {
+			lastErr = err
+			lynxlog.Warnf("eon-id worker ID recovery attempt %d/%d failed: %v", attempt, maxAttempts, err)
+			if generator != nil {
+				if m := generator.activeMetrics(); m != nil {
+					m.RecordWorkerIDRecovery(false)
+				}
+			}
+			continue
+		}
+
+		p.mu.Lock()
+		select {
+		case <-p.shutdownCh:
+			// Cleanup may already have unregistered; do not leave the new claim behind
+			p.mu.Unlock()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = workerManager.UnregisterWorkerID(ctx)
+			cancel()
+			return
+		default:
+		}
+		p.applyWorkerIDLocked(workerID)
+		p.mu.Unlock()
+
+		if generator != nil {
+			if m := generator.activeMetrics(); m != nil {
+				m.RecordWorkerIDRecovery(true)
+			}
+		}
+		lynxlog.Infof("eon-id recovered from lost worker ID %d with worker ID %d", lostWorkerID, workerID)
+		workerManager.emit(EventWorkerReregistered, WorkerReregisteredPayload{
+			PreviousWorkerID: lostWorkerID,
+			SameWorkerID:     workerID == lostWorkerID,
+		})
+		p.emitRuntimeEvent(plugins.PluginEvent{
+			Type:     plugins.EventHealthStatusOK,
+			Priority: plugins.PriorityNormal,
+			Source:   "WorkerIDRecovery",
+			Category: "worker_id",
+			Metadata: map[string]any{"lost_worker_id": lostWorkerID, "worker_id": workerID, "attempts": attempt},
+		})
+		return
+	}
+
+	if maxAttempts == 0 {
+		lynxlog.Errorf("eon-id worker ID recovery is disabled, ID generation stays fenced")
+	} else {
+		lynxlog.Errorf("eon-id worker ID recovery gave up after %d attempts: %v", maxAttempts, lastErr)
+	}
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     plugins.EventHealthStatusCritical,
+		Priority: plugins.PriorityCritical,
+		Source:   "WorkerIDRecovery",
+		Category: "worker_id",
+		Metadata: map[string]any{"lost_worker_id": lostWorkerID, "attempts": maxAttempts, "reason": "recovery failed"},
+		Error:    lastErr,
+	})
+}
@@ -0,0 +1,134 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// newRecoveringPlugin starts a plugin that auto-registers its worker ID through allocator
+func newRecoveringPlugin(t *testing.T, allocator WorkerIDAllocator, conf *pb.EonId) (*PlugSnowflake, *WorkerIDManager) {
+	conf.AutoRegisterWorkerId = true
+	mgr := NewWorkerIDManagerWithAllocator(allocator, 1, &WorkerManagerConfig{TTL: time.Hour, HeartbeatInterval: time.Hour})
+	generator, err := NewSnowflakeGeneratorCore(1, 0, nil)
+	require.NoError(t, err)
+
+	plugin := NewSnowflakePlugin()
+	plugin.conf = conf
+	plugin.generator = generator
+	plugin.workerManager = mgr
+	mgr.SetLeaseLostHandler(plugin.onWorkerLeaseLost)
+	mgr.SetRevokedHandler(plugin.onWorkerRevoked)
+	mgr.SetEventHandler(plugin.events.publish)
+	generator.SetEventHandler(plugin.events.publish)
+	generator.SetLeaseCheck(mgr.LeaseExpired)
+	require.NoError(t, plugin.startupTasksContext(context.Background()))
+	t.Cleanup(func() { _ = plugin.cleanupTasksContext(context.Background()) })
+	return plugin, mgr
+}
+
+// stealWorkerID hands the manager's worker ID to another instance and lets re-registration discover it
+func stealWorkerID(t *testing.T, allocator WorkerIDAllocator, mgr *WorkerIDManager) int64 {
+	ctx := context.Background()
+	workerID := mgr.GetWorkerID()
+	workers, err := allocator.List(ctx)
+	require.NoError(t, err)
+	for _, w := range workers {
+		if w.WorkerID == workerID {
+			require.NoError(t, allocator.Release(ctx, w))
+			w.InstanceID = "other"
+			_, err = allocator.Acquire(ctx, w, workerID, time.Hour)
+			require.NoError(t, err)
+		}
+	}
+
+	var lost *WorkerLeaseLostError
+	require.True(t, errors.As(mgr.tryReRegister(ctx), &lost))
+	return workerID
+}
+
+func TestWorkerIDRecovery_NewWorkerID(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:          5,
+		WorkerRecoveryBackoff: durationpb.New(time.Millisecond),
+	})
+	_, err := plugin.GenerateID()
+	require.NoError(t, err)
+
+	lostWorkerID := stealWorkerID(t, allocator, mgr)
+	require.Eventually(t, func() bool { return mgr.IsRegistered() && !plugin.generator.IsFenced() },
+		2*time.Second, 5*time.Millisecond)
+
+	newWorkerID := mgr.GetWorkerID()
+	assert.NotEqual(t, lostWorkerID, newWorkerID)
+	_, sid, err := plugin.GenerateIDWithMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, newWorkerID, sid.WorkerID)
+	assert.True(t, plugin.Readiness().OK)
+
+	snapshot := plugin.generator.GetMetrics().GetSnapshot()
+	assert.Equal(t, int64(1), snapshot.WorkerIDRecoveries)
+	assert.Zero(t, snapshot.WorkerIDRecoveryFailures)
+}
+
+func TestWorkerIDRecovery_GivesUp(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	// One worker ID bit: worker IDs 0 and 1, and 1 belongs to another instance
+	_, err := allocator.Acquire(context.Background(), WorkerInfo{WorkerID: 1, DatacenterID: 1, InstanceID: "neighbour"}, 1, time.Hour)
+	require.NoError(t, err)
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:              1,
+		WorkerRecoveryMaxAttempts: 3,
+		WorkerRecoveryBackoff:     durationpb.New(time.Millisecond),
+	})
+	require.Equal(t, int64(0), mgr.GetWorkerID())
+
+	stealWorkerID(t, allocator, mgr)
+	require.Eventually(t, func() bool {
+		return plugin.generator.GetMetrics().GetSnapshot().WorkerIDRecoveryFailures == 3
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// The generator stays fenced, even for callers that bypass the worker manager check
+	var fenced *WorkerIDFencedError
+	_, err = plugin.generator.GenerateID()
+	require.True(t, errors.As(err, &fenced), "got %v", err)
+	assert.Equal(t, int64(0), fenced.WorkerID)
+	_, err = plugin.GenerateID()
+	assert.Error(t, err)
+	assert.False(t, plugin.Readiness().OK)
+	assert.False(t, mgr.IsRegistered())
+	assert.Zero(t, plugin.generator.GetMetrics().GetSnapshot().WorkerIDRecoveries)
+}
+
+func TestWorkerIDRecovery_Disabled(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{WorkerIdBits: 5, WorkerRecoveryMaxAttempts: -1})
+
+	stealWorkerID(t, allocator, mgr)
+	// Fencing happens before the lease-lost handler returns
+	assert.True(t, plugin.generator.IsFenced())
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, mgr.IsRegistered())
+	assert.True(t, plugin.generator.IsFenced())
+}
+
+func TestWorkerRecoveryPolicy(t *testing.T) {
+	attempts, backoff := workerRecoveryPolicy(&pb.EonId{})
+	assert.Equal(t, DefaultWorkerRecoveryMaxAttempts, attempts)
+	assert.Equal(t, DefaultWorkerRecoveryBackoff, backoff)
+
+	attempts, backoff = workerRecoveryPolicy(&pb.EonId{WorkerRecoveryMaxAttempts: 4, WorkerRecoveryBackoff: durationpb.New(time.Minute)})
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, time.Minute, backoff)
+
+	attempts, _ = workerRecoveryPolicy(&pb.EonId{WorkerRecoveryMaxAttempts: -1})
+	assert.Zero(t, attempts)
+}
@@ -0,0 +1,118 @@
+package eonId
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	lynxlog "github.com/go-lynx/lynx/log"
+	"github.com/go-lynx/lynx/plugins"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+// workerAuditLogger records administrative actions on worker IDs
+var workerAuditLogger = &AuditLogger{}
+
+// auditWorkerAction writes an administrative action on a worker ID to the audit log
+func auditWorkerAction(action, clientIP string, datacenterID, workerID int64, result, details string) {
+	workerAuditLogger.Log(&AuditEvent{
+		Timestamp: time.Now(),
+		ClientIP:  clientIP,
+		UserAgent: PluginName + "/" + PluginVersion,
+		Action:    action,
+		Resource:  fmt.Sprintf("datacenter %d worker ID %d", datacenterID, workerID),
+		Result:    result,
+		Details:   details,
+	})
+}
+
+// revocationDetails describes a revocation for the audit log; holder is the revoked instance, if known
+func revocationDetails(revocation WorkerRevocation, holder string) string {
+	details := "by " + revocation.By
+	if holder != "" {
+		details += ", held by " + holder
+	}
+	if revocation.Reason != "" {
+		details += ", reason: " + revocation.Reason
+	}
+	return details
+}
+
+// workerRevokeAction returns what the plugin does after its worker ID has been revoked
+func workerRevokeAction(conf *pb.EonId) string {
+	if conf == nil || conf.WorkerRevokeAction == "" {
+		return WorkerRevokeActionReregister
+	}
+	return conf.WorkerRevokeAction
+}
+
+// RevokeWorkerID revokes the claim on a worker ID in the plugin's datacenter, whichever instance holds it; see
+// WorkerIDManager.RevokeWorkerID. It emits an audit event.
+func (p *PlugSnowflake) RevokeWorkerID(ctx context.Context, workerID int64, reason string) (*WorkerInfo, error) {
+	p.mu.RLock()
+	workerManager := p.workerManager
+	p.mu.RUnlock()
+	if workerManager == nil {
+		return nil, fmt.Errorf("eon-id worker manager not initialized")
+	}
+
+	info, err := workerManager.RevokeWorkerID(ctx, workerID, reason)
+	metadata := map[string]any{"action": "revoke", "worker_id": workerID, "reason": reason}
+	if info != nil {
+		metadata["instance_id"] = info.InstanceID
+	}
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     plugins.EventHealthStatusWarning,
+		Priority: plugins.PriorityNormal,
+		Source:   "WorkerIDRevocation",
+		Category: "audit",
+		Metadata: metadata,
+		Error:    err,
+	})
+	return info, err
+}
+
+// onWorkerRevoked is the WorkerIDManager revoked handler. It fences the generator before returning, since the
+// manager releases the claim right after, records the revocation and, unless worker_revoke_action is "fence",
+// registers a new worker ID like after a lost lease.
+func (p *PlugSnowflake) onWorkerRevoked(revoked *WorkerRevokedError) {
+	p.mu.RLock()
+	generator := p.generator
+	action := workerRevokeAction(p.conf)
+	workerManager := p.workerManager
+	p.mu.RUnlock()
+	if generator != nil {
+		generator.Fence()
+	}
+
+	clientIP := ""
+	if workerManager != nil {
+		clientIP = workerManager.localIP
+	}
+	auditWorkerAction("worker_id.revoked", clientIP, revoked.DatacenterID, revoked.WorkerID,
+		"fenced, then "+action, revocationDetails(revoked.Revocation, ""))
+	lynxlog.Errorf("eon-id %v, ID generation fenced (worker_revoke_action: %s)", revoked, action)
+	p.emitRuntimeEvent(plugins.PluginEvent{
+		Type:     plugins.EventHealthStatusCritical,
+		Priority: plugins.PriorityHigh,
+		Source:   "WorkerIDRevocation",
+		Category: "audit",
+		Metadata: map[string]any{
+			"action":        "revoked",
+			"worker_id":     revoked.WorkerID,
+			"revoked_by":    revoked.Revocation.By,
+			"revoked_at":    revoked.Revocation.At,
+			"reason":        revoked.Revocation.Reason,
+			"revoke_action": action,
+		},
+	})
+
+	if action == WorkerRevokeActionFence {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&p.recovering, 0, 1) {
+		go p.recoverWorkerID(revoked.WorkerID)
+	}
+}
@@ -0,0 +1,94 @@
+package eonId
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/go-lynx/lynx-eon-id/conf"
+)
+
+func TestWorkerRevoke_Reregisters(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:          5,
+		WorkerRecoveryBackoff: durationpb.New(time.Millisecond),
+	})
+	generator := plugin.generator
+	mgr.SetLastTimestampSource(func() int64 { return generator.GetStats().LastGeneratedTime })
+	_, err := plugin.GenerateID()
+	require.NoError(t, err)
+	revokedWorkerID := mgr.GetWorkerID()
+
+	// An operator revokes the worker ID through another manager; the owner keeps issuing until its next heartbeat
+	admin := NewWorkerIDManagerWithAllocator(allocator, 1, nil)
+	info, err := admin.RevokeWorkerID(context.Background(), revokedWorkerID, "wedged")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, "wedged", info.Revocation.Reason)
+	_, err = plugin.GenerateID()
+	require.NoError(t, err)
+	last := generator.GetStats().LastGeneratedTime
+
+	var revoked *WorkerRevokedError
+	require.True(t, errors.As(mgr.sendHeartbeat(), &revoked))
+	assert.Equal(t, revokedWorkerID, revoked.WorkerID)
+	require.Eventually(t, func() bool { return mgr.IsRegistered() && !plugin.generator.IsFenced() },
+		2*time.Second, 5*time.Millisecond)
+	assert.NotEqual(t, revokedWorkerID, mgr.GetWorkerID())
+	_, sid, err := plugin.GenerateIDWithMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, mgr.GetWorkerID(), sid.WorkerID)
+
+	// The revoked claim was released with the last timestamp issued under it
+	workers, err := allocator.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	assert.Equal(t, mgr.GetWorkerID(), workers[0].WorkerID)
+	assert.Nil(t, workers[0].Revocation)
+	assert.GreaterOrEqual(t, allocator.lastTimestamp[workerClaimKey{1, revokedWorkerID}], last)
+}
+
+func TestWorkerRevoke_Fence(t *testing.T) {
+	allocator := NewMemoryWorkerIDAllocator()
+	plugin, mgr := newRecoveringPlugin(t, allocator, &pb.EonId{
+		WorkerIdBits:       5,
+		WorkerRevokeAction: WorkerRevokeActionFence,
+	})
+	revokedWorkerID := mgr.GetWorkerID()
+
+	info, err := plugin.RevokeWorkerID(context.Background(), revokedWorkerID, "")
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	var revoked *WorkerRevokedError
+	require.True(t, errors.As(mgr.sendHeartbeat(), &revoked))
+
+	// Fencing happens before the revoked handler returns, and nothing registers a new worker ID
+	var fenced *WorkerIDFencedError
+	_, err = plugin.generator.GenerateID()
+	require.True(t, errors.As(err, &fenced), "got %v", err)
+	_, err = plugin.GenerateID()
+	assert.Error(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, mgr.IsRegistered())
+	assert.False(t, plugin.Readiness().OK)
+	workers, err := allocator.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, workers)
+
+	// A free worker ID is not revoked
+	info, err = plugin.RevokeWorkerID(context.Background(), revokedWorkerID, "")
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func TestWorkerIDManager_RevokeWithoutSupport(t *testing.T) {
+	mgr := NewWorkerIDManagerWithAllocator(&StatefulSetWorkerIDAllocator{}, 1, nil)
+	_, err := mgr.RevokeWorkerID(context.Background(), 0, "")
+	assert.Error(t, err)
+}